@@ -0,0 +1,173 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	propelauth "github.com/propelauth/propelauth-go/pkg"
+	"github.com/propelauth/propelauth-go/pkg/models"
+)
+
+// apiKeysList prints every API key of a user or org, or of the whole project.
+func apiKeysList(o *cli, args []string) error {
+	flags := flag.NewFlagSet("apikeys list", flag.ContinueOnError)
+	orgID := flags.String("org-id", "", "only the keys of this org")
+	userID := flags.String("user-id", "", "only the keys of this user")
+	userEmail := flags.String("user-email", "", "only the keys of the user with this email")
+	archived := flags.Bool("archived", false, "list the archived keys instead of the current ones")
+	if _, err := o.parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	params := models.APIKeysQueryParams{}
+	var err error
+	if params.OrgID, err = optionalUUID("org ID", *orgID); err != nil {
+		return err
+	}
+	if params.UserID, err = optionalUUID("user ID", *userID); err != nil {
+		return err
+	}
+	if *userEmail != "" {
+		params.UserEmail = userEmail
+	}
+
+	client, err := o.connect()
+	if err != nil {
+		return err
+	}
+
+	iterate := propelauth.IterateCurrentAPIKeys
+	if *archived {
+		iterate = propelauth.IterateArchivedAPIKeys
+	}
+
+	apiKeys, err := iterate(client, params).All(o.limit)
+	if err != nil {
+		return err
+	}
+
+	result := table{headers: []string{"API KEY ID", "NAME", "USER ID", "ORG ID", "CREATED", "EXPIRES"}}
+	for _, apiKey := range apiKeys {
+		result.rows = append(result.rows, []string{
+			apiKey.APIKeyId, optional(apiKey.DisplayName), nilUUID(apiKey.UserID), nilUUID(apiKey.OrgID),
+			unixTime(int64(apiKey.CreatedAt)), unixTime(int64(apiKey.ExpiresAtSeconds)),
+		})
+	}
+
+	return o.print(apiKeys, result)
+}
+
+// apiKeysCreate creates an API key, and prints its token, which can't be fetched again.
+func apiKeysCreate(o *cli, args []string) error {
+	flags := flag.NewFlagSet("apikeys create", flag.ContinueOnError)
+	orgID := flags.String("org-id", "", "the org the key belongs to")
+	userID := flags.String("user-id", "", "the user the key belongs to")
+	name := flags.String("name", "", "a display name for the key")
+	expiresIn := flags.Duration("expires-in", 0, "how long until the key expires, like 720h, or 0 to never expire")
+	if _, err := o.parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	params := models.APIKeyCreateParams{}
+	var err error
+	if params.OrgID, err = optionalUUID("org ID", *orgID); err != nil {
+		return err
+	}
+	if params.UserID, err = optionalUUID("user ID", *userID); err != nil {
+		return err
+	}
+	if *name != "" {
+		params.DisplayName = name
+	}
+	if *expiresIn > 0 {
+		expiresAtSeconds := int(time.Now().Add(*expiresIn).Unix())
+		params.ExpiresAtSeconds = &expiresAtSeconds
+	}
+
+	client, err := o.connect()
+	if err != nil {
+		return err
+	}
+
+	apiKey, err := client.CreateAPIKey(params)
+	if err != nil {
+		return err
+	}
+
+	return o.print(apiKey, fields([]string{"API key ID", "Token"}, apiKey.APIKeyID, apiKey.APIKeyToken))
+}
+
+// apiKeysRevoke deletes an API key, so it can't be used anymore.
+func apiKeysRevoke(o *cli, args []string) error {
+	flags := flag.NewFlagSet("apikeys revoke", flag.ContinueOnError)
+	args, err := o.parseFlags(flags, args, "API key ID")
+	if err != nil {
+		return err
+	}
+
+	client, err := o.connect()
+	if err != nil {
+		return err
+	}
+
+	if _, err := client.DeleteAPIKey(args[0]); err != nil {
+		return err
+	}
+
+	return o.printDone(fmt.Sprintf("Revoked API key %s", args[0]), map[string]interface{}{"api_key_id": args[0], "success": true})
+}
+
+// apiKeysValidate prints who an API key token belongs to, or fails if it's not valid.
+func apiKeysValidate(o *cli, args []string) error {
+	flags := flag.NewFlagSet("apikeys validate", flag.ContinueOnError)
+	args, err := o.parseFlags(flags, args, "API key token")
+	if err != nil {
+		return err
+	}
+
+	client, err := o.connect()
+	if err != nil {
+		return err
+	}
+
+	validation, err := client.ValidateAPIKey(args[0])
+	if err != nil {
+		return err
+	}
+
+	result := fields([]string{"Valid"}, "true")
+	if validation.User != nil {
+		result.rows = append(result.rows, []string{"User ID", validation.User.UserID.String()}, []string{"Email", validation.User.Email})
+	}
+	if validation.Org != nil {
+		result.rows = append(result.rows, []string{"Org ID", validation.Org.OrgID.String()}, []string{"Org name", validation.Org.OrgName})
+	}
+	if validation.UserInOrg != nil {
+		result.rows = append(result.rows, []string{"Role", validation.UserInOrg.UserAssignedRole})
+	}
+
+	return o.print(validation, result)
+}
+
+func optionalUUID(name string, value string) (*uuid.UUID, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	parsed, err := uuid.Parse(value)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid %s: %w", name, err)
+	}
+
+	return &parsed, nil
+}
+
+// nilUUID prints the zero UUID, which the backend uses for a key with no user or org, as nothing.
+func nilUUID(value uuid.UUID) string {
+	if value == uuid.Nil {
+		return ""
+	}
+	return value.String()
+}
@@ -0,0 +1,261 @@
+// Command propelauth administers the users, orgs and API keys of a PropelAuth project from the command line.
+//
+// Usage:
+//
+//	propelauth [global flags] <command> <subcommand> [flags] [arguments]
+//
+// The commands are:
+//
+//	users get|search|create|disable|logout
+//	orgs list|create|members|invite
+//	apikeys list|create|revoke|validate
+//	token decode|verify
+//
+// The auth URL and integration API key are read from the -auth-url and -api-key flags, then the PROPELAUTH_AUTH_URL
+// and PROPELAUTH_API_KEY environment variables, then a JSON config file like:
+//
+//	{"auth_url": "https://auth.example.com", "api_key": "..."}
+//
+// at -config, PROPELAUTH_CONFIG, or propelauth/config.json in your user config directory. Results are printed as a
+// table, or as JSON with -output json. Lists are fetched page by page until they run out, or until -limit items.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	propelauth "github.com/propelauth/propelauth-go/pkg"
+)
+
+// config is how to connect to PropelAuth.
+type config struct {
+	AuthURL string `json:"auth_url"`
+	APIKey  string `json:"api_key"`
+}
+
+// command runs a subcommand with the arguments after its name.
+type command func(o *cli, args []string) error
+
+var commands = map[string]map[string]command{
+	"users": {
+		"get":     usersGet,
+		"search":  usersSearch,
+		"create":  usersCreate,
+		"disable": usersDisable,
+		"logout":  usersLogout,
+	},
+	"orgs": {
+		"list":    orgsList,
+		"create":  orgsCreate,
+		"members": orgsMembers,
+		"invite":  orgsInvite,
+	},
+	"apikeys": {
+		"list":     apiKeysList,
+		"create":   apiKeysCreate,
+		"revoke":   apiKeysRevoke,
+		"validate": apiKeysValidate,
+	},
+	"token": {
+		"decode": tokenDecode,
+		"verify": tokenVerify,
+	},
+}
+
+// errUsage is returned when the command line is wrong, after the usage has been printed.
+var errUsage = errors.New("usage")
+
+// cli is one run of the command.
+type cli struct {
+	stdout io.Writer
+	stderr io.Writer
+	getenv func(string) string
+	// newClient connects to PropelAuth, it's replaced in tests.
+	newClient func(config config) (propelauth.ClientInterface, error)
+
+	config config
+	output string
+	limit  int
+	client propelauth.ClientInterface
+}
+
+func main() {
+	o := &cli{
+		stdout:    os.Stdout,
+		stderr:    os.Stderr,
+		getenv:    os.Getenv,
+		newClient: initClient,
+	}
+
+	os.Exit(o.run(os.Args[1:]))
+}
+
+func initClient(config config) (propelauth.ClientInterface, error) {
+	return propelauth.InitBaseAuth(config.AuthURL, config.APIKey, nil)
+}
+
+// run runs the command line in args, and returns the exit code.
+func (o *cli) run(args []string) int {
+	flags := flag.NewFlagSet("propelauth", flag.ContinueOnError)
+	flags.SetOutput(o.stderr)
+	flags.Usage = func() { o.usage(flags) }
+
+	authURL := flags.String("auth-url", "", "the auth URL of your project, like https://auth.example.com")
+	apiKey := flags.String("api-key", "", "the integration API key of your project")
+	configPath := flags.String("config", "", "a JSON config file with auth_url and api_key")
+	flags.StringVar(&o.output, "output", "table", "how to print results, table or json")
+	flags.IntVar(&o.limit, "limit", 0, "the most items a list prints, or 0 for all of them")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if o.output != "table" && o.output != "json" {
+		fmt.Fprintf(o.stderr, "-output must be table or json, got %s\n", o.output)
+		return 2
+	}
+
+	args = flags.Args()
+	if len(args) < 2 {
+		o.usage(flags)
+		return 2
+	}
+	run, ok := commands[args[0]][args[1]]
+	if !ok {
+		fmt.Fprintf(o.stderr, "Unknown command: %s\n", strings.Join(args[:2], " "))
+		o.usage(flags)
+		return 2
+	}
+
+	config, err := o.loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintln(o.stderr, err)
+		return 1
+	}
+	if *authURL != "" {
+		config.AuthURL = *authURL
+	}
+	if *apiKey != "" {
+		config.APIKey = *apiKey
+	}
+	o.config = config
+
+	if err := run(o, args[2:]); errors.Is(err, errUsage) {
+		return 2
+	} else if err != nil {
+		fmt.Fprintln(o.stderr, err)
+		return 1
+	}
+
+	return 0
+}
+
+func (o *cli) usage(flags *flag.FlagSet) {
+	fmt.Fprintln(o.stderr, "Usage: propelauth [global flags] <command> <subcommand> [flags] [arguments]")
+	fmt.Fprintln(o.stderr)
+	fmt.Fprintln(o.stderr, "Commands:")
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		subcommands := make([]string, 0, len(commands[name]))
+		for subcommand := range commands[name] {
+			subcommands = append(subcommands, subcommand)
+		}
+		sort.Strings(subcommands)
+		fmt.Fprintf(o.stderr, "  %s %s\n", name, strings.Join(subcommands, "|"))
+	}
+
+	fmt.Fprintln(o.stderr)
+	fmt.Fprintln(o.stderr, "Global flags:")
+	flags.PrintDefaults()
+}
+
+// loadConfig reads the config file, then overrides it with the environment.
+func (o *cli) loadConfig(path string) (config, error) {
+	loaded := config{}
+
+	required := path != ""
+	if path == "" {
+		path = o.getenv("PROPELAUTH_CONFIG")
+		required = path != ""
+	}
+	if path == "" {
+		if configDir, err := os.UserConfigDir(); err == nil {
+			path = filepath.Join(configDir, "propelauth", "config.json")
+		}
+	}
+
+	if path != "" {
+		contents, err := os.ReadFile(path)
+		if err != nil && (required || !errors.Is(err, os.ErrNotExist)) {
+			return loaded, fmt.Errorf("Error on reading the config file: %w", err)
+		}
+		if err == nil {
+			if err := json.Unmarshal(contents, &loaded); err != nil {
+				return loaded, fmt.Errorf("Error on unmarshalling the config file: %w", err)
+			}
+		}
+	}
+
+	if authURL := o.getenv("PROPELAUTH_AUTH_URL"); authURL != "" {
+		loaded.AuthURL = authURL
+	}
+	if apiKey := o.getenv("PROPELAUTH_API_KEY"); apiKey != "" {
+		loaded.APIKey = apiKey
+	}
+
+	return loaded, nil
+}
+
+// connect returns the client, connecting the first time it's needed, so commands like token decode work without
+// credentials.
+func (o *cli) connect() (propelauth.ClientInterface, error) {
+	if o.client != nil {
+		return o.client, nil
+	}
+
+	if o.config.AuthURL == "" || o.config.APIKey == "" {
+		return nil, errors.New("An auth URL and API key are needed, set PROPELAUTH_AUTH_URL and PROPELAUTH_API_KEY, or use -auth-url and -api-key")
+	}
+
+	client, err := o.newClient(o.config)
+	if err != nil {
+		return nil, fmt.Errorf("Error on connecting to PropelAuth: %w", err)
+	}
+	o.client = client
+
+	return client, nil
+}
+
+// parseFlags parses the flags of a subcommand, and checks there's one argument after them for each of argNames.
+func (o *cli) parseFlags(flags *flag.FlagSet, args []string, argNames ...string) ([]string, error) {
+	flags.SetOutput(o.stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(o.stderr, "Usage: propelauth %s [flags]", flags.Name())
+		for _, argName := range argNames {
+			fmt.Fprintf(o.stderr, " <%s>", argName)
+		}
+		fmt.Fprintln(o.stderr)
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return nil, errUsage
+	}
+	if flags.NArg() != len(argNames) {
+		flags.Usage()
+		return nil, errUsage
+	}
+
+	return flags.Args(), nil
+}
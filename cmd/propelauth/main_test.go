@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	propelauth "github.com/propelauth/propelauth-go/pkg"
+	"github.com/propelauth/propelauth-go/pkg/models"
+	testHelpers "github.com/propelauth/propelauth-go/pkg/test"
+)
+
+func TestCLI(t *testing.T) {
+	// setup a fake, and a way to run the command against it
+
+	fake := testHelpers.NewFakeClient()
+
+	// keep the user config directory from being read
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	env := map[string]string{"PROPELAUTH_AUTH_URL": "https://auth.example.com", "PROPELAUTH_API_KEY": "key"}
+
+	run := func(args ...string) (int, string, string) {
+		stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+		o := &cli{
+			stdout: stdout,
+			stderr: stderr,
+			getenv: func(name string) string { return env[name] },
+			newClient: func(config config) (propelauth.ClientInterface, error) {
+				if config.APIKey != "key" {
+					return nil, fmt.Errorf("wrong API key %s", config.APIKey)
+				}
+				return fake, nil
+			},
+		}
+		code := o.run(args)
+		return code, stdout.String(), stderr.String()
+	}
+
+	// run tests
+
+	t.Run("users are created, fetched and searched", func(t *testing.T) {
+		code, _, stderr := run("users", "create", "-first-name", "Ada", "ada@example.com")
+		if code != 0 {
+			t.Fatalf("expected users create to succeed, got %d: %s", code, stderr)
+		}
+
+		code, stdout, _ := run("-output", "json", "users", "get", "ada@example.com")
+		user := models.UserMetadata{}
+		if err := json.Unmarshal([]byte(stdout), &user); code != 0 || err != nil || *user.FirstName != "Ada" {
+			t.Fatalf("expected the user as JSON, got %d: %s", code, stdout)
+		}
+
+		code, stdout, _ = run("users", "get", user.UserID.String())
+		if code != 0 || !strings.Contains(stdout, "ada@example.com") {
+			t.Errorf("expected the user as a table, got %d: %s", code, stdout)
+		}
+
+		for i := 0; i < 5; i++ {
+			if _, err := fake.CreateUser(models.CreateUserParams{Email: fmt.Sprintf("user%d@example.com", i)}); err != nil {
+				t.Fatalf("CreateUser returned an error: %s", err)
+			}
+		}
+
+		code, stdout, _ = run("-output", "json", "-limit", "4", "users", "search", "-query", "example.com")
+		users := []models.UserMetadata{}
+		if err := json.Unmarshal([]byte(stdout), &users); code != 0 || err != nil || len(users) != 4 {
+			t.Errorf("expected 4 users, got %d: %s", code, stdout)
+		}
+
+		code, stdout, _ = run("users", "search")
+		if lines := strings.Split(strings.TrimSpace(stdout), "\n"); code != 0 || len(lines) != 7 || !strings.HasPrefix(lines[0], "USER ID") {
+			t.Errorf("expected a header and 6 users, got %d: %s", code, stdout)
+		}
+
+		if code, _, _ := run("users", "disable", user.UserID.String()); code != 0 {
+			t.Errorf("expected users disable to succeed, got %d", code)
+		}
+		if disabled, _ := fake.FetchUserMetadataByUserID(user.UserID, false); disabled.Enabled {
+			t.Errorf("expected the user to be disabled")
+		}
+	})
+
+	t.Run("orgs are created, and their members listed", func(t *testing.T) {
+		code, stdout, _ := run("-output", "json", "orgs", "create", "Acme")
+		org := models.OrgMetadata{}
+		if err := json.Unmarshal([]byte(stdout), &org); code != 0 || err != nil || org.Name != "Acme" {
+			t.Fatalf("expected the org as JSON, got %d: %s", code, stdout)
+		}
+
+		user, _ := fake.FetchUserMetadataByEmail("user0@example.com", false)
+		if _, err := fake.AddUserToOrg(models.AddUserToOrg{UserID: user.UserID, OrgID: org.OrgID, Role: "Admin"}); err != nil {
+			t.Fatalf("AddUserToOrg returned an error: %s", err)
+		}
+
+		code, stdout, _ = run("orgs", "members", org.OrgID.String())
+		if code != 0 || !strings.Contains(stdout, "user0@example.com") || !strings.Contains(stdout, "Admin") {
+			t.Errorf("expected the member and their role, got %d: %s", code, stdout)
+		}
+
+		if code, _, stderr := run("orgs", "invite", "-role", "Member", org.OrgID.String(), "new@example.com"); code != 0 {
+			t.Errorf("expected orgs invite to succeed, got %d: %s", code, stderr)
+		}
+		invites, _ := fake.FetchPendingInvites(models.FetchPendingInvitesParams{OrgID: &org.OrgID})
+		if len(invites.Invites) != 1 {
+			t.Errorf("expected an invite, got %+v", invites)
+		}
+	})
+
+	t.Run("API keys are created, validated and revoked", func(t *testing.T) {
+		user, _ := fake.FetchUserMetadataByEmail("user1@example.com", false)
+
+		code, stdout, _ := run("-output", "json", "apikeys", "create", "-user-id", user.UserID.String(), "-expires-in", "1h")
+		apiKey := models.APIKeyNew{}
+		if err := json.Unmarshal([]byte(stdout), &apiKey); code != 0 || err != nil || apiKey.APIKeyToken == "" {
+			t.Fatalf("expected the new key, got %d: %s", code, stdout)
+		}
+
+		code, stdout, _ = run("apikeys", "validate", apiKey.APIKeyToken)
+		if code != 0 || !strings.Contains(stdout, "user1@example.com") {
+			t.Errorf("expected the key's user, got %d: %s", code, stdout)
+		}
+
+		code, stdout, _ = run("apikeys", "list", "-user-id", user.UserID.String())
+		if code != 0 || !strings.Contains(stdout, apiKey.APIKeyID) {
+			t.Errorf("expected the key in the list, got %d: %s", code, stdout)
+		}
+
+		if code, _, _ := run("apikeys", "revoke", apiKey.APIKeyID); code != 0 {
+			t.Errorf("expected apikeys revoke to succeed, got %d", code)
+		}
+		if code, _, _ := run("apikeys", "validate", apiKey.APIKeyToken); code != 1 {
+			t.Errorf("expected the revoked key to be invalid, got %d", code)
+		}
+	})
+
+	t.Run("tokens are decoded without credentials, and verified with them", func(t *testing.T) {
+		user, _ := fake.FetchUserMetadataByEmail("user2@example.com", false)
+		accessToken, err := fake.CreateAccessToken(user.UserID, 10)
+		if err != nil {
+			t.Fatalf("CreateAccessToken returned an error: %s", err)
+		}
+
+		delete(env, "PROPELAUTH_API_KEY")
+		code, stdout, stderr := run("token", "decode", "Bearer "+accessToken.AccessToken)
+		if code != 0 || !strings.Contains(stdout, "header.alg") || !strings.Contains(stdout, user.UserID.String()) {
+			t.Errorf("expected the decoded claims, got %d: %s%s", code, stdout, stderr)
+		}
+
+		if code, _, stderr := run("token", "verify", accessToken.AccessToken); code != 1 || !strings.Contains(stderr, "PROPELAUTH_API_KEY") {
+			t.Errorf("expected verifying to need credentials, got %d: %s", code, stderr)
+		}
+		env["PROPELAUTH_API_KEY"] = "key"
+
+		code, stdout, _ = run("token", "verify", accessToken.AccessToken)
+		if code != 0 || !strings.Contains(stdout, "user2@example.com") {
+			t.Errorf("expected the verified user, got %d: %s", code, stdout)
+		}
+
+		if code, _, _ := run("token", "verify", "not.a.token"); code != 1 {
+			t.Errorf("expected an invalid token to fail, got %d", code)
+		}
+
+		org, _ := fake.CreateOrg("Globex")
+		if _, err := fake.AddUserToOrg(models.AddUserToOrg{UserID: user.UserID, OrgID: org.OrgID, Role: "Member"}); err != nil {
+			t.Fatalf("AddUserToOrg returned an error: %s", err)
+		}
+		withOrg, err := fake.CreateAccessToken(user.UserID, 10, models.CreateAccessTokenOptions{ActiveOrgId: &org.OrgID})
+		if err != nil {
+			t.Fatalf("CreateAccessToken returned an error: %s", err)
+		}
+
+		code, stdout, _ = run("token", "verify", withOrg.AccessToken)
+		if code != 0 || !strings.Contains(stdout, "Active org") || !strings.Contains(stdout, "Globex ("+org.OrgID.String()+") as Member") {
+			t.Errorf("expected the active org, got %d: %s", code, stdout)
+		}
+	})
+
+	t.Run("credentials are read from the config file", func(t *testing.T) {
+		configPath := filepath.Join(t.TempDir(), "config.json")
+		if err := os.WriteFile(configPath, []byte(`{"auth_url": "https://auth.example.com", "api_key": "key"}`), 0o600); err != nil {
+			t.Fatalf("couldn't write the config file: %s", err)
+		}
+
+		delete(env, "PROPELAUTH_API_KEY")
+		defer func() { env["PROPELAUTH_API_KEY"] = "key" }()
+
+		if code, _, stderr := run("-config", configPath, "orgs", "list"); code != 0 {
+			t.Errorf("expected the config file to be used, got %d: %s", code, stderr)
+		}
+		if code, _, stderr := run("-config", configPath, "-api-key", "other", "orgs", "list"); code != 1 || !strings.Contains(stderr, "wrong API key other") {
+			t.Errorf("expected the flag to override the config file, got %d: %s", code, stderr)
+		}
+	})
+
+	t.Run("bad command lines print the usage", func(t *testing.T) {
+		for _, args := range [][]string{{"users"}, {"users", "delete"}, {"users", "get"}, {"-output", "yaml", "orgs", "list"}} {
+			if code, _, stderr := run(args...); code != 2 || stderr == "" {
+				t.Errorf("expected a usage error for %v, got %d: %s", args, code, stderr)
+			}
+		}
+	})
+}
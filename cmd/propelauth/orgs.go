@@ -0,0 +1,148 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	propelauth "github.com/propelauth/propelauth-go/pkg"
+	"github.com/propelauth/propelauth-go/pkg/models"
+)
+
+// orgsList prints every org matching the query.
+func orgsList(o *cli, args []string) error {
+	flags := flag.NewFlagSet("orgs list", flag.ContinueOnError)
+	name := flags.String("name", "", "only orgs whose name contains this")
+	domain := flags.String("domain", "", "only orgs with this domain")
+	if _, err := o.parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	client, err := o.connect()
+	if err != nil {
+		return err
+	}
+
+	params := models.OrgQueryParams{}
+	if *name != "" {
+		params.Name = name
+	}
+	if *domain != "" {
+		params.Domain = domain
+	}
+
+	orgs, err := propelauth.IterateOrgsByQuery(client, params).All(o.limit)
+	if err != nil {
+		return err
+	}
+
+	result := table{headers: []string{"ORG ID", "NAME", "SAML", "CREATED"}}
+	for _, org := range orgs {
+		created := ""
+		if org.CreatedAt != nil {
+			created = unixTime(int64(*org.CreatedAt))
+		}
+		result.rows = append(result.rows, []string{org.OrgID.String(), org.Name, fmt.Sprint(org.IsSamlConfigured), created})
+	}
+
+	return o.print(orgs, result)
+}
+
+// orgsCreate creates an org, and prints its ID.
+func orgsCreate(o *cli, args []string) error {
+	flags := flag.NewFlagSet("orgs create", flag.ContinueOnError)
+	args, err := o.parseFlags(flags, args, "name")
+	if err != nil {
+		return err
+	}
+
+	client, err := o.connect()
+	if err != nil {
+		return err
+	}
+
+	org, err := client.CreateOrg(args[0])
+	if err != nil {
+		return err
+	}
+
+	return o.print(org, fields([]string{"Org ID", "Name"}, org.OrgID.String(), org.Name))
+}
+
+// orgsMembers prints every member of an org, with their role.
+func orgsMembers(o *cli, args []string) error {
+	flags := flag.NewFlagSet("orgs members", flag.ContinueOnError)
+	role := flags.String("role", "", "only members with this role")
+	args, err := o.parseFlags(flags, args, "org ID")
+	if err != nil {
+		return err
+	}
+
+	orgID, err := uuid.Parse(args[0])
+	if err != nil {
+		return fmt.Errorf("Invalid org ID: %w", err)
+	}
+
+	client, err := o.connect()
+	if err != nil {
+		return err
+	}
+
+	includeOrgs := true
+	params := models.UserInOrgQueryParams{IncludeOrgs: &includeOrgs}
+	if *role != "" {
+		params.Role = role
+	}
+
+	members, err := propelauth.IterateUsersInOrg(client, orgID, params).All(o.limit)
+	if err != nil {
+		return err
+	}
+
+	result := table{headers: append(append([]string{}, userHeaders...), "ROLE")}
+	for _, member := range members {
+		roles := ""
+		if member.OrgIDToOrgInfo != nil {
+			orgInfo := (*member.OrgIDToOrgInfo)[orgID]
+			roles = strings.Join(append([]string{orgInfo.UserRole}, orgInfo.AdditionalRoles...), ", ")
+		}
+		result.rows = append(result.rows, append(userRow(member), roles))
+	}
+
+	return o.print(members, result)
+}
+
+// orgsInvite invites an email to an org.
+func orgsInvite(o *cli, args []string) error {
+	flags := flag.NewFlagSet("orgs invite", flag.ContinueOnError)
+	role := flags.String("role", "Member", "the role the user gets when they accept")
+	additionalRoles := flags.String("additional-roles", "", "a comma separated list of other roles the user gets")
+	args, err := o.parseFlags(flags, args, "org ID", "email")
+	if err != nil {
+		return err
+	}
+
+	orgID, err := uuid.Parse(args[0])
+	if err != nil {
+		return fmt.Errorf("Invalid org ID: %w", err)
+	}
+
+	client, err := o.connect()
+	if err != nil {
+		return err
+	}
+
+	params := models.InviteUserToOrg{Email: args[1], OrgID: orgID, Role: *role}
+	if *additionalRoles != "" {
+		for _, additionalRole := range strings.Split(*additionalRoles, ",") {
+			params.AdditionalRoles = append(params.AdditionalRoles, strings.TrimSpace(additionalRole))
+		}
+	}
+
+	if _, err := client.InviteUserToOrg(params); err != nil {
+		return err
+	}
+
+	return o.printDone(fmt.Sprintf("Invited %s to org %s as %s", params.Email, orgID, params.Role), params)
+}
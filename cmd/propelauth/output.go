@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// table is how a result is printed with -output table.
+type table struct {
+	headers []string
+	rows    [][]string
+}
+
+// print prints value as JSON, or result as a table.
+func (o *cli) print(value interface{}, result table) error {
+	if o.output == "json" {
+		encoder := json.NewEncoder(o.stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(value); err != nil {
+			return fmt.Errorf("Error on marshalling the result: %w", err)
+		}
+		return nil
+	}
+
+	writer := tabwriter.NewWriter(o.stdout, 0, 4, 2, ' ', 0)
+	if len(result.headers) > 0 {
+		fmt.Fprintln(writer, strings.Join(result.headers, "\t"))
+	}
+	for _, row := range result.rows {
+		fmt.Fprintln(writer, strings.Join(row, "\t"))
+	}
+
+	return writer.Flush()
+}
+
+// printDone prints the outcome of a command that changes something, like disabling a user.
+func (o *cli) printDone(message string, value interface{}) error {
+	return o.print(value, table{rows: [][]string{{message}}})
+}
+
+// fields prints a single item as a table with a row per field.
+func fields(names []string, values ...string) table {
+	result := table{}
+	for i, name := range names {
+		result.rows = append(result.rows, []string{name, values[i]})
+	}
+	return result
+}
+
+// orgRows prints the orgs a user is in as rows of a fields table, sorted so the output is the same every time.
+func orgRows(orgs []string) [][]string {
+	sort.Strings(orgs)
+
+	rows := [][]string{}
+	for _, org := range orgs {
+		rows = append(rows, []string{"Org", org})
+	}
+	return rows
+}
+
+func optional(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+func unixTime(seconds int64) string {
+	if seconds == 0 {
+		return ""
+	}
+	return time.Unix(seconds, 0).UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// tokenDecode prints the header and claims of an access token, without verifying it, so it doesn't need credentials.
+func tokenDecode(o *cli, args []string) error {
+	flags := flag.NewFlagSet("token decode", flag.ContinueOnError)
+	args, err := o.parseFlags(flags, args, "access token")
+	if err != nil {
+		return err
+	}
+
+	claims := jwt.MapClaims{}
+	token, _, err := jwt.NewParser().ParseUnverified(stripBearer(args[0]), claims)
+	if err != nil {
+		return fmt.Errorf("Error on decoding the token: %w", err)
+	}
+
+	result := table{}
+	for _, name := range sortedClaims(token.Header) {
+		result.rows = append(result.rows, []string{"header." + name, claimString(token.Header[name])})
+	}
+	for _, name := range sortedClaims(claims) {
+		value := claimString(claims[name])
+		if seconds, ok := claims[name].(float64); ok && (name == "exp" || name == "iat" || name == "nbf") {
+			value = fmt.Sprintf("%s (%s)", value, unixTime(int64(seconds)))
+		}
+		result.rows = append(result.rows, []string{name, value})
+	}
+
+	return o.print(map[string]interface{}{"header": token.Header, "claims": claims}, result)
+}
+
+// tokenVerify checks an access token is valid, and prints the user it belongs to.
+func tokenVerify(o *cli, args []string) error {
+	flags := flag.NewFlagSet("token verify", flag.ContinueOnError)
+	args, err := o.parseFlags(flags, args, "access token")
+	if err != nil {
+		return err
+	}
+
+	client, err := o.connect()
+	if err != nil {
+		return err
+	}
+
+	user, err := client.GetUser("Bearer " + stripBearer(args[0]))
+	if err != nil {
+		return err
+	}
+
+	result := fields([]string{"Valid", "User ID", "Email"}, "true", user.UserID.String(), optional(user.Email))
+	if user.ImpersonatorUserID != nil {
+		result.rows = append(result.rows, []string{"Impersonated by", user.ImpersonatorUserID.String()})
+	}
+	if activeOrg := user.GetActiveOrgMemberInfo(); activeOrg != nil {
+		result.rows = append(result.rows, []string{"Active org", fmt.Sprintf("%s (%s) as %s", activeOrg.OrgName, activeOrg.OrgID, activeOrg.UserAssignedRole)})
+	}
+	if user.ExpiresAt != nil {
+		result.rows = append(result.rows, []string{"Expires", unixTime(user.ExpiresAt.Unix())})
+	}
+	orgs := []string{}
+	for _, orgMemberInfo := range user.OrgIDToOrgMemberInfo {
+		orgs = append(orgs, fmt.Sprintf("%s (%s) as %s", orgMemberInfo.OrgName, orgMemberInfo.OrgID, orgMemberInfo.UserAssignedRole))
+	}
+	result.rows = append(result.rows, orgRows(orgs)...)
+
+	return o.print(user, result)
+}
+
+// stripBearer lets tokens be pasted in with or without the Bearer prefix of an Authorization header.
+func stripBearer(token string) string {
+	return strings.TrimPrefix(strings.TrimSpace(token), "Bearer ")
+}
+
+func sortedClaims(claims map[string]interface{}) []string {
+	names := make([]string, 0, len(claims))
+	for name := range claims {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func claimString(value interface{}) string {
+	switch value := value.(type) {
+	case string:
+		return value
+	case float64:
+		return fmt.Sprint(int64(value))
+	default:
+		encoded, _ := json.Marshal(value)
+		return string(encoded)
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	propelauth "github.com/propelauth/propelauth-go/pkg"
+	"github.com/propelauth/propelauth-go/pkg/models"
+)
+
+var userHeaders = []string{"USER ID", "EMAIL", "NAME", "ENABLED", "LAST ACTIVE"}
+
+func userRow(user models.UserMetadata) []string {
+	name := strings.TrimSpace(optional(user.FirstName) + " " + optional(user.LastName))
+	return []string{user.UserID.String(), user.Email, name, strconv.FormatBool(user.Enabled), unixTime(user.LastActiveAt)}
+}
+
+// usersGet prints a user, found by ID, email or username.
+func usersGet(o *cli, args []string) error {
+	flags := flag.NewFlagSet("users get", flag.ContinueOnError)
+	includeOrgs := flags.Bool("orgs", false, "include the orgs the user is in")
+	args, err := o.parseFlags(flags, args, "user ID, email or username")
+	if err != nil {
+		return err
+	}
+
+	client, err := o.connect()
+	if err != nil {
+		return err
+	}
+
+	var user *models.UserMetadata
+	if userID, parseErr := uuid.Parse(args[0]); parseErr == nil {
+		user, err = client.FetchUserMetadataByUserID(userID, *includeOrgs)
+	} else if strings.Contains(args[0], "@") {
+		user, err = client.FetchUserMetadataByEmail(args[0], *includeOrgs)
+	} else {
+		user, err = client.FetchUserMetadataByUsername(args[0], *includeOrgs)
+	}
+	if err != nil {
+		return err
+	}
+
+	result := fields(
+		[]string{"User ID", "Email", "Email confirmed", "Username", "First name", "Last name", "Enabled", "Locked", "MFA enabled", "Legacy user ID", "Created", "Last active"},
+		user.UserID.String(), user.Email, strconv.FormatBool(user.EmailConfirmed), optional(user.Username),
+		optional(user.FirstName), optional(user.LastName), strconv.FormatBool(user.Enabled), strconv.FormatBool(user.Locked),
+		strconv.FormatBool(user.MfaEnabled), optional(user.LegacyUserID), unixTime(user.CreatedAt), unixTime(user.LastActiveAt))
+	if user.OrgIDToOrgInfo != nil {
+		orgs := []string{}
+		for _, orgInfo := range *user.OrgIDToOrgInfo {
+			orgs = append(orgs, fmt.Sprintf("%s (%s) as %s", orgInfo.OrgName, orgInfo.OrgID, orgInfo.UserRole))
+		}
+		result.rows = append(result.rows, orgRows(orgs)...)
+	}
+
+	return o.print(user, result)
+}
+
+// usersSearch prints every user matching the query.
+func usersSearch(o *cli, args []string) error {
+	flags := flag.NewFlagSet("users search", flag.ContinueOnError)
+	query := flags.String("query", "", "only users whose email or username contains this")
+	legacyUserID := flags.String("legacy-user-id", "", "only the user with this legacy user ID")
+	orderBy := flags.String("order-by", "", "CREATED_AT_ASC, CREATED_AT_DESC, LAST_ACTIVE_AT_ASC, LAST_ACTIVE_AT_DESC, EMAIL or USERNAME")
+	if _, err := o.parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	client, err := o.connect()
+	if err != nil {
+		return err
+	}
+
+	params := models.UserQueryParams{}
+	if *query != "" {
+		params.EmailOrUsername = query
+	}
+	if *legacyUserID != "" {
+		params.LegacyUserID = legacyUserID
+	}
+	if *orderBy != "" {
+		params.OrderBy = orderBy
+	}
+
+	users, err := propelauth.IterateUsersByQuery(client, params).All(o.limit)
+	if err != nil {
+		return err
+	}
+
+	result := table{headers: userHeaders}
+	for _, user := range users {
+		result.rows = append(result.rows, userRow(user))
+	}
+
+	return o.print(users, result)
+}
+
+// usersCreate creates a user, and prints its ID.
+func usersCreate(o *cli, args []string) error {
+	flags := flag.NewFlagSet("users create", flag.ContinueOnError)
+	firstName := flags.String("first-name", "", "the user's first name")
+	lastName := flags.String("last-name", "", "the user's last name")
+	username := flags.String("username", "", "the user's username")
+	password := flags.String("password", "", "the user's password, or leave it out to let them set one")
+	emailConfirmed := flags.Bool("email-confirmed", false, "mark the email as confirmed")
+	sendConfirmation := flags.Bool("send-confirmation-email", false, "send an email asking the user to confirm their email")
+	args, err := o.parseFlags(flags, args, "email")
+	if err != nil {
+		return err
+	}
+
+	client, err := o.connect()
+	if err != nil {
+		return err
+	}
+
+	params := models.CreateUserParams{Email: args[0]}
+	if *emailConfirmed {
+		params.EmailConfirmed = emailConfirmed
+	}
+	if *sendConfirmation {
+		params.SendEmailToConfirmEmailAddress = sendConfirmation
+	}
+	if *firstName != "" {
+		params.FirstName = firstName
+	}
+	if *lastName != "" {
+		params.LastName = lastName
+	}
+	if *username != "" {
+		params.Username = username
+	}
+	if *password != "" {
+		params.Password = password
+	}
+
+	created, err := client.CreateUser(params)
+	if err != nil {
+		return err
+	}
+
+	return o.print(created, fields([]string{"User ID"}, created.UserID.String()))
+}
+
+// usersDisable disables a user, so they can't log in.
+func usersDisable(o *cli, args []string) error {
+	return userAction(o, "users disable", args, "Disabled", propelauth.ClientInterface.DisableUser)
+}
+
+// usersLogout logs a user out of all their sessions.
+func usersLogout(o *cli, args []string) error {
+	return userAction(o, "users logout", args, "Logged out", propelauth.ClientInterface.LogoutAllUserSessions)
+}
+
+// userAction runs a change to a user that takes just their ID.
+func userAction(o *cli, name string, args []string, done string, action func(propelauth.ClientInterface, uuid.UUID) (bool, error)) error {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	args, err := o.parseFlags(flags, args, "user ID")
+	if err != nil {
+		return err
+	}
+
+	userID, err := uuid.Parse(args[0])
+	if err != nil {
+		return fmt.Errorf("Invalid user ID: %w", err)
+	}
+
+	client, err := o.connect()
+	if err != nil {
+		return err
+	}
+
+	if _, err := action(client, userID); err != nil {
+		return err
+	}
+
+	return o.printDone(fmt.Sprintf("%s user %s", done, userID), map[string]interface{}{"user_id": userID, "success": true})
+}
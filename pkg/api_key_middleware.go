@@ -0,0 +1,242 @@
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/propelauth/propelauth-go/pkg/models"
+)
+
+const defaultAPIKeyCacheTTL = 30 * time.Second
+const defaultAPIKeyCacheSize = 10000
+
+// APIKeyMiddlewareOption configures optional behavior of the APIKeyMiddleware returned by NewAPIKeyMiddleware.
+type APIKeyMiddlewareOption func(*APIKeyMiddleware)
+
+// APIKeyMiddleware protects net/http handlers by validating the API key sent with each request. Validations are
+// cached, so a key that's used for many requests is only sent to PropelAuth once per cache TTL.
+type APIKeyMiddleware struct {
+	client                ClientInterface
+	header                string
+	cache                 *ttlCache[[sha256.Size]byte, *models.APIKeyValidation]
+	unauthorizedResponder ErrorResponder
+	forbiddenResponder    ErrorResponder
+	rateLimitedResponder  ErrorResponder
+	unavailableResponder  ErrorResponder
+}
+
+type apiKeyValidationContextKey struct{}
+
+// NewAPIKeyMiddleware creates middleware that validates API keys with client.ValidateAPIKey. By default, the key
+// is read from the Authorization header, formatted "Bearer KEY", and validations are cached for 30 seconds.
+func NewAPIKeyMiddleware(client ClientInterface, opts ...APIKeyMiddlewareOption) *APIKeyMiddleware {
+	middleware := &APIKeyMiddleware{
+		client:                client,
+		header:                "Authorization",
+		cache:                 newTTLCache[[sha256.Size]byte, *models.APIKeyValidation](defaultAPIKeyCacheTTL, defaultAPIKeyCacheSize),
+		unauthorizedResponder: DefaultUnauthorizedResponder,
+		forbiddenResponder:    DefaultForbiddenResponder,
+		rateLimitedResponder:  DefaultRateLimitedResponder,
+		unavailableResponder:  DefaultUnavailableResponder,
+	}
+
+	for _, opt := range opts {
+		opt(middleware)
+	}
+
+	return middleware
+}
+
+// WithAPIKeyHeader reads the API key from the named header instead of Authorization. The whole value of the
+// header is used as the key.
+func WithAPIKeyHeader(name string) APIKeyMiddlewareOption {
+	return func(o *APIKeyMiddleware) {
+		o.header = name
+	}
+}
+
+// WithAPIKeyCache changes how long validations are cached, and how many are kept. The least recently used are
+// dropped first. A key that's deleted in PropelAuth can still be accepted until its validation expires from the
+// cache, so keep the TTL short, or call Invalidate when you delete a key. A ttl of 0 disables caching.
+func WithAPIKeyCache(ttl time.Duration, maxEntries int) APIKeyMiddlewareOption {
+	return func(o *APIKeyMiddleware) {
+		o.cache = newTTLCache[[sha256.Size]byte, *models.APIKeyValidation](ttl, maxEntries)
+	}
+}
+
+// WithAPIKeyUnauthorizedResponder replaces the response written when a request doesn't have a valid API key.
+func WithAPIKeyUnauthorizedResponder(responder ErrorResponder) APIKeyMiddlewareOption {
+	return func(o *APIKeyMiddleware) {
+		o.unauthorizedResponder = responder
+	}
+}
+
+// WithAPIKeyForbiddenResponder replaces the response written when a valid API key isn't the kind the route needs.
+func WithAPIKeyForbiddenResponder(responder ErrorResponder) APIKeyMiddlewareOption {
+	return func(o *APIKeyMiddleware) {
+		o.forbiddenResponder = responder
+	}
+}
+
+// WithAPIKeyRateLimitedResponder replaces the response written when an API key has hit the rate limit configured
+// in your PropelAuth dashboard. The Retry-After header is already set when it's called.
+func WithAPIKeyRateLimitedResponder(responder ErrorResponder) APIKeyMiddlewareOption {
+	return func(o *APIKeyMiddleware) {
+		o.rateLimitedResponder = responder
+	}
+}
+
+// WithAPIKeyUnavailableResponder replaces the response written when the API key couldn't be validated, like
+// when PropelAuth can't be reached.
+func WithAPIKeyUnavailableResponder(responder ErrorResponder) APIKeyMiddlewareOption {
+	return func(o *APIKeyMiddleware) {
+		o.unavailableResponder = responder
+	}
+}
+
+// RequireAPIKey only calls next if the request has a valid API key, of any kind. The validation is available to
+// next through APIKeyValidationFromContext.
+func (o *APIKeyMiddleware) RequireAPIKey(next http.Handler) http.Handler {
+	return o.requireAPIKey(next, func(validation *models.APIKeyValidation) error {
+		return nil
+	})
+}
+
+// RequirePersonalAPIKey is like RequireAPIKey, but responds with a 403 unless the key belongs to a user and not
+// to an org.
+func (o *APIKeyMiddleware) RequirePersonalAPIKey(next http.Handler) http.Handler {
+	return o.requireAPIKey(next, func(validation *models.APIKeyValidation) error {
+		if validation.Org != nil || validation.User == nil {
+			return errors.New("Not a personal API key")
+		}
+		return nil
+	})
+}
+
+// RequireOrgAPIKey is like RequireAPIKey, but responds with a 403 unless the key belongs to an org.
+func (o *APIKeyMiddleware) RequireOrgAPIKey(next http.Handler) http.Handler {
+	return o.requireAPIKey(next, func(validation *models.APIKeyValidation) error {
+		if validation.Org == nil {
+			return errors.New("Not an org API key")
+		}
+		return nil
+	})
+}
+
+// Invalidate drops the cached validation of the API key, so the next request with it is validated again.
+func (o *APIKeyMiddleware) Invalidate(apiKeyToken string) {
+	o.cache.remove(hashAPIKey(apiKeyToken))
+}
+
+func (o *APIKeyMiddleware) requireAPIKey(next http.Handler, check func(validation *models.APIKeyValidation) error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apiKeyToken, err := o.extractAPIKey(r)
+		if err != nil {
+			o.unauthorizedResponder(w, r, err)
+			return
+		}
+
+		validation, err := o.validate(r.Context(), apiKeyToken)
+		if err != nil {
+			o.respondToValidationError(w, r, err)
+			return
+		}
+
+		if err := check(validation); err != nil {
+			o.forbiddenResponder(w, r, err)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(ContextWithAPIKeyValidation(r.Context(), validation)))
+	})
+}
+
+func (o *APIKeyMiddleware) extractAPIKey(r *http.Request) (string, error) {
+	value := strings.TrimSpace(r.Header.Get(o.header))
+	if strings.EqualFold(o.header, "Authorization") {
+		if len(value) < 7 || !strings.EqualFold(value[:7], "Bearer ") {
+			return "", errors.New("Missing API key in the Authorization header")
+		}
+		value = strings.TrimSpace(value[7:])
+	}
+
+	if value == "" {
+		return "", fmt.Errorf("Missing API key in header %s", o.header)
+	}
+
+	return value, nil
+}
+
+// validate returns the cached validation of the key, or validates it and caches the result. Only successful
+// validations are cached.
+func (o *APIKeyMiddleware) validate(ctx context.Context, apiKeyToken string) (*models.APIKeyValidation, error) {
+	hash := hashAPIKey(apiKeyToken)
+	if validation, ok := o.cache.get(hash); ok {
+		return validation, nil
+	}
+
+	validation, err := o.client.WithContext(ctx).ValidateAPIKey(apiKeyToken)
+	if err != nil {
+		return nil, err
+	}
+
+	o.cache.add(hash, validation)
+
+	return validation, nil
+}
+
+// respondToValidationError responds with a 429 if the key hit its rate limit, a 401 if PropelAuth said the key
+// is invalid, and otherwise, like when PropelAuth couldn't be reached, with a 503.
+func (o *APIKeyMiddleware) respondToValidationError(w http.ResponseWriter, r *http.Request, err error) {
+	rateLimitError := &models.ApiKeyRateLimitError{}
+	if errors.As(err, &rateLimitError) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(rateLimitError.WaitSeconds))))
+		o.rateLimitedResponder(w, r, err)
+		return
+	}
+
+	apiError := &models.APIError{}
+	if errors.As(err, &apiError) && apiError.StatusCode >= 400 && apiError.StatusCode < 500 && apiError.StatusCode != 401 && apiError.StatusCode != 429 {
+		o.unauthorizedResponder(w, r, err)
+		return
+	}
+
+	o.unavailableResponder(w, r, err)
+}
+
+// ContextWithAPIKeyValidation returns a copy of ctx that carries the validation, which can be read back with
+// APIKeyValidationFromContext.
+func ContextWithAPIKeyValidation(ctx context.Context, validation *models.APIKeyValidation) context.Context {
+	return context.WithValue(ctx, apiKeyValidationContextKey{}, validation)
+}
+
+// APIKeyValidationFromContext returns the validation of the API key that the APIKeyMiddleware accepted, if there is
+// one. It has the user and org the key belongs to, and the user's membership in the org. It may be shared with other
+// requests through the cache, so don't modify it.
+func APIKeyValidationFromContext(ctx context.Context) (*models.APIKeyValidation, bool) {
+	validation, ok := ctx.Value(apiKeyValidationContextKey{}).(*models.APIKeyValidation)
+
+	return validation, ok && validation != nil
+}
+
+// DefaultRateLimitedResponder responds with a 429 and a JSON body like {"error": "rate_limited"}.
+func DefaultRateLimitedResponder(w http.ResponseWriter, r *http.Request, err error) {
+	writeJSONError(w, http.StatusTooManyRequests, "rate_limited")
+}
+
+// DefaultUnavailableResponder responds with a 503 and a JSON body like {"error": "service_unavailable"}.
+func DefaultUnavailableResponder(w http.ResponseWriter, r *http.Request, err error) {
+	writeJSONError(w, http.StatusServiceUnavailable, "service_unavailable")
+}
+
+// hashAPIKey is the cache key for an API key, so the cache never holds the keys themselves.
+func hashAPIKey(apiKeyToken string) [sha256.Size]byte {
+	return sha256.Sum256([]byte(apiKeyToken))
+}
@@ -0,0 +1,209 @@
+package client_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	propelauth "github.com/propelauth/propelauth-go/pkg"
+	"github.com/propelauth/propelauth-go/pkg/models"
+	testHelpers "github.com/propelauth/propelauth-go/pkg/test"
+)
+
+// countingClient counts the API key validations that reach the fake, and rate limits one key.
+type countingClient struct {
+	propelauth.ClientInterface
+	validations      atomic.Int32
+	rateLimitedToken string
+}
+
+func (o *countingClient) WithContext(ctx context.Context) propelauth.ClientInterface {
+	return o
+}
+
+func (o *countingClient) ValidateAPIKey(apiKeyToken string) (*models.APIKeyValidation, error) {
+	o.validations.Add(1)
+	if apiKeyToken == o.rateLimitedToken {
+		return nil, &models.ApiKeyRateLimitError{WaitSeconds: 1.5, ErrorCode: "api_key_rate_limited"}
+	}
+	return o.ClientInterface.ValidateAPIKey(apiKeyToken)
+}
+
+func TestAPIKeyMiddleware(t *testing.T) {
+	// setup a fake with a personal key and an org key
+
+	fake := testHelpers.NewFakeClient()
+
+	user, err := fake.CreateUser(models.CreateUserParams{Email: "ada@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser returned an error, cannot even begin the tests: %s", err)
+	}
+	org, err := fake.CreateOrg("Acme")
+	if err != nil {
+		t.Fatalf("CreateOrg returned an error, cannot even begin the tests: %s", err)
+	}
+	personalKey, err := fake.CreateAPIKey(models.APIKeyCreateParams{UserID: &user.UserID})
+	if err != nil {
+		t.Fatalf("CreateAPIKey returned an error, cannot even begin the tests: %s", err)
+	}
+	orgKey, err := fake.CreateAPIKey(models.APIKeyCreateParams{OrgID: &org.OrgID})
+	if err != nil {
+		t.Fatalf("CreateAPIKey returned an error, cannot even begin the tests: %s", err)
+	}
+
+	client := &countingClient{ClientInterface: fake, rateLimitedToken: "too-many-requests"}
+
+	// a handler that reports what kind of key it found in the context
+	whoami := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		validation, ok := propelauth.APIKeyValidationFromContext(r.Context())
+		switch {
+		case !ok:
+			_, _ = w.Write([]byte("anonymous"))
+		case validation.Org != nil:
+			_, _ = w.Write([]byte(validation.Org.OrgID.String()))
+		default:
+			_, _ = w.Write([]byte(validation.User.UserID.String()))
+		}
+	})
+
+	serve := func(handler http.Handler, header string, value string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest("GET", "/whoami", nil)
+		if value != "" {
+			request.Header.Set(header, value)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+
+		return recorder
+	}
+
+	// run tests
+
+	t.Run("RequireAPIKey passes the validation to the handler and caches it", func(t *testing.T) {
+		middleware := propelauth.NewAPIKeyMiddleware(client)
+		before := client.validations.Load()
+
+		for i := 0; i < 3; i++ {
+			recorder := serve(middleware.RequireAPIKey(whoami), "Authorization", "Bearer "+personalKey.APIKeyToken)
+			if recorder.Code != 200 || recorder.Body.String() != user.UserID.String() {
+				t.Fatalf("expected 200 with the user ID, got %d: %s", recorder.Code, recorder.Body.String())
+			}
+		}
+
+		if validations := client.validations.Load() - before; validations != 1 {
+			t.Errorf("expected 1 validation, got %d", validations)
+		}
+	})
+
+	t.Run("cached validations expire", func(t *testing.T) {
+		middleware := propelauth.NewAPIKeyMiddleware(client, propelauth.WithAPIKeyCache(20*time.Millisecond, 10))
+		before := client.validations.Load()
+
+		serve(middleware.RequireAPIKey(whoami), "Authorization", "Bearer "+personalKey.APIKeyToken)
+		time.Sleep(30 * time.Millisecond)
+		serve(middleware.RequireAPIKey(whoami), "Authorization", "Bearer "+personalKey.APIKeyToken)
+
+		if validations := client.validations.Load() - before; validations != 2 {
+			t.Errorf("expected 2 validations, got %d", validations)
+		}
+	})
+
+	t.Run("the least recently used validation is dropped first", func(t *testing.T) {
+		middleware := propelauth.NewAPIKeyMiddleware(client, propelauth.WithAPIKeyCache(time.Minute, 1))
+		before := client.validations.Load()
+
+		serve(middleware.RequireAPIKey(whoami), "Authorization", "Bearer "+personalKey.APIKeyToken)
+		serve(middleware.RequireAPIKey(whoami), "Authorization", "Bearer "+orgKey.APIKeyToken)
+		serve(middleware.RequireAPIKey(whoami), "Authorization", "Bearer "+orgKey.APIKeyToken)
+		serve(middleware.RequireAPIKey(whoami), "Authorization", "Bearer "+personalKey.APIKeyToken)
+
+		if validations := client.validations.Load() - before; validations != 3 {
+			t.Errorf("expected 3 validations, got %d", validations)
+		}
+	})
+
+	t.Run("Invalidate drops a cached validation", func(t *testing.T) {
+		middleware := propelauth.NewAPIKeyMiddleware(client)
+		before := client.validations.Load()
+
+		serve(middleware.RequireAPIKey(whoami), "Authorization", "Bearer "+personalKey.APIKeyToken)
+		middleware.Invalidate(personalKey.APIKeyToken)
+		serve(middleware.RequireAPIKey(whoami), "Authorization", "Bearer "+personalKey.APIKeyToken)
+
+		if validations := client.validations.Load() - before; validations != 2 {
+			t.Errorf("expected 2 validations, got %d", validations)
+		}
+	})
+
+	t.Run("the key can be read from another header", func(t *testing.T) {
+		middleware := propelauth.NewAPIKeyMiddleware(client, propelauth.WithAPIKeyHeader("X-API-Key"))
+
+		recorder := serve(middleware.RequireAPIKey(whoami), "X-API-Key", orgKey.APIKeyToken)
+		if recorder.Code != 200 || recorder.Body.String() != org.OrgID.String() {
+			t.Errorf("expected 200 with the org ID, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+	})
+
+	t.Run("missing and invalid keys are rejected with a 401", func(t *testing.T) {
+		middleware := propelauth.NewAPIKeyMiddleware(client)
+
+		for _, authHeader := range []string{"", personalKey.APIKeyToken, "Bearer ", "Bearer not-a-key"} {
+			recorder := serve(middleware.RequireAPIKey(whoami), "Authorization", authHeader)
+			if recorder.Code != 401 {
+				t.Errorf("expected 401 for %q, got %d", authHeader, recorder.Code)
+			}
+		}
+	})
+
+	t.Run("RequirePersonalAPIKey and RequireOrgAPIKey check the kind of key", func(t *testing.T) {
+		middleware := propelauth.NewAPIKeyMiddleware(client)
+
+		tests := []struct {
+			handler  http.Handler
+			token    string
+			expected int
+		}{
+			{middleware.RequirePersonalAPIKey(whoami), personalKey.APIKeyToken, 200},
+			{middleware.RequirePersonalAPIKey(whoami), orgKey.APIKeyToken, 403},
+			{middleware.RequireOrgAPIKey(whoami), orgKey.APIKeyToken, 200},
+			{middleware.RequireOrgAPIKey(whoami), personalKey.APIKeyToken, 403},
+		}
+
+		for _, test := range tests {
+			recorder := serve(test.handler, "Authorization", "Bearer "+test.token)
+			if recorder.Code != test.expected {
+				t.Errorf("expected %d, got %d: %s", test.expected, recorder.Code, recorder.Body.String())
+			}
+		}
+	})
+
+	t.Run("rate limited keys get a 429 with Retry-After", func(t *testing.T) {
+		middleware := propelauth.NewAPIKeyMiddleware(client)
+
+		recorder := serve(middleware.RequireAPIKey(whoami), "Authorization", "Bearer too-many-requests")
+		if recorder.Code != 429 || recorder.Header().Get("Retry-After") != "2" {
+			t.Errorf("expected 429 with Retry-After 2, got %d and %q", recorder.Code, recorder.Header().Get("Retry-After"))
+		}
+	})
+
+	t.Run("failures to reach PropelAuth get a 503", func(t *testing.T) {
+		server := testHelpers.NewFakeServer()
+		defer server.Close()
+
+		serverClient, err := server.NewClient()
+		if err != nil {
+			t.Fatalf("NewClient returned an error: %s", err)
+		}
+		server.InjectFault(testHelpers.FakeServerFault{PathPrefix: "/api/backend/v1/end_user_api_keys/validate", StatusCode: 502})
+
+		middleware := propelauth.NewAPIKeyMiddleware(serverClient)
+
+		recorder := serve(middleware.RequireAPIKey(whoami), "Authorization", "Bearer "+personalKey.APIKeyToken)
+		if recorder.Code != 503 {
+			t.Errorf("expected 503, got %d", recorder.Code)
+		}
+	})
+}
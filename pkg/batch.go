@@ -0,0 +1,150 @@
+package client
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+
+	"github.com/propelauth/propelauth-go/pkg/models"
+)
+
+// batchChunkSize is the most identifiers we send in one batch request.
+const batchChunkSize = 100
+
+// batchConcurrency is the most batch requests we send at a time for one call.
+const batchConcurrency = 4
+
+// BatchUsers is the result of fetching users by a list of identifiers. Users is keyed by the identifiers that
+// were asked for, and Missing lists the ones that didn't match a user, in the order they were asked for.
+type BatchUsers[K comparable] struct {
+	Users   map[K]models.UserMetadata
+	Missing []K
+}
+
+// FetchUsersByIDs fetches the users with client.FetchBatchUserMetadataByUserIds, and reports which IDs didn't
+// match a user.
+func FetchUsersByIDs(client ClientInterface, userIds []uuid.UUID, includeOrgs bool) (*BatchUsers[uuid.UUID], error) {
+	users, err := client.FetchBatchUserMetadataByUserIds(userIds, includeOrgs)
+	if err != nil {
+		return nil, err
+	}
+
+	return matchBatchUsers(userIds, users, func(userID uuid.UUID) uuid.UUID { return userID }), nil
+}
+
+// FetchUsersByEmails fetches the users with client.FetchBatchUserMetadataByEmails, and reports which emails didn't
+// match a user. Emails are matched case-insensitively, so Users is keyed by the emails as they were asked for.
+func FetchUsersByEmails(client ClientInterface, emails []string, includeOrgs bool) (*BatchUsers[string], error) {
+	users, err := client.FetchBatchUserMetadataByEmails(emails, includeOrgs)
+	if err != nil {
+		return nil, err
+	}
+
+	return matchBatchUsers(emails, users, strings.ToLower), nil
+}
+
+// FetchUsersByUsernames fetches the users with client.FetchBatchUserMetadataByUsernames, and reports which
+// usernames didn't match a user. Usernames are matched case-insensitively, like emails.
+func FetchUsersByUsernames(client ClientInterface, usernames []string, includeOrgs bool) (*BatchUsers[string], error) {
+	users, err := client.FetchBatchUserMetadataByUsernames(usernames, includeOrgs)
+	if err != nil {
+		return nil, err
+	}
+
+	return matchBatchUsers(usernames, users, strings.ToLower), nil
+}
+
+// matchBatchUsers pairs each requested identifier with the user fetched for it, comparing them after normalize.
+func matchBatchUsers[K comparable](requested []K, fetched map[K]models.UserMetadata, normalize func(key K) K) *BatchUsers[K] {
+	normalized := make(map[K]models.UserMetadata, len(fetched))
+	for key, user := range fetched {
+		normalized[normalize(key)] = user
+	}
+
+	result := &BatchUsers[K]{Users: map[K]models.UserMetadata{}, Missing: []K{}}
+	seen := make(map[K]struct{}, len(requested))
+	for _, key := range requested {
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+
+		if user, ok := normalized[normalize(key)]; ok {
+			result.Users[key] = user
+		} else {
+			result.Missing = append(result.Missing, key)
+		}
+	}
+
+	return result
+}
+
+// fetchInChunks splits keys into chunks of batchChunkSize, fetches up to batchConcurrency of them at a time, and
+// merges the results. If any chunk fails, the ones still running are cancelled, no more are started, and the first
+// error is returned.
+func fetchInChunks[K comparable](ctx context.Context, keys []K, fetchChunk func(ctx context.Context, chunk []K) (map[K]models.UserMetadata, error)) (map[K]models.UserMetadata, error) {
+	if len(keys) <= batchChunkSize {
+		return fetchChunk(ctx, keys)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var firstErr error
+	merged := map[K]models.UserMetadata{}
+	semaphore := make(chan struct{}, batchConcurrency)
+
+	for start := 0; start < len(keys); start += batchChunkSize {
+		end := start + batchChunkSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+		chunk := keys[start:end]
+
+		// stop starting chunks once one has failed or the caller has given up, including while we waited for a slot
+		if ctx.Err() != nil {
+			break
+		}
+		semaphore <- struct{}{}
+		if ctx.Err() != nil {
+			<-semaphore
+			break
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			users, err := fetchChunk(ctx, chunk)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				return
+			}
+			for key, user := range users {
+				merged[key] = user
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return merged, nil
+}
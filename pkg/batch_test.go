@@ -0,0 +1,137 @@
+package client_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/uuid"
+	propelauth "github.com/propelauth/propelauth-go/pkg"
+	"github.com/propelauth/propelauth-go/pkg/models"
+	testHelpers "github.com/propelauth/propelauth-go/pkg/test"
+)
+
+func TestBatchFetches(t *testing.T) {
+	// setup a fake server with more users than fit in one request
+
+	server := testHelpers.NewFakeServer()
+	defer server.Close()
+
+	client, err := server.NewClient()
+	if err != nil {
+		t.Fatalf("NewClient returned an error, cannot even begin the tests: %s", err)
+	}
+
+	userIDs := []uuid.UUID{}
+	emails := []string{}
+	for i := 0; i < 250; i++ {
+		email := fmt.Sprintf("user%d@example.com", i)
+		user, err := server.Fake().CreateUser(models.CreateUserParams{Email: email})
+		if err != nil {
+			t.Fatalf("CreateUser returned an error, cannot even begin the tests: %s", err)
+		}
+		userIDs = append(userIDs, user.UserID)
+		emails = append(emails, email)
+	}
+
+	// run tests
+
+	t.Run("large batches are split into requests of 100 and merged", func(t *testing.T) {
+		before := len(server.Requests())
+
+		users, err := client.FetchBatchUserMetadataByUserIds(userIDs, false)
+		if err != nil {
+			t.Fatalf("FetchBatchUserMetadataByUserIds returned an error: %s", err)
+		}
+		if len(users) != 250 {
+			t.Errorf("expected 250 users, got %d", len(users))
+		}
+
+		requests := server.Requests()[before:]
+		if len(requests) != 3 {
+			t.Fatalf("expected 3 requests, got %d", len(requests))
+		}
+		for _, request := range requests {
+			body := struct {
+				UserIds []uuid.UUID `json:"user_ids"`
+			}{}
+			if err := json.Unmarshal(request.Body, &body); err != nil || len(body.UserIds) > 100 {
+				t.Errorf("expected at most 100 IDs per request, got %d and %v", len(body.UserIds), err)
+			}
+		}
+	})
+
+	t.Run("a failed chunk fails the whole batch", func(t *testing.T) {
+		server.InjectFault(testHelpers.FakeServerFault{PathPrefix: "/api/backend/v1/user/emails", StatusCode: 401, Times: 1})
+
+		_, err := client.FetchBatchUserMetadataByEmails(emails, false)
+		if !errors.Is(err, models.ErrUnauthorized) {
+			t.Errorf("expected ErrUnauthorized, got %v", err)
+		}
+	})
+
+	t.Run("no more chunks are started once one has failed", func(t *testing.T) {
+		// the first request fails, and the others hang until they're cancelled
+		var requests atomic.Int32
+		transport := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if !strings.HasPrefix(req.URL.Path, "/api/backend/v1/user/emails") {
+				return http.DefaultTransport.RoundTrip(req)
+			}
+			if requests.Add(1) == 1 {
+				return &http.Response{
+					StatusCode: 401,
+					Status:     "401 Unauthorized",
+					Body:       io.NopCloser(strings.NewReader("")),
+					Header:     http.Header{},
+					Request:    req,
+				}, nil
+			}
+			<-req.Context().Done()
+			return nil, req.Context().Err()
+		})
+		failingClient, err := server.NewClient(propelauth.WithTransport(transport))
+		if err != nil {
+			t.Fatalf("NewClient returned an error: %s", err)
+		}
+
+		manyEmails := []string{}
+		for i := 0; i < 1000; i++ {
+			manyEmails = append(manyEmails, fmt.Sprintf("someone%d@example.com", i))
+		}
+
+		_, err = failingClient.FetchBatchUserMetadataByEmails(manyEmails, false)
+		if !errors.Is(err, models.ErrUnauthorized) {
+			t.Errorf("expected ErrUnauthorized, got %v", err)
+		}
+		if requests.Load() > 4 {
+			t.Errorf("expected at most the 4 chunks already running, got %d requests for 10 chunks", requests.Load())
+		}
+	})
+
+	t.Run("missing identifiers are reported", func(t *testing.T) {
+		missingID := uuid.New()
+		byID, err := propelauth.FetchUsersByIDs(client, append([]uuid.UUID{missingID}, userIDs...), false)
+		if err != nil {
+			t.Fatalf("FetchUsersByIDs returned an error: %s", err)
+		}
+		if len(byID.Users) != 250 || len(byID.Missing) != 1 || byID.Missing[0] != missingID {
+			t.Errorf("expected 250 users and the missing ID, got %d and %v", len(byID.Users), byID.Missing)
+		}
+
+		byEmail, err := propelauth.FetchUsersByEmails(client, []string{"USER7@example.com", "nobody@example.com", "nobody@example.com"}, false)
+		if err != nil {
+			t.Fatalf("FetchUsersByEmails returned an error: %s", err)
+		}
+		if byEmail.Users["USER7@example.com"].UserID != userIDs[7] {
+			t.Errorf("expected the user to be keyed by the email as it was asked for, got %v", byEmail.Users)
+		}
+		if len(byEmail.Missing) != 1 || byEmail.Missing[0] != "nobody@example.com" {
+			t.Errorf("expected the missing email once, got %v", byEmail.Missing)
+		}
+	})
+}
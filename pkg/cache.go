@@ -0,0 +1,204 @@
+package client
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// ttlCache is an LRU cache whose entries also expire after a TTL. A ttl or maxEntries of 0 disables it.
+//
+// Each removal bumps the cache's generation, and remembers it for the removed key. A value fetched by a call that
+// started before the key was removed is stale, so addIfCurrent drops it.
+type ttlCache[K comparable, V any] struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	entries    map[K]*list.Element
+	order      *list.List
+
+	generation   uint64
+	removedAt    map[K]uint64
+	removedAllAt uint64
+}
+
+type ttlCacheEntry[K comparable, V any] struct {
+	key       K
+	value     V
+	expiresAt time.Time
+}
+
+func newTTLCache[K comparable, V any](ttl time.Duration, maxEntries int) *ttlCache[K, V] {
+	return &ttlCache[K, V]{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    map[K]*list.Element{},
+		order:      list.New(),
+		removedAt:  map[K]uint64{},
+	}
+}
+
+func (o *ttlCache[K, V]) get(key K) (V, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	var zero V
+
+	element, ok := o.entries[key]
+	if !ok {
+		return zero, false
+	}
+
+	entry := element.Value.(*ttlCacheEntry[K, V])
+	if time.Now().After(entry.expiresAt) {
+		o.order.Remove(element)
+		delete(o.entries, key)
+		return zero, false
+	}
+
+	o.order.MoveToFront(element)
+
+	return entry.value, true
+}
+
+func (o *ttlCache[K, V]) add(key K, value V) {
+	if o.ttl <= 0 || o.maxEntries <= 0 {
+		return
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.addLocked(key, value)
+}
+
+// currentGeneration returns the cache's generation, to pass to addIfCurrent once a value has been fetched.
+func (o *ttlCache[K, V]) currentGeneration() uint64 {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.generation
+}
+
+// addIfCurrent adds the value, unless the key was removed after generation.
+func (o *ttlCache[K, V]) addIfCurrent(key K, value V, generation uint64) {
+	if o.ttl <= 0 || o.maxEntries <= 0 {
+		return
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.removedAllAt > generation || o.removedAt[key] > generation {
+		return
+	}
+
+	o.addLocked(key, value)
+}
+
+func (o *ttlCache[K, V]) addLocked(key K, value V) {
+	entry := &ttlCacheEntry[K, V]{key: key, value: value, expiresAt: time.Now().Add(o.ttl)}
+	if element, ok := o.entries[key]; ok {
+		element.Value = entry
+		o.order.MoveToFront(element)
+		return
+	}
+
+	o.entries[key] = o.order.PushFront(entry)
+	for o.order.Len() > o.maxEntries {
+		oldest := o.order.Back()
+		o.order.Remove(oldest)
+		delete(o.entries, oldest.Value.(*ttlCacheEntry[K, V]).key)
+	}
+}
+
+// removeWhere drops every entry whose key matches. Keys that aren't cached can match too, so values fetched
+// before it was called aren't added for any key.
+func (o *ttlCache[K, V]) removeWhere(matches func(key K) bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.generation++
+	o.removedAllAt = o.generation
+
+	for key, element := range o.entries {
+		if matches(key) {
+			o.order.Remove(element)
+			delete(o.entries, key)
+		}
+	}
+}
+
+func (o *ttlCache[K, V]) remove(key K) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.generation++
+	o.removedAt[key] = o.generation
+	if len(o.removedAt) > o.maxEntries {
+		// forgetting which keys were removed is safe, as long as nothing fetched before now is added
+		o.removedAt = map[K]uint64{}
+		o.removedAllAt = o.generation
+	}
+
+	if element, ok := o.entries[key]; ok {
+		o.order.Remove(element)
+		delete(o.entries, key)
+	}
+}
+
+// flightGroup collapses concurrent calls with the same key into one. Everyone waiting on a call gets its result,
+// but stops waiting early if their own context is done.
+type flightGroup[K comparable, V any] struct {
+	mu    sync.Mutex
+	calls map[K]*flightCall[V]
+}
+
+type flightCall[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+func (o *flightGroup[K, V]) do(ctx context.Context, key K, fetch func() (V, error)) (V, error) {
+	o.mu.Lock()
+	if o.calls == nil {
+		o.calls = map[K]*flightCall[V]{}
+	}
+	call, ok := o.calls[key]
+	if !ok {
+		call = &flightCall[V]{done: make(chan struct{})}
+		o.calls[key] = call
+
+		go func() {
+			call.value, call.err = fetch()
+
+			o.mu.Lock()
+			if o.calls[key] == call {
+				delete(o.calls, key)
+			}
+			o.mu.Unlock()
+
+			close(call.done)
+		}()
+	}
+	o.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.value, call.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+// forget makes the next call with the key start a new call, rather than wait on the one in flight. Use it when
+// the one in flight would return stale data.
+func (o *flightGroup[K, V]) forget(key K) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	delete(o.calls, key)
+}
@@ -0,0 +1,455 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/propelauth/propelauth-go/pkg/models"
+)
+
+const defaultUserCacheTTL = time.Minute
+const defaultOrgCacheTTL = time.Minute
+const defaultEntityCacheSize = 10000
+const defaultUserBatchWindow = 2 * time.Millisecond
+const maxUserBatchSize = 100
+
+// CachingClientOption configures optional behavior of the CachingClient returned by NewCachingClient.
+type CachingClientOption func(*CachingClient)
+
+// CachingClient wraps a ClientInterface and caches FetchUserMetadataByUserID, FetchBatchUserMetadataByUserIds
+// and FetchOrg. Concurrent fetches of the same user or org are collapsed into one request, and single user
+// lookups made within a couple of milliseconds of each other are sent together with FetchBatchUserMetadataByUserIds.
+//
+// Calls made through the CachingClient that change a user or org, like UpdateUserMetadata or DeleteOrg, drop what
+// was cached about it. Changes made anywhere else, like in the dashboard or by another server, are only seen once
+// the cached entry expires, unless you call InvalidateUser or InvalidateOrg, for example from a webhook.
+//
+// Every other method is passed straight through to the wrapped client.
+type CachingClient struct {
+	ClientInterface
+	ctx    context.Context
+	caches *clientCaches
+}
+
+var _ ClientInterface = (*CachingClient)(nil)
+
+// clientCaches is shared by a CachingClient and the copies WithContext makes of it.
+type clientCaches struct {
+	// base is the wrapped client without a request's context. Fetches that are shared between callers are made
+	// with it, so one caller giving up doesn't fail the fetch for everyone else.
+	base        ClientInterface
+	users       *ttlCache[userCacheKey, models.UserMetadata]
+	orgs        *ttlCache[uuid.UUID, models.OrgCompleteMetadata]
+	userFlights flightGroup[userCacheKey, models.UserMetadata]
+	orgFlights  flightGroup[uuid.UUID, models.OrgCompleteMetadata]
+	batcher     *userBatcher
+}
+
+type userCacheKey struct {
+	userID      uuid.UUID
+	includeOrgs bool
+}
+
+// NewCachingClient wraps client with a cache. By default, users and orgs are cached for a minute, and up to
+// 10,000 of each are kept.
+func NewCachingClient(client ClientInterface, opts ...CachingClientOption) *CachingClient {
+	caches := &clientCaches{
+		base:  client,
+		users: newTTLCache[userCacheKey, models.UserMetadata](defaultUserCacheTTL, defaultEntityCacheSize),
+		orgs:  newTTLCache[uuid.UUID, models.OrgCompleteMetadata](defaultOrgCacheTTL, defaultEntityCacheSize),
+	}
+	caches.batcher = newUserBatcher(defaultUserBatchWindow, caches.fetchBatch)
+
+	cachingClient := &CachingClient{
+		ClientInterface: client,
+		ctx:             context.Background(),
+		caches:          caches,
+	}
+
+	for _, opt := range opts {
+		opt(cachingClient)
+	}
+
+	return cachingClient
+}
+
+// WithUserCache changes how long users are cached, and how many are kept. The least recently used are dropped
+// first. A ttl of 0 disables caching users, but concurrent fetches are still collapsed and batched.
+func WithUserCache(ttl time.Duration, maxEntries int) CachingClientOption {
+	return func(o *CachingClient) {
+		o.caches.users = newTTLCache[userCacheKey, models.UserMetadata](ttl, maxEntries)
+	}
+}
+
+// WithOrgCache changes how long orgs are cached, and how many are kept. The least recently used are dropped
+// first. A ttl of 0 disables caching orgs, but concurrent fetches are still collapsed.
+func WithOrgCache(ttl time.Duration, maxEntries int) CachingClientOption {
+	return func(o *CachingClient) {
+		o.caches.orgs = newTTLCache[uuid.UUID, models.OrgCompleteMetadata](ttl, maxEntries)
+	}
+}
+
+// WithUserBatchWindow changes how long a user lookup waits for others to send with it in one batch. A window of
+// 0 sends every lookup on its own.
+func WithUserBatchWindow(window time.Duration) CachingClientOption {
+	return func(o *CachingClient) {
+		o.caches.batcher = newUserBatcher(window, o.caches.fetchBatch)
+	}
+}
+
+// WithContext returns a copy of the CachingClient that makes its requests with ctx. The copy shares the cache.
+// A fetch shared with other callers keeps going if ctx is cancelled, but this caller stops waiting for it.
+func (o *CachingClient) WithContext(ctx context.Context) ClientInterface {
+	if ctx == nil {
+		panic("nil context")
+	}
+
+	return &CachingClient{
+		ClientInterface: o.caches.base.WithContext(ctx),
+		ctx:             ctx,
+		caches:          o.caches,
+	}
+}
+
+// InvalidateUser drops everything cached about the user. Fetches of the user that are in flight aren't cached,
+// and later lookups don't wait on them.
+func (o *CachingClient) InvalidateUser(userID uuid.UUID) {
+	for _, includeOrgs := range []bool{false, true} {
+		key := userCacheKey{userID: userID, includeOrgs: includeOrgs}
+		o.caches.users.remove(key)
+		o.caches.userFlights.forget(key)
+	}
+}
+
+// InvalidateOrg drops the cached org, and every cached user's list of orgs, since they include the org's name.
+// Like InvalidateUser, fetches of the org that are in flight aren't cached.
+func (o *CachingClient) InvalidateOrg(orgID uuid.UUID) {
+	o.invalidateOrg(orgID)
+	o.invalidateUsersWithOrgs()
+}
+
+// invalidateOrg drops the cached org, but not the users.
+func (o *CachingClient) invalidateOrg(orgID uuid.UUID) {
+	o.caches.orgs.remove(orgID)
+	o.caches.orgFlights.forget(orgID)
+}
+
+// invalidateUsersWithOrgs drops the users that were fetched with includeOrgs.
+func (o *CachingClient) invalidateUsersWithOrgs() {
+	o.caches.users.removeWhere(func(key userCacheKey) bool {
+		return key.includeOrgs
+	})
+}
+
+// cached methods
+
+// FetchUserMetadataByUserID returns the cached user, or fetches it along with any other users looked up at the
+// same time. The result may share maps and slices with the cache, so don't modify them.
+func (o *CachingClient) FetchUserMetadataByUserID(userID uuid.UUID, includeOrgs bool) (*models.UserMetadata, error) {
+	key := userCacheKey{userID: userID, includeOrgs: includeOrgs}
+	if user, ok := o.caches.users.get(key); ok {
+		return &user, nil
+	}
+
+	user, err := o.caches.userFlights.do(o.ctx, key, func() (models.UserMetadata, error) {
+		return o.caches.batcher.load(key)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// FetchBatchUserMetadataByUserIds returns the cached users, and fetches the rest.
+func (o *CachingClient) FetchBatchUserMetadataByUserIds(userIds []uuid.UUID, includeOrgs bool) (map[uuid.UUID]models.UserMetadata, error) {
+	users := map[uuid.UUID]models.UserMetadata{}
+	missing := []uuid.UUID{}
+	for _, userID := range userIds {
+		if user, ok := o.caches.users.get(userCacheKey{userID: userID, includeOrgs: includeOrgs}); ok {
+			users[userID] = user
+		} else {
+			missing = append(missing, userID)
+		}
+	}
+
+	if len(missing) == 0 {
+		return users, nil
+	}
+
+	generation := o.caches.users.currentGeneration()
+	fetched, err := o.ClientInterface.FetchBatchUserMetadataByUserIds(missing, includeOrgs)
+	if err != nil {
+		return nil, err
+	}
+
+	for userID, user := range fetched {
+		o.caches.users.addIfCurrent(userCacheKey{userID: userID, includeOrgs: includeOrgs}, user, generation)
+		users[userID] = user
+	}
+
+	return users, nil
+}
+
+// FetchOrg returns the cached org, or fetches it. The result may share maps and slices with the cache, so don't
+// modify them.
+func (o *CachingClient) FetchOrg(orgID uuid.UUID) (*models.OrgCompleteMetadata, error) {
+	if org, ok := o.caches.orgs.get(orgID); ok {
+		return &org, nil
+	}
+
+	org, err := o.caches.orgFlights.do(o.ctx, orgID, func() (models.OrgCompleteMetadata, error) {
+		generation := o.caches.orgs.currentGeneration()
+		org, err := o.caches.base.FetchOrg(orgID)
+		if err != nil {
+			return models.OrgCompleteMetadata{}, err
+		}
+
+		o.caches.orgs.addIfCurrent(orgID, *org, generation)
+
+		return *org, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &org, nil
+}
+
+// fetchBatch fetches the users for the batcher, and caches the ones that weren't invalidated in the meantime.
+func (o *clientCaches) fetchBatch(userIds []uuid.UUID, includeOrgs bool) (map[uuid.UUID]models.UserMetadata, error) {
+	generation := o.users.currentGeneration()
+
+	var users map[uuid.UUID]models.UserMetadata
+	if len(userIds) == 1 {
+		// a lone lookup keeps the error, like not found, that FetchUserMetadataByUserID would return
+		user, err := o.base.FetchUserMetadataByUserID(userIds[0], includeOrgs)
+		if err != nil {
+			return nil, err
+		}
+		users = map[uuid.UUID]models.UserMetadata{userIds[0]: *user}
+	} else {
+		batch, err := o.base.FetchBatchUserMetadataByUserIds(userIds, includeOrgs)
+		if err != nil {
+			return nil, err
+		}
+		users = batch
+	}
+
+	for userID, user := range users {
+		o.users.addIfCurrent(userCacheKey{userID: userID, includeOrgs: includeOrgs}, user, generation)
+	}
+
+	return users, nil
+}
+
+// methods that change a user
+
+func (o *CachingClient) DeleteUser(userID uuid.UUID) (bool, error) {
+	defer o.InvalidateUser(userID)
+	return o.ClientInterface.DeleteUser(userID)
+}
+
+func (o *CachingClient) DisableUser(userID uuid.UUID) (bool, error) {
+	defer o.InvalidateUser(userID)
+	return o.ClientInterface.DisableUser(userID)
+}
+
+func (o *CachingClient) EnableUser(userID uuid.UUID) (bool, error) {
+	defer o.InvalidateUser(userID)
+	return o.ClientInterface.EnableUser(userID)
+}
+
+func (o *CachingClient) UpdateUserEmail(userID uuid.UUID, params models.UpdateEmail) (bool, error) {
+	defer o.InvalidateUser(userID)
+	return o.ClientInterface.UpdateUserEmail(userID, params)
+}
+
+func (o *CachingClient) UpdateUserMetadata(userID uuid.UUID, params models.UpdateUserMetadata) (bool, error) {
+	defer o.InvalidateUser(userID)
+	return o.ClientInterface.UpdateUserMetadata(userID, params)
+}
+
+func (o *CachingClient) UpdateUserPassword(userID uuid.UUID, params models.UpdateUserPasswordParam) (bool, error) {
+	defer o.InvalidateUser(userID)
+	return o.ClientInterface.UpdateUserPassword(userID, params)
+}
+
+func (o *CachingClient) MigrateUserPassword(params models.MigrateUserPasswordParams) (bool, error) {
+	defer o.InvalidateUser(params.UserID)
+	return o.ClientInterface.MigrateUserPassword(params)
+}
+
+func (o *CachingClient) EnableUserCanCreateOrgs(userID uuid.UUID) (bool, error) {
+	defer o.InvalidateUser(userID)
+	return o.ClientInterface.EnableUserCanCreateOrgs(userID)
+}
+
+func (o *CachingClient) DisableUserCanCreateOrgs(userID uuid.UUID) (bool, error) {
+	defer o.InvalidateUser(userID)
+	return o.ClientInterface.DisableUserCanCreateOrgs(userID)
+}
+
+func (o *CachingClient) ClearUserPassword(userID uuid.UUID) (bool, error) {
+	defer o.InvalidateUser(userID)
+	return o.ClientInterface.ClearUserPassword(userID)
+}
+
+func (o *CachingClient) DisableUser2fa(userID uuid.UUID) (bool, error) {
+	defer o.InvalidateUser(userID)
+	return o.ClientInterface.DisableUser2fa(userID)
+}
+
+// methods that change an org, or a user's membership in one
+
+func (o *CachingClient) DeleteOrg(orgID uuid.UUID) (bool, error) {
+	defer o.InvalidateOrg(orgID)
+	return o.ClientInterface.DeleteOrg(orgID)
+}
+
+func (o *CachingClient) UpdateOrgMetadata(orgID uuid.UUID, params models.UpdateOrg) (bool, error) {
+	defer o.InvalidateOrg(orgID)
+	return o.ClientInterface.UpdateOrgMetadata(orgID, params)
+}
+
+func (o *CachingClient) AllowOrgToSetupSamlConnection(orgID uuid.UUID) (bool, error) {
+	defer o.invalidateOrg(orgID)
+	return o.ClientInterface.AllowOrgToSetupSamlConnection(orgID)
+}
+
+func (o *CachingClient) DisallowOrgToSetupSamlConnection(orgID uuid.UUID) (bool, error) {
+	defer o.invalidateOrg(orgID)
+	return o.ClientInterface.DisallowOrgToSetupSamlConnection(orgID)
+}
+
+func (o *CachingClient) SubscribeOrgToRoleMapping(orgID uuid.UUID, params models.OrgRoleMappingSubscription) (bool, error) {
+	defer o.InvalidateOrg(orgID)
+	return o.ClientInterface.SubscribeOrgToRoleMapping(orgID, params)
+}
+
+func (o *CachingClient) SetSamlIdpMetadata(params models.SamlIdpMetadata) (bool, error) {
+	defer o.invalidateOrg(params.OrgId)
+	return o.ClientInterface.SetSamlIdpMetadata(params)
+}
+
+func (o *CachingClient) SamlGoLive(orgId uuid.UUID) (bool, error) {
+	defer o.invalidateOrg(orgId)
+	return o.ClientInterface.SamlGoLive(orgId)
+}
+
+func (o *CachingClient) DeleteSamlConnection(orgId uuid.UUID) (bool, error) {
+	defer o.invalidateOrg(orgId)
+	return o.ClientInterface.DeleteSamlConnection(orgId)
+}
+
+// SetOidcIdpMetadata drops every cached org, since each kind of request keeps the org ID in a different type.
+func (o *CachingClient) SetOidcIdpMetadata(params models.SetOidcIdpMetadataRequest) (bool, error) {
+	defer o.caches.orgs.removeWhere(func(uuid.UUID) bool { return true })
+	return o.ClientInterface.SetOidcIdpMetadata(params)
+}
+
+func (o *CachingClient) AddUserToOrg(params models.AddUserToOrg) (bool, error) {
+	defer o.InvalidateUser(params.UserID)
+	return o.ClientInterface.AddUserToOrg(params)
+}
+
+func (o *CachingClient) RemoveUserFromOrg(params models.RemoveUserFromOrg) (bool, error) {
+	defer o.InvalidateUser(params.UserID)
+	return o.ClientInterface.RemoveUserFromOrg(params)
+}
+
+func (o *CachingClient) ChangeUserRoleInOrg(params models.ChangeUserRoleInOrg) (bool, error) {
+	defer o.InvalidateUser(params.UserID)
+	return o.ClientInterface.ChangeUserRoleInOrg(params)
+}
+
+// userBatcher gathers user lookups made within a window of each other, and fetches them together.
+type userBatcher struct {
+	mu      sync.Mutex
+	window  time.Duration
+	fetch   func(userIds []uuid.UUID, includeOrgs bool) (map[uuid.UUID]models.UserMetadata, error)
+	pending map[bool]*userBatch
+}
+
+type userBatch struct {
+	userIds []uuid.UUID
+	queued  map[uuid.UUID]struct{}
+	done    chan struct{}
+	users   map[uuid.UUID]models.UserMetadata
+	err     error
+}
+
+func newUserBatcher(window time.Duration, fetch func(userIds []uuid.UUID, includeOrgs bool) (map[uuid.UUID]models.UserMetadata, error)) *userBatcher {
+	return &userBatcher{
+		window:  window,
+		fetch:   fetch,
+		pending: map[bool]*userBatch{},
+	}
+}
+
+// load adds the user to the pending batch, starting one if there isn't one, and waits for it to be fetched.
+func (o *userBatcher) load(key userCacheKey) (models.UserMetadata, error) {
+	if o.window <= 0 {
+		return o.loadAlone(key)
+	}
+
+	o.mu.Lock()
+	batch, ok := o.pending[key.includeOrgs]
+	if !ok {
+		batch = &userBatch{queued: map[uuid.UUID]struct{}{}, done: make(chan struct{})}
+		o.pending[key.includeOrgs] = batch
+		time.AfterFunc(o.window, func() {
+			o.flush(key.includeOrgs, batch)
+		})
+	}
+	// the user can already be queued if they were invalidated after being added, since the next lookup doesn't
+	// wait on the one that's pending
+	if _, ok := batch.queued[key.userID]; !ok {
+		batch.queued[key.userID] = struct{}{}
+		batch.userIds = append(batch.userIds, key.userID)
+	}
+	full := len(batch.userIds) >= maxUserBatchSize
+	o.mu.Unlock()
+
+	if full {
+		go o.flush(key.includeOrgs, batch)
+	}
+
+	<-batch.done
+	if batch.err != nil {
+		return models.UserMetadata{}, batch.err
+	}
+
+	user, ok := batch.users[key.userID]
+	if !ok {
+		return models.UserMetadata{}, models.NewAPIError(404, nil, "User not found", models.ErrNotFound)
+	}
+
+	return user, nil
+}
+
+func (o *userBatcher) loadAlone(key userCacheKey) (models.UserMetadata, error) {
+	users, err := o.fetch([]uuid.UUID{key.userID}, key.includeOrgs)
+	if err != nil {
+		return models.UserMetadata{}, err
+	}
+
+	return users[key.userID], nil
+}
+
+// flush fetches the batch, unless it was already flushed because it filled up.
+func (o *userBatcher) flush(includeOrgs bool, batch *userBatch) {
+	o.mu.Lock()
+	if o.pending[includeOrgs] != batch {
+		o.mu.Unlock()
+		return
+	}
+	delete(o.pending, includeOrgs)
+	o.mu.Unlock()
+
+	batch.users, batch.err = o.fetch(batch.userIds, includeOrgs)
+	close(batch.done)
+}
@@ -0,0 +1,314 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	propelauth "github.com/propelauth/propelauth-go/pkg"
+	"github.com/propelauth/propelauth-go/pkg/models"
+	testHelpers "github.com/propelauth/propelauth-go/pkg/test"
+)
+
+// fetchCountingClient counts the fetches that reach the fake, and slows them down so concurrent ones overlap.
+type fetchCountingClient struct {
+	propelauth.ClientInterface
+	userFetches  atomic.Int32
+	batchFetches atomic.Int32
+	orgFetches   atomic.Int32
+}
+
+func (o *fetchCountingClient) WithContext(ctx context.Context) propelauth.ClientInterface {
+	return o
+}
+
+func (o *fetchCountingClient) FetchUserMetadataByUserID(userID uuid.UUID, includeOrgs bool) (*models.UserMetadata, error) {
+	o.userFetches.Add(1)
+	time.Sleep(10 * time.Millisecond)
+	return o.ClientInterface.FetchUserMetadataByUserID(userID, includeOrgs)
+}
+
+func (o *fetchCountingClient) FetchBatchUserMetadataByUserIds(userIds []uuid.UUID, includeOrgs bool) (map[uuid.UUID]models.UserMetadata, error) {
+	o.batchFetches.Add(1)
+	time.Sleep(10 * time.Millisecond)
+	return o.ClientInterface.FetchBatchUserMetadataByUserIds(userIds, includeOrgs)
+}
+
+func (o *fetchCountingClient) FetchOrg(orgID uuid.UUID) (*models.OrgCompleteMetadata, error) {
+	o.orgFetches.Add(1)
+	time.Sleep(10 * time.Millisecond)
+	return o.ClientInterface.FetchOrg(orgID)
+}
+
+// gatedClient holds each user and org fetch, after it reached the fake, until release is closed.
+type gatedClient struct {
+	propelauth.ClientInterface
+	fetched chan struct{}
+	release chan struct{}
+}
+
+func (o *gatedClient) WithContext(ctx context.Context) propelauth.ClientInterface {
+	return o
+}
+
+func (o *gatedClient) FetchUserMetadataByUserID(userID uuid.UUID, includeOrgs bool) (*models.UserMetadata, error) {
+	user, err := o.ClientInterface.FetchUserMetadataByUserID(userID, includeOrgs)
+	o.fetched <- struct{}{}
+	<-o.release
+	return user, err
+}
+
+func (o *gatedClient) FetchOrg(orgID uuid.UUID) (*models.OrgCompleteMetadata, error) {
+	org, err := o.ClientInterface.FetchOrg(orgID)
+	o.fetched <- struct{}{}
+	<-o.release
+	return org, err
+}
+
+func TestCachingClient(t *testing.T) {
+	// setup a fake with a few users and an org
+
+	fake := testHelpers.NewFakeClient()
+
+	userIDs := []uuid.UUID{}
+	for _, email := range []string{"ada@example.com", "grace@example.com", "linus@example.com"} {
+		user, err := fake.CreateUser(models.CreateUserParams{Email: email})
+		if err != nil {
+			t.Fatalf("CreateUser returned an error, cannot even begin the tests: %s", err)
+		}
+		userIDs = append(userIDs, user.UserID)
+	}
+
+	org, err := fake.CreateOrg("Acme")
+	if err != nil {
+		t.Fatalf("CreateOrg returned an error, cannot even begin the tests: %s", err)
+	}
+
+	newClient := func(opts ...propelauth.CachingClientOption) (*propelauth.CachingClient, *fetchCountingClient) {
+		counting := &fetchCountingClient{ClientInterface: fake}
+		return propelauth.NewCachingClient(counting, opts...), counting
+	}
+
+	// run tests
+
+	t.Run("users are cached", func(t *testing.T) {
+		client, counting := newClient()
+
+		for i := 0; i < 3; i++ {
+			user, err := client.FetchUserMetadataByUserID(userIDs[0], false)
+			if err != nil || user.Email != "ada@example.com" {
+				t.Fatalf("expected ada, got %v and %v", user, err)
+			}
+		}
+
+		if fetches := counting.userFetches.Load(); fetches != 1 {
+			t.Errorf("expected 1 fetch, got %d", fetches)
+		}
+	})
+
+	t.Run("concurrent lookups are collapsed and batched", func(t *testing.T) {
+		client, counting := newClient(propelauth.WithUserBatchWindow(20 * time.Millisecond))
+
+		var wg sync.WaitGroup
+		errs := make(chan error, 30)
+		for i := 0; i < 30; i++ {
+			wg.Add(1)
+			go func(userID uuid.UUID) {
+				defer wg.Done()
+				user, err := client.WithContext(context.Background()).FetchUserMetadataByUserID(userID, false)
+				if err == nil && user.UserID != userID {
+					err = errors.New("got the wrong user")
+				}
+				errs <- err
+			}(userIDs[i%len(userIDs)])
+		}
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			if err != nil {
+				t.Fatalf("expected every lookup to succeed, got %s", err)
+			}
+		}
+		if fetches := counting.batchFetches.Load() + counting.userFetches.Load(); fetches != 1 {
+			t.Errorf("expected 1 fetch, got %d", fetches)
+		}
+	})
+
+	t.Run("missing users in a batch are not found", func(t *testing.T) {
+		client, _ := newClient(propelauth.WithUserBatchWindow(20 * time.Millisecond))
+
+		var wg sync.WaitGroup
+		var missingErr error
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, _ = client.FetchUserMetadataByUserID(userIDs[0], false)
+		}()
+		go func() {
+			defer wg.Done()
+			_, missingErr = client.FetchUserMetadataByUserID(uuid.New(), false)
+		}()
+		wg.Wait()
+
+		if !errors.Is(missingErr, models.ErrNotFound) {
+			t.Errorf("expected ErrNotFound, got %v", missingErr)
+		}
+	})
+
+	t.Run("users invalidated while their lookup is batched are only sent once", func(t *testing.T) {
+		client, counting := newClient(propelauth.WithUserBatchWindow(50 * time.Millisecond))
+
+		var wg sync.WaitGroup
+		errs := make(chan error, 2)
+		lookup := func() {
+			defer wg.Done()
+			_, err := client.FetchUserMetadataByUserID(userIDs[1], false)
+			errs <- err
+		}
+		wg.Add(2)
+		go lookup()
+		time.Sleep(10 * time.Millisecond)
+		client.InvalidateUser(userIDs[1])
+		go lookup()
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			if err != nil {
+				t.Fatalf("expected both lookups to succeed, got %s", err)
+			}
+		}
+		// a batch of one user is fetched on its own, so a batch fetch means the user was sent twice
+		if counting.userFetches.Load() != 1 || counting.batchFetches.Load() != 0 {
+			t.Errorf("expected 1 user fetch, got %d user fetches and %d batch fetches", counting.userFetches.Load(), counting.batchFetches.Load())
+		}
+	})
+
+	t.Run("batch fetches only fetch the users that aren't cached", func(t *testing.T) {
+		client, counting := newClient()
+
+		if _, err := client.FetchUserMetadataByUserID(userIDs[0], true); err != nil {
+			t.Fatalf("FetchUserMetadataByUserID returned an error: %s", err)
+		}
+
+		users, err := client.FetchBatchUserMetadataByUserIds(userIDs, true)
+		if err != nil || len(users) != 3 {
+			t.Fatalf("expected 3 users, got %d and %v", len(users), err)
+		}
+
+		if _, err := client.FetchBatchUserMetadataByUserIds(userIDs, true); err != nil {
+			t.Fatalf("FetchBatchUserMetadataByUserIds returned an error: %s", err)
+		}
+		if fetches := counting.batchFetches.Load(); fetches != 1 {
+			t.Errorf("expected 1 batch fetch, got %d", fetches)
+		}
+	})
+
+	t.Run("concurrent org fetches are collapsed and cached", func(t *testing.T) {
+		client, counting := newClient()
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if fetched, err := client.FetchOrg(org.OrgID); err != nil || fetched.Name != "Acme" {
+					t.Errorf("expected Acme, got %v and %v", fetched, err)
+				}
+			}()
+		}
+		wg.Wait()
+
+		if _, err := client.FetchOrg(org.OrgID); err != nil {
+			t.Fatalf("FetchOrg returned an error: %s", err)
+		}
+		if fetches := counting.orgFetches.Load(); fetches != 1 {
+			t.Errorf("expected 1 fetch, got %d", fetches)
+		}
+	})
+
+	t.Run("updates through the client invalidate the cache", func(t *testing.T) {
+		client, counting := newClient()
+
+		if _, err := client.FetchUserMetadataByUserID(userIDs[1], false); err != nil {
+			t.Fatalf("FetchUserMetadataByUserID returned an error: %s", err)
+		}
+		firstName := "Grace"
+		if _, err := client.UpdateUserMetadata(userIDs[1], models.UpdateUserMetadata{FirstName: &firstName}); err != nil {
+			t.Fatalf("UpdateUserMetadata returned an error: %s", err)
+		}
+		user, err := client.FetchUserMetadataByUserID(userIDs[1], false)
+		if err != nil || user.FirstName == nil || *user.FirstName != "Grace" {
+			t.Errorf("expected the updated user, got %v and %v", user, err)
+		}
+
+		if _, err := client.FetchOrg(org.OrgID); err != nil {
+			t.Fatalf("FetchOrg returned an error: %s", err)
+		}
+		name := "Acme Corp"
+		if _, err := client.UpdateOrgMetadata(org.OrgID, models.UpdateOrg{Name: &name}); err != nil {
+			t.Fatalf("UpdateOrgMetadata returned an error: %s", err)
+		}
+		fetched, err := client.FetchOrg(org.OrgID)
+		if err != nil || fetched.Name != "Acme Corp" {
+			t.Errorf("expected the updated org, got %v and %v", fetched, err)
+		}
+
+		if userFetches, orgFetches := counting.userFetches.Load(), counting.orgFetches.Load(); userFetches != 2 || orgFetches != 2 {
+			t.Errorf("expected 2 fetches of each, got %d and %d", userFetches, orgFetches)
+		}
+	})
+
+	t.Run("fetches that were in flight during an update aren't cached", func(t *testing.T) {
+		gated := &gatedClient{ClientInterface: fake, fetched: make(chan struct{}, 10), release: make(chan struct{})}
+		client := propelauth.NewCachingClient(gated, propelauth.WithUserBatchWindow(0))
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			_, _ = client.FetchUserMetadataByUserID(userIDs[0], false)
+			_, _ = client.FetchOrg(org.OrgID)
+		}()
+
+		<-gated.fetched
+		firstName := "Ada"
+		if _, err := client.UpdateUserMetadata(userIDs[0], models.UpdateUserMetadata{FirstName: &firstName}); err != nil {
+			t.Fatalf("UpdateUserMetadata returned an error: %s", err)
+		}
+		gated.release <- struct{}{}
+
+		<-gated.fetched
+		name := "Acme Inc"
+		if _, err := client.UpdateOrgMetadata(org.OrgID, models.UpdateOrg{Name: &name}); err != nil {
+			t.Fatalf("UpdateOrgMetadata returned an error: %s", err)
+		}
+		close(gated.release)
+		<-done
+
+		user, err := client.FetchUserMetadataByUserID(userIDs[0], false)
+		if err != nil || user.FirstName == nil || *user.FirstName != "Ada" {
+			t.Errorf("expected the updated user, got %v and %v", user, err)
+		}
+		fetched, err := client.FetchOrg(org.OrgID)
+		if err != nil || fetched.Name != "Acme Inc" {
+			t.Errorf("expected the updated org, got %v and %v", fetched, err)
+		}
+	})
+
+	t.Run("cached users expire", func(t *testing.T) {
+		client, counting := newClient(propelauth.WithUserCache(20*time.Millisecond, 10))
+
+		_, _ = client.FetchUserMetadataByUserID(userIDs[2], false)
+		time.Sleep(30 * time.Millisecond)
+		_, _ = client.FetchUserMetadataByUserID(userIDs[2], false)
+
+		if fetches := counting.userFetches.Load(); fetches != 2 {
+			t.Errorf("expected 2 fetches, got %d", fetches)
+		}
+	})
+}
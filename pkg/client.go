@@ -2,6 +2,7 @@
 package client
 
 import (
+	"context"
 	"fmt"
 	"slices"
 	"strconv"
@@ -16,7 +17,7 @@ import (
 )
 
 const backendURLApiOrigin = "https://propelauth-api.com"
-const backendURLApiPrefix = backendURLApiOrigin + "/api/backend/v1/"
+const backendURLApiPath = "/api/backend/v1/"
 
 // ClientInterface contains all the methods for interacting with the PropelAuth backend and the JWT.
 // It's also a convient listing of all the methods available to the integration programmer.
@@ -115,18 +116,23 @@ type ClientInterface interface {
 	FetchOrgReengagementReport(reportInterval *string, pagination *models.ReportPagination) (*models.OrgReport, error)
 	FetchChartMetricData(chartMetric string, cadence *string, chartRange *models.ChartRange) (*models.ChartData, error)
 
-	// a method to validate the JWT
+	// methods to validate the JWT
 	GetUser(authHeader string) (*models.UserFromToken, error)
+	TokenVerificationStatus() models.TokenVerificationStatus
+
+	// a method to bind requests to a context
+	WithContext(ctx context.Context) ClientInterface
 }
 
 // Client is the main struct for the PropelAuth Go library. It contains all the methods for interacting with the
 // PropelAuth backend.
 type Client struct {
-	integrationAPIKey         string
-	authURL                   string
-	tokenVerificationMetadata models.TokenVerificationMetadata
-	queryHelper               helpers.QueryHelperInterface
-	validationHelper          helpers.ValidationHelperInterface
+	ctx               context.Context
+	integrationAPIKey string
+	authURL           string
+	verifier          *TokenVerifier
+	queryHelper       helpers.QueryHelperWithContextInterface
+	validationHelper  helpers.ValidationHelperInterface
 }
 
 // InitBaseAuth initializes the PropelAuth client with the authURL, integrationAPIKey.
@@ -134,87 +140,108 @@ type Client struct {
 // This is the normal entrance to accessing the PropelAuth backend.
 //
 // The authURL and integrationAPIKey can be found in your PropelAuth dashboard, in the "Backend Integrations" section.
-// You can pass in a tokenVerificationMetadata if you have it, but it's not required.
-func InitBaseAuth(authURL string, integrationAPIKey string, tokenVerificationMetadataInput *models.TokenVerificationMetadataInput) (ClientInterface, error) {
-	// validate the authURL
-	parsedAuthUrl, err := url.ParseRequestURI(authURL)
+// You can pass in a tokenVerificationMetadata if you have it, but it's not required. Any ClientOptions, like
+// WithTimeout or WithTransport, configure how requests are sent to the PropelAuth backend.
+// Options like WithTokenLeeway and WithTokenMaxAge add checks to GetUser.
+func InitBaseAuth(authURL string, integrationAPIKey string, tokenVerificationMetadataInput *models.TokenVerificationMetadataInput, opts ...ClientOption) (ClientInterface, error) {
+	options := newClientOptions(opts)
+
+	// validate the authURL and the backend origin
+	parsedAuthUrl, err := validateOrigin(authURL, options.allowInsecureHTTP)
 	if err != nil {
-		return nil, fmt.Errorf("Couldn't parse the authURL: %w", err)
-	} else if parsedAuthUrl.Scheme != "https" {
-		return nil, fmt.Errorf("URL must start with https://")
-	} else if parsedAuthUrl.Path != "" {
-		return nil, fmt.Errorf("URL must not end with a trailing slash")
-	} else if parsedAuthUrl.Host == "" {
-		return nil, fmt.Errorf("Invalid URL")
+		return nil, fmt.Errorf("Invalid authURL: %w", err)
+	}
+
+	backendOrigin := backendURLApiOrigin
+	if options.backendOrigin != "" {
+		if _, err := validateOrigin(options.backendOrigin, options.allowInsecureHTTP); err != nil {
+			return nil, fmt.Errorf("Invalid backend origin: %w", err)
+		}
+		backendOrigin = options.backendOrigin
 	}
 
 	// setup helpers
-	queryHelper := helpers.NewQueryHelper(parsedAuthUrl.Host, backendURLApiPrefix)
+	queryHelper := helpers.NewQueryHelper(parsedAuthUrl.Host, backendOrigin+backendURLApiPath, options.buildHTTPClient(), options.retryPolicy)
 	validationHelper := &helpers.ValidationHelper{}
 
-	var tokenVerificationMetadata *models.TokenVerificationMetadata
+	var keyManager *verifierKeyManager
 
-	// if tokenVerificationMetadata wasn't passed in, create one
+	// if tokenVerificationMetadata wasn't passed in, fetch it, and keep it fresh afterwards
 	if tokenVerificationMetadataInput == nil {
-		endpointURL := backendURLApiOrigin + "/api/v1/token_verification_metadata"
-
-		queryResponse, err := queryHelper.RequestHelper("GET", integrationAPIKey, endpointURL, nil)
-		if err != nil {
-			return nil, fmt.Errorf("Error on fetching token verification metadata: %w", err)
-		}
-
-		if queryResponse.StatusCode != 200 {
-			switch statusCode := queryResponse.StatusCode; statusCode {
-			case 401:
-				return nil, fmt.Errorf("integrationAPIKey is incorrect")
-			case 400:
-				return nil, fmt.Errorf("Bad request: %s", queryResponse.BodyText)
-			case 404:
-				return nil, fmt.Errorf("URL is incorrect")
-			case 429:
-				return nil, fmt.Errorf("Rate limit exceeded")
-			default:
-				return nil, fmt.Errorf("Unknown error when fetching token verification metadata. Status code: %s. Body: %s", strconv.Itoa(queryResponse.StatusCode), queryResponse.BodyText)
+		endpointURL := backendOrigin + "/api/v1/token_verification_metadata"
+		fetchKeys := func(ctx context.Context) ([]models.VerifierKey, error) {
+			rsaPublicKey, err := fetchVerifierKey(ctx, queryHelper, validationHelper, integrationAPIKey, endpointURL)
+			if err != nil {
+				return nil, err
 			}
+			return []models.VerifierKey{{PublicKey: *rsaPublicKey}}, nil
 		}
 
-		authTokenVerificationMetadataResponse := &models.AuthTokenVerificationMetadataResponse{}
-		if err := json.Unmarshal(queryResponse.BodyBytes, authTokenVerificationMetadataResponse); err != nil {
-			return nil, fmt.Errorf("Error on unmarshalling bytes to AuthTokenVerificationMetadataResponse: %w", err)
-		}
-
-		rsaPublicKey, err := validationHelper.ConvertPEMStringToRSAPublicKey(authTokenVerificationMetadataResponse.VerifierKeyPem)
+		keys, err := fetchKeys(context.Background())
 		if err != nil {
-			return nil, fmt.Errorf("Error converting a PEM string to an RSA Public Key: %w", err)
+			return nil, err
 		}
 
-		tokenVerificationMetadata = &models.TokenVerificationMetadata{
-			VerifierKey: *rsaPublicKey,
-			Issuer:      authURL,
+		tokenVerificationMetadata := models.TokenVerificationMetadata{
+			VerifierKey:   keys[0].PublicKey,
+			VerifierKeyID: keys[0].KeyID,
+			Issuer:        authURL,
 		}
+		keyManager = newVerifierKeyManager(tokenVerificationMetadata, fetchKeys, options)
 	} else {
-		rsaPublicKey, err := validationHelper.ConvertPEMStringToRSAPublicKey(tokenVerificationMetadataInput.VerifierKey)
+		tokenVerificationMetadata, err := parseTokenVerificationMetadata(*tokenVerificationMetadataInput, validationHelper)
 		if err != nil {
-			return nil, fmt.Errorf("Error converting a PEM string to an RSA Public Key: %w", err)
+			return nil, err
 		}
 
-		tokenVerificationMetadata = &models.TokenVerificationMetadata{
-			VerifierKey: *rsaPublicKey,
-			Issuer:      tokenVerificationMetadataInput.Issuer,
-		}
+		keyManager = newVerifierKeyManager(*tokenVerificationMetadata, nil, options)
 	}
 
 	client := &Client{
-		integrationAPIKey:         integrationAPIKey,
-		authURL:                   authURL,
-		tokenVerificationMetadata: *tokenVerificationMetadata,
-		queryHelper:               queryHelper,
-		validationHelper:          validationHelper,
+		ctx:               context.Background(),
+		integrationAPIKey: integrationAPIKey,
+		authURL:           authURL,
+		verifier:          newTokenVerifier(keyManager, validationHelper, options),
+		queryHelper:       queryHelper,
+		validationHelper:  validationHelper,
 	}
 
 	return client, nil
 }
 
+// validateOrigin checks that rawURL is just a scheme and a host, like https://auth.example.com. Only https is
+// allowed, unless allowInsecureHTTP is set.
+func validateOrigin(rawURL string, allowInsecureHTTP bool) (*url.URL, error) {
+	parsedURL, err := url.ParseRequestURI(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("Couldn't parse the URL: %w", err)
+	} else if parsedURL.Scheme != "https" && !(allowInsecureHTTP && parsedURL.Scheme == "http") {
+		return nil, fmt.Errorf("URL must start with https://")
+	} else if parsedURL.Path != "" {
+		return nil, fmt.Errorf("URL must not end with a trailing slash")
+	} else if parsedURL.Host == "" {
+		return nil, fmt.Errorf("Invalid URL")
+	}
+
+	return parsedURL, nil
+}
+
+// WithContext returns a shallow copy of the client where every request to the PropelAuth backend is bound to ctx.
+// Cancelling ctx, or letting its deadline pass, aborts any in-flight request, and the returned error wraps
+// context.Canceled or context.DeadlineExceeded. The original client is unchanged, so this is cheap to call per request:
+//
+//	user, err := client.WithContext(r.Context()).FetchUserMetadataByUserID(userID, false)
+func (o *Client) WithContext(ctx context.Context) ClientInterface {
+	if ctx == nil {
+		panic("nil context")
+	}
+
+	client := *o
+	client.ctx = ctx
+
+	return &client
+}
+
 // Public methods to fetch a user or users
 
 // FetchUserMetadataByUserID will fetch a single user by their user ID. If includeOrgs is true, we'll also
@@ -226,7 +253,7 @@ func (o *Client) FetchUserMetadataByUserID(userID uuid.UUID, includeOrgs bool) (
 		"include_orgs": {strconv.FormatBool(includeOrgs)},
 	}
 
-	queryResponse, err := o.queryHelper.Get(o.integrationAPIKey, urlPostfix, queryParams)
+	queryResponse, err := o.queryHelper.GetWithContext(o.ctx, o.integrationAPIKey, urlPostfix, queryParams)
 	if err != nil {
 		return nil, fmt.Errorf("Error on fetching user by id: %w", err)
 	}
@@ -253,7 +280,7 @@ func (o *Client) FetchUserMetadataByEmail(email string, includeOrgs bool) (*mode
 		"include_orgs": {strconv.FormatBool(includeOrgs)},
 	}
 
-	queryResponse, err := o.queryHelper.Get(o.integrationAPIKey, urlPostfix, queryParams)
+	queryResponse, err := o.queryHelper.GetWithContext(o.ctx, o.integrationAPIKey, urlPostfix, queryParams)
 	if err != nil {
 		return nil, fmt.Errorf("Error on fetching user by email: %w", err)
 	}
@@ -280,7 +307,7 @@ func (o *Client) FetchUserMetadataByUsername(username string, includeOrgs bool)
 		"include_orgs": {strconv.FormatBool(includeOrgs)},
 	}
 
-	queryResponse, err := o.queryHelper.Get(o.integrationAPIKey, urlPostfix, queryParams)
+	queryResponse, err := o.queryHelper.GetWithContext(o.ctx, o.integrationAPIKey, urlPostfix, queryParams)
 	if err != nil {
 		return nil, fmt.Errorf("Error on fetching user by username: %w", err)
 	}
@@ -299,7 +326,16 @@ func (o *Client) FetchUserMetadataByUsername(username string, includeOrgs bool)
 
 // FetchBatchUserMetadataByUserIds will fetch all the users with the listed IDS. If includeOrgs is true, we'll
 // also fetch the organizations data for each organization the user is in.
+// Long lists are split into requests of 100, a few of which are sent at a time, and the results merged. Users
+// that don't exist are left out, use FetchUsersByIDs to find out which.
 func (o *Client) FetchBatchUserMetadataByUserIds(userIds []uuid.UUID, includeOrgs bool) (map[uuid.UUID]models.UserMetadata, error) {
+	return fetchInChunks(o.ctx, userIds, func(ctx context.Context, chunk []uuid.UUID) (map[uuid.UUID]models.UserMetadata, error) {
+		return o.fetchBatchUserMetadataByUserIds(ctx, chunk, includeOrgs)
+	})
+}
+
+// fetchBatchUserMetadataByUserIds fetches one chunk of IDs, small enough for a single request.
+func (o *Client) fetchBatchUserMetadataByUserIds(ctx context.Context, userIds []uuid.UUID, includeOrgs bool) (map[uuid.UUID]models.UserMetadata, error) {
 	urlPostfix := "user/user_ids"
 
 	// assemble the parameters
@@ -321,9 +357,9 @@ func (o *Client) FetchBatchUserMetadataByUserIds(userIds []uuid.UUID, includeOrg
 		return nil, fmt.Errorf("Error on marshalling body params: %w", err)
 	}
 
-	// make the request
+	// make the request, which only reads data so it's safe to retry
 
-	queryResponse, err := o.queryHelper.Post(o.integrationAPIKey, urlPostfix, queryParams, bodyJSON)
+	queryResponse, err := o.queryHelper.PostWithContext(helpers.ContextWithRetry(ctx), o.integrationAPIKey, urlPostfix, queryParams, bodyJSON)
 	if err != nil {
 		return nil, fmt.Errorf("Error on fetching batch users by ids: %w", err)
 	}
@@ -349,7 +385,16 @@ func (o *Client) FetchBatchUserMetadataByUserIds(userIds []uuid.UUID, includeOrg
 
 // FetchBatchUserMetadataByEmails will fetch all the users with the listed emails. If includeOrgs is true, we'll
 // also fetch the organizations data for each organization the user is in.
+// Long lists are split into requests of 100, a few of which are sent at a time, and the results merged. Users
+// that don't exist are left out, use FetchUsersByEmails to find out which.
 func (o *Client) FetchBatchUserMetadataByEmails(emails []string, includeOrgs bool) (map[string]models.UserMetadata, error) {
+	return fetchInChunks(o.ctx, emails, func(ctx context.Context, chunk []string) (map[string]models.UserMetadata, error) {
+		return o.fetchBatchUserMetadataByEmails(ctx, chunk, includeOrgs)
+	})
+}
+
+// fetchBatchUserMetadataByEmails fetches one chunk of emails, small enough for a single request.
+func (o *Client) fetchBatchUserMetadataByEmails(ctx context.Context, emails []string, includeOrgs bool) (map[string]models.UserMetadata, error) {
 	urlPostfix := "user/emails"
 
 	// assemble the parameters
@@ -371,9 +416,9 @@ func (o *Client) FetchBatchUserMetadataByEmails(emails []string, includeOrgs boo
 		return nil, fmt.Errorf("Error on marshalling body params: %w", err)
 	}
 
-	// make the request
+	// make the request, which only reads data so it's safe to retry
 
-	queryResponse, err := o.queryHelper.Post(o.integrationAPIKey, urlPostfix, queryParams, bodyJSON)
+	queryResponse, err := o.queryHelper.PostWithContext(helpers.ContextWithRetry(ctx), o.integrationAPIKey, urlPostfix, queryParams, bodyJSON)
 	if err != nil {
 		return nil, fmt.Errorf("Error on fetching batch users by emails: %w", err)
 	}
@@ -399,7 +444,16 @@ func (o *Client) FetchBatchUserMetadataByEmails(emails []string, includeOrgs boo
 
 // FetchBatchUserMetadataByUsernames will fetch all the users with the listed usernames. If includeOrgs is true,
 // we'll also fetch the organizations data for each organization the user is in.
+// Long lists are split into requests of 100, a few of which are sent at a time, and the results merged. Users
+// that don't exist are left out, use FetchUsersByUsernames to find out which.
 func (o *Client) FetchBatchUserMetadataByUsernames(usernames []string, includeOrgs bool) (map[string]models.UserMetadata, error) {
+	return fetchInChunks(o.ctx, usernames, func(ctx context.Context, chunk []string) (map[string]models.UserMetadata, error) {
+		return o.fetchBatchUserMetadataByUsernames(ctx, chunk, includeOrgs)
+	})
+}
+
+// fetchBatchUserMetadataByUsernames fetches one chunk of usernames, small enough for a single request.
+func (o *Client) fetchBatchUserMetadataByUsernames(ctx context.Context, usernames []string, includeOrgs bool) (map[string]models.UserMetadata, error) {
 	urlPostfix := "user/usernames"
 
 	// assemble the parameters
@@ -421,9 +475,9 @@ func (o *Client) FetchBatchUserMetadataByUsernames(usernames []string, includeOr
 		return nil, fmt.Errorf("Error on marshalling body params: %w", err)
 	}
 
-	// make the request
+	// make the request, which only reads data so it's safe to retry
 
-	queryResponse, err := o.queryHelper.Post(o.integrationAPIKey, urlPostfix, queryParams, bodyJSON)
+	queryResponse, err := o.queryHelper.PostWithContext(helpers.ContextWithRetry(ctx), o.integrationAPIKey, urlPostfix, queryParams, bodyJSON)
 	if err != nil {
 		return nil, fmt.Errorf("Error on fetching batch users by usernames: %w", err)
 	}
@@ -469,7 +523,7 @@ func (o *Client) FetchUsersByQuery(params models.UserQueryParams) (*models.UserL
 		queryParams.Add("include_orgs", strconv.FormatBool(*params.IncludeOrgs))
 	}
 
-	queryResponse, err := o.queryHelper.Get(o.integrationAPIKey, urlPostfix, queryParams)
+	queryResponse, err := o.queryHelper.GetWithContext(o.ctx, o.integrationAPIKey, urlPostfix, queryParams)
 	if err != nil {
 		return nil, fmt.Errorf("Error on fetching users by query: %w", err)
 	}
@@ -497,7 +551,7 @@ func (o *Client) CreateUser(params models.CreateUserParams) (*models.UserID, err
 		return nil, fmt.Errorf("Error on marshalling body params: %w", err)
 	}
 
-	queryResponse, err := o.queryHelper.Post(o.integrationAPIKey, urlPostfix, nil, bodyJSON)
+	queryResponse, err := o.queryHelper.PostWithContext(o.ctx, o.integrationAPIKey, urlPostfix, nil, bodyJSON)
 	if err != nil {
 		return nil, fmt.Errorf("Error on creating user: %w", err)
 	}
@@ -524,7 +578,7 @@ func (o *Client) UpdateUserEmail(userID uuid.UUID, params models.UpdateEmail) (b
 		return false, fmt.Errorf("Error on marshalling body params: %w", err)
 	}
 
-	queryResponse, err := o.queryHelper.Put(o.integrationAPIKey, urlPostfix, nil, bodyJSON)
+	queryResponse, err := o.queryHelper.PutWithContext(o.ctx, o.integrationAPIKey, urlPostfix, nil, bodyJSON)
 	if err != nil {
 		return false, fmt.Errorf("Error on updating user email: %w", err)
 	}
@@ -540,7 +594,7 @@ func (o *Client) UpdateUserEmail(userID uuid.UUID, params models.UpdateEmail) (b
 func (o *Client) ClearUserPassword(userID uuid.UUID) (bool, error) {
 	urlPostfix := fmt.Sprintf("user/%s/clear_password", userID)
 
-	queryResponse, err := o.queryHelper.Put(o.integrationAPIKey, urlPostfix, nil, nil)
+	queryResponse, err := o.queryHelper.PutWithContext(o.ctx, o.integrationAPIKey, urlPostfix, nil, nil)
 	if err != nil {
 		return false, fmt.Errorf("Error on clearing user password: %w", err)
 	}
@@ -562,7 +616,7 @@ func (o *Client) UpdateUserMetadata(userID uuid.UUID, params models.UpdateUserMe
 		return false, fmt.Errorf("Error on marshalling body params: %w", err)
 	}
 
-	queryResponse, err := o.queryHelper.Put(o.integrationAPIKey, urlPostfix, nil, bodyJSON)
+	queryResponse, err := o.queryHelper.PutWithContext(o.ctx, o.integrationAPIKey, urlPostfix, nil, bodyJSON)
 	if err != nil {
 		return false, fmt.Errorf("Error on updating user metadata: %w", err)
 	}
@@ -583,7 +637,7 @@ func (o *Client) UpdateUserPassword(userID uuid.UUID, params models.UpdateUserPa
 		return false, fmt.Errorf("Error on marshalling body params: %w", err)
 	}
 
-	queryResponse, err := o.queryHelper.Put(o.integrationAPIKey, urlPostfix, nil, bodyJSON)
+	queryResponse, err := o.queryHelper.PutWithContext(o.ctx, o.integrationAPIKey, urlPostfix, nil, bodyJSON)
 	if err != nil {
 		return false, fmt.Errorf("Error on updating user password: %w", err)
 	}
@@ -604,7 +658,7 @@ func (o *Client) MigrateUserFromExternalSource(params models.MigrateUserParams)
 		return false, fmt.Errorf("Error on marshalling body params: %w", err)
 	}
 
-	queryResponse, err := o.queryHelper.Post(o.integrationAPIKey, urlPostfix, nil, bodyJSON)
+	queryResponse, err := o.queryHelper.PostWithContext(o.ctx, o.integrationAPIKey, urlPostfix, nil, bodyJSON)
 	if err != nil {
 		return false, fmt.Errorf("Error on migrating user: %w", err)
 	}
@@ -625,7 +679,7 @@ func (o *Client) MigrateUserPassword(params models.MigrateUserPasswordParams) (b
 		return false, fmt.Errorf("Error on marshalling body params: %w", err)
 	}
 
-	queryResponse, err := o.queryHelper.Post(o.integrationAPIKey, urlPostfix, nil, bodyJSON)
+	queryResponse, err := o.queryHelper.PostWithContext(o.ctx, o.integrationAPIKey, urlPostfix, nil, bodyJSON)
 	if err != nil {
 		return false, fmt.Errorf("Error on migrating user password: %w", err)
 	}
@@ -642,7 +696,7 @@ func (o *Client) MigrateUserPassword(params models.MigrateUserPasswordParams) (b
 func (o *Client) DeleteUser(userID uuid.UUID) (bool, error) {
 	urlPostfix := fmt.Sprintf("user/%s", userID)
 
-	queryResponse, err := o.queryHelper.Delete(o.integrationAPIKey, urlPostfix, nil, nil)
+	queryResponse, err := o.queryHelper.DeleteWithContext(o.ctx, o.integrationAPIKey, urlPostfix, nil, nil)
 	if err != nil {
 		return false, fmt.Errorf("Error on deleting user: %w", err)
 	}
@@ -658,7 +712,7 @@ func (o *Client) DeleteUser(userID uuid.UUID) (bool, error) {
 func (o *Client) DisableUser(userID uuid.UUID) (bool, error) {
 	urlPostfix := fmt.Sprintf("user/%s/disable", userID)
 
-	queryResponse, err := o.queryHelper.Post(o.integrationAPIKey, urlPostfix, nil, nil)
+	queryResponse, err := o.queryHelper.PostWithContext(o.ctx, o.integrationAPIKey, urlPostfix, nil, nil)
 	if err != nil {
 		return false, fmt.Errorf("Error on disabling user: %w", err)
 	}
@@ -674,7 +728,7 @@ func (o *Client) DisableUser(userID uuid.UUID) (bool, error) {
 func (o *Client) EnableUser(userID uuid.UUID) (bool, error) {
 	urlPostfix := fmt.Sprintf("user/%s/enable", userID)
 
-	queryResponse, err := o.queryHelper.Post(o.integrationAPIKey, urlPostfix, nil, nil)
+	queryResponse, err := o.queryHelper.PostWithContext(o.ctx, o.integrationAPIKey, urlPostfix, nil, nil)
 	if err != nil {
 		return false, fmt.Errorf("Error on enabling user: %w", err)
 	}
@@ -690,7 +744,7 @@ func (o *Client) EnableUser(userID uuid.UUID) (bool, error) {
 func (o *Client) EnableUserCanCreateOrgs(userID uuid.UUID) (bool, error) {
 	urlPostfix := fmt.Sprintf("user/%s/can_create_orgs/enable", userID)
 
-	queryResponse, err := o.queryHelper.Put(o.integrationAPIKey, urlPostfix, nil, nil)
+	queryResponse, err := o.queryHelper.PutWithContext(o.ctx, o.integrationAPIKey, urlPostfix, nil, nil)
 	if err != nil {
 		return false, fmt.Errorf("Error on enable user can create orgs: %w", err)
 	}
@@ -706,7 +760,7 @@ func (o *Client) EnableUserCanCreateOrgs(userID uuid.UUID) (bool, error) {
 func (o *Client) DisableUserCanCreateOrgs(userID uuid.UUID) (bool, error) {
 	urlPostfix := fmt.Sprintf("user/%s/can_create_orgs/disable", userID)
 
-	queryResponse, err := o.queryHelper.Put(o.integrationAPIKey, urlPostfix, nil, nil)
+	queryResponse, err := o.queryHelper.PutWithContext(o.ctx, o.integrationAPIKey, urlPostfix, nil, nil)
 	if err != nil {
 		return false, fmt.Errorf("Error on disable user can create orgs: %w", err)
 	}
@@ -722,7 +776,7 @@ func (o *Client) DisableUserCanCreateOrgs(userID uuid.UUID) (bool, error) {
 func (o *Client) DisableUser2fa(userID uuid.UUID) (bool, error) {
 	urlPostfix := fmt.Sprintf("user/%s/disable_2fa", userID)
 
-	queryResponse, err := o.queryHelper.Post(o.integrationAPIKey, urlPostfix, nil, nil)
+	queryResponse, err := o.queryHelper.PostWithContext(o.ctx, o.integrationAPIKey, urlPostfix, nil, nil)
 	if err != nil {
 		return false, fmt.Errorf("Error on disabling user 2fa: %w", err)
 	}
@@ -755,7 +809,7 @@ func (o *Client) FetchUsersInOrg(orgID uuid.UUID, params models.UserInOrgQueryPa
 		queryParams.Add("role", *params.Role)
 	}
 
-	queryResponse, err := o.queryHelper.Get(o.integrationAPIKey, urlPostfix, queryParams)
+	queryResponse, err := o.queryHelper.GetWithContext(o.ctx, o.integrationAPIKey, urlPostfix, queryParams)
 	if err != nil {
 		return nil, fmt.Errorf("Error on fetching users in org: %w", err)
 	}
@@ -781,7 +835,7 @@ func (o *Client) AddUserToOrg(params models.AddUserToOrg) (bool, error) {
 		return false, fmt.Errorf("Error on marshalling body params: %w", err)
 	}
 
-	queryResponse, err := o.queryHelper.Post(o.integrationAPIKey, urlPostfix, nil, bodyJSON)
+	queryResponse, err := o.queryHelper.PostWithContext(o.ctx, o.integrationAPIKey, urlPostfix, nil, bodyJSON)
 	if err != nil {
 		return false, fmt.Errorf("Error on adding user to org: %w", err)
 	}
@@ -802,7 +856,7 @@ func (o *Client) RemoveUserFromOrg(params models.RemoveUserFromOrg) (bool, error
 		return false, fmt.Errorf("Error on marshalling body params: %w", err)
 	}
 
-	queryResponse, err := o.queryHelper.Post(o.integrationAPIKey, urlPostfix, nil, bodyJSON)
+	queryResponse, err := o.queryHelper.PostWithContext(o.ctx, o.integrationAPIKey, urlPostfix, nil, bodyJSON)
 	if err != nil {
 		return false, fmt.Errorf("Error on removing user from org: %w", err)
 	}
@@ -823,7 +877,7 @@ func (o *Client) ChangeUserRoleInOrg(params models.ChangeUserRoleInOrg) (bool, e
 		return false, fmt.Errorf("Error on marshalling body params: %w", err)
 	}
 
-	queryResponse, err := o.queryHelper.Post(o.integrationAPIKey, urlPostfix, nil, bodyJSON)
+	queryResponse, err := o.queryHelper.PostWithContext(o.ctx, o.integrationAPIKey, urlPostfix, nil, bodyJSON)
 	if err != nil {
 		return false, fmt.Errorf("Error on changing user role in org: %w", err)
 	}
@@ -846,7 +900,7 @@ func (o *Client) InviteUserToOrg(params models.InviteUserToOrg) (bool, error) {
 		return false, fmt.Errorf("Error on marshalling body params: %w", err)
 	}
 
-	queryResponse, err := o.queryHelper.Post(o.integrationAPIKey, urlPostfix, nil, bodyJSON)
+	queryResponse, err := o.queryHelper.PostWithContext(o.ctx, o.integrationAPIKey, urlPostfix, nil, bodyJSON)
 	if err != nil {
 		return false, fmt.Errorf("Error on inviting user to org: %w", err)
 	}
@@ -866,7 +920,7 @@ func (o *Client) InviteUserToOrgByUserID(params models.InviteUserToOrgByUserID)
 		return false, fmt.Errorf("Error on marshalling body params: %w", err)
 	}
 
-	queryResponse, err := o.queryHelper.Post(o.integrationAPIKey, urlPostfix, nil, bodyJSON)
+	queryResponse, err := o.queryHelper.PostWithContext(o.ctx, o.integrationAPIKey, urlPostfix, nil, bodyJSON)
 	if err != nil {
 		return false, fmt.Errorf("Error on inviting user to org: %w", err)
 	}
@@ -894,7 +948,7 @@ func (o *Client) ResendEmailConfirmation(userID uuid.UUID) (bool, error) {
 		return false, fmt.Errorf("Error on marshalling body params: %w", err)
 	}
 
-	queryResponse, err := o.queryHelper.Post(o.integrationAPIKey, urlPostfix, nil, bodyJSON)
+	queryResponse, err := o.queryHelper.PostWithContext(o.ctx, o.integrationAPIKey, urlPostfix, nil, bodyJSON)
 	if err != nil {
 		return false, fmt.Errorf("Error on resending email confirmation to user: %w", err)
 	}
@@ -910,7 +964,7 @@ func (o *Client) ResendEmailConfirmation(userID uuid.UUID) (bool, error) {
 func (o *Client) LogoutAllUserSessions(userID uuid.UUID) (bool, error) {
 	urlPostfix := fmt.Sprintf("user/%s/logout_all_sessions", userID)
 
-	queryResponse, err := o.queryHelper.Post(o.integrationAPIKey, urlPostfix, nil, nil)
+	queryResponse, err := o.queryHelper.PostWithContext(o.ctx, o.integrationAPIKey, urlPostfix, nil, nil)
 	if err != nil {
 		return false, fmt.Errorf("Error on logging out all user sessions : %w", err)
 	}
@@ -928,7 +982,7 @@ func (o *Client) LogoutAllUserSessions(userID uuid.UUID) (bool, error) {
 func (o *Client) FetchOrg(orgID uuid.UUID) (*models.OrgCompleteMetadata, error) {
 	urlPostfix := fmt.Sprintf("org/%s", orgID)
 
-	queryResponse, err := o.queryHelper.Get(o.integrationAPIKey, urlPostfix, nil)
+	queryResponse, err := o.queryHelper.GetWithContext(o.ctx, o.integrationAPIKey, urlPostfix, nil)
 	if err != nil {
 		return nil, fmt.Errorf("Error on fetching org: %w", err)
 	}
@@ -954,7 +1008,8 @@ func (o *Client) FetchOrgByQuery(params models.OrgQueryParams) (*models.OrgList,
 		return nil, fmt.Errorf("Error on marshalling body params: %w", err)
 	}
 
-	queryResponse, err := o.queryHelper.Post(o.integrationAPIKey, urlPostfix, nil, bodyJSON)
+	// this only reads data, so it's safe to retry
+	queryResponse, err := o.queryHelper.PostWithContext(helpers.ContextWithRetry(o.ctx), o.integrationAPIKey, urlPostfix, nil, bodyJSON)
 	if err != nil {
 		return nil, fmt.Errorf("Error on fetching orgs by query: %w", err)
 	}
@@ -975,7 +1030,7 @@ func (o *Client) FetchOrgByQuery(params models.OrgQueryParams) (*models.OrgList,
 func (o *Client) FetchCustomRoleMappings() (*models.CustomRoleMappingList, error) {
 	urlPostfix := "custom_role_mappings"
 
-	queryResponse, err := o.queryHelper.Get(o.integrationAPIKey, urlPostfix, nil)
+	queryResponse, err := o.queryHelper.GetWithContext(o.ctx, o.integrationAPIKey, urlPostfix, nil)
 	if err != nil {
 		return nil, fmt.Errorf("Error on fetching custom_role_mappings: %w", err)
 	}
@@ -1008,7 +1063,7 @@ func (o *Client) FetchPendingInvites(params models.FetchPendingInvitesParams) (*
 		queryParams.Add("org_id", params.OrgID.String())
 	}
 
-	queryResponse, err := o.queryHelper.Get(o.integrationAPIKey, urlPostfix, queryParams)
+	queryResponse, err := o.queryHelper.GetWithContext(o.ctx, o.integrationAPIKey, urlPostfix, queryParams)
 	if err != nil {
 		return nil, fmt.Errorf("Error on fetching pending invites: %w", err)
 	}
@@ -1033,7 +1088,7 @@ func (o *Client) RevokePendingOrgInvite(params models.RevokePendingOrgInvite) (b
 		return false, fmt.Errorf("Error on marshalling body params: %w", err)
 	}
 
-	queryResponse, err := o.queryHelper.Delete(o.integrationAPIKey, urlPostfix, nil, bodyJSON)
+	queryResponse, err := o.queryHelper.DeleteWithContext(o.ctx, o.integrationAPIKey, urlPostfix, nil, bodyJSON)
 	if err != nil {
 		return false, fmt.Errorf("Error deleting pending org invite: %w", err)
 	}
@@ -1067,7 +1122,7 @@ func (o *Client) CreateOrg(name string) (*models.OrgMetadata, error) {
 
 	// make the request
 
-	queryResponse, err := o.queryHelper.Post(o.integrationAPIKey, urlPostfix, nil, bodyJSON)
+	queryResponse, err := o.queryHelper.PostWithContext(o.ctx, o.integrationAPIKey, urlPostfix, nil, bodyJSON)
 	if err != nil {
 		return nil, fmt.Errorf("Error on creating org: %w", err)
 	}
@@ -1093,7 +1148,7 @@ func (o *Client) CreateOrgV2(params models.CreateOrgV2Params) (*models.CreateOrg
 		return nil, fmt.Errorf("Error on marshalling body params: %w", err)
 	}
 
-	queryResponse, err := o.queryHelper.Post(o.integrationAPIKey, urlPostfix, nil, bodyJSON)
+	queryResponse, err := o.queryHelper.PostWithContext(o.ctx, o.integrationAPIKey, urlPostfix, nil, bodyJSON)
 	if err != nil {
 		return nil, fmt.Errorf("Error on creating org: %w", err)
 	}
@@ -1114,7 +1169,7 @@ func (o *Client) CreateOrgV2(params models.CreateOrgV2Params) (*models.CreateOrg
 func (o *Client) DeleteOrg(orgID uuid.UUID) (bool, error) {
 	urlPostfix := fmt.Sprintf("org/%s", orgID)
 
-	queryResponse, err := o.queryHelper.Delete(o.integrationAPIKey, urlPostfix, nil, nil)
+	queryResponse, err := o.queryHelper.DeleteWithContext(o.ctx, o.integrationAPIKey, urlPostfix, nil, nil)
 	if err != nil {
 		return false, fmt.Errorf("Error on deleting an org: %w", err)
 	}
@@ -1136,7 +1191,7 @@ func (o *Client) UpdateOrgMetadata(orgID uuid.UUID, params models.UpdateOrg) (bo
 		return false, fmt.Errorf("Error on marshalling body params: %w", err)
 	}
 
-	queryResponse, err := o.queryHelper.Put(o.integrationAPIKey, urlPostfix, nil, bodyJSON)
+	queryResponse, err := o.queryHelper.PutWithContext(o.ctx, o.integrationAPIKey, urlPostfix, nil, bodyJSON)
 	if err != nil {
 		return false, fmt.Errorf("Error on updating org metadata: %w", err)
 	}
@@ -1157,7 +1212,7 @@ func (o *Client) SubscribeOrgToRoleMapping(orgID uuid.UUID, params models.OrgRol
 		return false, fmt.Errorf("Error on marshalling body params: %w", err)
 	}
 
-	queryResponse, err := o.queryHelper.Put(o.integrationAPIKey, urlPostfix, nil, bodyJSON)
+	queryResponse, err := o.queryHelper.PutWithContext(o.ctx, o.integrationAPIKey, urlPostfix, nil, bodyJSON)
 	if err != nil {
 		return false, fmt.Errorf("Error on subscribing org to a role mapping: %w", err)
 	}
@@ -1173,7 +1228,7 @@ func (o *Client) SubscribeOrgToRoleMapping(orgID uuid.UUID, params models.OrgRol
 func (o *Client) AllowOrgToSetupSamlConnection(orgID uuid.UUID) (bool, error) {
 	urlPostfix := fmt.Sprintf("org/%s/allow_saml", orgID)
 
-	queryResponse, err := o.queryHelper.Post(o.integrationAPIKey, urlPostfix, nil, nil)
+	queryResponse, err := o.queryHelper.PostWithContext(o.ctx, o.integrationAPIKey, urlPostfix, nil, nil)
 	if err != nil {
 		return false, fmt.Errorf("Error on allowing org to setup SAML connection: %w", err)
 	}
@@ -1189,7 +1244,7 @@ func (o *Client) AllowOrgToSetupSamlConnection(orgID uuid.UUID) (bool, error) {
 func (o *Client) DisallowOrgToSetupSamlConnection(orgID uuid.UUID) (bool, error) {
 	urlPostfix := fmt.Sprintf("org/%s/disallow_saml", orgID)
 
-	queryResponse, err := o.queryHelper.Post(o.integrationAPIKey, urlPostfix, nil, nil)
+	queryResponse, err := o.queryHelper.PostWithContext(o.ctx, o.integrationAPIKey, urlPostfix, nil, nil)
 	if err != nil {
 		return false, fmt.Errorf("Error on disallowing org to setup SAML connection: %w", err)
 	}
@@ -1210,7 +1265,7 @@ func (o *Client) CreateOrgSamlConnectionLink(orgID uuid.UUID, params models.Crea
 		return nil, fmt.Errorf("Error on marshalling body params: %w", err)
 	}
 
-	queryResponse, err := o.queryHelper.Post(o.integrationAPIKey, urlPostfix, nil, bodyJSON)
+	queryResponse, err := o.queryHelper.PostWithContext(o.ctx, o.integrationAPIKey, urlPostfix, nil, bodyJSON)
 	if err != nil {
 		return nil, fmt.Errorf("Error on creating SAML connection link for org: %w", err)
 	}
@@ -1231,7 +1286,7 @@ func (o *Client) CreateOrgSamlConnectionLink(orgID uuid.UUID, params models.Crea
 func (o *Client) FetchSamlSpMetadata(orgID uuid.UUID) (*models.SamlSpMetadata, error) {
 	urlPostfix := fmt.Sprintf("saml_sp_metadata/%s", orgID)
 
-	queryResponse, err := o.queryHelper.Get(o.integrationAPIKey, urlPostfix, nil)
+	queryResponse, err := o.queryHelper.GetWithContext(o.ctx, o.integrationAPIKey, urlPostfix, nil)
 	if err != nil {
 		return nil, fmt.Errorf("Error on fetching SAML SP Metadata for org: %w", err)
 	}
@@ -1269,7 +1324,7 @@ func (o *Client) SetSamlIdpMetadata(params models.SamlIdpMetadata) (bool, error)
 		return false, fmt.Errorf("Error on marshalling body params: %w", err)
 	}
 
-	queryResponse, err := o.queryHelper.Post(o.integrationAPIKey, urlPostfix, nil, bodyJSON)
+	queryResponse, err := o.queryHelper.PostWithContext(o.ctx, o.integrationAPIKey, urlPostfix, nil, bodyJSON)
 	if err != nil {
 		return false, fmt.Errorf("Error on setting SAML IDP Metadata for org: %w", err)
 	}
@@ -1290,7 +1345,7 @@ func (o *Client) SetOidcIdpMetadata(params models.SetOidcIdpMetadataRequest) (bo
 		return false, fmt.Errorf("Error on marshalling body params: %w", err)
 	}
 
-	queryResponse, err := o.queryHelper.Post(o.integrationAPIKey, urlPostfix, nil, bodyJSON)
+	queryResponse, err := o.queryHelper.PostWithContext(o.ctx, o.integrationAPIKey, urlPostfix, nil, bodyJSON)
 	if err != nil {
 		return false, fmt.Errorf("Error on setting OIDC IDP Metadata for org: %w", err)
 	}
@@ -1306,7 +1361,7 @@ func (o *Client) SetOidcIdpMetadata(params models.SetOidcIdpMetadataRequest) (bo
 func (o *Client) SamlGoLive(orgID uuid.UUID) (bool, error) {
 	urlPostfix := fmt.Sprintf("saml_idp_metadata/go_live/%s", orgID)
 
-	queryResponse, err := o.queryHelper.Post(o.integrationAPIKey, urlPostfix, nil, nil)
+	queryResponse, err := o.queryHelper.PostWithContext(o.ctx, o.integrationAPIKey, urlPostfix, nil, nil)
 	if err != nil {
 		return false, fmt.Errorf("Error on setting SAML connection to live for org: %w", err)
 	}
@@ -1322,7 +1377,7 @@ func (o *Client) SamlGoLive(orgID uuid.UUID) (bool, error) {
 func (o *Client) DeleteSamlConnection(orgID uuid.UUID) (bool, error) {
 	urlPostfix := fmt.Sprintf("saml_idp_metadata/%s", orgID)
 
-	queryResponse, err := o.queryHelper.Delete(o.integrationAPIKey, urlPostfix, nil, nil)
+	queryResponse, err := o.queryHelper.DeleteWithContext(o.ctx, o.integrationAPIKey, urlPostfix, nil, nil)
 	if err != nil {
 		return false, fmt.Errorf("Error on deleting SAML connection for org: %w", err)
 	}
@@ -1339,7 +1394,7 @@ func (o *Client) DeleteSamlConnection(orgID uuid.UUID) (bool, error) {
 func (o *Client) FetchAPIKey(apiKeyID string) (*models.APIKeyFull, error) {
 	urlPostfix := fmt.Sprintf("end_user_api_keys/%s", apiKeyID)
 
-	queryResponse, err := o.queryHelper.Get(o.integrationAPIKey, urlPostfix, nil)
+	queryResponse, err := o.queryHelper.GetWithContext(o.ctx, o.integrationAPIKey, urlPostfix, nil)
 	if err != nil {
 		return nil, fmt.Errorf("Error on fetching an API key: %w", err)
 	}
@@ -1364,7 +1419,7 @@ func (o *Client) CreateAPIKey(params models.APIKeyCreateParams) (*models.APIKeyN
 		return nil, fmt.Errorf("Error on marshalling body params: %w", err)
 	}
 
-	queryResponse, err := o.queryHelper.Post(o.integrationAPIKey, urlPostfix, nil, bodyJSON)
+	queryResponse, err := o.queryHelper.PostWithContext(o.ctx, o.integrationAPIKey, urlPostfix, nil, bodyJSON)
 	if err != nil {
 		return nil, fmt.Errorf("Error on creating an API key: %w", err)
 	}
@@ -1389,7 +1444,7 @@ func (o *Client) ImportAPIKey(params models.APIKeyImportParams) (*models.APIKeyI
 		return nil, fmt.Errorf("Error on marshalling body params: %w", err)
 	}
 
-	queryResponse, err := o.queryHelper.Post(o.integrationAPIKey, urlPostfix, nil, bodyJSON)
+	queryResponse, err := o.queryHelper.PostWithContext(o.ctx, o.integrationAPIKey, urlPostfix, nil, bodyJSON)
 	if err != nil {
 		return nil, fmt.Errorf("Error on importing an API key: %w", err)
 	}
@@ -1414,7 +1469,7 @@ func (o *Client) UpdateAPIKey(apiKeyID string, params models.APIKeyUpdateParams)
 		return false, fmt.Errorf("Error on marshalling body params: %w", err)
 	}
 
-	queryResponse, err := o.queryHelper.Patch(o.integrationAPIKey, urlPostfix, nil, bodyJSON)
+	queryResponse, err := o.queryHelper.PatchWithContext(o.ctx, o.integrationAPIKey, urlPostfix, nil, bodyJSON)
 	if err != nil {
 		return false, fmt.Errorf("Error on updating an API key: %w", err)
 	}
@@ -1429,7 +1484,7 @@ func (o *Client) UpdateAPIKey(apiKeyID string, params models.APIKeyUpdateParams)
 func (o *Client) DeleteAPIKey(apiKeyID string) (bool, error) {
 	urlPostfix := fmt.Sprintf("end_user_api_keys/%s", apiKeyID)
 
-	queryResponse, err := o.queryHelper.Delete(o.integrationAPIKey, urlPostfix, nil, nil)
+	queryResponse, err := o.queryHelper.DeleteWithContext(o.ctx, o.integrationAPIKey, urlPostfix, nil, nil)
 	if err != nil {
 		return false, fmt.Errorf("Error on deleting an API key: %w", err)
 	}
@@ -1466,7 +1521,7 @@ func (o *Client) FetchCurrentAPIKeys(params models.APIKeysQueryParams) (*models.
 
 	// make the request
 
-	queryResponse, err := o.queryHelper.Get(o.integrationAPIKey, urlPostfix, queryParams)
+	queryResponse, err := o.queryHelper.GetWithContext(o.ctx, o.integrationAPIKey, urlPostfix, queryParams)
 	if err != nil {
 		return nil, fmt.Errorf("Error on querying API keys: %w", err)
 	}
@@ -1508,7 +1563,7 @@ func (o *Client) FetchArchivedAPIKeys(params models.APIKeysQueryParams) (*models
 
 	// make the request
 
-	queryResponse, err := o.queryHelper.Get(o.integrationAPIKey, urlPostfix, queryParams)
+	queryResponse, err := o.queryHelper.GetWithContext(o.ctx, o.integrationAPIKey, urlPostfix, queryParams)
 	if err != nil {
 		return nil, fmt.Errorf("Error on querying archived API keys: %w", err)
 	}
@@ -1573,9 +1628,9 @@ func (o *Client) ValidateAPIKey(apiKeyToken string) (*models.APIKeyValidation, e
 		return nil, fmt.Errorf("Error on marshalling body params: %w", err)
 	}
 
-	// make the request
+	// make the request, which only reads data so it's safe to retry
 
-	queryResponse, err := o.queryHelper.Post(o.integrationAPIKey, urlPostfix, nil, bodyJSON)
+	queryResponse, err := o.queryHelper.PostWithContext(helpers.ContextWithRetry(o.ctx), o.integrationAPIKey, urlPostfix, nil, bodyJSON)
 	if err != nil {
 		return nil, fmt.Errorf("Error on validating an API Key: %w", err)
 	}
@@ -1622,9 +1677,9 @@ func (o *Client) ValidateImportedAPIKey(apiKeyToken string) (*models.APIKeyValid
 		return nil, fmt.Errorf("Error on marshalling body params: %w", err)
 	}
 
-	// make the request
+	// make the request, which only reads data so it's safe to retry
 
-	queryResponse, err := o.queryHelper.Post(o.integrationAPIKey, urlPostfix, nil, bodyJSON)
+	queryResponse, err := o.queryHelper.PostWithContext(helpers.ContextWithRetry(o.ctx), o.integrationAPIKey, urlPostfix, nil, bodyJSON)
 	if err != nil {
 		return nil, fmt.Errorf("Error on validating an API Key: %w", err)
 	}
@@ -1673,7 +1728,7 @@ func (o *Client) FetchAPIKeyUsage(params models.FetchAPIKeyUsageParams) (*models
 
 	// make the request
 
-	queryResponse, err := o.queryHelper.Get(o.integrationAPIKey, urlPostfix, queryParams)
+	queryResponse, err := o.queryHelper.GetWithContext(o.ctx, o.integrationAPIKey, urlPostfix, queryParams)
 	if err != nil {
 		return nil, fmt.Errorf("Error on querying API key usage: %w", err)
 	}
@@ -1700,16 +1755,16 @@ func (o *Client) VerifyStepUpGrant(params models.VerifyStepUpGrantRequest) (*mod
 	}
 
 	// Make the request
-	queryResponse, err := o.queryHelper.Post(o.integrationAPIKey, urlPostfix, nil, bodyJSON)
+	queryResponse, err := o.queryHelper.PostWithContext(o.ctx, o.integrationAPIKey, urlPostfix, nil, bodyJSON)
 	if err != nil {
 		return nil, fmt.Errorf("Error on verifying step-up grant: %w", err)
 	}
 
 	// Check for common HTTP errors first
 	if queryResponse.StatusCode == 401 {
-		return nil, fmt.Errorf("integrationAPIKey is incorrect")
+		return nil, newAPIError(queryResponse, "integrationAPIKey is incorrect")
 	} else if queryResponse.StatusCode == 429 {
-		return nil, fmt.Errorf("Rate limit exceeded: %s", queryResponse.BodyText)
+		return nil, newAPIError(queryResponse, fmt.Sprintf("Rate limit exceeded: %s", queryResponse.BodyText))
 	}
 
 	// Success case
@@ -1719,11 +1774,9 @@ func (o *Client) VerifyStepUpGrant(params models.VerifyStepUpGrantRequest) (*mod
 		}, nil
 	}
 
-	// Handle other error cases that require JSON parsing
+	// Handle other error cases that require JSON parsing, bodies that aren't JSON are unknown errors
 	var errorResponse map[string]interface{}
-	if err := json.Unmarshal(queryResponse.BodyBytes, &errorResponse); err != nil {
-		return nil, fmt.Errorf("Error on unmarshalling error response: %w", err)
-	}
+	_ = json.Unmarshal(queryResponse.BodyBytes, &errorResponse)
 
 	// Check specific error conditions
 	if errorResponse["error_code"] == "invalid_request_fields" {
@@ -1733,12 +1786,12 @@ func (o *Client) VerifyStepUpGrant(params models.VerifyStepUpGrantRequest) (*mod
 				Success: false,
 			}, nil
 		}
-		return nil, fmt.Errorf("Bad request: %s", queryResponse.BodyText)
+		return nil, newAPIError(queryResponse, fmt.Sprintf("Bad request: %s", queryResponse.BodyText))
 	} else if errorResponse["error_code"] == "feature_gated" {
-		return nil, fmt.Errorf("This feature isn't available on your current pricing plan")
+		return nil, newAPIError(queryResponse, "This feature isn't available on your current pricing plan")
 	}
 
-	return nil, fmt.Errorf("Unknown error when verifying step up grant: %s", queryResponse.BodyText)
+	return nil, newAPIError(queryResponse, fmt.Sprintf("Unknown error when verifying step up grant: %s", queryResponse.BodyText))
 }
 
 // VerifyStepUpTotpChallenge verifies a TOTP challenge for step-up MFA
@@ -1751,16 +1804,16 @@ func (o *Client) VerifyStepUpTotpChallenge(params models.VerifyTotpChallengeRequ
 	}
 
 	// Make the request
-	queryResponse, err := o.queryHelper.Post(o.integrationAPIKey, urlPostfix, nil, bodyJSON)
+	queryResponse, err := o.queryHelper.PostWithContext(o.ctx, o.integrationAPIKey, urlPostfix, nil, bodyJSON)
 	if err != nil {
 		return nil, fmt.Errorf("Error on verifying TOTP challenge: %w", err)
 	}
 
 	// Check for HTTP errors first
 	if queryResponse.StatusCode == 401 {
-		return nil, fmt.Errorf("integrationAPIKey is incorrect")
+		return nil, newAPIError(queryResponse, "integrationAPIKey is incorrect")
 	} else if queryResponse.StatusCode == 429 {
-		return nil, fmt.Errorf("Rate limit exceeded: %s", queryResponse.BodyText)
+		return nil, newAPIError(queryResponse, fmt.Sprintf("Rate limit exceeded: %s", queryResponse.BodyText))
 	}
 
 	// Success case
@@ -1780,28 +1833,26 @@ func (o *Client) VerifyStepUpTotpChallenge(params models.VerifyTotpChallengeRequ
 		}, nil
 	}
 
-	// Handle other error cases that require JSON parsing
+	// Handle other error cases that require JSON parsing, bodies that aren't JSON are unknown errors
 	var errorResponse map[string]interface{}
-	if err := json.Unmarshal(queryResponse.BodyBytes, &errorResponse); err != nil {
-		return nil, fmt.Errorf("Error on unmarshalling error response: %w", err)
-	}
+	_ = json.Unmarshal(queryResponse.BodyBytes, &errorResponse)
 
 	// Check specific error conditions
 	errorCode, _ := errorResponse["error_code"].(string)
 	switch errorCode {
 	case "user_not_found":
-		return nil, fmt.Errorf("User not found")
+		return nil, newAPIError(queryResponse, "User not found")
 	case "mfa_not_enabled":
-		return nil, fmt.Errorf("MFA not enabled for this user")
+		return nil, newAPIError(queryResponse, "MFA not enabled for this user")
 	case "incorrect_mfa_code":
-		return nil, fmt.Errorf("Incorrect MFA code")
+		return nil, newAPIError(queryResponse, "Incorrect MFA code")
 	case "invalid_request_fields":
-		return nil, fmt.Errorf("Bad request: %s", queryResponse.BodyText)
+		return nil, newAPIError(queryResponse, fmt.Sprintf("Bad request: %s", queryResponse.BodyText))
 	case "feature_gated":
-		return nil, fmt.Errorf("This feature isn't available on your current pricing plan")
+		return nil, newAPIError(queryResponse, "This feature isn't available on your current pricing plan")
 	}
 
-	return nil, fmt.Errorf("Unknown error when verifying TOTP challenge: %s", queryResponse.BodyText)
+	return nil, newAPIError(queryResponse, fmt.Sprintf("Unknown error when verifying TOTP challenge: %s", queryResponse.BodyText))
 }
 
 func (o *Client) SendSmsMfaCode(params models.SendSmsMfaCodeRequest) (*models.SendSmsMfaCodeResponse, error) {
@@ -1813,16 +1864,16 @@ func (o *Client) SendSmsMfaCode(params models.SendSmsMfaCodeRequest) (*models.Se
 	}
 
 	// Make the request
-	queryResponse, err := o.queryHelper.Post(o.integrationAPIKey, urlPostfix, nil, bodyJSON)
+	queryResponse, err := o.queryHelper.PostWithContext(o.ctx, o.integrationAPIKey, urlPostfix, nil, bodyJSON)
 	if err != nil {
 		return nil, fmt.Errorf("Error on sending sms mfa code: %w", err)
 	}
 
 	// Check for HTTP errors first
 	if queryResponse.StatusCode == 401 {
-		return nil, fmt.Errorf("integrationAPIKey is incorrect")
+		return nil, newAPIError(queryResponse, "integrationAPIKey is incorrect")
 	} else if queryResponse.StatusCode == 429 {
-		return nil, fmt.Errorf("Rate limit exceeded: %s", queryResponse.BodyText)
+		return nil, newAPIError(queryResponse, fmt.Sprintf("Rate limit exceeded: %s", queryResponse.BodyText))
 	}
 
 	// Success case
@@ -1842,26 +1893,24 @@ func (o *Client) SendSmsMfaCode(params models.SendSmsMfaCodeRequest) (*models.Se
 		}, nil
 	}
 
-	// Handle other error cases that require JSON parsing
+	// Handle other error cases that require JSON parsing, bodies that aren't JSON are unknown errors
 	var errorResponse map[string]interface{}
-	if err := json.Unmarshal(queryResponse.BodyBytes, &errorResponse); err != nil {
-		return nil, fmt.Errorf("Error on unmarshalling error response: %w", err)
-	}
+	_ = json.Unmarshal(queryResponse.BodyBytes, &errorResponse)
 
 	// Check specific error conditions
 	errorCode, _ := errorResponse["error_code"].(string)
 	switch errorCode {
 	case "user_not_found":
-		return nil, fmt.Errorf("User not found")
+		return nil, newAPIError(queryResponse, "User not found")
 	case "mfa_not_enabled":
-		return nil, fmt.Errorf("MFA not enabled for this user")
+		return nil, newAPIError(queryResponse, "MFA not enabled for this user")
 	case "invalid_request_fields":
-		return nil, fmt.Errorf("Bad request: %s", queryResponse.BodyText)
+		return nil, newAPIError(queryResponse, fmt.Sprintf("Bad request: %s", queryResponse.BodyText))
 	case "feature_gated":
-		return nil, fmt.Errorf("This feature isn't available on your current pricing plan")
+		return nil, newAPIError(queryResponse, "This feature isn't available on your current pricing plan")
 	}
 
-	return nil, fmt.Errorf("Error on sending sms mfa code: %s", queryResponse.BodyText)
+	return nil, newAPIError(queryResponse, fmt.Sprintf("Unknown error when sending sms mfa code: %s", queryResponse.BodyText))
 }
 
 func (o *Client) VerifySmsChallenge(params models.VerifySmsChallengeRequest) (*models.VerifySmsChallengeResponse, error) {
@@ -1872,7 +1921,7 @@ func (o *Client) VerifySmsChallenge(params models.VerifySmsChallengeRequest) (*m
 		return nil, fmt.Errorf("Error on marshalling body params: %w", err)
 	}
 
-	queryResponse, err := o.queryHelper.Post(o.integrationAPIKey, urlPostfix, nil, bodyJSON)
+	queryResponse, err := o.queryHelper.PostWithContext(o.ctx, o.integrationAPIKey, urlPostfix, nil, bodyJSON)
 	if err != nil {
 		return nil, fmt.Errorf("Error on verifying sms challenge: %w", err)
 	}
@@ -1892,7 +1941,7 @@ func (o *Client) VerifySmsChallenge(params models.VerifySmsChallengeRequest) (*m
 func (o *Client) FetchUserMfaMethods(UserID uuid.UUID) (*models.FetchUserMfaMethodsResponse, error) {
 	urlPostfix := fmt.Sprintf("user/%s/mfa", UserID)
 
-	queryResponse, err := o.queryHelper.Get(o.integrationAPIKey, urlPostfix, nil)
+	queryResponse, err := o.queryHelper.GetWithContext(o.ctx, o.integrationAPIKey, urlPostfix, nil)
 	if err != nil {
 		return nil, fmt.Errorf("Error on fetching user mfa methods: %w", err)
 	}
@@ -1939,7 +1988,7 @@ func (o *Client) CreateAccessToken(userID uuid.UUID, durationInMinutes int, crea
 
 	// make the request
 
-	queryResponse, err := o.queryHelper.Post(o.integrationAPIKey, urlPostfix, nil, bodyJSON)
+	queryResponse, err := o.queryHelper.PostWithContext(o.ctx, o.integrationAPIKey, urlPostfix, nil, bodyJSON)
 	if err != nil {
 		return nil, fmt.Errorf("Error on creating access token: %w", err)
 	}
@@ -1947,15 +1996,15 @@ func (o *Client) CreateAccessToken(userID uuid.UUID, durationInMinutes int, crea
 	if queryResponse.StatusCode != 200 {
 		switch statusCode := queryResponse.StatusCode; statusCode {
 		case 401:
-			return nil, fmt.Errorf("API Key is incorrect")
+			return nil, newAPIError(queryResponse, "API Key is incorrect")
 		case 400:
-			return nil, fmt.Errorf("Bad request: %s", queryResponse.BodyText)
+			return nil, newAPIError(queryResponse, fmt.Sprintf("Bad request: %s", queryResponse.BodyText))
 		case 403:
-			return nil, fmt.Errorf("User not found")
+			return nil, newAPIErrorOfKind(queryResponse, models.ErrNotFound, "User not found")
 		case 404:
-			return nil, fmt.Errorf("Access token creation not enabled")
+			return nil, newAPIErrorOfKind(queryResponse, models.ErrFeatureGated, "Access token creation not enabled")
 		default:
-			return nil, fmt.Errorf("Unknown error when creating access token. Status code: %s. Body: %s", strconv.Itoa(queryResponse.StatusCode), queryResponse.BodyText)
+			return nil, newAPIError(queryResponse, fmt.Sprintf("Unknown error when creating access token. Status code: %s. Body: %s", strconv.Itoa(queryResponse.StatusCode), queryResponse.BodyText))
 		}
 	}
 
@@ -1976,7 +2025,7 @@ func (o *Client) CreateMagicLink(params models.CreateMagicLinkParams) (*models.C
 		return nil, fmt.Errorf("Error on marshalling body params: %w", err)
 	}
 
-	queryResponse, err := o.queryHelper.Post(o.integrationAPIKey, urlPostfix, nil, bodyJSON)
+	queryResponse, err := o.queryHelper.PostWithContext(o.ctx, o.integrationAPIKey, urlPostfix, nil, bodyJSON)
 	if err != nil {
 		return nil, fmt.Errorf("Error on creating magic link: %w", err)
 	}
@@ -1996,7 +2045,7 @@ func (o *Client) CreateMagicLink(params models.CreateMagicLinkParams) (*models.C
 func (o *Client) FetchUserSignupQueryParameters(UserID uuid.UUID) (*models.UserSignupQueryParamsResponse, error) {
 	urlPostfix := fmt.Sprintf("user/%s/signup_query_parameters", UserID)
 
-	queryResponse, err := o.queryHelper.Get(o.integrationAPIKey, urlPostfix, nil)
+	queryResponse, err := o.queryHelper.GetWithContext(o.ctx, o.integrationAPIKey, urlPostfix, nil)
 	if err != nil {
 		return nil, fmt.Errorf("Error on fetching user signup query params: %w", err)
 	}
@@ -2016,7 +2065,7 @@ func (o *Client) FetchUserSignupQueryParameters(UserID uuid.UUID) (*models.UserS
 func (o *Client) FetchEmployeeByID(employeeID uuid.UUID) (*models.FetchEmployeeByIDResponse, error) {
 	urlPostfix := fmt.Sprintf("employee/%s", employeeID)
 
-	queryResponse, err := o.queryHelper.Get(o.integrationAPIKey, urlPostfix, nil)
+	queryResponse, err := o.queryHelper.GetWithContext(o.ctx, o.integrationAPIKey, urlPostfix, nil)
 	if err != nil {
 		return nil, fmt.Errorf("Error on fetching employee by id: %w", err)
 	}
@@ -2059,7 +2108,7 @@ func (o *Client) FetchUserTopInviterReport(reportInterval *string, pagination *m
 		queryParams.Add("report_interval", *reportInterval)
 	}
 
-	queryResponse, err := o.queryHelper.Get(o.integrationAPIKey, "user_report/top_inviter", queryParams)
+	queryResponse, err := o.queryHelper.GetWithContext(o.ctx, o.integrationAPIKey, "user_report/top_inviter", queryParams)
 	if err != nil {
 		return nil, fmt.Errorf("Error on fetching report: %w", err)
 	}
@@ -2100,7 +2149,7 @@ func (o *Client) FetchUserChampionReport(reportInterval *string, pagination *mod
 		queryParams.Add("report_interval", *reportInterval)
 	}
 
-	queryResponse, err := o.queryHelper.Get(o.integrationAPIKey, "user_report/champion", queryParams)
+	queryResponse, err := o.queryHelper.GetWithContext(o.ctx, o.integrationAPIKey, "user_report/champion", queryParams)
 	if err != nil {
 		return nil, fmt.Errorf("Error on fetching report: %w", err)
 	}
@@ -2141,7 +2190,7 @@ func (o *Client) FetchUserChurnReport(reportInterval *string, pagination *models
 		queryParams.Add("report_interval", *reportInterval)
 	}
 
-	queryResponse, err := o.queryHelper.Get(o.integrationAPIKey, "user_report/churn", queryParams)
+	queryResponse, err := o.queryHelper.GetWithContext(o.ctx, o.integrationAPIKey, "user_report/churn", queryParams)
 	if err != nil {
 		return nil, fmt.Errorf("Error on fetching report: %w", err)
 	}
@@ -2181,7 +2230,7 @@ func (o *Client) FetchUserReengagementReport(reportInterval *string, pagination
 		queryParams.Add("report_interval", *reportInterval)
 	}
 
-	queryResponse, err := o.queryHelper.Get(o.integrationAPIKey, "user_report/reengagement", queryParams)
+	queryResponse, err := o.queryHelper.GetWithContext(o.ctx, o.integrationAPIKey, "user_report/reengagement", queryParams)
 	if err != nil {
 		return nil, fmt.Errorf("Error on fetching report: %w", err)
 	}
@@ -2222,7 +2271,7 @@ func (o *Client) FetchOrgGrowthReport(reportInterval *string, pagination *models
 		queryParams.Add("report_interval", *reportInterval)
 	}
 
-	queryResponse, err := o.queryHelper.Get(o.integrationAPIKey, "org_report/growth", queryParams)
+	queryResponse, err := o.queryHelper.GetWithContext(o.ctx, o.integrationAPIKey, "org_report/growth", queryParams)
 	if err != nil {
 		return nil, fmt.Errorf("Error on fetching report: %w", err)
 	}
@@ -2263,7 +2312,7 @@ func (o *Client) FetchOrgAttritionReport(reportInterval *string, pagination *mod
 		queryParams.Add("report_interval", *reportInterval)
 	}
 
-	queryResponse, err := o.queryHelper.Get(o.integrationAPIKey, "org_report/attrition", queryParams)
+	queryResponse, err := o.queryHelper.GetWithContext(o.ctx, o.integrationAPIKey, "org_report/attrition", queryParams)
 	if err != nil {
 		return nil, fmt.Errorf("Error on fetching report: %w", err)
 	}
@@ -2304,7 +2353,7 @@ func (o *Client) FetchOrgChurnReport(reportInterval *string, pagination *models.
 		queryParams.Add("report_interval", *reportInterval)
 	}
 
-	queryResponse, err := o.queryHelper.Get(o.integrationAPIKey, "org_report/churn", queryParams)
+	queryResponse, err := o.queryHelper.GetWithContext(o.ctx, o.integrationAPIKey, "org_report/churn", queryParams)
 	if err != nil {
 		return nil, fmt.Errorf("Error on fetching report: %w", err)
 	}
@@ -2344,7 +2393,7 @@ func (o *Client) FetchOrgReengagementReport(reportInterval *string, pagination *
 		queryParams.Add("report_interval", *reportInterval)
 	}
 
-	queryResponse, err := o.queryHelper.Get(o.integrationAPIKey, "org_report/reengagement", queryParams)
+	queryResponse, err := o.queryHelper.GetWithContext(o.ctx, o.integrationAPIKey, "org_report/reengagement", queryParams)
 	if err != nil {
 		return nil, fmt.Errorf("Error on fetching report: %w", err)
 	}
@@ -2397,7 +2446,7 @@ func (o *Client) FetchChartMetricData(chartMetric string, cadence *string, chart
 		queryParams.Add("cadence", *cadence)
 	}
 
-	queryResponse, err := o.queryHelper.Get(o.integrationAPIKey, urlPostfix, queryParams)
+	queryResponse, err := o.queryHelper.GetWithContext(o.ctx, o.integrationAPIKey, urlPostfix, queryParams)
 	if err != nil {
 		return nil, fmt.Errorf("Error on fetching chart data: %w", err)
 	}
@@ -2429,7 +2478,7 @@ func (o *Client) FetchOrgScimGroups(params models.FetchOrgScimGroupsRequest) (*m
 		queryParams.Add("user_id", params.UserID.String())
 	}
 
-	queryResponse, err := o.queryHelper.Get(o.integrationAPIKey, urlPostfix, queryParams)
+	queryResponse, err := o.queryHelper.GetWithContext(o.ctx, o.integrationAPIKey, urlPostfix, queryParams)
 	if err != nil {
 		return nil, fmt.Errorf("Error on fetching org SCIM groups: %w", err)
 	}
@@ -2458,7 +2507,7 @@ func (o *Client) FetchScimGroup(params models.FetchScimGroupRequest) (*models.Sc
 		queryParams.Add("members_page_size", strconv.Itoa(*params.MembersPageSize))
 	}
 
-	queryResponse, err := o.queryHelper.Get(o.integrationAPIKey, urlPostfix, queryParams)
+	queryResponse, err := o.queryHelper.GetWithContext(o.ctx, o.integrationAPIKey, urlPostfix, queryParams)
 	if err != nil {
 		return nil, fmt.Errorf("Error on fetching SCIM group: %w", err)
 	}
@@ -2478,7 +2527,7 @@ func (o *Client) FetchScimGroup(params models.FetchScimGroupRequest) (*models.Sc
 func (o *Client) FetchUserOAuthTokens(userID uuid.UUID) (*models.SocialLoginTokensResponse, error) {
 	urlPostfix := fmt.Sprintf("user/%s/oauth_token", userID)
 
-	queryResponse, err := o.queryHelper.Get(o.integrationAPIKey, urlPostfix, nil)
+	queryResponse, err := o.queryHelper.GetWithContext(o.ctx, o.integrationAPIKey, urlPostfix, nil)
 	if err != nil {
 		return nil, fmt.Errorf("Error on fetching user OAuth tokens: %w", err)
 	}
@@ -2498,7 +2547,7 @@ func (o *Client) FetchUserOAuthTokens(userID uuid.UUID) (*models.SocialLoginToke
 func (o *Client) FetchFreshTokenFromProvider(userID uuid.UUID, provider models.SocialLoginTokenProvider) (*models.SocialLoginToken, error) {
 	urlPostfix := fmt.Sprintf("user/%s/%s/fresh_token", userID, provider)
 
-	queryResponse, err := o.queryHelper.Get(o.integrationAPIKey, urlPostfix, nil)
+	queryResponse, err := o.queryHelper.GetWithContext(o.ctx, o.integrationAPIKey, urlPostfix, nil)
 	if err != nil {
 		return nil, fmt.Errorf("Error on fetching fresh user OAuth token: %w", err)
 	}
@@ -2518,40 +2567,46 @@ func (o *Client) FetchFreshTokenFromProvider(userID uuid.UUID, provider models.S
 // public methods around authorization
 
 // GetUser will get a user from a JWT token. From there you get orgs the user is in, and validate the user's
-// permissions or roles. See the UserFromToken type for more info.
+// permissions or roles. See the UserFromToken type for more info. To verify tokens without an integration API key,
+// use a TokenVerifier instead.
 func (o *Client) GetUser(authHeader string) (*models.UserFromToken, error) {
-	accessToken, err := o.validationHelper.ExtractTokenFromAuthorizationHeader(authHeader)
-	if err != nil {
-		return nil, fmt.Errorf("Error on extracting token from authorization header: %w", err)
-	}
-
-	user, err := o.validationHelper.ValidateAccessTokenAndGetUser(accessToken, o.tokenVerificationMetadata)
-	if err != nil {
-		return nil, fmt.Errorf("Error on validating access token and getting user: %w", err)
-	}
+	return o.verifier.verifyHeader(o.ctx, authHeader)
+}
 
-	return user, nil
+// TokenVerificationStatus reports the keys used to verify access tokens, and when they were last refreshed.
+func (o *Client) TokenVerificationStatus() models.TokenVerificationStatus {
+	return o.verifier.Status()
 }
 
-// private method to handle errors
+// private methods to handle errors
 
 func (o *Client) returnErrorMessageIfNotOk(queryResponse *helpers.QueryResponse) error {
 	if queryResponse.StatusCode != 200 && queryResponse.StatusCode != 204 {
 		switch statusCode := queryResponse.StatusCode; statusCode {
 		case 401:
-			return fmt.Errorf("API Key is incorrect")
+			return newAPIError(queryResponse, "API Key is incorrect")
 		case 400:
-			return fmt.Errorf("Bad request: %s", queryResponse.BodyText)
+			return newAPIError(queryResponse, fmt.Sprintf("Bad request: %s", queryResponse.BodyText))
 		case 404:
-			return fmt.Errorf("API not found")
+			return newAPIError(queryResponse, "API not found")
 		case 426:
-			return fmt.Errorf("Cannot use organizations unless B2B support is enabled--enable it in your PropelAuth dashboard")
+			return newAPIError(queryResponse, "Cannot use organizations unless B2B support is enabled--enable it in your PropelAuth dashboard")
 		case 429:
-			return fmt.Errorf("%s", queryResponse.BodyText)
+			return newAPIError(queryResponse, queryResponse.BodyText)
 		default:
-			return fmt.Errorf("Unknown error when performing operation. Status code: %s. Body: %s", strconv.Itoa(queryResponse.StatusCode), queryResponse.BodyText)
+			return newAPIError(queryResponse, fmt.Sprintf("Unknown error when performing operation. Status code: %s. Body: %s", strconv.Itoa(queryResponse.StatusCode), queryResponse.BodyText))
 		}
 	}
 
 	return nil
 }
+
+// newAPIError wraps an unsuccessful response from the backend in a *models.APIError.
+func newAPIError(queryResponse *helpers.QueryResponse, message string) error {
+	return models.NewAPIError(queryResponse.StatusCode, queryResponse.BodyBytes, message, nil)
+}
+
+// newAPIErrorOfKind is newAPIError for responses where the status code alone doesn't say what went wrong.
+func newAPIErrorOfKind(queryResponse *helpers.QueryResponse, kind error, message string) error {
+	return models.NewAPIError(queryResponse.StatusCode, queryResponse.BodyBytes, message, kind)
+}
@@ -1,7 +1,14 @@
 package client_test
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
 
 	propelauth "github.com/propelauth/propelauth-go/pkg"
@@ -34,6 +41,292 @@ func TestInitializations(t *testing.T) {
 			t.Errorf("NewClient should have returned an error about https, but did not")
 		}
 	})
+
+	t.Run("test init with an http backend origin fails by default", func(t *testing.T) {
+		_, err := propelauth.InitBaseAuth("https://auth.example.com", "apikey", tokenVerificationMetadataInput,
+			propelauth.WithBackendOrigin("http://127.0.0.1:8080"))
+		if err == nil {
+			t.Errorf("NewClient should have returned an error about https, but did not")
+		}
+	})
+
+	t.Run("test init with a local backend origin sends requests to it", func(t *testing.T) {
+		var paths []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			paths = append(paths, r.URL.Path)
+			w.WriteHeader(200)
+			_, _ = w.Write([]byte(`{"orgs": []}`))
+		}))
+		defer server.Close()
+
+		client, err := propelauth.InitBaseAuth("http://localhost:3000", "apikey", tokenVerificationMetadataInput,
+			propelauth.WithBackendOrigin(server.URL), propelauth.WithAllowInsecureHTTP())
+		if err != nil {
+			t.Errorf("NewClient returned an error, cannot continue this test: %s", err)
+
+			return
+		}
+
+		_, err = client.FetchOrgByQuery(models.OrgQueryParams{})
+		if err != nil {
+			t.Errorf("FetchOrgByQuery returned an error: %s", err)
+		}
+		if len(paths) != 1 || paths[0] != "/api/backend/v1/org/query" {
+			t.Errorf("the local backend should have received a request to /api/backend/v1/org/query, got %v", paths)
+		}
+	})
+
+	t.Run("test init with a custom transport routes requests through it", func(t *testing.T) {
+		var requests []*http.Request
+		transport := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			requests = append(requests, req)
+
+			return &http.Response{
+				StatusCode: 200,
+				Status:     "200 OK",
+				Body:       io.NopCloser(strings.NewReader(`{"org_id": "` + testHelpers.RandomOrgID().String() + `", "name": "orgname"}`)),
+				Header:     http.Header{},
+				Request:    req,
+			}, nil
+		})
+
+		client, err := propelauth.InitBaseAuth("https://auth.example.com", "apikey", tokenVerificationMetadataInput,
+			propelauth.WithTransport(transport))
+		if err != nil {
+			t.Errorf("NewClient returned an error, cannot continue this test: %s", err)
+
+			return
+		}
+
+		_, err = client.FetchOrg(testHelpers.RandomOrgID())
+		if err != nil {
+			t.Errorf("FetchOrg returned an error: %s", err)
+		}
+		if len(requests) != 1 {
+			t.Errorf("the custom transport should have received 1 request, got %d", len(requests))
+		} else if requests[0].Header.Get("Authorization") != "Bearer apikey" {
+			t.Errorf("the request should have been authorized with the integration API key")
+		}
+	})
+}
+
+func TestKeyRotation(t *testing.T) {
+	// setup a backend whose verifier key can be rotated
+
+	oldPrivateKey, oldPublicKey := testHelpers.GenerateRSAKeys()
+	newPrivateKey, newPublicKey := testHelpers.GenerateRSAKeys()
+
+	var mu sync.Mutex
+	currentPublicKey := oldPublicKey
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		_ = json.NewEncoder(w).Encode(models.AuthTokenVerificationMetadataResponse{VerifierKeyPem: currentPublicKey})
+	}))
+	defer server.Close()
+
+	authURL := "http://localhost:3000"
+	client, err := propelauth.InitBaseAuth(authURL, "apikey", nil,
+		propelauth.WithBackendOrigin(server.URL), propelauth.WithAllowInsecureHTTP(), propelauth.WithMinKeyRefreshInterval(0))
+	if err != nil {
+		t.Fatalf("NewClient returned an error, cannot even begin the tests: %s", err)
+	}
+
+	user := models.UserFromToken{UserID: testHelpers.RandomUserID()}
+	oldAuthHeader := "Bearer " + testHelpers.CreateAccessTokenWithIssuer(user, oldPrivateKey, authURL)
+	newAuthHeader := "Bearer " + testHelpers.CreateAccessTokenWithIssuer(user, newPrivateKey, authURL)
+
+	// run tests
+
+	t.Run("token signed with the fetched key is valid", func(t *testing.T) {
+		_, err := client.GetUser(oldAuthHeader)
+		if err != nil {
+			t.Errorf("GetUser returned an error: %s", err)
+		}
+	})
+
+	t.Run("token signed with a rotated key is valid after a refresh", func(t *testing.T) {
+		mu.Lock()
+		currentPublicKey = newPublicKey
+		mu.Unlock()
+
+		_, err := client.GetUser(newAuthHeader)
+		if err != nil {
+			t.Errorf("GetUser returned an error: %s", err)
+		}
+
+		status := client.TokenVerificationStatus()
+		if status.KeyCount != 2 || status.LastRefreshError != nil {
+			t.Errorf("TokenVerificationStatus should have 2 keys and no error, got: %+v", status)
+		}
+	})
+
+	t.Run("token signed with the previous key is still valid", func(t *testing.T) {
+		_, err := client.GetUser(oldAuthHeader)
+		if err != nil {
+			t.Errorf("GetUser returned an error: %s", err)
+		}
+	})
+
+	t.Run("token signed with an unknown key is invalid", func(t *testing.T) {
+		otherPrivateKey, _ := testHelpers.GenerateRSAKeys()
+		otherAuthHeader := "Bearer " + testHelpers.CreateAccessTokenWithIssuer(user, otherPrivateKey, authURL)
+
+		_, err := client.GetUser(otherAuthHeader)
+		if !errors.Is(err, models.ErrTokenSignatureInvalid) {
+			t.Errorf("GetUser should have returned ErrTokenSignatureInvalid, got: %v", err)
+		}
+	})
+}
+
+func TestErrors(t *testing.T) {
+	// setup common test data
+
+	_, publicKey := testHelpers.GenerateRSAKeys()
+
+	tokenVerificationMetadataInput := &models.TokenVerificationMetadataInput{
+		VerifierKey: publicKey,
+		Issuer:      "issuertest",
+	}
+
+	clientRespondingWith := func(statusCode int, body string) propelauth.ClientInterface {
+		transport := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: statusCode,
+				Body:       io.NopCloser(strings.NewReader(body)),
+				Header:     http.Header{},
+				Request:    req,
+			}, nil
+		})
+
+		client, err := propelauth.InitBaseAuth("https://auth.example.com", "apikey", tokenVerificationMetadataInput,
+			propelauth.WithTransport(transport))
+		if err != nil {
+			t.Fatalf("NewClient returned an error, cannot continue this test: %s", err)
+		}
+
+		return client
+	}
+
+	// run tests
+
+	t.Run("404 is ErrNotFound", func(t *testing.T) {
+		client := clientRespondingWith(404, "")
+
+		_, err := client.FetchUserMetadataByUserID(testHelpers.RandomUserID(), false)
+		if !errors.Is(err, propelauth.ErrNotFound) {
+			t.Errorf("FetchUserMetadataByUserID should have returned ErrNotFound, got: %v", err)
+		}
+
+		var apiErr *propelauth.APIError
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != 404 {
+			t.Errorf("FetchUserMetadataByUserID should have returned an APIError with status 404, got: %v", err)
+		}
+	})
+
+	t.Run("401 is ErrUnauthorized", func(t *testing.T) {
+		client := clientRespondingWith(401, "")
+
+		_, err := client.DeleteOrg(testHelpers.RandomOrgID())
+		if !errors.Is(err, propelauth.ErrUnauthorized) || errors.Is(err, propelauth.ErrNotFound) {
+			t.Errorf("DeleteOrg should have returned only ErrUnauthorized, got: %v", err)
+		}
+	})
+
+	t.Run("400 exposes the field errors", func(t *testing.T) {
+		client := clientRespondingWith(400, `{"error_code": "invalid_request_fields", "field_to_errors": {"email": "already_taken", "password": ["too_short", "too_common"]}}`)
+
+		_, err := client.CreateUser(models.CreateUserParams{Email: "test@example.com"})
+
+		var apiErr *propelauth.APIError
+		if !errors.As(err, &apiErr) {
+			t.Fatalf("CreateUser should have returned an APIError, got: %v", err)
+		}
+		if apiErr.ErrorCode != "invalid_request_fields" {
+			t.Errorf("ErrorCode should have been invalid_request_fields, got %s", apiErr.ErrorCode)
+		}
+		if len(apiErr.FieldToErrors["email"]) != 1 || len(apiErr.FieldToErrors["password"]) != 2 {
+			t.Errorf("FieldToErrors wasn't parsed correctly: %v", apiErr.FieldToErrors)
+		}
+	})
+
+	t.Run("426 is ErrB2BDisabled", func(t *testing.T) {
+		client := clientRespondingWith(426, "")
+
+		_, err := client.FetchOrg(testHelpers.RandomOrgID())
+		if !errors.Is(err, propelauth.ErrB2BDisabled) {
+			t.Errorf("FetchOrg should have returned ErrB2BDisabled, got: %v", err)
+		}
+	})
+
+	t.Run("feature_gated is ErrFeatureGated", func(t *testing.T) {
+		client := clientRespondingWith(400, `{"error_code": "feature_gated"}`)
+
+		_, err := client.VerifyStepUpGrant(models.VerifyStepUpGrantRequest{UserID: testHelpers.RandomUserID()})
+		if !errors.Is(err, propelauth.ErrFeatureGated) {
+			t.Errorf("VerifyStepUpGrant should have returned ErrFeatureGated, got: %v", err)
+		}
+	})
+
+	t.Run("step-up errors are APIErrors", func(t *testing.T) {
+		tests := []struct {
+			name       string
+			statusCode int
+			body       string
+			call       func(client propelauth.ClientInterface) error
+			expected   error
+		}{
+			{"VerifyStepUpGrant with a body that isn't JSON", 404, "Not Found", func(client propelauth.ClientInterface) error {
+				_, err := client.VerifyStepUpGrant(models.VerifyStepUpGrantRequest{UserID: testHelpers.RandomUserID()})
+				return err
+			}, propelauth.ErrNotFound},
+			{"VerifyStepUpTotpChallenge with a body that isn't JSON", 404, "Not Found", func(client propelauth.ClientInterface) error {
+				_, err := client.VerifyStepUpTotpChallenge(models.VerifyTotpChallengeRequest{UserID: testHelpers.RandomUserID()})
+				return err
+			}, propelauth.ErrNotFound},
+			{"SendSmsMfaCode with a body that isn't JSON", 404, "Not Found", func(client propelauth.ClientInterface) error {
+				_, err := client.SendSmsMfaCode(models.SendSmsMfaCodeRequest{UserID: testHelpers.RandomUserID()})
+				return err
+			}, propelauth.ErrNotFound},
+			{"SendSmsMfaCode with an unknown error code", 400, `{"error_code": "phone_not_found"}`, func(client propelauth.ClientInterface) error {
+				_, err := client.SendSmsMfaCode(models.SendSmsMfaCodeRequest{UserID: testHelpers.RandomUserID()})
+				return err
+			}, nil},
+		}
+
+		for _, test := range tests {
+			err := test.call(clientRespondingWith(test.statusCode, test.body))
+
+			var apiErr *propelauth.APIError
+			if !errors.As(err, &apiErr) || apiErr.StatusCode != test.statusCode {
+				t.Errorf("%s: expected an APIError with status %d, got: %v", test.name, test.statusCode, err)
+			}
+			if test.expected != nil && !errors.Is(err, test.expected) {
+				t.Errorf("%s: expected %v, got: %v", test.name, test.expected, err)
+			}
+		}
+	})
+
+	t.Run("API key rate limit is ErrRateLimited", func(t *testing.T) {
+		client := clientRespondingWith(429, `{"wait_seconds": 2.5, "error_code": "rate_limited", "user_facing_error": "Too many requests"}`)
+
+		_, err := client.ValidateAPIKey("token")
+		if !errors.Is(err, propelauth.ErrRateLimited) {
+			t.Errorf("ValidateAPIKey should have returned ErrRateLimited, got: %v", err)
+		}
+
+		var rateLimitErr *models.ApiKeyRateLimitError
+		if !errors.As(err, &rateLimitErr) || rateLimitErr.WaitSeconds != 2.5 {
+			t.Errorf("ValidateAPIKey should have returned an ApiKeyRateLimitError, got: %v", err)
+		}
+	})
+}
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
 }
 
 func TestValidations(t *testing.T) {
@@ -0,0 +1,38 @@
+package client
+
+import (
+	"github.com/propelauth/propelauth-go/pkg/models"
+)
+
+// APIError is returned when the PropelAuth backend responds with an error. It carries the status code, the
+// PropelAuth error_code, any field errors, and the raw body:
+//
+//	var apiErr *propelauth.APIError
+//	if errors.As(err, &apiErr) && apiErr.FieldToErrors["email"] != nil { ... }
+type APIError = models.APIError
+
+// Sentinel errors for the common ways a call can fail. Every method on the client returns errors that can be
+// checked against these with errors.Is, for example:
+//
+//	if errors.Is(err, propelauth.ErrNotFound) { ... }
+var (
+	ErrNotFound     = models.ErrNotFound
+	ErrUnauthorized = models.ErrUnauthorized
+	ErrRateLimited  = models.ErrRateLimited
+	ErrFeatureGated = models.ErrFeatureGated
+	ErrB2BDisabled  = models.ErrB2BDisabled
+)
+
+// Errors returned when an access token can't be validated, by GetUser or a TokenVerifier.
+var (
+	ErrAuthorizationHeaderInvalid = models.ErrAuthorizationHeaderInvalid
+	ErrTokenMalformed             = models.ErrTokenMalformed
+	ErrTokenSignatureInvalid      = models.ErrTokenSignatureInvalid
+	ErrTokenExpired               = models.ErrTokenExpired
+	ErrTokenNotValidYet           = models.ErrTokenNotValidYet
+	ErrTokenTooOld                = models.ErrTokenTooOld
+	ErrTokenIssuerInvalid         = models.ErrTokenIssuerInvalid
+	ErrTokenAudienceInvalid       = models.ErrTokenAudienceInvalid
+	ErrTokenLoginMethodInvalid    = models.ErrTokenLoginMethodInvalid
+	ErrTokenImpersonated          = models.ErrTokenImpersonated
+)
@@ -2,6 +2,7 @@ package helpers
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -15,6 +16,7 @@ type QueryResponse struct {
 	ResponseText string
 	BodyBytes    []byte
 	BodyText     string
+	Header       http.Header
 }
 
 // Interface for the QueryHelper.
@@ -26,57 +28,141 @@ type QueryHelperInterface interface {
 	Delete(token string, urlPostfix string, queryParams url.Values, bodyParams []byte) (*QueryResponse, error)
 }
 
+// QueryHelperWithContextInterface adds methods that bind each request to a context. It's separate from
+// QueryHelperInterface, so existing implementations and mocks of that keep compiling.
+type QueryHelperWithContextInterface interface {
+	QueryHelperInterface
+	GetWithContext(ctx context.Context, token string, urlPostfix string, queryParams url.Values) (*QueryResponse, error)
+	PatchWithContext(ctx context.Context, token string, urlPostfix string, queryParams url.Values, bodyParams []byte) (*QueryResponse, error)
+	PostWithContext(ctx context.Context, token string, urlPostfix string, queryParams url.Values, bodyParams []byte) (*QueryResponse, error)
+	PutWithContext(ctx context.Context, token string, urlPostfix string, queryParams url.Values, bodyParams []byte) (*QueryResponse, error)
+	DeleteWithContext(ctx context.Context, token string, urlPostfix string, queryParams url.Values, bodyParams []byte) (*QueryResponse, error)
+}
+
 type QueryHelper struct {
 	authHostname        string
 	backendURLAPIPrefix string
+	httpClient          *http.Client
+	retryPolicy         RetryPolicy
 }
 
-func NewQueryHelper(authHostname string, backendURLAPIPrefix string) *QueryHelper {
+// NewQueryHelper creates a QueryHelper that sends every request through httpClient, so connections are reused
+// between calls. If httpClient is nil, a plain http.Client is used. Failed requests are retried according to
+// retryPolicy; pass the zero value to never retry.
+func NewQueryHelper(authHostname string, backendURLAPIPrefix string, httpClient *http.Client, retryPolicy RetryPolicy) *QueryHelper {
+	if httpClient == nil {
+		httpClient = &http.Client{}
+	}
+
 	return &QueryHelper{
 		authHostname:        authHostname,
 		backendURLAPIPrefix: backendURLAPIPrefix,
+		httpClient:          httpClient,
+		retryPolicy:         retryPolicy,
 	}
 }
 
 // public http methods
 
 func (o *QueryHelper) Get(token string, urlPostfix string, queryParams url.Values) (*QueryResponse, error) {
+	return o.GetWithContext(context.Background(), token, urlPostfix, queryParams)
+}
+
+func (o *QueryHelper) Patch(token string, urlPostfix string, queryParams url.Values, bodyParams []byte) (*QueryResponse, error) {
+	return o.PatchWithContext(context.Background(), token, urlPostfix, queryParams, bodyParams)
+}
+
+func (o *QueryHelper) Post(token string, urlPostfix string, queryParams url.Values, bodyParams []byte) (*QueryResponse, error) {
+	return o.PostWithContext(context.Background(), token, urlPostfix, queryParams, bodyParams)
+}
+
+func (o *QueryHelper) Put(token string, urlPostfix string, queryParams url.Values, bodyParams []byte) (*QueryResponse, error) {
+	return o.PutWithContext(context.Background(), token, urlPostfix, queryParams, bodyParams)
+}
+
+func (o *QueryHelper) Delete(token string, urlPostfix string, queryParams url.Values, bodyParams []byte) (*QueryResponse, error) {
+	return o.DeleteWithContext(context.Background(), token, urlPostfix, queryParams, bodyParams)
+}
+
+func (o *QueryHelper) GetWithContext(ctx context.Context, token string, urlPostfix string, queryParams url.Values) (*QueryResponse, error) {
 	url := o.assembleURL(urlPostfix, queryParams)
 
-	return o.RequestHelper("GET", token, url, nil)
+	return o.RequestHelperWithContext(ctx, "GET", token, url, nil)
 }
 
-func (o *QueryHelper) Patch(token string, urlPostfix string, queryParams url.Values, bodyParams []byte) (*QueryResponse, error) {
+func (o *QueryHelper) PatchWithContext(ctx context.Context, token string, urlPostfix string, queryParams url.Values, bodyParams []byte) (*QueryResponse, error) {
 	url := o.assembleURL(urlPostfix, queryParams)
 
-	return o.RequestHelper("PATCH", token, url, bodyParams)
+	return o.RequestHelperWithContext(ctx, "PATCH", token, url, bodyParams)
 }
 
-func (o *QueryHelper) Post(token string, urlPostfix string, queryParams url.Values, bodyParams []byte) (*QueryResponse, error) {
+func (o *QueryHelper) PostWithContext(ctx context.Context, token string, urlPostfix string, queryParams url.Values, bodyParams []byte) (*QueryResponse, error) {
 	url := o.assembleURL(urlPostfix, queryParams)
 
-	return o.RequestHelper("POST", token, url, bodyParams)
+	return o.RequestHelperWithContext(ctx, "POST", token, url, bodyParams)
 }
 
-func (o *QueryHelper) Put(token string, urlPostfix string, queryParams url.Values, bodyParams []byte) (*QueryResponse, error) {
+func (o *QueryHelper) PutWithContext(ctx context.Context, token string, urlPostfix string, queryParams url.Values, bodyParams []byte) (*QueryResponse, error) {
 	url := o.assembleURL(urlPostfix, queryParams)
 
-	return o.RequestHelper("PUT", token, url, bodyParams)
+	return o.RequestHelperWithContext(ctx, "PUT", token, url, bodyParams)
 }
 
-func (o *QueryHelper) Delete(token string, urlPostfix string, queryParams url.Values, bodyParams []byte) (*QueryResponse, error) {
+func (o *QueryHelper) DeleteWithContext(ctx context.Context, token string, urlPostfix string, queryParams url.Values, bodyParams []byte) (*QueryResponse, error) {
 	url := o.assembleURL(urlPostfix, queryParams)
 
-	return o.RequestHelper("DELETE", token, url, bodyParams)
+	return o.RequestHelperWithContext(ctx, "DELETE", token, url, bodyParams)
 }
 
 // public helper method
 
+// RequestHelper sends the request and reads the whole response, like RequestHelperWithContext with a background
+// context.
 func (o *QueryHelper) RequestHelper(method string, token string, url string, body []byte) (*QueryResponse, error) {
+	return o.RequestHelperWithContext(context.Background(), method, token, url, body)
+}
+
+// RequestHelperWithContext sends the request and reads the whole response. The request is bound to ctx, so
+// cancelling it or letting its deadline pass aborts the request, and the returned error wraps ctx.Err().
+//
+// If the request fails with a 429, a 5xx, or a network error, and the retry policy allows it for this method,
+// we wait and try again. The last response or error is returned once we run out of attempts.
+func (o *QueryHelper) RequestHelperWithContext(ctx context.Context, method string, token string, url string, body []byte) (*QueryResponse, error) {
+	canRetry := o.retryPolicy.allowsRetry(ctx, method)
+
+	for attempt := 1; ; attempt++ {
+		queryResponse, err := o.sendRequest(ctx, method, token, url, body)
+
+		// decide if this attempt is final
+		if !canRetry || attempt >= o.retryPolicy.MaxAttempts || ctx.Err() != nil {
+			return queryResponse, err
+		}
+
+		delay := o.retryPolicy.backoff(attempt)
+		if err == nil {
+			if !isRetryableStatus(queryResponse.StatusCode) {
+				return queryResponse, nil
+			}
+
+			if wait := retryAfter(queryResponse.Header, queryResponse.BodyBytes); wait > 0 {
+				if o.retryPolicy.MaxBackoff > 0 && wait > o.retryPolicy.MaxBackoff {
+					return queryResponse, nil
+				}
+				delay = wait
+			}
+		}
+
+		if sleepErr := sleepContext(ctx, delay); sleepErr != nil {
+			return nil, fmt.Errorf("Error on waiting to retry request: %w", sleepErr)
+		}
+	}
+}
+
+func (o *QueryHelper) sendRequest(ctx context.Context, method string, token string, url string, body []byte) (*QueryResponse, error) {
 	requestBody := bytes.NewBuffer(body)
 
 	// create request
-	req, err := http.NewRequest(method, url, requestBody)
+	req, err := http.NewRequestWithContext(ctx, method, url, requestBody)
 	if err != nil {
 		return nil, fmt.Errorf("Error on creating request: %w", err)
 	}
@@ -88,8 +174,7 @@ func (o *QueryHelper) RequestHelper(method string, token string, url string, bod
 	req.Header.Set("User-Agent", "propelauth-go/0.8 go/"+runtime.Version()+" "+runtime.GOOS+"/"+runtime.GOARCH)
 
 	// send request
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := o.httpClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("Error on response: %w", err)
 	}
@@ -110,6 +195,7 @@ func (o *QueryHelper) RequestHelper(method string, token string, url string, bod
 		ResponseText: resp.Status,
 		BodyBytes:    respBytes,
 		BodyText:     string(respBytes[:]),
+		Header:       resp.Header,
 	}
 
 	return &queryResponse, nil
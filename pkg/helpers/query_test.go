@@ -0,0 +1,158 @@
+package helpers_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/propelauth/propelauth-go/pkg/helpers"
+)
+
+func TestQueryHelperContext(t *testing.T) {
+	// setup a backend that never answers in time
+
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+	}))
+	defer server.Close()
+	defer close(done)
+
+	queryHelper := helpers.NewQueryHelper("auth.example.com", server.URL+"/api/backend/v1/", nil, helpers.DefaultRetryPolicy())
+
+	// run tests
+
+	t.Run("cancelled context aborts the request", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := queryHelper.GetWithContext(ctx, "apikey", "user/query", nil)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Get should have returned an error wrapping context.Canceled, got: %v", err)
+		}
+	})
+
+	t.Run("deadline aborts the in-flight request", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		_, err := queryHelper.PostWithContext(ctx, "apikey", "user/", nil, []byte("{}"))
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Post should have returned an error wrapping context.DeadlineExceeded, got: %v", err)
+		}
+		if time.Since(start) > 2*time.Second {
+			t.Errorf("Post should have been aborted by the deadline, took %s", time.Since(start))
+		}
+	})
+}
+
+func TestQueryHelperRetries(t *testing.T) {
+	// setup a backend that fails a set number of times before succeeding
+
+	var calls int
+	var failures int
+	var failureStatus int
+	var failureHeader http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls <= failures {
+			for key, values := range failureHeader {
+				w.Header()[key] = values
+			}
+			w.WriteHeader(failureStatus)
+			return
+		}
+		w.WriteHeader(200)
+	}))
+	defer server.Close()
+
+	retryPolicy := helpers.RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     50 * time.Millisecond,
+	}
+	queryHelper := helpers.NewQueryHelper("auth.example.com", server.URL+"/api/backend/v1/", nil, retryPolicy)
+
+	reset := func(count int, status int, header http.Header) {
+		calls = 0
+		failures = count
+		failureStatus = status
+		failureHeader = header
+	}
+
+	// run tests
+
+	t.Run("GET is retried after a 503", func(t *testing.T) {
+		reset(2, 503, nil)
+
+		queryResponse, err := queryHelper.Get("apikey", "user/query", nil)
+		if err != nil {
+			t.Errorf("Get returned an error: %s", err)
+		} else if queryResponse.StatusCode != 200 || calls != 3 {
+			t.Errorf("Get should have succeeded on the third attempt, got status %d after %d calls", queryResponse.StatusCode, calls)
+		}
+	})
+
+	t.Run("GET gives up after MaxAttempts", func(t *testing.T) {
+		reset(5, 500, nil)
+
+		queryResponse, err := queryHelper.Get("apikey", "user/query", nil)
+		if err != nil {
+			t.Errorf("Get returned an error: %s", err)
+		} else if queryResponse.StatusCode != 500 || calls != 3 {
+			t.Errorf("Get should have returned the 500 after 3 calls, got status %d after %d calls", queryResponse.StatusCode, calls)
+		}
+	})
+
+	t.Run("POST is not retried by default", func(t *testing.T) {
+		reset(1, 503, nil)
+
+		queryResponse, err := queryHelper.Post("apikey", "user/", nil, []byte("{}"))
+		if err != nil {
+			t.Errorf("Post returned an error: %s", err)
+		} else if queryResponse.StatusCode != 503 || calls != 1 {
+			t.Errorf("Post should not have been retried, got status %d after %d calls", queryResponse.StatusCode, calls)
+		}
+	})
+
+	t.Run("POST is retried when opted in", func(t *testing.T) {
+		reset(1, 429, http.Header{"Retry-After": {"0"}})
+
+		ctx := helpers.ContextWithRetry(context.Background())
+		queryResponse, err := queryHelper.PostWithContext(ctx, "apikey", "user/", nil, []byte("{}"))
+		if err != nil {
+			t.Errorf("Post returned an error: %s", err)
+		} else if queryResponse.StatusCode != 200 || calls != 2 {
+			t.Errorf("Post should have been retried, got status %d after %d calls", queryResponse.StatusCode, calls)
+		}
+	})
+
+	t.Run("Retry-After longer than MaxBackoff is not waited on", func(t *testing.T) {
+		reset(1, 429, http.Header{"Retry-After": {"120"}})
+
+		queryResponse, err := queryHelper.Get("apikey", "user/query", nil)
+		if err != nil {
+			t.Errorf("Get returned an error: %s", err)
+		} else if queryResponse.StatusCode != 429 || calls != 1 {
+			t.Errorf("Get should have returned the 429 right away, got status %d after %d calls", queryResponse.StatusCode, calls)
+		}
+	})
+
+	t.Run("client errors are not retried", func(t *testing.T) {
+		reset(1, 400, nil)
+
+		queryResponse, err := queryHelper.Get("apikey", "user/query", nil)
+		if err != nil {
+			t.Errorf("Get returned an error: %s", err)
+		} else if queryResponse.StatusCode != 400 || calls != 1 {
+			t.Errorf("Get should not have retried a 400, got status %d after %d calls", queryResponse.StatusCode, calls)
+		}
+	})
+}
@@ -0,0 +1,119 @@
+package helpers
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how the QueryHelper retries requests that fail with a 429, a 5xx, or a network error.
+// The zero value disables retries.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one. Values of 1 or less disable retries.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry. It doubles on every following retry, and each delay is
+	// jittered so that many clients don't retry in lockstep.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between attempts. If the backend asks us to wait longer than this, through
+	// Retry-After or an API key rate limit's wait_seconds, we give up and return the response instead.
+	MaxBackoff time.Duration
+	// RetryNonIdempotent retries POST and PATCH requests too. By default only GET, PUT, and DELETE requests,
+	// and requests marked with ContextWithRetry, are retried.
+	RetryNonIdempotent bool
+}
+
+// DefaultRetryPolicy is a reasonable policy for most applications: up to 3 attempts, starting at 200ms and
+// backing off to at most 5s.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 200 * time.Millisecond,
+		MaxBackoff:     5 * time.Second,
+	}
+}
+
+type retryContextKey struct{}
+
+// ContextWithRetry marks the requests made with ctx as safe to retry, even if they use a non-idempotent method.
+func ContextWithRetry(ctx context.Context) context.Context {
+	return context.WithValue(ctx, retryContextKey{}, true)
+}
+
+func (o RetryPolicy) allowsRetry(ctx context.Context, method string) bool {
+	if o.MaxAttempts <= 1 {
+		return false
+	}
+
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	}
+
+	optedIn, _ := ctx.Value(retryContextKey{}).(bool)
+
+	return o.RetryNonIdempotent || optedIn
+}
+
+// backoff returns the jittered delay before the given retry, where retry 1 is the first retry.
+func (o RetryPolicy) backoff(retry int) time.Duration {
+	delay := o.InitialBackoff
+	for i := 1; i < retry && (o.MaxBackoff <= 0 || delay < o.MaxBackoff); i++ {
+		delay *= 2
+	}
+	if o.MaxBackoff > 0 && delay > o.MaxBackoff {
+		delay = o.MaxBackoff
+	}
+
+	// wait somewhere between half and all of the delay
+	half := int64(delay / 2)
+	if half <= 0 {
+		return delay
+	}
+
+	return time.Duration(half + rand.Int63n(half+1))
+}
+
+func isRetryableStatus(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= 500
+}
+
+// retryAfter returns how long the backend asked us to wait before trying again, or 0 if it didn't say. We look at
+// the Retry-After header first, then at the wait_seconds field PropelAuth returns for API key rate limits.
+func retryAfter(header http.Header, body []byte) time.Duration {
+	if value := header.Get("Retry-After"); value != "" {
+		if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+			return time.Duration(seconds) * time.Second
+		}
+		if date, err := http.ParseTime(value); err == nil {
+			if wait := time.Until(date); wait > 0 {
+				return wait
+			}
+			return 0
+		}
+	}
+
+	rateLimit := struct {
+		WaitSeconds *float64 `json:"wait_seconds"`
+	}{}
+	if err := json.Unmarshal(body, &rateLimit); err == nil && rateLimit.WaitSeconds != nil && *rateLimit.WaitSeconds > 0 {
+		return time.Duration(*rateLimit.WaitSeconds * float64(time.Second))
+	}
+
+	return 0
+}
+
+// sleepContext waits for the delay, returning early with the context's error if it's cancelled first.
+func sleepContext(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
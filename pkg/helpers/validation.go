@@ -16,6 +16,7 @@ import (
 
 type ValidationHelperInterface interface {
 	ValidateAccessTokenAndGetUser(accessToken string, tokenVerificationMetadata models.TokenVerificationMetadata) (*models.UserFromToken, error)
+	ValidateAccessTokenAndGetUserWithOptions(accessToken string, tokenVerificationMetadata models.TokenVerificationMetadata, options TokenValidationOptions) (*models.UserFromToken, error)
 	ExtractTokenFromAuthorizationHeader(authHeader string) (string, error)
 	ConvertPEMStringToRSAPublicKey(pemString string) (*rsa.PublicKey, error)
 	IsValidIsoDate(dateStr string) bool
@@ -23,36 +24,73 @@ type ValidationHelperInterface interface {
 
 type ValidationHelper struct{}
 
+// TokenValidationOptions are checks on an access token beyond its signature, issuer and expiration. The zero value
+// adds none of them.
+type TokenValidationOptions struct {
+	// Leeway is how far the token's exp, nbf and iat can be off, to allow for clock skew.
+	Leeway time.Duration
+	// MaxAge rejects tokens issued longer ago than this, according to iat. Tokens without an iat are rejected too.
+	MaxAge time.Duration
+	// Audience, if set, must be in the token's aud.
+	Audience string
+	// LoginMethods, if set, are the only login_method values allowed, like "password" or "social_sso".
+	LoginMethods []string
+	// DisallowImpersonation rejects tokens for a user that's being impersonated by an employee.
+	DisallowImpersonation bool
+}
+
 // ValidateAccessTokenAndGetUser validates the access token and returns the user data. Instead of using this
 // directly, look at client.GetUser(authHeader) instead.
 func (o *ValidationHelper) ValidateAccessTokenAndGetUser(accessToken string, tokenVerificationMetadata models.TokenVerificationMetadata) (*models.UserFromToken, error) {
+	return o.ValidateAccessTokenAndGetUserWithOptions(accessToken, tokenVerificationMetadata, TokenValidationOptions{})
+}
+
+// ValidateAccessTokenAndGetUserWithOptions is ValidateAccessTokenAndGetUser with the extra checks in options.
+func (o *ValidationHelper) ValidateAccessTokenAndGetUserWithOptions(accessToken string, tokenVerificationMetadata models.TokenVerificationMetadata, options TokenValidationOptions) (*models.UserFromToken, error) {
 	userFromToken := &models.UserFromToken{}
 
+	parserOptions := []jwt.ParserOption{
+		jwt.WithIssuer(tokenVerificationMetadata.Issuer),
+		jwt.WithValidMethods([]string{"RS256"}),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(options.Leeway),
+	}
+	if options.Audience != "" {
+		parserOptions = append(parserOptions, jwt.WithAudience(options.Audience))
+	}
+	if options.MaxAge > 0 {
+		parserOptions = append(parserOptions, jwt.WithIssuedAt())
+	}
+
 	token, err := jwt.ParseWithClaims(accessToken, userFromToken, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
 			return nil, fmt.Errorf("Error decoding JWT: Unexpected signing method: %v", token.Header["alg"])
 		}
 
-		return &tokenVerificationMetadata.VerifierKey, nil
-	},
-		jwt.WithIssuer(tokenVerificationMetadata.Issuer),
-		jwt.WithValidMethods([]string{"RS256"}),
-		jwt.WithExpirationRequired(),
-	)
+		return verificationKeys(token, tokenVerificationMetadata), nil
+	}, parserOptions...)
 
 	// friendly error messages
 	if errors.Is(err, jwt.ErrTokenMalformed) {
-		return nil, fmt.Errorf("Error decoding JWT: malformed token")
+		return nil, fmt.Errorf("Error decoding JWT: %w", models.ErrTokenMalformed)
 	} else if errors.Is(err, jwt.ErrTokenSignatureInvalid) {
-		return nil, fmt.Errorf("Error decoding JWT: invalid token signature")
-	} else if errors.Is(err, jwt.ErrTokenExpired) || errors.Is(err, jwt.ErrTokenNotValidYet) {
-		return nil, fmt.Errorf("Error decoding JWT: expired token")
+		return nil, fmt.Errorf("Error decoding JWT: %w", models.ErrTokenSignatureInvalid)
+	} else if errors.Is(err, jwt.ErrTokenExpired) {
+		return nil, fmt.Errorf("Error decoding JWT: %w", models.ErrTokenExpired)
+	} else if errors.Is(err, jwt.ErrTokenNotValidYet) || errors.Is(err, jwt.ErrTokenUsedBeforeIssued) {
+		return nil, fmt.Errorf("Error decoding JWT: %w", models.ErrTokenNotValidYet)
+	} else if errors.Is(err, jwt.ErrTokenInvalidIssuer) {
+		return nil, fmt.Errorf("Error decoding JWT: %w", models.ErrTokenIssuerInvalid)
+	} else if errors.Is(err, jwt.ErrTokenInvalidAudience) {
+		return nil, fmt.Errorf("Error decoding JWT: %w", models.ErrTokenAudienceInvalid)
+	} else if errors.Is(err, jwt.ErrTokenRequiredClaimMissing) {
+		return nil, fmt.Errorf("Error decoding JWT: %w", missingClaimError(userFromToken, options))
 	} else if err != nil {
 		return nil, fmt.Errorf("Error decoding JWT: unknown error: %w", err)
 	} else if !token.Valid {
 		return nil, fmt.Errorf("Error decoding JWT: invalid token")
 	} else if userFromToken.Issuer != tokenVerificationMetadata.Issuer {
-		return nil, fmt.Errorf("Error decoding JWT: invalid issuer")
+		return nil, fmt.Errorf("Error decoding JWT: %w", models.ErrTokenIssuerInvalid)
 	}
 
 	userFromTokenWithActiveOrg := AssignActiveOrg(userFromToken)
@@ -64,9 +102,85 @@ func (o *ValidationHelper) ValidateAccessTokenAndGetUser(accessToken string, tok
 		}
 	}
 
+	if err := checkTokenOptions(userFromTokenWithActiveOrg, options); err != nil {
+		return nil, fmt.Errorf("Error decoding JWT: %w", err)
+	}
+
 	return userFromTokenWithActiveOrg, nil
 }
 
+// missingClaimError says which required claim the token is missing. The parser doesn't say which, but the claims
+// are decoded before they're validated, so we can look. We require exp and iss, and aud when there's an audience to
+// check.
+func missingClaimError(userFromToken *models.UserFromToken, options TokenValidationOptions) error {
+	if userFromToken.ExpiresAt == nil {
+		return fmt.Errorf("%w: it has no exp", models.ErrTokenMalformed)
+	}
+	if userFromToken.Issuer == "" {
+		return fmt.Errorf("%w: it has no iss", models.ErrTokenIssuerInvalid)
+	}
+	if options.Audience != "" && len(userFromToken.Audience) == 0 {
+		return fmt.Errorf("%w: it has no aud", models.ErrTokenAudienceInvalid)
+	}
+
+	return fmt.Errorf("%w: a required claim is missing", models.ErrTokenMalformed)
+}
+
+// checkTokenOptions checks the claims the JWT parser doesn't know about. The login method must already be set.
+func checkTokenOptions(userFromToken *models.UserFromToken, options TokenValidationOptions) error {
+	if options.MaxAge > 0 {
+		if userFromToken.IssuedAt == nil {
+			return fmt.Errorf("%w: it has no iat", models.ErrTokenTooOld)
+		}
+		if time.Since(userFromToken.IssuedAt.Time) > options.MaxAge+options.Leeway {
+			return models.ErrTokenTooOld
+		}
+	}
+
+	if len(options.LoginMethods) > 0 {
+		loginMethod := userFromToken.LoginMethod.LoginMethod
+		allowed := false
+		for _, allowedLoginMethod := range options.LoginMethods {
+			allowed = allowed || allowedLoginMethod == loginMethod
+		}
+		if !allowed {
+			return fmt.Errorf("%w: %s", models.ErrTokenLoginMethodInvalid, loginMethod)
+		}
+	}
+
+	if options.DisallowImpersonation && userFromToken.ImpersonatorUserID != nil {
+		return models.ErrTokenImpersonated
+	}
+
+	return nil
+}
+
+// verificationKeys returns the keys to check the token's signature against. If the token's kid header matches the
+// ID of one of the keys, that's the only one we try, otherwise we try them all.
+func verificationKeys(token *jwt.Token, tokenVerificationMetadata models.TokenVerificationMetadata) interface{} {
+	if keyID, ok := token.Header["kid"].(string); ok && keyID != "" {
+		if tokenVerificationMetadata.VerifierKeyID == keyID {
+			return &tokenVerificationMetadata.VerifierKey
+		}
+		for i := range tokenVerificationMetadata.AdditionalVerifierKeys {
+			if tokenVerificationMetadata.AdditionalVerifierKeys[i].KeyID == keyID {
+				return &tokenVerificationMetadata.AdditionalVerifierKeys[i].PublicKey
+			}
+		}
+	}
+
+	if len(tokenVerificationMetadata.AdditionalVerifierKeys) == 0 {
+		return &tokenVerificationMetadata.VerifierKey
+	}
+
+	keys := []jwt.VerificationKey{&tokenVerificationMetadata.VerifierKey}
+	for i := range tokenVerificationMetadata.AdditionalVerifierKeys {
+		keys = append(keys, &tokenVerificationMetadata.AdditionalVerifierKeys[i].PublicKey)
+	}
+
+	return jwt.VerificationKeySet{Keys: keys}
+}
+
 func AssignActiveOrg(userFromToken *models.UserFromToken) *models.UserFromToken {
 	// Properly assign OrgIdToOrgMemberInfo for Active Org Support
 	if userFromToken.OrgMemberInfo != nil {
@@ -84,10 +198,10 @@ func (o *ValidationHelper) ExtractTokenFromAuthorizationHeader(authHeader string
 	split := strings.Split(authHeader, " ")
 
 	if len(split) != 2 {
-		return "", fmt.Errorf("Authorization header is not in the correct format")
+		return "", models.ErrAuthorizationHeaderInvalid
 	}
 	if split[0] != "Bearer" {
-		return "", fmt.Errorf("Authorization header is not in the correct format")
+		return "", models.ErrAuthorizationHeaderInvalid
 	}
 
 	return split[1], nil
@@ -0,0 +1,245 @@
+package client
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/propelauth/propelauth-go/pkg/models"
+)
+
+// Iterator walks through every item of a paged list endpoint, fetching the next page when the current one runs
+// out. Use it like this:
+//
+//	users := propelauth.IterateUsersByQuery(client, models.UserQueryParams{})
+//	for users.Next() {
+//		user := users.Value()
+//		// ...
+//	}
+//	if err := users.Err(); err != nil {
+//		// ...
+//	}
+//
+// Pages are fetched by page number, so if items are created or deleted while iterating, they can shift from one
+// page to another. Items that shift onto a page we haven't fetched yet are only returned once, and iterating stops
+// on the first empty page, even if the backend said there were more results. To iterate with a deadline, pass in
+// client.WithContext(ctx).
+type Iterator[T any] struct {
+	fetchPage  func(pageNumber int) (page []T, hasMoreResults bool, err error)
+	key        func(item T) string
+	pageNumber int
+	page       []T
+	index      int
+	current    T
+	seen       map[string]bool
+	done       bool
+	err        error
+}
+
+// Iterators for each kind of paged list.
+type UserIterator = Iterator[models.UserMetadata]
+type OrgIterator = Iterator[models.OrgMetadata]
+type APIKeyIterator = Iterator[models.APIKeyFull]
+type PendingInviteIterator = Iterator[models.PendingInvite]
+type ScimGroupIterator = Iterator[models.ScimGroupResult]
+type UserReportIterator = Iterator[models.UserReportRecord]
+type OrgReportIterator = Iterator[models.OrgReportRecord]
+
+func newIterator[T any](firstPage *int, fetchPage func(pageNumber int) ([]T, bool, error), key func(item T) string) *Iterator[T] {
+	iterator := &Iterator[T]{
+		fetchPage: fetchPage,
+		key:       key,
+		seen:      map[string]bool{},
+	}
+	if firstPage != nil {
+		iterator.pageNumber = *firstPage
+	}
+
+	return iterator
+}
+
+// Next advances to the next item, fetching the next page if needed. It returns false when there are no more items,
+// or when fetching a page failed, in which case Err returns the error.
+func (o *Iterator[T]) Next() bool {
+	for {
+		for o.index < len(o.page) {
+			item := o.page[o.index]
+			o.index++
+
+			key := o.key(item)
+			if o.seen[key] {
+				continue
+			}
+			o.seen[key] = true
+			o.current = item
+
+			return true
+		}
+
+		if o.done {
+			return false
+		}
+
+		page, hasMoreResults, err := o.fetchPage(o.pageNumber)
+		if err != nil {
+			o.err = err
+			o.done = true
+			return false
+		}
+
+		o.pageNumber++
+		o.page = page
+		o.index = 0
+		o.done = !hasMoreResults || len(page) == 0
+	}
+}
+
+// Value returns the current item. It's only valid after a call to Next returned true.
+func (o *Iterator[T]) Value() T {
+	return o.current
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (o *Iterator[T]) Err() error {
+	return o.err
+}
+
+// All collects the remaining items into a slice. If maxItems is positive, it stops after that many items, so a
+// large result set can't use unbounded memory. On error, it returns the items collected so far along with the error.
+func (o *Iterator[T]) All(maxItems int) ([]T, error) {
+	items := []T{}
+	for (maxItems <= 0 || len(items) < maxItems) && o.Next() {
+		items = append(items, o.Value())
+	}
+
+	return items, o.Err()
+}
+
+// IterateUsersByQuery iterates through every user matching the query, starting from params.PageNumber.
+func IterateUsersByQuery(client ClientInterface, params models.UserQueryParams) *UserIterator {
+	return newIterator(params.PageNumber, func(pageNumber int) ([]models.UserMetadata, bool, error) {
+		params.PageNumber = &pageNumber
+		users, err := client.FetchUsersByQuery(params)
+		if err != nil {
+			return nil, false, err
+		}
+		return users.Users, users.HasMoreResults, nil
+	}, userKey)
+}
+
+// IterateUsersInOrg iterates through every user in the organization, starting from params.PageNumber.
+func IterateUsersInOrg(client ClientInterface, orgID uuid.UUID, params models.UserInOrgQueryParams) *UserIterator {
+	return newIterator(params.PageNumber, func(pageNumber int) ([]models.UserMetadata, bool, error) {
+		params.PageNumber = &pageNumber
+		users, err := client.FetchUsersInOrg(orgID, params)
+		if err != nil {
+			return nil, false, err
+		}
+		return users.Users, users.HasMoreResults, nil
+	}, userKey)
+}
+
+// IterateOrgsByQuery iterates through every organization matching the query, starting from params.PageNumber.
+func IterateOrgsByQuery(client ClientInterface, params models.OrgQueryParams) *OrgIterator {
+	return newIterator(params.PageNumber, func(pageNumber int) ([]models.OrgMetadata, bool, error) {
+		params.PageNumber = &pageNumber
+		orgs, err := client.FetchOrgByQuery(params)
+		if err != nil {
+			return nil, false, err
+		}
+		return orgs.Orgs, orgs.HasMoreResults, nil
+	}, func(org models.OrgMetadata) string {
+		return org.OrgID.String()
+	})
+}
+
+// IterateCurrentAPIKeys iterates through every current API key matching the query, starting from params.PageNumber.
+func IterateCurrentAPIKeys(client ClientInterface, params models.APIKeysQueryParams) *APIKeyIterator {
+	return newIterator(params.PageNumber, func(pageNumber int) ([]models.APIKeyFull, bool, error) {
+		params.PageNumber = &pageNumber
+		apiKeys, err := client.FetchCurrentAPIKeys(params)
+		if err != nil {
+			return nil, false, err
+		}
+		return apiKeys.APIKeys, apiKeys.HasMoreResults, nil
+	}, apiKeyKey)
+}
+
+// IterateArchivedAPIKeys iterates through every archived API key matching the query, starting from params.PageNumber.
+func IterateArchivedAPIKeys(client ClientInterface, params models.APIKeysQueryParams) *APIKeyIterator {
+	return newIterator(params.PageNumber, func(pageNumber int) ([]models.APIKeyFull, bool, error) {
+		params.PageNumber = &pageNumber
+		apiKeys, err := client.FetchArchivedAPIKeys(params)
+		if err != nil {
+			return nil, false, err
+		}
+		return apiKeys.APIKeys, apiKeys.HasMoreResults, nil
+	}, apiKeyKey)
+}
+
+// IteratePendingInvites iterates through every pending invite matching the query, starting from params.PageNumber.
+func IteratePendingInvites(client ClientInterface, params models.FetchPendingInvitesParams) *PendingInviteIterator {
+	return newIterator(params.PageNumber, func(pageNumber int) ([]models.PendingInvite, bool, error) {
+		params.PageNumber = &pageNumber
+		invites, err := client.FetchPendingInvites(params)
+		if err != nil {
+			return nil, false, err
+		}
+		return invites.Invites, invites.HasMoreResults, nil
+	}, func(invite models.PendingInvite) string {
+		return fmt.Sprintf("%s:%s", invite.OrgID, invite.InviteeEmail)
+	})
+}
+
+// IterateOrgScimGroups iterates through every SCIM group in the organization, starting from params.PageNumber.
+func IterateOrgScimGroups(client ClientInterface, params models.FetchOrgScimGroupsRequest) *ScimGroupIterator {
+	return newIterator(params.PageNumber, func(pageNumber int) ([]models.ScimGroupResult, bool, error) {
+		params.PageNumber = &pageNumber
+		groups, err := client.FetchOrgScimGroups(params)
+		if err != nil {
+			return nil, false, err
+		}
+		// this endpoint doesn't say if there are more results, so work it out from the total
+		hasMoreResults := groups.PageSize > 0 && (groups.PageNumber+1)*groups.PageSize < groups.TotalGroups
+		return groups.Groups, hasMoreResults, nil
+	}, func(group models.ScimGroupResult) string {
+		return group.GroupID.String()
+	})
+}
+
+// IterateUserReport iterates through every record of a user report. Pass in the method for the report you want:
+//
+//	records := propelauth.IterateUserReport(client.FetchUserChurnReport, &reportInterval, nil)
+func IterateUserReport(fetchReport func(reportInterval *string, pagination *models.ReportPagination) (*models.UserReport, error), reportInterval *string, pageSize *int) *UserReportIterator {
+	return newIterator(nil, func(pageNumber int) ([]models.UserReportRecord, bool, error) {
+		report, err := fetchReport(reportInterval, &models.ReportPagination{PageSize: pageSize, PageNumber: &pageNumber})
+		if err != nil {
+			return nil, false, err
+		}
+		return report.UserReports, report.HasMoreResults, nil
+	}, func(record models.UserReportRecord) string {
+		return record.Id
+	})
+}
+
+// IterateOrgReport iterates through every record of an org report. Pass in the method for the report you want:
+//
+//	records := propelauth.IterateOrgReport(client.FetchOrgGrowthReport, &reportInterval, nil)
+func IterateOrgReport(fetchReport func(reportInterval *string, pagination *models.ReportPagination) (*models.OrgReport, error), reportInterval *string, pageSize *int) *OrgReportIterator {
+	return newIterator(nil, func(pageNumber int) ([]models.OrgReportRecord, bool, error) {
+		report, err := fetchReport(reportInterval, &models.ReportPagination{PageSize: pageSize, PageNumber: &pageNumber})
+		if err != nil {
+			return nil, false, err
+		}
+		return report.OrgReports, report.HasMoreResults, nil
+	}, func(record models.OrgReportRecord) string {
+		return record.Id
+	})
+}
+
+func userKey(user models.UserMetadata) string {
+	return user.UserID.String()
+}
+
+func apiKeyKey(apiKey models.APIKeyFull) string {
+	return apiKey.APIKeyId
+}
@@ -0,0 +1,148 @@
+package client_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/google/uuid"
+	propelauth "github.com/propelauth/propelauth-go/pkg"
+	"github.com/propelauth/propelauth-go/pkg/models"
+	testHelpers "github.com/propelauth/propelauth-go/pkg/test"
+)
+
+// clientServingUserPages creates a client whose backend serves the pages for FetchUsersByQuery, keyed by page
+// number, and records which page numbers were requested.
+func clientServingUserPages(t *testing.T, pages map[int]models.UserList) (propelauth.ClientInterface, *[]int) {
+	requested := []int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pageNumber, _ := strconv.Atoi(r.URL.Query().Get("page_number"))
+		requested = append(requested, pageNumber)
+
+		page, ok := pages[pageNumber]
+		if !ok {
+			w.WriteHeader(500)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(page)
+	}))
+	t.Cleanup(server.Close)
+
+	_, publicKey := testHelpers.GenerateRSAKeys()
+	client, err := propelauth.InitBaseAuth("http://localhost:3000", "apikey",
+		&models.TokenVerificationMetadataInput{VerifierKey: publicKey, Issuer: "issuertest"},
+		propelauth.WithBackendOrigin(server.URL), propelauth.WithAllowInsecureHTTP())
+	if err != nil {
+		t.Fatalf("NewClient returned an error, cannot even begin the tests: %s", err)
+	}
+
+	return client, &requested
+}
+
+func usersWithIDs(userIDs ...uuid.UUID) []models.UserMetadata {
+	users := []models.UserMetadata{}
+	for _, userID := range userIDs {
+		users = append(users, models.UserMetadata{UserID: userID})
+	}
+	return users
+}
+
+func TestIterators(t *testing.T) {
+	// setup common test data
+
+	a, b, c, d := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+
+	// run tests
+
+	t.Run("walks every page until there are no more results", func(t *testing.T) {
+		client, requested := clientServingUserPages(t, map[int]models.UserList{
+			0: {Users: usersWithIDs(a, b), HasMoreResults: true},
+			1: {Users: usersWithIDs(c, d), HasMoreResults: false},
+		})
+
+		users, err := propelauth.IterateUsersByQuery(client, models.UserQueryParams{}).All(0)
+		if err != nil {
+			t.Fatalf("All returned an error: %s", err)
+		}
+		if len(users) != 4 || users[0].UserID != a || users[3].UserID != d {
+			t.Errorf("expected users a, b, c, d, got %v", users)
+		}
+		if len(*requested) != 2 {
+			t.Errorf("expected 2 pages to be fetched, got %v", *requested)
+		}
+	})
+
+	t.Run("skips users that shifted onto the next page", func(t *testing.T) {
+		client, _ := clientServingUserPages(t, map[int]models.UserList{
+			0: {Users: usersWithIDs(a, b), HasMoreResults: true},
+			1: {Users: usersWithIDs(b, c), HasMoreResults: false},
+		})
+
+		users, err := propelauth.IterateUsersByQuery(client, models.UserQueryParams{}).All(0)
+		if err != nil {
+			t.Fatalf("All returned an error: %s", err)
+		}
+		if len(users) != 3 {
+			t.Errorf("expected users a, b, c, got %v", users)
+		}
+	})
+
+	t.Run("stops on an empty page even if there are more results", func(t *testing.T) {
+		client, requested := clientServingUserPages(t, map[int]models.UserList{
+			0: {Users: usersWithIDs(a), HasMoreResults: true},
+			1: {Users: usersWithIDs(), HasMoreResults: true},
+		})
+
+		users, err := propelauth.IterateUsersByQuery(client, models.UserQueryParams{}).All(0)
+		if err != nil || len(users) != 1 {
+			t.Errorf("expected just user a, got %v, %v", users, err)
+		}
+		if len(*requested) != 2 {
+			t.Errorf("expected 2 pages to be fetched, got %v", *requested)
+		}
+	})
+
+	t.Run("starts at the requested page", func(t *testing.T) {
+		client, requested := clientServingUserPages(t, map[int]models.UserList{
+			2: {Users: usersWithIDs(c), HasMoreResults: false},
+		})
+
+		pageNumber := 2
+		users, err := propelauth.IterateUsersByQuery(client, models.UserQueryParams{PageNumber: &pageNumber}).All(0)
+		if err != nil || len(users) != 1 || (*requested)[0] != 2 {
+			t.Errorf("expected to start at page 2, got %v, %v, %v", users, err, *requested)
+		}
+	})
+
+	t.Run("All stops at the max items", func(t *testing.T) {
+		client, requested := clientServingUserPages(t, map[int]models.UserList{
+			0: {Users: usersWithIDs(a, b), HasMoreResults: true},
+			1: {Users: usersWithIDs(c, d), HasMoreResults: false},
+		})
+
+		users, err := propelauth.IterateUsersByQuery(client, models.UserQueryParams{}).All(2)
+		if err != nil || len(users) != 2 {
+			t.Errorf("expected 2 users, got %v, %v", users, err)
+		}
+		if len(*requested) != 1 {
+			t.Errorf("expected only 1 page to be fetched, got %v", *requested)
+		}
+	})
+
+	t.Run("returns the users so far and the error when a page fails", func(t *testing.T) {
+		client, _ := clientServingUserPages(t, map[int]models.UserList{
+			0: {Users: usersWithIDs(a), HasMoreResults: true},
+		})
+
+		iterator := propelauth.IterateUsersByQuery(client, models.UserQueryParams{})
+		count := 0
+		for iterator.Next() {
+			count++
+		}
+		if count != 1 || iterator.Err() == nil {
+			t.Errorf("expected 1 user and an error, got %d users and %v", count, iterator.Err())
+		}
+	})
+}
@@ -0,0 +1,249 @@
+package client
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/propelauth/propelauth-go/pkg/helpers"
+	"github.com/propelauth/propelauth-go/pkg/models"
+)
+
+const defaultKeyMaxAge = time.Hour
+const defaultMinKeyRefreshInterval = time.Minute
+const retiredKeyLifetime = time.Hour
+
+// verifierKeyManager holds the keys used to verify access tokens. If the keys were fetched from the backend, it
+// also keeps them fresh: in the background once they reach their max age, and right away when a token's
+// signature doesn't match, at most once per minRefreshInterval. Each refresh replaces the whole set of keys, and
+// keys that are no longer in it are kept for a while, so tokens signed before a rotation still validate.
+type verifierKeyManager struct {
+	mu                 sync.RWMutex
+	metadata           models.TokenVerificationMetadata
+	retiredKeys        []retiredKey
+	fetchKeys          func(ctx context.Context) ([]models.VerifierKey, error)
+	maxAge             time.Duration
+	minRefreshInterval time.Duration
+	refreshing         bool
+	lastRefresh        time.Time
+	lastRefreshAttempt time.Time
+	lastRefreshError   error
+	lastKeyChange      time.Time
+}
+
+type retiredKey struct {
+	key        models.VerifierKey
+	validUntil time.Time
+}
+
+// newVerifierKeyManager creates a manager for the metadata. fetchKeys returns the current keys, with the one tokens
+// are signed with first. If it's nil, the keys are never refreshed.
+func newVerifierKeyManager(metadata models.TokenVerificationMetadata, fetchKeys func(ctx context.Context) ([]models.VerifierKey, error), options *clientOptions) *verifierKeyManager {
+	keyManager := &verifierKeyManager{
+		metadata:           metadata,
+		fetchKeys:          fetchKeys,
+		maxAge:             defaultKeyMaxAge,
+		minRefreshInterval: defaultMinKeyRefreshInterval,
+	}
+
+	if options.keyMaxAge != nil {
+		keyManager.maxAge = *options.keyMaxAge
+	}
+	if options.minKeyRefreshInterval != nil {
+		keyManager.minRefreshInterval = *options.minKeyRefreshInterval
+	}
+	if fetchKeys != nil {
+		keyManager.lastRefresh = time.Now()
+		keyManager.lastRefreshAttempt = keyManager.lastRefresh
+		keyManager.lastKeyChange = keyManager.lastRefresh
+	}
+
+	return keyManager
+}
+
+// current returns the metadata to validate tokens with, including any retired keys that are still valid.
+func (o *verifierKeyManager) current() models.TokenVerificationMetadata {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	metadata := o.metadata
+	if len(o.retiredKeys) == 0 {
+		return metadata
+	}
+
+	// copy the keys, so appending doesn't modify the ones we hold
+	metadata.AdditionalVerifierKeys = append([]models.VerifierKey{}, o.metadata.AdditionalVerifierKeys...)
+
+	now := time.Now()
+	for _, retired := range o.retiredKeys {
+		if now.Before(retired.validUntil) {
+			metadata.AdditionalVerifierKeys = append(metadata.AdditionalVerifierKeys, retired.key)
+		}
+	}
+
+	return metadata
+}
+
+// status reports the keys in use and the outcome of the last refresh.
+func (o *verifierKeyManager) status() models.TokenVerificationStatus {
+	metadata := o.current()
+
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	return models.TokenVerificationStatus{
+		KeyCount:             1 + len(metadata.AdditionalVerifierKeys),
+		LastRefreshAt:        o.lastRefresh,
+		LastRefreshAttemptAt: o.lastRefreshAttempt,
+		LastRefreshError:     o.lastRefreshError,
+		LastKeyChangeAt:      o.lastKeyChange,
+	}
+}
+
+// refreshIfStale starts a background refresh if the max age has passed since the last attempt.
+func (o *verifierKeyManager) refreshIfStale() {
+	if o.fetchKeys == nil || o.maxAge <= 0 {
+		return
+	}
+
+	o.mu.Lock()
+	if o.refreshing || time.Since(o.lastRefreshAttempt) < o.maxAge {
+		o.mu.Unlock()
+		return
+	}
+	o.refreshing = true
+	o.mu.Unlock()
+
+	go func() {
+		_, _ = o.refresh(context.Background())
+	}()
+}
+
+// refreshAfterFailure refreshes the keys right away, unless we already tried within minRefreshInterval. It
+// returns true if the keys changed, meaning it's worth validating the token again.
+func (o *verifierKeyManager) refreshAfterFailure(ctx context.Context) bool {
+	if o.fetchKeys == nil {
+		return false
+	}
+
+	o.mu.Lock()
+	if o.refreshing || time.Since(o.lastRefreshAttempt) < o.minRefreshInterval {
+		o.mu.Unlock()
+		return false
+	}
+	o.refreshing = true
+	o.mu.Unlock()
+
+	changed, err := o.refresh(ctx)
+
+	return err == nil && changed
+}
+
+// refresh fetches the current keys and swaps them in, retiring the ones that are gone. The caller must have set
+// refreshing.
+func (o *verifierKeyManager) refresh(ctx context.Context) (bool, error) {
+	keys, err := o.fetchKeys(ctx)
+	if err == nil && len(keys) == 0 {
+		err = errors.New("No verifier keys were fetched")
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	now := time.Now()
+	o.refreshing = false
+	o.lastRefreshAttempt = now
+	o.lastRefreshError = err
+	if err != nil {
+		return false, err
+	}
+	o.lastRefresh = now
+
+	previousKeys := append([]models.VerifierKey{{KeyID: o.metadata.VerifierKeyID, PublicKey: o.metadata.VerifierKey}}, o.metadata.AdditionalVerifierKeys...)
+	if sameVerifierKeys(previousKeys, keys) {
+		return false, nil
+	}
+
+	// keep keys that were removed around, so tokens they signed can still be validated until they expire
+	retiredKeys := []retiredKey{}
+	for _, key := range previousKeys {
+		if !containsVerifierKey(keys, key.PublicKey) {
+			retiredKeys = append(retiredKeys, retiredKey{key: key, validUntil: now.Add(retiredKeyLifetime)})
+		}
+	}
+	for _, retired := range o.retiredKeys {
+		if now.Before(retired.validUntil) && !containsVerifierKey(keys, retired.key.PublicKey) && !containsVerifierKey(previousKeys, retired.key.PublicKey) {
+			retiredKeys = append(retiredKeys, retired)
+		}
+	}
+
+	o.retiredKeys = retiredKeys
+	o.lastKeyChange = now
+	o.metadata.VerifierKey = keys[0].PublicKey
+	o.metadata.VerifierKeyID = keys[0].KeyID
+	o.metadata.AdditionalVerifierKeys = keys[1:]
+
+	return true, nil
+}
+
+// sameVerifierKeys reports whether a and b are the same keys, with the same IDs, in the same order.
+func sameVerifierKeys(a []models.VerifierKey, b []models.VerifierKey) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].KeyID != b[i].KeyID || !a[i].PublicKey.Equal(&b[i].PublicKey) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsVerifierKey(keys []models.VerifierKey, publicKey rsa.PublicKey) bool {
+	for i := range keys {
+		if keys[i].PublicKey.Equal(&publicKey) {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchVerifierKey fetches the public key that access tokens are currently signed with.
+func fetchVerifierKey(ctx context.Context, queryHelper *helpers.QueryHelper, validationHelper helpers.ValidationHelperInterface, integrationAPIKey string, endpointURL string) (*rsa.PublicKey, error) {
+	queryResponse, err := queryHelper.RequestHelperWithContext(ctx, "GET", integrationAPIKey, endpointURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Error on fetching token verification metadata: %w", err)
+	}
+
+	if queryResponse.StatusCode != 200 {
+		switch statusCode := queryResponse.StatusCode; statusCode {
+		case 401:
+			return nil, newAPIError(queryResponse, "integrationAPIKey is incorrect")
+		case 400:
+			return nil, newAPIError(queryResponse, fmt.Sprintf("Bad request: %s", queryResponse.BodyText))
+		case 404:
+			return nil, newAPIError(queryResponse, "URL is incorrect")
+		case 429:
+			return nil, newAPIError(queryResponse, "Rate limit exceeded")
+		default:
+			return nil, newAPIError(queryResponse, fmt.Sprintf("Unknown error when fetching token verification metadata. Status code: %s. Body: %s", strconv.Itoa(queryResponse.StatusCode), queryResponse.BodyText))
+		}
+	}
+
+	authTokenVerificationMetadataResponse := &models.AuthTokenVerificationMetadataResponse{}
+	if err := json.Unmarshal(queryResponse.BodyBytes, authTokenVerificationMetadataResponse); err != nil {
+		return nil, fmt.Errorf("Error on unmarshalling bytes to AuthTokenVerificationMetadataResponse: %w", err)
+	}
+
+	rsaPublicKey, err := validationHelper.ConvertPEMStringToRSAPublicKey(authTokenVerificationMetadataResponse.VerifierKeyPem)
+	if err != nil {
+		return nil, fmt.Errorf("Error converting a PEM string to an RSA Public Key: %w", err)
+	}
+
+	return rsaPublicKey, nil
+}
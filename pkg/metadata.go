@@ -0,0 +1,119 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/propelauth/propelauth-go/pkg/models"
+)
+
+// ErrMetadataMismatch is returned when metadata or properties can't be decoded into the type asked for, or when a
+// value can't be encoded as metadata because it isn't a JSON object.
+var ErrMetadataMismatch = errors.New("metadata doesn't match the type")
+
+// DecodeMetadata decodes metadata, like the Metadata on a UserMetadata, OrgMetadata or APIKeyFull, into a T by
+// round-tripping it through JSON, so T's json tags are respected. Keys T doesn't have are ignored, and missing
+// keys are left as their zero value. If a value has the wrong type, like a string for an int field, the error
+// matches ErrMetadataMismatch, and the *json.UnmarshalTypeError, with errors.As.
+func DecodeMetadata[T any](metadata map[string]interface{}) (T, error) {
+	var decoded T
+
+	if metadata == nil {
+		return decoded, nil
+	}
+
+	metadataJSON, err := json.Marshal(metadata)
+	if err != nil {
+		return decoded, fmt.Errorf("Error on marshalling metadata: %w", err)
+	}
+
+	if err := json.Unmarshal(metadataJSON, &decoded); err != nil {
+		return decoded, fmt.Errorf("%w: %w", ErrMetadataMismatch, err)
+	}
+
+	return decoded, nil
+}
+
+// DecodeUserMetadata decodes the metadata in a user's access token into a T, like DecodeMetadata.
+func DecodeUserMetadata[T any](user *models.UserFromToken) (T, error) {
+	return DecodeMetadata[T](user.Metadata)
+}
+
+// DecodeUserProperties decodes the custom properties in a user's access token into a T, like DecodeMetadata.
+func DecodeUserProperties[T any](user *models.UserFromToken) (T, error) {
+	return DecodeMetadata[T](user.Properties)
+}
+
+// DecodeOrgMetadata decodes the metadata of an org in an access token into a T, like DecodeMetadata.
+func DecodeOrgMetadata[T any](org *models.OrgMemberInfoFromToken) (T, error) {
+	return DecodeMetadata[T](org.OrgMetadata)
+}
+
+// EncodeMetadata converts value, usually a struct, into the map that metadata is sent as, using its json tags.
+// The error matches ErrMetadataMismatch if value isn't encoded as a JSON object, like a string, slice or nil
+// pointer.
+func EncodeMetadata(value interface{}) (map[string]interface{}, error) {
+	valueJSON, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("Error on marshalling metadata: %w", err)
+	}
+
+	if !bytes.HasPrefix(bytes.TrimSpace(valueJSON), []byte("{")) {
+		return nil, fmt.Errorf("%w: %T is not encoded as a JSON object", ErrMetadataMismatch, value)
+	}
+
+	metadata := map[string]interface{}{}
+	if err := json.Unmarshal(valueJSON, &metadata); err != nil {
+		return nil, fmt.Errorf("Error on unmarshalling metadata: %w", err)
+	}
+
+	return metadata, nil
+}
+
+// UpdateUserMetadataFrom replaces a user's metadata with metadata, encoded with EncodeMetadata.
+func UpdateUserMetadataFrom[T any](client ClientInterface, userID uuid.UUID, metadata T) (bool, error) {
+	encoded, err := EncodeMetadata(metadata)
+	if err != nil {
+		return false, err
+	}
+
+	return client.UpdateUserMetadata(userID, models.UpdateUserMetadata{Metadata: &encoded})
+}
+
+// UpdateUserPropertiesFrom updates a user's custom properties with properties, encoded with EncodeMetadata. Like
+// UpdateUserMetadata, only the properties that are sent are changed, so use omitempty to leave a field alone.
+func UpdateUserPropertiesFrom[T any](client ClientInterface, userID uuid.UUID, properties T) (bool, error) {
+	encoded, err := EncodeMetadata(properties)
+	if err != nil {
+		return false, err
+	}
+
+	return client.UpdateUserMetadata(userID, models.UpdateUserMetadata{Properties: &encoded})
+}
+
+// UpdateOrgMetadataFrom replaces an org's metadata with metadata, encoded with EncodeMetadata.
+func UpdateOrgMetadataFrom[T any](client ClientInterface, orgID uuid.UUID, metadata T) (bool, error) {
+	encoded, err := EncodeMetadata(metadata)
+	if err != nil {
+		return false, err
+	}
+
+	return client.UpdateOrgMetadata(orgID, models.UpdateOrg{Metadata: &encoded})
+}
+
+// CreateAPIKeyWithMetadata creates an API key with params, and metadata encoded with EncodeMetadata. Decode it
+// back with DecodeMetadata on the Metadata of the key returned by FetchAPIKey or ValidateAPIKey.
+func CreateAPIKeyWithMetadata[T any](client ClientInterface, params models.APIKeyCreateParams, metadata T) (*models.APIKeyNew, error) {
+	encoded, err := EncodeMetadata(metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	params.Metadata = &encoded
+
+	return client.CreateAPIKey(params)
+}
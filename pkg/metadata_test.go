@@ -0,0 +1,130 @@
+package client_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	propelauth "github.com/propelauth/propelauth-go/pkg"
+	"github.com/propelauth/propelauth-go/pkg/models"
+	testHelpers "github.com/propelauth/propelauth-go/pkg/test"
+)
+
+type accountProperties struct {
+	Plan     string `json:"plan,omitempty"`
+	Seats    int    `json:"seats,omitempty"`
+	Verified bool   `json:"verified,omitempty"`
+}
+
+type orgSettings struct {
+	Region   string   `json:"region"`
+	Features []string `json:"features"`
+}
+
+func TestTypedMetadata(t *testing.T) {
+	// setup a user in an org, with a token to read their metadata back from
+
+	fake := testHelpers.NewFakeClient(testHelpers.WithFakeRoles("Owner", "Member"))
+
+	user, err := fake.CreateUser(models.CreateUserParams{Email: "typed@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser returned an error, cannot even begin the tests: %s", err)
+	}
+	org, err := fake.CreateOrg("Acme")
+	if err != nil {
+		t.Fatalf("CreateOrg returned an error, cannot even begin the tests: %s", err)
+	}
+	if _, err := fake.AddUserToOrg(models.AddUserToOrg{UserID: user.UserID, OrgID: org.OrgID, Role: "Member"}); err != nil {
+		t.Fatalf("AddUserToOrg returned an error, cannot even begin the tests: %s", err)
+	}
+
+	userFromToken := func() *models.UserFromToken {
+		accessToken, err := fake.CreateAccessToken(user.UserID, 10)
+		if err != nil {
+			t.Fatalf("CreateAccessToken returned an error: %s", err)
+		}
+		fromToken, err := fake.GetUser("Bearer " + accessToken.AccessToken)
+		if err != nil {
+			t.Fatalf("GetUser returned an error: %s", err)
+		}
+		return fromToken
+	}
+
+	// run tests
+
+	t.Run("user properties and metadata round trip", func(t *testing.T) {
+		if _, err := propelauth.UpdateUserPropertiesFrom(fake, user.UserID, accountProperties{Plan: "pro", Seats: 5}); err != nil {
+			t.Fatalf("UpdateUserPropertiesFrom returned an error: %s", err)
+		}
+		// properties are merged, so omitted fields are left alone
+		if _, err := propelauth.UpdateUserPropertiesFrom(fake, user.UserID, accountProperties{Verified: true}); err != nil {
+			t.Fatalf("UpdateUserPropertiesFrom returned an error: %s", err)
+		}
+		if _, err := propelauth.UpdateUserMetadataFrom(fake, user.UserID, map[string]int{"logins": 3}); err != nil {
+			t.Fatalf("UpdateUserMetadataFrom returned an error: %s", err)
+		}
+
+		fromToken := userFromToken()
+
+		properties, err := propelauth.DecodeUserProperties[accountProperties](fromToken)
+		if err != nil || properties != (accountProperties{Plan: "pro", Seats: 5, Verified: true}) {
+			t.Errorf("expected the merged properties, got %+v and %v", properties, err)
+		}
+
+		metadata, err := propelauth.DecodeUserMetadata[struct{ Logins int }](fromToken)
+		if err != nil || metadata.Logins != 3 {
+			t.Errorf("expected 3 logins, got %+v and %v", metadata, err)
+		}
+	})
+
+	t.Run("org metadata round trips", func(t *testing.T) {
+		settings := orgSettings{Region: "eu", Features: []string{"sso", "scim"}}
+		if _, err := propelauth.UpdateOrgMetadataFrom(fake, org.OrgID, settings); err != nil {
+			t.Fatalf("UpdateOrgMetadataFrom returned an error: %s", err)
+		}
+
+		decoded, err := propelauth.DecodeOrgMetadata[orgSettings](userFromToken().GetOrgMemberInfo(org.OrgID))
+		if err != nil || decoded.Region != "eu" || len(decoded.Features) != 2 {
+			t.Errorf("expected the org's settings, got %+v and %v", decoded, err)
+		}
+	})
+
+	t.Run("API key metadata round trips", func(t *testing.T) {
+		apiKey, err := propelauth.CreateAPIKeyWithMetadata(fake, models.APIKeyCreateParams{OrgID: &org.OrgID}, orgSettings{Region: "us"})
+		if err != nil {
+			t.Fatalf("CreateAPIKeyWithMetadata returned an error: %s", err)
+		}
+
+		fetched, err := fake.FetchAPIKey(apiKey.APIKeyID)
+		if err != nil {
+			t.Fatalf("FetchAPIKey returned an error: %s", err)
+		}
+
+		decoded, err := propelauth.DecodeMetadata[orgSettings](fetched.Metadata)
+		if err != nil || decoded.Region != "us" {
+			t.Errorf("expected the key's region, got %+v and %v", decoded, err)
+		}
+	})
+
+	t.Run("mismatched types return ErrMetadataMismatch", func(t *testing.T) {
+		_, err := propelauth.DecodeMetadata[accountProperties](map[string]interface{}{"seats": "five"})
+		var typeErr *json.UnmarshalTypeError
+		if !errors.Is(err, propelauth.ErrMetadataMismatch) || !errors.As(err, &typeErr) || typeErr.Field != "seats" {
+			t.Errorf("expected a mismatch on seats, got %v", err)
+		}
+
+		if _, err := propelauth.UpdateUserMetadataFrom(fake, user.UserID, []string{"not", "an", "object"}); !errors.Is(err, propelauth.ErrMetadataMismatch) {
+			t.Errorf("expected ErrMetadataMismatch for a slice, got %v", err)
+		}
+		if _, err := propelauth.EncodeMetadata((*orgSettings)(nil)); !errors.Is(err, propelauth.ErrMetadataMismatch) {
+			t.Errorf("expected ErrMetadataMismatch for a nil pointer, got %v", err)
+		}
+	})
+
+	t.Run("missing metadata decodes to the zero value", func(t *testing.T) {
+		decoded, err := propelauth.DecodeOrgMetadata[orgSettings](&models.OrgMemberInfoFromToken{})
+		if err != nil || decoded.Region != "" || decoded.Features != nil {
+			t.Errorf("expected the zero value, got %+v and %v", decoded, err)
+		}
+	})
+}
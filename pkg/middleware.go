@@ -0,0 +1,156 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/propelauth/propelauth-go/pkg/models"
+)
+
+// ErrorResponder writes the response for a request that was rejected by the middleware. The error says why the
+// request was rejected, and is meant for logging rather than for the caller.
+type ErrorResponder func(w http.ResponseWriter, r *http.Request, err error)
+
+// MiddlewareOption configures optional behavior of the AuthMiddleware returned by NewAuthMiddleware.
+type MiddlewareOption func(*AuthMiddleware)
+
+// AuthMiddleware protects net/http handlers by validating the access token in the Authorization header.
+type AuthMiddleware struct {
+	client                ClientInterface
+	unauthorizedResponder ErrorResponder
+	forbiddenResponder    ErrorResponder
+	badRequestResponder   ErrorResponder
+}
+
+type userContextKey struct{}
+
+// NewAuthMiddleware creates middleware that validates access tokens with client.GetUser. By default, rejected
+// requests get a JSON error body, with a 401 when there's no valid access token, a 403 when the user isn't allowed
+// to access the route, and a 400 when the org middleware can't find an org ID in the request.
+func NewAuthMiddleware(client ClientInterface, opts ...MiddlewareOption) *AuthMiddleware {
+	middleware := &AuthMiddleware{
+		client:                client,
+		unauthorizedResponder: DefaultUnauthorizedResponder,
+		forbiddenResponder:    DefaultForbiddenResponder,
+		badRequestResponder:   DefaultBadRequestResponder,
+	}
+
+	for _, opt := range opts {
+		opt(middleware)
+	}
+
+	return middleware
+}
+
+// WithUnauthorizedResponder replaces the response written when a request doesn't have a valid access token.
+func WithUnauthorizedResponder(responder ErrorResponder) MiddlewareOption {
+	return func(o *AuthMiddleware) {
+		o.unauthorizedResponder = responder
+	}
+}
+
+// WithForbiddenResponder replaces the response written when a user isn't allowed to access a route.
+func WithForbiddenResponder(responder ErrorResponder) MiddlewareOption {
+	return func(o *AuthMiddleware) {
+		o.forbiddenResponder = responder
+	}
+}
+
+// WithBadRequestResponder replaces the response written when the org middleware can't find an org ID in the
+// request.
+func WithBadRequestResponder(responder ErrorResponder) MiddlewareOption {
+	return func(o *AuthMiddleware) {
+		o.badRequestResponder = responder
+	}
+}
+
+// RequireUser only calls next if the request has a valid access token, and otherwise responds with a 401. The
+// user is available to next through UserFromContext.
+func (o *AuthMiddleware) RequireUser(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, err := o.client.WithContext(r.Context()).GetUser(r.Header.Get("Authorization"))
+		if err != nil {
+			o.Unauthorized(w, r, err)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(ContextWithUser(r.Context(), user)))
+	})
+}
+
+// OptionalUser always calls next, and if the request has a valid access token, the user is available to next
+// through UserFromContext. A missing or invalid access token is treated the same as an anonymous request.
+func (o *AuthMiddleware) OptionalUser(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authHeader := r.Header.Get("Authorization")
+		if authHeader == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		user, err := o.client.WithContext(r.Context()).GetUser(authHeader)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(ContextWithUser(r.Context(), user)))
+	})
+}
+
+// Unauthorized writes the configured 401 response. Use it from your own handlers to reject requests the same
+// way the middleware does.
+func (o *AuthMiddleware) Unauthorized(w http.ResponseWriter, r *http.Request, err error) {
+	o.unauthorizedResponder(w, r, err)
+}
+
+// Forbidden writes the configured 403 response. Use it from your own handlers to reject requests the same way
+// the middleware does.
+func (o *AuthMiddleware) Forbidden(w http.ResponseWriter, r *http.Request, err error) {
+	o.forbiddenResponder(w, r, err)
+}
+
+// BadRequest writes the configured 400 response. Use it from your own handlers to reject requests the same way
+// the middleware does.
+func (o *AuthMiddleware) BadRequest(w http.ResponseWriter, r *http.Request, err error) {
+	o.badRequestResponder(w, r, err)
+}
+
+// ContextWithUser returns a copy of ctx that carries the user, which can be read back with UserFromContext.
+func ContextWithUser(ctx context.Context, user *models.UserFromToken) context.Context {
+	return context.WithValue(ctx, userContextKey{}, user)
+}
+
+// UserFromContext returns the user that RequireUser or OptionalUser validated, if there is one.
+func UserFromContext(ctx context.Context) (*models.UserFromToken, bool) {
+	user, ok := ctx.Value(userContextKey{}).(*models.UserFromToken)
+
+	return user, ok && user != nil
+}
+
+// DefaultUnauthorizedResponder responds with a 401 and a JSON body like {"error": "unauthorized"}.
+func DefaultUnauthorizedResponder(w http.ResponseWriter, r *http.Request, err error) {
+	w.Header().Set("WWW-Authenticate", "Bearer")
+	writeJSONError(w, http.StatusUnauthorized, "unauthorized")
+}
+
+// DefaultForbiddenResponder responds with a 403 and a JSON body like {"error": "forbidden"}.
+func DefaultForbiddenResponder(w http.ResponseWriter, r *http.Request, err error) {
+	writeJSONError(w, http.StatusForbidden, "forbidden")
+}
+
+// DefaultBadRequestResponder responds with a 400 and a JSON body like {"error": "bad_request"}.
+func DefaultBadRequestResponder(w http.ResponseWriter, r *http.Request, err error) {
+	writeJSONError(w, http.StatusBadRequest, "bad_request")
+}
+
+func writeJSONError(w http.ResponseWriter, statusCode int, errorCode string) {
+	writeJSON(w, statusCode, map[string]string{"error": errorCode})
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(body)
+}
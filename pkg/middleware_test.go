@@ -0,0 +1,127 @@
+package client_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	propelauth "github.com/propelauth/propelauth-go/pkg"
+	"github.com/propelauth/propelauth-go/pkg/models"
+	testHelpers "github.com/propelauth/propelauth-go/pkg/test"
+)
+
+func TestAuthMiddleware(t *testing.T) {
+	// setup a client and a valid access token
+
+	privateKey, publicKey := testHelpers.GenerateRSAKeys()
+
+	client, err := propelauth.InitBaseAuth("https://auth.example.com", "apikey", &models.TokenVerificationMetadataInput{
+		VerifierKey: publicKey,
+		Issuer:      "issuertest",
+	})
+	if err != nil {
+		t.Fatalf("NewClient returned an error, cannot even begin the tests: %s", err)
+	}
+
+	userID := testHelpers.RandomUserID()
+	authHeader := "Bearer " + testHelpers.CreateAccessToken(models.UserFromToken{UserID: userID}, privateKey)
+
+	// a handler that reports the user it found in the context
+	whoami := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, ok := propelauth.UserFromContext(r.Context())
+		if !ok {
+			_, _ = w.Write([]byte("anonymous"))
+			return
+		}
+		_, _ = w.Write([]byte(user.UserID.String()))
+	})
+
+	serve := func(handler http.Handler, authHeader string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest("GET", "/whoami", nil)
+		if authHeader != "" {
+			request.Header.Set("Authorization", authHeader)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+
+		return recorder
+	}
+
+	middleware := propelauth.NewAuthMiddleware(client)
+
+	// run tests
+
+	t.Run("RequireUser passes a valid user to the handler", func(t *testing.T) {
+		recorder := serve(middleware.RequireUser(whoami), authHeader)
+		if recorder.Code != 200 || recorder.Body.String() != userID.String() {
+			t.Errorf("expected 200 with the user ID, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+	})
+
+	t.Run("RequireUser rejects a missing token with a JSON 401", func(t *testing.T) {
+		recorder := serve(middleware.RequireUser(whoami), "")
+		if recorder.Code != 401 {
+			t.Errorf("expected 401, got %d", recorder.Code)
+		}
+
+		body := map[string]string{}
+		if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil || body["error"] != "unauthorized" {
+			t.Errorf("expected a JSON error body, got %s", recorder.Body.String())
+		}
+		if recorder.Header().Get("Content-Type") != "application/json" {
+			t.Errorf("expected a JSON content type, got %s", recorder.Header().Get("Content-Type"))
+		}
+	})
+
+	t.Run("RequireUser rejects a token signed with another key", func(t *testing.T) {
+		otherPrivateKey, _ := testHelpers.GenerateRSAKeys()
+		otherAuthHeader := "Bearer " + testHelpers.CreateAccessToken(models.UserFromToken{UserID: userID}, otherPrivateKey)
+
+		recorder := serve(middleware.RequireUser(whoami), otherAuthHeader)
+		if recorder.Code != 401 {
+			t.Errorf("expected 401, got %d", recorder.Code)
+		}
+	})
+
+	t.Run("OptionalUser passes a valid user to the handler", func(t *testing.T) {
+		recorder := serve(middleware.OptionalUser(whoami), authHeader)
+		if recorder.Code != 200 || recorder.Body.String() != userID.String() {
+			t.Errorf("expected 200 with the user ID, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+	})
+
+	t.Run("OptionalUser treats a missing or invalid token as anonymous", func(t *testing.T) {
+		for _, header := range []string{"", "Bearer invalid"} {
+			recorder := serve(middleware.OptionalUser(whoami), header)
+			if recorder.Code != 200 || recorder.Body.String() != "anonymous" {
+				t.Errorf("expected 200 and anonymous for %q, got %d: %s", header, recorder.Code, recorder.Body.String())
+			}
+		}
+	})
+
+	t.Run("custom responders are used", func(t *testing.T) {
+		var unauthorizedErr error
+		custom := propelauth.NewAuthMiddleware(client,
+			propelauth.WithUnauthorizedResponder(func(w http.ResponseWriter, r *http.Request, err error) {
+				unauthorizedErr = err
+				w.WriteHeader(418)
+			}),
+			propelauth.WithForbiddenResponder(func(w http.ResponseWriter, r *http.Request, err error) {
+				w.WriteHeader(451)
+			}),
+		)
+
+		recorder := serve(custom.RequireUser(whoami), "")
+		if recorder.Code != 418 || unauthorizedErr == nil {
+			t.Errorf("expected the custom 401 responder to be called with an error, got %d", recorder.Code)
+		}
+
+		recorder = httptest.NewRecorder()
+		custom.Forbidden(recorder, httptest.NewRequest("GET", "/", nil), errors.New("not allowed"))
+		if recorder.Code != 451 {
+			t.Errorf("expected the custom 403 responder to be called, got %d", recorder.Code)
+		}
+	})
+}
@@ -0,0 +1,119 @@
+package migration
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/propelauth/propelauth-go/pkg/models"
+)
+
+// auth0User has the fields we use from a line of an Auth0 export. It covers both the NDJSON from a user export job,
+// and the password hash export you can request from Auth0 support, which has _id and passwordHash instead.
+type auth0User struct {
+	UserID        string   `json:"user_id"`
+	MongoID       *mongoID `json:"_id"`
+	Email         string   `json:"email"`
+	EmailVerified bool     `json:"email_verified"`
+	Username      string   `json:"username"`
+	GivenName     string   `json:"given_name"`
+	FamilyName    string   `json:"family_name"`
+	Picture       string   `json:"picture"`
+	Blocked       bool     `json:"blocked"`
+	PasswordHash  string   `json:"passwordHash"`
+}
+
+type mongoID struct {
+	OID string `json:"$oid"`
+}
+
+// Auth0Reader reads the NDJSON of an Auth0 user export, or of a password hash export. The Auth0 user ID is the legacy
+// ID, bcrypt hashes are migrated as they are, and blocked users are migrated disabled. Users without an email, like
+// ones who only log in with a phone number, are returned as a RecordError that wraps ErrMissingEmail.
+type Auth0Reader struct {
+	scanner *bufio.Scanner
+	number  int
+}
+
+// NewAuth0Reader creates a reader of the Auth0 export in r.
+func NewAuth0Reader(r io.Reader) *Auth0Reader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+
+	return &Auth0Reader{scanner: scanner}
+}
+
+func (o *Auth0Reader) Next() (*Record, error) {
+	for o.scanner.Scan() {
+		line := bytes.TrimSpace(o.scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		o.number++
+		user := auth0User{}
+		if err := json.Unmarshal(line, &user); err != nil {
+			return nil, &RecordError{Number: o.number, Err: fmt.Errorf("Error on unmarshalling the record: %w", err)}
+		}
+
+		params, err := user.migrateUserParams()
+		if err != nil {
+			return nil, &RecordError{Number: o.number, Err: err}
+		}
+
+		return &Record{Number: o.number, Params: *params}, nil
+	}
+
+	if err := o.scanner.Err(); err != nil {
+		return nil, fmt.Errorf("Error on reading the records: %w", err)
+	}
+
+	return nil, io.EOF
+}
+
+func (o auth0User) migrateUserParams() (*models.MigrateUserParams, error) {
+	if strings.TrimSpace(o.Email) == "" {
+		return nil, ErrMissingEmail
+	}
+
+	// users in a database connection have IDs like auth0|<_id>
+	legacyUserID := o.UserID
+	if legacyUserID == "" && o.MongoID != nil && o.MongoID.OID != "" {
+		legacyUserID = "auth0|" + o.MongoID.OID
+	}
+
+	enabled := !o.Blocked
+	emailConfirmed := o.EmailVerified
+	params := &models.MigrateUserParams{
+		Email:          o.Email,
+		EmailConfirmed: &emailConfirmed,
+		Enabled:        &enabled,
+		ExistingUserID: optionalString(legacyUserID),
+		Username:       optionalString(o.Username),
+		FirstName:      optionalString(o.GivenName),
+		LastName:       optionalString(o.FamilyName),
+		PictureUrl:     optionalString(o.Picture),
+	}
+
+	if o.PasswordHash != "" {
+		if !strings.HasPrefix(o.PasswordHash, "$2") {
+			return nil, fmt.Errorf("%w: the password hash isn't bcrypt", ErrUnsupportedPasswordHash)
+		}
+		params.ExistingPasswordHash = &o.PasswordHash
+	}
+
+	return params, nil
+}
+
+// optionalString returns nil for an empty string, so it's left unset.
+func optionalString(value string) *string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+
+	return &value
+}
@@ -0,0 +1,111 @@
+package migration
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/propelauth/propelauth-go/pkg/models"
+)
+
+// CognitoReader reads a CSV of Cognito users, with the same columns as Cognito's user import CSV, like
+// cognito:username, email, email_verified, given_name and family_name. Columns can be in any order, and unknown
+// columns are ignored. The sub column is the legacy ID if there is one, otherwise it's cognito:username. An enabled
+// column, like the Enabled field of ListUsers, migrates users with false disabled.
+//
+// Cognito never exports password hashes, so migrated users will have to reset their password or log in another
+// way. Users without an email, like ones who only log in with a phone number, are returned as a RecordError that
+// wraps ErrMissingEmail.
+type CognitoReader struct {
+	reader  *csv.Reader
+	columns map[string]int
+	number  int
+}
+
+// NewCognitoReader creates a reader of the Cognito CSV in r, and reads its header.
+func NewCognitoReader(r io.Reader) (*CognitoReader, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("Error on reading the CSV header: %w", err)
+	}
+
+	columns := map[string]int{}
+	for i, column := range header {
+		columns[strings.ToLower(strings.TrimSpace(column))] = i
+	}
+	if _, ok := columns["email"]; !ok {
+		return nil, errors.New("The CSV header must have an email column")
+	}
+
+	return &CognitoReader{reader: reader, columns: columns}, nil
+}
+
+func (o *CognitoReader) Next() (*Record, error) {
+	row, err := o.reader.Read()
+	if err == io.EOF {
+		return nil, io.EOF
+	}
+	o.number++
+
+	parseError := &csv.ParseError{}
+	if errors.As(err, &parseError) {
+		return nil, &RecordError{Number: o.number, Err: err}
+	} else if err != nil {
+		return nil, fmt.Errorf("Error on reading the records: %w", err)
+	}
+
+	params, err := o.parseRow(row)
+	if err != nil {
+		return nil, &RecordError{Number: o.number, Err: err}
+	}
+
+	return &Record{Number: o.number, Params: *params}, nil
+}
+
+func (o *CognitoReader) parseRow(row []string) (*models.MigrateUserParams, error) {
+	cell := func(column string) string {
+		i, ok := o.columns[column]
+		if !ok || i >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+
+	email := cell("email")
+	if email == "" {
+		return nil, ErrMissingEmail
+	}
+
+	legacyUserID := cell("sub")
+	if legacyUserID == "" {
+		legacyUserID = cell("cognito:username")
+	}
+
+	params := &models.MigrateUserParams{
+		Email:          email,
+		ExistingUserID: optionalString(legacyUserID),
+		Username:       optionalString(cell("preferred_username")),
+		FirstName:      optionalString(cell("given_name")),
+		LastName:       optionalString(cell("family_name")),
+		PictureUrl:     optionalString(cell("picture")),
+	}
+
+	var err error
+	if value := cell("email_verified"); value != "" {
+		if params.EmailConfirmed, err = parseBool("email_verified", value); err != nil {
+			return nil, err
+		}
+	}
+	if value := cell("enabled"); value != "" {
+		if params.Enabled, err = parseBool("enabled", value); err != nil {
+			return nil, err
+		}
+	}
+
+	return params, nil
+}
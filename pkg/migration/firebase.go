@@ -0,0 +1,188 @@
+package migration
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/propelauth/propelauth-go/pkg/models"
+)
+
+// FirebaseHashConfig has the parameters Firebase used to hash your project's passwords. They're not in the export,
+// you can find them in the Firebase console, under Authentication, Users, and Password hash parameters.
+type FirebaseHashConfig struct {
+	// SignerKey is base64_signer_key.
+	SignerKey string
+	// SaltSeparator is base64_salt_separator.
+	SaltSeparator string
+	Rounds        int
+	MemCost       int
+}
+
+// FirebaseScryptHash is a user's password hash, with everything needed to check a password against it. These are
+// the inputs of Firebase's modified scrypt, as documented at https://github.com/firebase/scrypt: PasswordHash and
+// Salt are the user's passwordHash and salt from the export, and the rest come from the FirebaseHashConfig. Every
+// key, salt and hash is base64, exactly as Firebase gives it to you.
+type FirebaseScryptHash struct {
+	PasswordHash  string
+	Salt          string
+	SignerKey     string
+	SaltSeparator string
+	Rounds        int
+	MemCost       int
+}
+
+// firebaseUser has the fields we use from a user in the output of firebase auth:export.
+type firebaseUser struct {
+	LocalID       string `json:"localId"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"emailVerified"`
+	PasswordHash  string `json:"passwordHash"`
+	Salt          string `json:"salt"`
+	DisplayName   string `json:"displayName"`
+	PhotoURL      string `json:"photoUrl"`
+	Disabled      bool   `json:"disabled"`
+}
+
+// FirebaseReader reads the JSON file written by firebase auth:export. The Firebase user ID is the legacy ID, the
+// display name is split into a first and last name at its first space, and disabled users are migrated disabled.
+//
+// Firebase hashes passwords with its own version of scrypt, which needs the project's FirebaseHashConfig as well as
+// the user's salt to check a password. There's no documented way to pass all of that as an existing password hash,
+// so it's left out of the params, and returned as the record's FirebaseScrypt instead. Users with a password hash
+// but no salt are returned as a RecordError that wraps ErrUnsupportedPasswordHash, and users without an email, like
+// ones who only log in with a phone number, as a RecordError that wraps ErrMissingEmail.
+type FirebaseReader struct {
+	decoder    *json.Decoder
+	hashConfig *FirebaseHashConfig
+	started    bool
+	done       bool
+	number     int
+}
+
+// NewFirebaseReader creates a reader of the Firebase export in r. If hashConfig is nil, the records have no
+// FirebaseScrypt.
+func NewFirebaseReader(r io.Reader, hashConfig *FirebaseHashConfig) *FirebaseReader {
+	return &FirebaseReader{decoder: json.NewDecoder(r), hashConfig: hashConfig}
+}
+
+func (o *FirebaseReader) Next() (*Record, error) {
+	if o.done {
+		return nil, io.EOF
+	}
+
+	if !o.started {
+		if err := o.findUsers(); err != nil {
+			return nil, err
+		}
+		o.started = true
+	}
+
+	if !o.decoder.More() {
+		o.done = true
+		return nil, io.EOF
+	}
+
+	// decode each user in two steps, so a user with unexpected fields doesn't stop the others from being read
+	raw := json.RawMessage{}
+	if err := o.decoder.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("Error on reading the records: %w", err)
+	}
+
+	o.number++
+	user := firebaseUser{}
+	if err := json.Unmarshal(raw, &user); err != nil {
+		return nil, &RecordError{Number: o.number, Err: fmt.Errorf("Error on unmarshalling the record: %w", err)}
+	}
+
+	params, err := user.migrateUserParams()
+	if err != nil {
+		return nil, &RecordError{Number: o.number, Err: err}
+	}
+	scryptHash, err := user.scryptHash(o.hashConfig)
+	if err != nil {
+		return nil, &RecordError{Number: o.number, Err: err}
+	}
+
+	return &Record{Number: o.number, Params: *params, FirebaseScrypt: scryptHash}, nil
+}
+
+// findUsers moves the decoder to the start of the users array, skipping anything else in the file.
+func (o *FirebaseReader) findUsers() error {
+	if err := o.expectDelim('{'); err != nil {
+		return err
+	}
+
+	for o.decoder.More() {
+		token, err := o.decoder.Token()
+		if err != nil {
+			return fmt.Errorf("Error on reading the records: %w", err)
+		}
+
+		if token == "users" {
+			return o.expectDelim('[')
+		}
+
+		skipped := json.RawMessage{}
+		if err := o.decoder.Decode(&skipped); err != nil {
+			return fmt.Errorf("Error on reading the records: %w", err)
+		}
+	}
+
+	return errors.New("The Firebase export has no users")
+}
+
+func (o *FirebaseReader) expectDelim(delim json.Delim) error {
+	token, err := o.decoder.Token()
+	if err != nil {
+		return fmt.Errorf("Error on reading the records: %w", err)
+	}
+	if token != delim {
+		return fmt.Errorf("Error on reading the records: expected %s, got %v", delim, token)
+	}
+
+	return nil
+}
+
+func (o firebaseUser) migrateUserParams() (*models.MigrateUserParams, error) {
+	if strings.TrimSpace(o.Email) == "" {
+		return nil, ErrMissingEmail
+	}
+
+	enabled := !o.Disabled
+	emailConfirmed := o.EmailVerified
+	firstName, lastName, _ := strings.Cut(strings.TrimSpace(o.DisplayName), " ")
+	params := &models.MigrateUserParams{
+		Email:          o.Email,
+		EmailConfirmed: &emailConfirmed,
+		Enabled:        &enabled,
+		ExistingUserID: optionalString(o.LocalID),
+		FirstName:      optionalString(firstName),
+		LastName:       optionalString(lastName),
+		PictureUrl:     optionalString(o.PhotoURL),
+	}
+
+	return params, nil
+}
+
+// scryptHash returns the user's password hash with the parameters to check it, or nil if the user has no password
+// or there's no hashConfig.
+func (o firebaseUser) scryptHash(hashConfig *FirebaseHashConfig) (*FirebaseScryptHash, error) {
+	if o.PasswordHash == "" || hashConfig == nil {
+		return nil, nil
+	}
+	if o.Salt == "" {
+		return nil, fmt.Errorf("%w: the password hash has no salt", ErrUnsupportedPasswordHash)
+	}
+
+	return &FirebaseScryptHash{
+		PasswordHash:  o.PasswordHash,
+		Salt:          o.Salt,
+		SignerKey:     hashConfig.SignerKey,
+		SaltSeparator: hashConfig.SaltSeparator,
+		Rounds:        hashConfig.Rounds,
+		MemCost:       hashConfig.MemCost,
+	}, nil
+}
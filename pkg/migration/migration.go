@@ -0,0 +1,409 @@
+// Package migration moves users from another system into PropelAuth in bulk, with MigrateUserFromExternalSource.
+package migration
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	propelauth "github.com/propelauth/propelauth-go/pkg"
+	"github.com/propelauth/propelauth-go/pkg/models"
+)
+
+const defaultConcurrency = 4
+
+// Status is what happened to a record.
+type Status string
+
+const (
+	// StatusMigrated means the user was created.
+	StatusMigrated Status = "migrated"
+	// StatusAlreadyExists means a user with the email, and the same legacy ID if the record has one, was already
+	// in PropelAuth, usually because an earlier run migrated it but crashed before its checkpoint was saved.
+	StatusAlreadyExists Status = "already_exists"
+	// StatusFailed means the record couldn't be migrated, see the result's error.
+	StatusFailed Status = "failed"
+)
+
+// Result is written to the result file, one JSON line per record, mapping the legacy ID to the PropelAuth user ID.
+type Result struct {
+	Number       int        `json:"number"`
+	Email        string     `json:"email,omitempty"`
+	LegacyUserID *string    `json:"legacy_user_id,omitempty"`
+	UserID       *uuid.UUID `json:"user_id,omitempty"`
+	Status       Status     `json:"status"`
+	Error        string     `json:"error,omitempty"`
+}
+
+// Summary counts what happened to the records in one run.
+type Summary struct {
+	Migrated      int
+	AlreadyExists int
+	Failed        int
+	// Skipped is how many records were already done according to the checkpoint file.
+	Skipped int
+}
+
+// checkpoint is the content of the checkpoint file. Every record up to and including CompletedThrough is done,
+// whether it was migrated or failed.
+type checkpoint struct {
+	CompletedThrough int `json:"completed_through"`
+}
+
+// MigratorOption configures optional behavior of the Migrator returned by NewMigrator.
+type MigratorOption func(*Migrator)
+
+// Migrator migrates records with a few requests at a time. Use it like this:
+//
+//	reader, err := migration.NewCSVReader(file)
+//	migrator := migration.NewMigrator(client,
+//		migration.WithCheckpointFile("migration.checkpoint"),
+//		migration.WithResultFile("migration.results.jsonl"))
+//	summary, err := migrator.Run(ctx, reader)
+type Migrator struct {
+	client         propelauth.ClientInterface
+	concurrency    int
+	ratePerSecond  float64
+	checkpointPath string
+	resultPath     string
+}
+
+// NewMigrator creates a migrator that migrates users with client, 4 at a time.
+func NewMigrator(client propelauth.ClientInterface, opts ...MigratorOption) *Migrator {
+	migrator := &Migrator{
+		client:      client,
+		concurrency: defaultConcurrency,
+	}
+
+	for _, opt := range opts {
+		opt(migrator)
+	}
+
+	return migrator
+}
+
+// WithConcurrency changes how many users are migrated at a time.
+func WithConcurrency(concurrency int) MigratorOption {
+	return func(o *Migrator) {
+		if concurrency > 0 {
+			o.concurrency = concurrency
+		}
+	}
+}
+
+// WithRateLimit starts at most ratePerSecond migrations a second, to stay under your backend rate limit.
+func WithRateLimit(ratePerSecond float64) MigratorOption {
+	return func(o *Migrator) {
+		o.ratePerSecond = ratePerSecond
+	}
+}
+
+// WithCheckpointFile keeps track of progress in the file at path. If a run stops partway, running again with the
+// same file and source skips the records that were done. A few records after the checkpoint may have been
+// migrated already, and are reported as StatusAlreadyExists.
+func WithCheckpointFile(path string) MigratorOption {
+	return func(o *Migrator) {
+		o.checkpointPath = path
+	}
+}
+
+// WithResultFile appends a Result for every record to the file at path, as JSON lines.
+func WithResultFile(path string) MigratorOption {
+	return func(o *Migrator) {
+		o.resultPath = path
+	}
+}
+
+// Run migrates every record from records. Records that fail don't stop the run, they're counted in the summary and
+// written to the result file. An error is only returned if the records, checkpoint or result file can't be read or
+// written, or if ctx is done, in which case the summary counts what was done before it stopped.
+func (o *Migrator) Run(ctx context.Context, records RecordReader) (*Summary, error) {
+	progress, err := o.loadCheckpoint()
+	if err != nil {
+		return nil, err
+	}
+
+	results := io.Discard
+	if o.resultPath != "" {
+		resultFile, err := os.OpenFile(o.resultPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			return nil, fmt.Errorf("Error on opening the result file: %w", err)
+		}
+		defer resultFile.Close()
+		results = resultFile
+	}
+
+	run := &migrationRun{
+		migrator:    o,
+		client:      o.client.WithContext(ctx),
+		resumeAfter: progress.CompletedThrough,
+		progress:    progress,
+		pending:     map[int]bool{},
+		results:     json.NewEncoder(results),
+		summary:     &Summary{},
+	}
+
+	var tick <-chan time.Time
+	if o.ratePerSecond > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / o.ratePerSecond))
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	work := make(chan *Record)
+	var wg sync.WaitGroup
+	for i := 0; i < o.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for record := range work {
+				result := run.migrate(record)
+				if ctx.Err() != nil {
+					// the record may have failed because we're stopping, so leave it for the next run
+					continue
+				}
+				run.finish(result)
+			}
+		}()
+	}
+
+	readErr := o.dispatch(ctx, records, run, work, tick)
+	close(work)
+	wg.Wait()
+
+	if readErr != nil {
+		return run.summary, readErr
+	}
+	if err := ctx.Err(); err != nil {
+		return run.summary, err
+	}
+	if run.err != nil {
+		return run.summary, run.err
+	}
+
+	return run.summary, nil
+}
+
+// dispatch reads the records and hands them to the workers, waiting for the rate limit.
+func (o *Migrator) dispatch(ctx context.Context, records RecordReader, run *migrationRun, work chan<- *Record, tick <-chan time.Time) error {
+	for {
+		record, err := records.Next()
+		if err == io.EOF {
+			return nil
+		}
+
+		recordErr := &RecordError{}
+		if errors.As(err, &recordErr) {
+			if recordErr.Number > run.resumeAfter {
+				run.start(recordErr.Number)
+				run.finish(Result{Number: recordErr.Number, Status: StatusFailed, Error: recordErr.Err.Error()})
+			}
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		if record.Number <= run.resumeAfter {
+			run.skip()
+			continue
+		}
+
+		if tick != nil {
+			select {
+			case <-tick:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		run.start(record.Number)
+		select {
+		case work <- record:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (o *Migrator) loadCheckpoint() (checkpoint, error) {
+	progress := checkpoint{}
+	if o.checkpointPath == "" {
+		return progress, nil
+	}
+
+	contents, err := os.ReadFile(o.checkpointPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return progress, nil
+	} else if err != nil {
+		return progress, fmt.Errorf("Error on reading the checkpoint file: %w", err)
+	}
+
+	if err := json.Unmarshal(contents, &progress); err != nil {
+		return progress, fmt.Errorf("Error on unmarshalling the checkpoint file: %w", err)
+	}
+
+	return progress, nil
+}
+
+// saveCheckpoint replaces the checkpoint file, by writing a temporary file and renaming it, so a crash never
+// leaves it half written.
+func (o *Migrator) saveCheckpoint(progress checkpoint) error {
+	if o.checkpointPath == "" {
+		return nil
+	}
+
+	contents, err := json.Marshal(progress)
+	if err != nil {
+		return fmt.Errorf("Error on marshalling the checkpoint: %w", err)
+	}
+
+	temporary, err := os.CreateTemp(filepath.Dir(o.checkpointPath), filepath.Base(o.checkpointPath)+".*")
+	if err != nil {
+		return fmt.Errorf("Error on writing the checkpoint file: %w", err)
+	}
+	defer os.Remove(temporary.Name())
+
+	if _, err := temporary.Write(contents); err != nil {
+		temporary.Close()
+		return fmt.Errorf("Error on writing the checkpoint file: %w", err)
+	}
+	if err := temporary.Close(); err != nil {
+		return fmt.Errorf("Error on writing the checkpoint file: %w", err)
+	}
+	if err := os.Rename(temporary.Name(), o.checkpointPath); err != nil {
+		return fmt.Errorf("Error on writing the checkpoint file: %w", err)
+	}
+
+	return nil
+}
+
+// migrationRun is the state of one call to Run, shared by its workers.
+type migrationRun struct {
+	migrator *Migrator
+	client   propelauth.ClientInterface
+	// resumeAfter is where the checkpoint was when the run started, records up to it are skipped.
+	resumeAfter int
+
+	mu       sync.Mutex
+	progress checkpoint
+	// pending has the records that were started, and whether they've finished. Records are started in order, so
+	// the checkpoint only moves past a record once it and every record before it have finished.
+	pending map[int]bool
+	results *json.Encoder
+	summary *Summary
+	err     error
+}
+
+// migrate migrates one record, and finds the ID of the new user.
+func (o *migrationRun) migrate(record *Record) Result {
+	params := record.Params
+	result := Result{Number: record.Number, Email: params.Email, LegacyUserID: params.ExistingUserID}
+
+	_, migrateErr := o.client.MigrateUserFromExternalSource(params)
+	if migrateErr != nil && !isEmailTaken(migrateErr) {
+		result.Status = StatusFailed
+		result.Error = migrateErr.Error()
+		return result
+	}
+
+	user, err := o.client.FetchUserMetadataByEmail(params.Email, false)
+	if err != nil {
+		if migrateErr != nil {
+			err = migrateErr
+		}
+		result.Status = StatusFailed
+		result.Error = fmt.Sprintf("Error on finding the migrated user: %s", err)
+		return result
+	}
+
+	if migrateErr != nil {
+		// the email is taken, which is only fine if it's the same user, migrated by an earlier run
+		sameUser := params.ExistingUserID == nil || (user.LegacyUserID != nil && *user.LegacyUserID == *params.ExistingUserID)
+		if !sameUser {
+			result.Status = StatusFailed
+			result.Error = migrateErr.Error()
+			return result
+		}
+		result.Status = StatusAlreadyExists
+	} else {
+		result.Status = StatusMigrated
+	}
+
+	result.UserID = &user.UserID
+
+	return result
+}
+
+// isEmailTaken checks if MigrateUserFromExternalSource failed because a user already has the email.
+func isEmailTaken(err error) bool {
+	apiError := &models.APIError{}
+	if !errors.As(err, &apiError) || apiError.StatusCode != 400 {
+		return false
+	}
+
+	for _, message := range apiError.FieldToErrors["email"] {
+		message = strings.ToLower(message)
+		if strings.Contains(message, "already") || strings.Contains(message, "exists") || strings.Contains(message, "in use") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (o *migrationRun) start(number int) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.pending[number] = false
+}
+
+func (o *migrationRun) skip() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.summary.Skipped++
+}
+
+// finish records the result, and moves the checkpoint forward if it can.
+func (o *migrationRun) finish(result Result) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	switch result.Status {
+	case StatusMigrated:
+		o.summary.Migrated++
+	case StatusAlreadyExists:
+		o.summary.AlreadyExists++
+	default:
+		o.summary.Failed++
+	}
+
+	if err := o.results.Encode(result); err != nil && o.err == nil {
+		o.err = fmt.Errorf("Error on writing the result file: %w", err)
+	}
+
+	o.pending[result.Number] = true
+
+	completedThrough := o.progress.CompletedThrough
+	for o.pending[completedThrough+1] {
+		delete(o.pending, completedThrough+1)
+		completedThrough++
+	}
+
+	if completedThrough != o.progress.CompletedThrough {
+		o.progress.CompletedThrough = completedThrough
+		if err := o.migrator.saveCheckpoint(o.progress); err != nil && o.err == nil {
+			o.err = err
+		}
+	}
+}
@@ -0,0 +1,308 @@
+package migration_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/propelauth/propelauth-go/pkg/migration"
+	"github.com/propelauth/propelauth-go/pkg/models"
+	testHelpers "github.com/propelauth/propelauth-go/pkg/test"
+)
+
+func TestReaders(t *testing.T) {
+	t.Run("CSV columns are mapped to MigrateUserParams", func(t *testing.T) {
+		csv := "email,existing_user_id,email_confirmed,existing_password_hash,first_name,properties,properties.plan\n" +
+			`ada@example.com,42,true,$2a$12$hash,Ada,"{""team"": ""engines""}",pro` + "\n" +
+			"grace@example.com,43,maybe,,,,\n" +
+			"linus@example.com,44,,,,,\n"
+
+		reader, err := migration.NewCSVReader(strings.NewReader(csv))
+		if err != nil {
+			t.Fatalf("NewCSVReader returned an error: %s", err)
+		}
+
+		record, err := reader.Next()
+		if err != nil {
+			t.Fatalf("Next returned an error: %s", err)
+		}
+		params := record.Params
+		if params.Email != "ada@example.com" || *params.ExistingUserID != "42" || !*params.EmailConfirmed ||
+			*params.ExistingPasswordHash != "$2a$12$hash" || *params.FirstName != "Ada" || params.LastName != nil {
+			t.Errorf("expected the columns to be mapped, got %+v", params)
+		}
+		if properties := *params.Properties; properties["team"] != "engines" || properties["plan"] != "pro" {
+			t.Errorf("expected both properties, got %v", properties)
+		}
+
+		_, err = reader.Next()
+		recordErr := &migration.RecordError{}
+		if !errors.As(err, &recordErr) || recordErr.Number != 2 {
+			t.Errorf("expected a RecordError for record 2, got %v", err)
+		}
+
+		record, err = reader.Next()
+		if err != nil || record.Number != 3 || record.Params.EmailConfirmed != nil {
+			t.Errorf("expected record 3 with empty cells left unset, got %+v and %v", record, err)
+		}
+
+		if _, err := reader.Next(); err != io.EOF {
+			t.Errorf("expected io.EOF, got %v", err)
+		}
+	})
+
+	t.Run("CSV files need an email column", func(t *testing.T) {
+		if _, err := migration.NewCSVReader(strings.NewReader("username\nada\n")); err == nil {
+			t.Errorf("expected an error")
+		}
+	})
+}
+
+func TestMigrator(t *testing.T) {
+	// setup a fake, and a source with a malformed record and a taken email
+
+	fake := testHelpers.NewFakeClient()
+	if _, err := fake.CreateUser(models.CreateUserParams{Email: "taken@example.com"}); err != nil {
+		t.Fatalf("CreateUser returned an error, cannot even begin the tests: %s", err)
+	}
+
+	source := strings.Join([]string{
+		`{"email": "ada@example.com", "existing_user_id": "1"}`,
+		`{"email": "grace@example.com", "existing_user_id": "2"}`,
+		`{"email": "linus@example.com", "existing_user_id": "3"}`,
+		`not json`,
+		`{"email": "taken@example.com", "existing_user_id": "5"}`,
+		`{"email": "margaret@example.com", "existing_user_id": "6"}`,
+	}, "\n")
+
+	readResults := func(path string) []migration.Result {
+		file, err := os.Open(path)
+		if err != nil {
+			t.Fatalf("couldn't open the result file: %s", err)
+		}
+		defer file.Close()
+
+		results := []migration.Result{}
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			result := migration.Result{}
+			if err := json.Unmarshal(scanner.Bytes(), &result); err != nil {
+				t.Fatalf("couldn't unmarshal a result: %s", err)
+			}
+			results = append(results, result)
+		}
+		return results
+	}
+
+	// run tests
+
+	t.Run("a crashed run resumes from its checkpoint", func(t *testing.T) {
+		directory := t.TempDir()
+		checkpointPath := filepath.Join(directory, "checkpoint.json")
+		resultPath := filepath.Join(directory, "results.jsonl")
+
+		// the first two records are checkpointed, and the third was migrated but not checkpointed
+		if err := os.WriteFile(checkpointPath, []byte(`{"completed_through": 2}`), 0o600); err != nil {
+			t.Fatalf("couldn't write the checkpoint: %s", err)
+		}
+		legacyID := "3"
+		if _, err := fake.MigrateUserFromExternalSource(models.MigrateUserParams{Email: "linus@example.com", ExistingUserID: &legacyID}); err != nil {
+			t.Fatalf("MigrateUserFromExternalSource returned an error: %s", err)
+		}
+
+		migrator := migration.NewMigrator(fake,
+			migration.WithCheckpointFile(checkpointPath),
+			migration.WithResultFile(resultPath),
+			migration.WithConcurrency(2),
+			migration.WithRateLimit(500))
+
+		summary, err := migrator.Run(context.Background(), migration.NewJSONLReader(strings.NewReader(source)))
+		if err != nil {
+			t.Fatalf("Run returned an error: %s", err)
+		}
+
+		expected := migration.Summary{Migrated: 1, AlreadyExists: 1, Failed: 2, Skipped: 2}
+		if *summary != expected {
+			t.Errorf("expected %+v, got %+v", expected, *summary)
+		}
+
+		checkpoint, _ := os.ReadFile(checkpointPath)
+		if strings.ReplaceAll(string(checkpoint), " ", "") != `{"completed_through":6}` {
+			t.Errorf("expected the checkpoint to be at the end, got %s", checkpoint)
+		}
+
+		statuses := map[int]migration.Status{}
+		for _, result := range readResults(resultPath) {
+			statuses[result.Number] = result.Status
+			if result.Number == 6 {
+				user, err := fake.FetchUserMetadataByEmail("margaret@example.com", false)
+				if err != nil || result.UserID == nil || *result.UserID != user.UserID || *result.LegacyUserID != "6" {
+					t.Errorf("expected the legacy ID to map to the new user, got %+v", result)
+				}
+			}
+		}
+		if statuses[3] != migration.StatusAlreadyExists || statuses[4] != migration.StatusFailed || statuses[5] != migration.StatusFailed || statuses[6] != migration.StatusMigrated {
+			t.Errorf("unexpected statuses %v", statuses)
+		}
+	})
+
+	t.Run("a finished run skips everything", func(t *testing.T) {
+		checkpointPath := filepath.Join(t.TempDir(), "checkpoint.json")
+		if err := os.WriteFile(checkpointPath, []byte(`{"completed_through": 6}`), 0o600); err != nil {
+			t.Fatalf("couldn't write the checkpoint: %s", err)
+		}
+
+		summary, err := migration.NewMigrator(fake, migration.WithCheckpointFile(checkpointPath)).
+			Run(context.Background(), migration.NewJSONLReader(strings.NewReader(source)))
+		if err != nil || summary.Skipped != 5 || summary.Migrated != 0 || summary.Failed != 0 {
+			t.Errorf("expected every valid record to be skipped, got %+v and %v", summary, err)
+		}
+	})
+
+	t.Run("cancelled runs stop", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		start := time.Now()
+		_, err := migration.NewMigrator(fake, migration.WithRateLimit(1)).
+			Run(ctx, migration.NewJSONLReader(strings.NewReader(`{"email": "late@example.com"}`)))
+		if !errors.Is(err, context.Canceled) || time.Since(start) > time.Second {
+			t.Errorf("expected to stop right away with context.Canceled, got %v", err)
+		}
+	})
+}
+
+func TestImporters(t *testing.T) {
+	t.Run("Auth0 exports keep their IDs, hashes and blocked users", func(t *testing.T) {
+		export := `{"user_id": "auth0|abc", "email": "ada@example.com", "email_verified": true, "given_name": "Ada", "family_name": "Lovelace", "blocked": true}` + "\n" +
+			`{"_id": {"$oid": "def"}, "email": "grace@example.com", "passwordHash": "$2b$10$hash"}` + "\n" +
+			`{"user_id": "sms|123", "phone_number": "+15555550123"}` + "\n" +
+			`{"user_id": "auth0|ghi", "email": "linus@example.com", "passwordHash": "md5hash"}` + "\n"
+		reader := migration.NewAuth0Reader(strings.NewReader(export))
+
+		record, err := reader.Next()
+		if err != nil {
+			t.Fatalf("Next returned an error: %s", err)
+		}
+		params := record.Params
+		if *params.ExistingUserID != "auth0|abc" || !*params.EmailConfirmed || *params.Enabled ||
+			*params.FirstName != "Ada" || *params.LastName != "Lovelace" || params.ExistingPasswordHash != nil {
+			t.Errorf("expected the profile to be mapped, got %+v", params)
+		}
+
+		record, err = reader.Next()
+		if err != nil || *record.Params.ExistingUserID != "auth0|def" || *record.Params.ExistingPasswordHash != "$2b$10$hash" {
+			t.Errorf("expected the password hash export to be mapped, got %+v and %v", record, err)
+		}
+
+		if _, err := reader.Next(); !errors.Is(err, migration.ErrMissingEmail) {
+			t.Errorf("expected ErrMissingEmail, got %v", err)
+		}
+		if _, err := reader.Next(); !errors.Is(err, migration.ErrUnsupportedPasswordHash) {
+			t.Errorf("expected ErrUnsupportedPasswordHash, got %v", err)
+		}
+		if _, err := reader.Next(); err != io.EOF {
+			t.Errorf("expected io.EOF, got %v", err)
+		}
+	})
+
+	t.Run("Firebase exports keep the scrypt parameters with the hash", func(t *testing.T) {
+		// the sample user and project parameters from https://github.com/firebase/scrypt, whose password is
+		// user1password
+		export := `{"users": [
+			{"localId": "f1", "email": "ada@example.com", "emailVerified": true, "displayName": "Ada King Lovelace",
+				"passwordHash": "lSrfV15cpx95/sZS2W9c9Kp6i/LVgQNDNC/qzrCnh1SAyZvqmZqAjTdn3aoItz+VHjoZilo78198JAdRuid5lQ==",
+				"salt": "42xEC+ixf3L2lw=="},
+			{"localId": "f2", "phoneNumber": "+15555550123"},
+			{"localId": "f3", "email": "grace@example.com", "passwordHash": "aGFzaA=="},
+			{"localId": "f4", "email": "linus@example.com", "disabled": true}
+		]}`
+		hashConfig := &migration.FirebaseHashConfig{
+			SignerKey:     "jxspr8Ki0RYycVU8zykbdLGjFQ3McFUH0uiiTvC8pVMXAn210wjLNmdZJzxUECKbm0QsEmYUSDzZvpjeJ9WmXA==",
+			SaltSeparator: "Bw==",
+			Rounds:        8,
+			MemCost:       14,
+		}
+		reader := migration.NewFirebaseReader(strings.NewReader(export), hashConfig)
+
+		record, err := reader.Next()
+		if err != nil {
+			t.Fatalf("Next returned an error: %s", err)
+		}
+		params := record.Params
+		if *params.ExistingUserID != "f1" || *params.FirstName != "Ada" || *params.LastName != "King Lovelace" || params.ExistingPasswordHash != nil {
+			t.Errorf("expected the profile to be mapped, got %+v", params)
+		}
+		expected := migration.FirebaseScryptHash{
+			PasswordHash:  "lSrfV15cpx95/sZS2W9c9Kp6i/LVgQNDNC/qzrCnh1SAyZvqmZqAjTdn3aoItz+VHjoZilo78198JAdRuid5lQ==",
+			Salt:          "42xEC+ixf3L2lw==",
+			SignerKey:     "jxspr8Ki0RYycVU8zykbdLGjFQ3McFUH0uiiTvC8pVMXAn210wjLNmdZJzxUECKbm0QsEmYUSDzZvpjeJ9WmXA==",
+			SaltSeparator: "Bw==",
+			Rounds:        8,
+			MemCost:       14,
+		}
+		if record.FirebaseScrypt == nil || *record.FirebaseScrypt != expected {
+			t.Errorf("expected the hash with its parameters, got %+v", record.FirebaseScrypt)
+		}
+
+		if _, err := reader.Next(); !errors.Is(err, migration.ErrMissingEmail) {
+			t.Errorf("expected ErrMissingEmail, got %v", err)
+		}
+		if _, err := reader.Next(); !errors.Is(err, migration.ErrUnsupportedPasswordHash) {
+			t.Errorf("expected ErrUnsupportedPasswordHash, got %v", err)
+		}
+
+		record, err = reader.Next()
+		if err != nil || record.Number != 4 || *record.Params.Enabled || record.FirebaseScrypt != nil {
+			t.Errorf("expected record 4 to be disabled, without a password, got %+v and %v", record, err)
+		}
+		if _, err := reader.Next(); err != io.EOF {
+			t.Errorf("expected io.EOF, got %v", err)
+		}
+
+		reader = migration.NewFirebaseReader(strings.NewReader(export), nil)
+		if record, err := reader.Next(); err != nil || record.FirebaseScrypt != nil {
+			t.Errorf("expected the hash to be left out without a hash config, got %+v and %v", record, err)
+		}
+	})
+
+	t.Run("Cognito CSVs use sub as the legacy ID", func(t *testing.T) {
+		export := "cognito:username,sub,email,email_verified,given_name,family_name,phone_number,enabled\n" +
+			"ada,1111-aaaa,ada@example.com,true,Ada,Lovelace,,false\n" +
+			"grace,,grace@example.com,false,,,,\n" +
+			"linus,3333-cccc,,,,,+15555550123,\n"
+
+		reader, err := migration.NewCognitoReader(strings.NewReader(export))
+		if err != nil {
+			t.Fatalf("NewCognitoReader returned an error: %s", err)
+		}
+
+		record, err := reader.Next()
+		if err != nil {
+			t.Fatalf("Next returned an error: %s", err)
+		}
+		params := record.Params
+		if *params.ExistingUserID != "1111-aaaa" || !*params.EmailConfirmed || *params.Enabled || *params.FirstName != "Ada" || params.ExistingPasswordHash != nil {
+			t.Errorf("expected the columns to be mapped, got %+v", params)
+		}
+
+		record, err = reader.Next()
+		if err != nil || *record.Params.ExistingUserID != "grace" || *record.Params.EmailConfirmed || record.Params.Enabled != nil {
+			t.Errorf("expected cognito:username as the legacy ID, got %+v and %v", record, err)
+		}
+
+		if _, err := reader.Next(); !errors.Is(err, migration.ErrMissingEmail) {
+			t.Errorf("expected ErrMissingEmail, got %v", err)
+		}
+		if _, err := reader.Next(); err != io.EOF {
+			t.Errorf("expected io.EOF, got %v", err)
+		}
+	})
+}
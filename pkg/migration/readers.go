@@ -0,0 +1,211 @@
+package migration
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/propelauth/propelauth-go/pkg/models"
+)
+
+// Record is one user to migrate. Number is its position in the source, starting at 1, and is what the checkpoint
+// file keeps track of. FirebaseScrypt is only set by the FirebaseReader, for users with a password.
+type Record struct {
+	Number         int
+	Params         models.MigrateUserParams
+	FirebaseScrypt *FirebaseScryptHash
+}
+
+// RecordReader streams the records to migrate. Next returns io.EOF after the last record. If a single record is
+// malformed, it returns a *RecordError, and the next call moves on to the following record.
+type RecordReader interface {
+	Next() (*Record, error)
+}
+
+var (
+	// ErrMissingEmail is the reason a record without an email can't be migrated.
+	ErrMissingEmail = errors.New("the user has no email")
+	// ErrUnsupportedPasswordHash is the reason a record with a password hash we can't migrate isn't migrated.
+	ErrUnsupportedPasswordHash = errors.New("the password hash can't be migrated")
+)
+
+// RecordError is returned by a RecordReader when a record can't be turned into MigrateUserParams. Err is the reason,
+// and wraps ErrMissingEmail or ErrUnsupportedPasswordHash when the record is well formed but can't be migrated.
+type RecordError struct {
+	Number int
+	Err    error
+}
+
+func (e *RecordError) Error() string {
+	return fmt.Sprintf("record %d: %s", e.Number, e.Err)
+}
+
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
+
+// JSONLReader reads one JSON object per line, with the same fields as MigrateUserParams, like:
+//
+//	{"email": "ada@example.com", "existing_user_id": "42", "existing_password_hash": "$2a$12$..."}
+//
+// Blank lines are skipped.
+type JSONLReader struct {
+	scanner *bufio.Scanner
+	number  int
+}
+
+// NewJSONLReader creates a reader of the JSON lines in r.
+func NewJSONLReader(r io.Reader) *JSONLReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+
+	return &JSONLReader{scanner: scanner}
+}
+
+func (o *JSONLReader) Next() (*Record, error) {
+	for o.scanner.Scan() {
+		line := bytes.TrimSpace(o.scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		o.number++
+		params := models.MigrateUserParams{}
+		if err := json.Unmarshal(line, &params); err != nil {
+			return nil, &RecordError{Number: o.number, Err: fmt.Errorf("Error on unmarshalling the record: %w", err)}
+		}
+
+		return &Record{Number: o.number, Params: params}, nil
+	}
+
+	if err := o.scanner.Err(); err != nil {
+		return nil, fmt.Errorf("Error on reading the records: %w", err)
+	}
+
+	return nil, io.EOF
+}
+
+// CSVReader reads a CSV file whose header names the columns the same way as the JSON fields of MigrateUserParams,
+// like email, existing_user_id and existing_password_hash. Columns can be in any order, and unknown columns are
+// ignored. A properties column holds a JSON object, and columns named like properties.company each set a single
+// property. Empty cells are left unset.
+type CSVReader struct {
+	reader *csv.Reader
+	header []string
+	number int
+}
+
+// NewCSVReader creates a reader of the CSV in r, and reads its header.
+func NewCSVReader(r io.Reader) (*CSVReader, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("Error on reading the CSV header: %w", err)
+	}
+	for i, column := range header {
+		header[i] = strings.TrimSpace(column)
+	}
+	if !slices.Contains(header, "email") {
+		return nil, errors.New("The CSV header must have an email column")
+	}
+
+	return &CSVReader{reader: reader, header: header}, nil
+}
+
+func (o *CSVReader) Next() (*Record, error) {
+	row, err := o.reader.Read()
+	if err == io.EOF {
+		return nil, io.EOF
+	}
+	o.number++
+
+	parseError := &csv.ParseError{}
+	if errors.As(err, &parseError) {
+		return nil, &RecordError{Number: o.number, Err: err}
+	} else if err != nil {
+		return nil, fmt.Errorf("Error on reading the records: %w", err)
+	}
+	if len(row) != len(o.header) {
+		return nil, &RecordError{Number: o.number, Err: fmt.Errorf("expected %d columns, got %d", len(o.header), len(row))}
+	}
+
+	params, err := o.parseRow(row)
+	if err != nil {
+		return nil, &RecordError{Number: o.number, Err: err}
+	}
+
+	return &Record{Number: o.number, Params: *params}, nil
+}
+
+func (o *CSVReader) parseRow(row []string) (*models.MigrateUserParams, error) {
+	params := &models.MigrateUserParams{}
+	properties := map[string]interface{}{}
+
+	for i, column := range o.header {
+		value := strings.TrimSpace(row[i])
+		if value == "" {
+			continue
+		}
+
+		var err error
+		switch column {
+		case "email":
+			params.Email = value
+		case "email_confirmed":
+			params.EmailConfirmed, err = parseBool(column, value)
+		case "existing_user_id":
+			params.ExistingUserID = &value
+		case "existing_password_hash":
+			params.ExistingPasswordHash = &value
+		case "existing_mfa_base32_encoded_secret":
+			params.ExistingMfaBase32EncodedSecret = &value
+		case "enabled":
+			params.Enabled, err = parseBool(column, value)
+		case "username":
+			params.Username = &value
+		case "first_name":
+			params.FirstName = &value
+		case "last_name":
+			params.LastName = &value
+		case "picture_url":
+			params.PictureUrl = &value
+		case "update_password_required":
+			params.UpdatePasswordRequired, err = parseBool(column, value)
+		case "properties":
+			if err = json.Unmarshal([]byte(value), &properties); err != nil {
+				err = fmt.Errorf("properties is not a JSON object: %w", err)
+			}
+		default:
+			if name, ok := strings.CutPrefix(column, "properties."); ok {
+				properties[name] = value
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(properties) > 0 {
+		params.Properties = &properties
+	}
+
+	return params, nil
+}
+
+func parseBool(column string, value string) (*bool, error) {
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not true or false: %s", column, value)
+	}
+
+	return &parsed, nil
+}
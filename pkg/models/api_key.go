@@ -112,3 +112,8 @@ type ApiKeyRateLimitError struct {
 func (e *ApiKeyRateLimitError) Error() string {
 	return e.UserFacingError
 }
+
+// Is lets errors.Is match an end user's API key rate limit against ErrRateLimited.
+func (e *ApiKeyRateLimitError) Is(target error) bool {
+	return target == ErrRateLimited
+}
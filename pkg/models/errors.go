@@ -0,0 +1,134 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// Sentinel errors for the common ways a call to the PropelAuth backend can fail. Every error returned by the
+// client can be checked against these with errors.Is, for example:
+//
+//	if errors.Is(err, models.ErrNotFound) { ... }
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrRateLimited  = errors.New("rate limited")
+	ErrFeatureGated = errors.New("feature not available on the current pricing plan")
+	ErrB2BDisabled  = errors.New("B2B support is not enabled")
+)
+
+// Errors returned when an access token can't be validated. Check for them with errors.Is.
+var (
+	ErrAuthorizationHeaderInvalid = errors.New("Authorization header is not in the correct format")
+	ErrTokenMalformed             = errors.New("malformed token")
+	ErrTokenSignatureInvalid      = errors.New("invalid token signature")
+	ErrTokenExpired               = errors.New("expired token")
+	ErrTokenNotValidYet           = errors.New("token is not valid yet")
+	ErrTokenTooOld                = errors.New("token is too old")
+	ErrTokenIssuerInvalid         = errors.New("invalid issuer")
+	ErrTokenAudienceInvalid       = errors.New("invalid audience")
+	ErrTokenLoginMethodInvalid    = errors.New("login method is not allowed")
+	ErrTokenImpersonated          = errors.New("impersonated tokens are not allowed")
+)
+
+// Errors returned when a webhook can't be verified.
+var (
+	ErrWebhookSignatureInvalid = errors.New("invalid webhook signature")
+	ErrWebhookTimestampInvalid = errors.New("webhook timestamp is too old or too far in the future")
+)
+
+// APIError is returned when the PropelAuth backend responds with an error. Use errors.As to inspect it, or
+// errors.Is with the sentinel errors above to check what kind of error it is.
+type APIError struct {
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+	// ErrorCode is the error_code PropelAuth included in the response, if any, e.g. "user_not_found".
+	ErrorCode string
+	// FieldToErrors maps each invalid request field to what was wrong with it, for 400 responses.
+	FieldToErrors map[string][]string
+	// Body is the raw response body.
+	Body string
+
+	message string
+	kind    error
+}
+
+// NewAPIError creates an APIError from a backend response. The message is what Error() returns. The kind is
+// the sentinel error it matches, and if it's nil we work it out from the status code and error_code.
+func NewAPIError(statusCode int, body []byte, message string, kind error) *APIError {
+	apiError := &APIError{
+		StatusCode: statusCode,
+		Body:       string(body),
+		message:    message,
+		kind:       kind,
+	}
+
+	errorResponse := struct {
+		ErrorCode     string                     `json:"error_code"`
+		FieldToErrors map[string]json.RawMessage `json:"field_to_errors"`
+	}{}
+	if err := json.Unmarshal(body, &errorResponse); err == nil {
+		apiError.ErrorCode = errorResponse.ErrorCode
+		apiError.FieldToErrors = parseFieldToErrors(errorResponse.FieldToErrors)
+	}
+
+	if apiError.kind == nil {
+		apiError.kind = apiError.kindFromResponse()
+	}
+
+	return apiError
+}
+
+func (e *APIError) Error() string {
+	return e.message
+}
+
+// Is lets errors.Is match an APIError against ErrNotFound, ErrUnauthorized, ErrRateLimited, ErrFeatureGated
+// and ErrB2BDisabled.
+func (e *APIError) Is(target error) bool {
+	return e.kind != nil && e.kind == target
+}
+
+func (e *APIError) kindFromResponse() error {
+	switch e.ErrorCode {
+	case "feature_gated":
+		return ErrFeatureGated
+	case "user_not_found", "org_not_found", "not_found":
+		return ErrNotFound
+	}
+
+	switch e.StatusCode {
+	case 401:
+		return ErrUnauthorized
+	case 404:
+		return ErrNotFound
+	case 426:
+		return ErrB2BDisabled
+	case 429:
+		return ErrRateLimited
+	}
+
+	return nil
+}
+
+// parseFieldToErrors normalizes field_to_errors, whose values can be either a single string or a list of strings.
+func parseFieldToErrors(raw map[string]json.RawMessage) map[string][]string {
+	if len(raw) == 0 {
+		return nil
+	}
+
+	fieldToErrors := make(map[string][]string, len(raw))
+	for field, value := range raw {
+		var single string
+		var multiple []string
+		if err := json.Unmarshal(value, &single); err == nil {
+			fieldToErrors[field] = []string{single}
+		} else if err := json.Unmarshal(value, &multiple); err == nil {
+			fieldToErrors[field] = multiple
+		} else {
+			fieldToErrors[field] = []string{string(value)}
+		}
+	}
+
+	return fieldToErrors
+}
@@ -2,6 +2,7 @@ package models
 
 import (
 	"crypto/rsa"
+	"time"
 
 	jwt "github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
@@ -33,9 +34,18 @@ type AccessTokenData struct {
 // Models to hold public key data, that is used when initializing the client.
 
 // TokenVerificationMetadataInput is a public key type the user can pass in to initialize the client. The public key is a string.
+// AdditionalVerifierKeys are optional, and let tokens signed by other keys validate too, like during a key rotation.
 type TokenVerificationMetadataInput struct {
+	VerifierKey            string
+	Issuer                 string
+	AdditionalVerifierKeys []VerifierKeyInput
+}
+
+// VerifierKeyInput is an additional public key, as a PEM string, that can verify access tokens. KeyID is optional,
+// and is matched against the kid header of the token.
+type VerifierKeyInput struct {
+	KeyID       string
 	VerifierKey string
-	Issuer      string
 }
 
 // AuthTokenVerificationMetadataResponse is the response from the auth server when getting the public key.
@@ -43,10 +53,32 @@ type AuthTokenVerificationMetadataResponse struct {
 	VerifierKeyPem string `json:"verifier_key_pem"`
 }
 
-// TokenVerificationMetadata is the public key type we use internally.
+// TokenVerificationMetadata is the public key type we use internally. VerifierKeyID is the kid of VerifierKey, if
+// it has one, like when the keys come from a JSON Web Key Set.
 type TokenVerificationMetadata struct {
-	VerifierKey rsa.PublicKey
-	Issuer      string
+	VerifierKey            rsa.PublicKey
+	VerifierKeyID          string
+	Issuer                 string
+	AdditionalVerifierKeys []VerifierKey
+}
+
+// VerifierKey is an additional public key that can verify access tokens. If a token's kid header matches KeyID,
+// only this key is tried for it.
+type VerifierKey struct {
+	KeyID     string
+	PublicKey rsa.PublicKey
+}
+
+// TokenVerificationStatus describes the keys the client uses to verify access tokens, and how refreshing them
+// from the PropelAuth backend is going. The refresh fields are only set if the keys were fetched, rather than
+// passed in with TokenVerificationMetadataInput. LastKeyChangeAt is when the keys were first fetched, or when a
+// refresh last found different ones.
+type TokenVerificationStatus struct {
+	KeyCount             int
+	LastRefreshAt        time.Time
+	LastRefreshAttemptAt time.Time
+	LastRefreshError     error
+	LastKeyChangeAt      time.Time
 }
 
 // Data from token
@@ -0,0 +1,92 @@
+package models
+
+import (
+	"github.com/google/uuid"
+)
+
+// WebhookEventType is the kind of event a webhook is about, from its event_type field.
+type WebhookEventType string
+
+const (
+	WebhookEventUserCreated          WebhookEventType = "user.created"
+	WebhookEventUserUpdated          WebhookEventType = "user.updated"
+	WebhookEventUserDeleted          WebhookEventType = "user.deleted"
+	WebhookEventUserEnabled          WebhookEventType = "user.enabled"
+	WebhookEventUserDisabled         WebhookEventType = "user.disabled"
+	WebhookEventUserLocked           WebhookEventType = "user.locked"
+	WebhookEventUserAddedToOrg       WebhookEventType = "user.added_to_org"
+	WebhookEventUserRemovedFromOrg   WebhookEventType = "user.removed_from_org"
+	WebhookEventUserRoleChangedInOrg WebhookEventType = "user.role_changed_within_org"
+	WebhookEventOrgCreated           WebhookEventType = "org.created"
+	WebhookEventOrgUpdated           WebhookEventType = "org.updated"
+	WebhookEventOrgDeleted           WebhookEventType = "org.deleted"
+)
+
+// WebhookEvent has the fields every webhook event shares.
+type WebhookEvent struct {
+	EventType WebhookEventType `json:"event_type"`
+}
+
+// UserCreatedEvent is sent when a user signs up or is created through the API.
+type UserCreatedEvent struct {
+	WebhookEvent
+	UserID         uuid.UUID `json:"user_id"`
+	Email          string    `json:"email"`
+	EmailConfirmed bool      `json:"email_confirmed"`
+	Username       *string   `json:"username"`
+	FirstName      *string   `json:"first_name"`
+	LastName       *string   `json:"last_name"`
+	PictureURL     *string   `json:"picture_url"`
+}
+
+// UserUpdatedEvent is sent when a user's information changes. Fields that didn't change may be left out.
+type UserUpdatedEvent struct {
+	WebhookEvent
+	UserID         uuid.UUID               `json:"user_id"`
+	Email          *string                 `json:"email"`
+	EmailConfirmed *bool                   `json:"email_confirmed"`
+	Username       *string                 `json:"username"`
+	FirstName      *string                 `json:"first_name"`
+	LastName       *string                 `json:"last_name"`
+	PictureURL     *string                 `json:"picture_url"`
+	Metadata       *map[string]interface{} `json:"metadata"`
+	Properties     *map[string]interface{} `json:"properties"`
+}
+
+// UserEvent is sent when a user is deleted, enabled, disabled or locked. Check EventType to see which.
+type UserEvent struct {
+	WebhookEvent
+	UserID uuid.UUID `json:"user_id"`
+	Email  string    `json:"email"`
+}
+
+// UserAddedToOrgEvent is sent when a user joins an organization.
+type UserAddedToOrgEvent struct {
+	WebhookEvent
+	UserID uuid.UUID `json:"user_id"`
+	OrgID  uuid.UUID `json:"org_id"`
+	Role   string    `json:"role"`
+}
+
+// UserRemovedFromOrgEvent is sent when a user leaves or is removed from an organization.
+type UserRemovedFromOrgEvent struct {
+	WebhookEvent
+	UserID uuid.UUID `json:"user_id"`
+	OrgID  uuid.UUID `json:"org_id"`
+}
+
+// UserRoleChangedInOrgEvent is sent when a user's role in an organization changes.
+type UserRoleChangedInOrgEvent struct {
+	WebhookEvent
+	UserID  uuid.UUID `json:"user_id"`
+	OrgID   uuid.UUID `json:"org_id"`
+	OldRole string    `json:"old_role"`
+	NewRole string    `json:"new_role"`
+}
+
+// OrgEvent is sent when an organization is created, updated or deleted. Check EventType to see which.
+type OrgEvent struct {
+	WebhookEvent
+	OrgID uuid.UUID `json:"org_id"`
+	Name  string    `json:"name"`
+}
@@ -0,0 +1,196 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/propelauth/propelauth-go/pkg/helpers"
+)
+
+// ClientOption configures optional behavior of the client returned by InitBaseAuth. Options are applied in order,
+// so a later option overrides an earlier one.
+type ClientOption func(*clientOptions)
+
+type clientOptions struct {
+	httpClient   *http.Client
+	transport    http.RoundTripper
+	timeout      time.Duration
+	maxIdleConns int
+	retryPolicy  helpers.RetryPolicy
+
+	backendOrigin     string
+	allowInsecureHTTP bool
+
+	keyMaxAge             *time.Duration
+	minKeyRefreshInterval *time.Duration
+
+	tokenValidation helpers.TokenValidationOptions
+}
+
+// RetryPolicy controls how failed requests to the PropelAuth backend are retried. See WithRetryPolicy.
+type RetryPolicy = helpers.RetryPolicy
+
+// DefaultRetryPolicy is a reasonable policy for most applications: up to 3 attempts, starting at 200ms and
+// backing off to at most 5s.
+func DefaultRetryPolicy() RetryPolicy {
+	return helpers.DefaultRetryPolicy()
+}
+
+// ContextWithRetry opts every call made with ctx into the retry policy, including calls that create or modify
+// data, which are otherwise never retried. Only use it for calls you know are safe to repeat:
+//
+//	ok, err := client.WithContext(propelauth.ContextWithRetry(ctx)).AddUserToOrg(params)
+func ContextWithRetry(ctx context.Context) context.Context {
+	return helpers.ContextWithRetry(ctx)
+}
+
+// WithHTTPClient sends every request to the PropelAuth backend through httpClient. The client is copied, so
+// WithTransport and WithTimeout can still be combined with it without modifying the original.
+func WithHTTPClient(httpClient *http.Client) ClientOption {
+	return func(o *clientOptions) {
+		o.httpClient = httpClient
+	}
+}
+
+// WithTransport sets the http.RoundTripper used for requests to the PropelAuth backend. Use this to route
+// through an egress proxy or to configure custom TLS, such as client certificates for mTLS.
+func WithTransport(transport http.RoundTripper) ClientOption {
+	return func(o *clientOptions) {
+		o.transport = transport
+	}
+}
+
+// WithTimeout sets the default timeout for each request to the PropelAuth backend, including reading the
+// response body. A deadline on the context passed to WithContext still applies if it's shorter.
+func WithTimeout(timeout time.Duration) ClientOption {
+	return func(o *clientOptions) {
+		o.timeout = timeout
+	}
+}
+
+// WithMaxIdleConns limits the number of idle keep-alive connections kept open to the PropelAuth backend. This
+// only applies to the transport we create ourselves, and is ignored if you provide one with WithTransport or
+// WithHTTPClient.
+func WithMaxIdleConns(maxIdleConns int) ClientOption {
+	return func(o *clientOptions) {
+		o.maxIdleConns = maxIdleConns
+	}
+}
+
+// WithRetryPolicy retries requests that fail with a 429, a 5xx, or a network error, with a jittered exponential
+// backoff that honors the Retry-After header and API key rate limits. By default only requests that are safe to
+// repeat are retried, see ContextWithRetry to opt in others. Without this option, requests are never retried.
+func WithRetryPolicy(retryPolicy RetryPolicy) ClientOption {
+	return func(o *clientOptions) {
+		o.retryPolicy = retryPolicy
+	}
+}
+
+// WithBackendOrigin points the client at a different PropelAuth backend, like a regional or dedicated deployment,
+// instead of https://propelauth-api.com. The origin is just the scheme and host, without a trailing slash.
+func WithBackendOrigin(origin string) ClientOption {
+	return func(o *clientOptions) {
+		o.backendOrigin = origin
+	}
+}
+
+// WithAllowInsecureHTTP allows the authURL and backend origin to use http:// instead of https://. This is meant for
+// integration tests against a local stand-in, like http://127.0.0.1:8080, and should never be used in production.
+func WithAllowInsecureHTTP() ClientOption {
+	return func(o *clientOptions) {
+		o.allowInsecureHTTP = true
+	}
+}
+
+// WithKeyMaxAge sets how old the keys used to verify access tokens can get before they're refreshed from the
+// backend, so a rotated key is picked up without restarting. There's no timer: the first token validated after
+// the keys reach maxAge starts a refresh in the background, and is checked against the keys we already have. It
+// defaults to an hour, and zero disables it. This has no effect if you pass in a TokenVerificationMetadataInput.
+func WithKeyMaxAge(maxAge time.Duration) ClientOption {
+	return func(o *clientOptions) {
+		o.keyMaxAge = &maxAge
+	}
+}
+
+// WithMinKeyRefreshInterval limits how often a token with an unknown signature can trigger an immediate key
+// refresh. It defaults to a minute.
+func WithMinKeyRefreshInterval(interval time.Duration) ClientOption {
+	return func(o *clientOptions) {
+		o.minKeyRefreshInterval = &interval
+	}
+}
+
+// WithTokenLeeway lets the exp, nbf and iat of access tokens be off by up to leeway, to allow for clock skew between
+// your servers and PropelAuth's.
+func WithTokenLeeway(leeway time.Duration) ClientOption {
+	return func(o *clientOptions) {
+		o.tokenValidation.Leeway = leeway
+	}
+}
+
+// WithTokenMaxAge rejects access tokens issued more than maxAge ago with ErrTokenTooOld, even if they haven't
+// expired yet. Tokens without an iat claim are rejected too.
+func WithTokenMaxAge(maxAge time.Duration) ClientOption {
+	return func(o *clientOptions) {
+		o.tokenValidation.MaxAge = maxAge
+	}
+}
+
+// WithTokenAudience rejects access tokens that don't have audience in their aud claim with ErrTokenAudienceInvalid.
+func WithTokenAudience(audience string) ClientOption {
+	return func(o *clientOptions) {
+		o.tokenValidation.Audience = audience
+	}
+}
+
+// WithAllowedLoginMethods rejects access tokens whose login_method isn't one of loginMethods, like "password" or
+// "saml_sso", with ErrTokenLoginMethodInvalid.
+func WithAllowedLoginMethods(loginMethods ...string) ClientOption {
+	return func(o *clientOptions) {
+		o.tokenValidation.LoginMethods = loginMethods
+	}
+}
+
+// WithoutImpersonation rejects the access tokens of users being impersonated by one of your employees with
+// ErrTokenImpersonated.
+func WithoutImpersonation() ClientOption {
+	return func(o *clientOptions) {
+		o.tokenValidation.DisallowImpersonation = true
+	}
+}
+
+func newClientOptions(opts []ClientOption) *clientOptions {
+	options := &clientOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	return options
+}
+
+// buildHTTPClient assembles the http.Client shared by every request the client makes.
+func (o *clientOptions) buildHTTPClient() *http.Client {
+	httpClient := &http.Client{}
+	if o.httpClient != nil {
+		copied := *o.httpClient
+		httpClient = &copied
+	}
+
+	if o.transport != nil {
+		httpClient.Transport = o.transport
+	} else if httpClient.Transport == nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		if o.maxIdleConns > 0 {
+			transport.MaxIdleConns = o.maxIdleConns
+			transport.MaxIdleConnsPerHost = o.maxIdleConns
+		}
+		httpClient.Transport = transport
+	}
+
+	if o.timeout > 0 {
+		httpClient.Timeout = o.timeout
+	}
+
+	return httpClient
+}
@@ -0,0 +1,166 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/propelauth/propelauth-go/pkg/models"
+)
+
+// OrgIDExtractor finds the ID of the organization a request is for, like from a path value, header, or query
+// parameter. It's called after the access token is validated, so it can use UserFromContext.
+type OrgIDExtractor func(r *http.Request) (uuid.UUID, error)
+
+type orgMemberInfoContextKey struct{}
+
+// OrgIDFromHeader reads the org ID from the named request header.
+func OrgIDFromHeader(name string) OrgIDExtractor {
+	return func(r *http.Request) (uuid.UUID, error) {
+		return parseOrgID(r.Header.Get(name), fmt.Sprintf("header %s", name))
+	}
+}
+
+// OrgIDFromQueryParam reads the org ID from the named query parameter.
+func OrgIDFromQueryParam(name string) OrgIDExtractor {
+	return func(r *http.Request) (uuid.UUID, error) {
+		return parseOrgID(r.URL.Query().Get(name), fmt.Sprintf("query parameter %s", name))
+	}
+}
+
+// OrgIDFromActiveOrg uses the active org from the user's access token.
+func OrgIDFromActiveOrg() OrgIDExtractor {
+	return func(r *http.Request) (uuid.UUID, error) {
+		user, ok := UserFromContext(r.Context())
+		if !ok {
+			return uuid.Nil, errors.New("No user in the request context")
+		}
+
+		activeOrgID := user.GetActiveOrgID()
+		if activeOrgID == nil {
+			return uuid.Nil, errors.New("The access token doesn't have an active org")
+		}
+
+		return *activeOrgID, nil
+	}
+}
+
+func parseOrgID(value string, source string) (uuid.UUID, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return uuid.Nil, fmt.Errorf("Missing org ID in %s", source)
+	}
+
+	orgID, err := uuid.Parse(value)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("Error parsing org ID in %s: %w", source, err)
+	}
+
+	return orgID, nil
+}
+
+// RequireOrgMember only calls next if the request has a valid access token for a user who is a member of the
+// org that orgIDExtractor finds. It responds with a 401 if the access token is missing or invalid, a 400 if
+// orgIDExtractor can't find a valid org ID, and a 403 if the user isn't a member. The membership is available to
+// next through OrgMemberInfoFromContext.
+func (o *AuthMiddleware) RequireOrgMember(orgIDExtractor OrgIDExtractor) func(http.Handler) http.Handler {
+	return o.requireOrg(orgIDExtractor, func(orgMemberInfo *models.OrgMemberInfoFromToken) error {
+		return nil
+	})
+}
+
+// RequireOrgRole is like RequireOrgMember, but also requires the user to have exactly the role in the org.
+func (o *AuthMiddleware) RequireOrgRole(orgIDExtractor OrgIDExtractor, role string) func(http.Handler) http.Handler {
+	return o.requireOrg(orgIDExtractor, func(orgMemberInfo *models.OrgMemberInfoFromToken) error {
+		if !orgMemberInfo.IsRole(role) {
+			return fmt.Errorf("User is not a %s in org %s", role, orgMemberInfo.OrgID)
+		}
+		return nil
+	})
+}
+
+// RequireAtLeastOrgRole is like RequireOrgMember, but also requires the user to have the role, or a role above
+// it, in the org.
+func (o *AuthMiddleware) RequireAtLeastOrgRole(orgIDExtractor OrgIDExtractor, minimumRole string) func(http.Handler) http.Handler {
+	return o.requireOrg(orgIDExtractor, func(orgMemberInfo *models.OrgMemberInfoFromToken) error {
+		if !orgMemberInfo.IsAtLeastRole(minimumRole) {
+			return fmt.Errorf("User is not at least a %s in org %s", minimumRole, orgMemberInfo.OrgID)
+		}
+		return nil
+	})
+}
+
+// RequireOrgPermission is like RequireOrgMember, but also requires the user to have the permission in the org.
+func (o *AuthMiddleware) RequireOrgPermission(orgIDExtractor OrgIDExtractor, permission string) func(http.Handler) http.Handler {
+	return o.requireOrg(orgIDExtractor, func(orgMemberInfo *models.OrgMemberInfoFromToken) error {
+		if !orgMemberInfo.HasPermission(permission) {
+			return fmt.Errorf("User does not have permission %s in org %s", permission, orgMemberInfo.OrgID)
+		}
+		return nil
+	})
+}
+
+// RequireOrgPermissions is like RequireOrgMember, but also requires the user to have all of the permissions in
+// the org.
+func (o *AuthMiddleware) RequireOrgPermissions(orgIDExtractor OrgIDExtractor, permissions []string) func(http.Handler) http.Handler {
+	return o.requireOrg(orgIDExtractor, func(orgMemberInfo *models.OrgMemberInfoFromToken) error {
+		if !orgMemberInfo.HasAllPermissions(permissions) {
+			return fmt.Errorf("User does not have all of the permissions %v in org %s", permissions, orgMemberInfo.OrgID)
+		}
+		return nil
+	})
+}
+
+// requireOrg validates the access token, unless an earlier middleware already did, then looks up the user's
+// membership in the org and runs check on it.
+func (o *AuthMiddleware) requireOrg(orgIDExtractor OrgIDExtractor, check func(orgMemberInfo *models.OrgMemberInfoFromToken) error) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, ok := UserFromContext(r.Context())
+			if !ok {
+				var err error
+				user, err = o.client.WithContext(r.Context()).GetUser(r.Header.Get("Authorization"))
+				if err != nil {
+					o.Unauthorized(w, r, err)
+					return
+				}
+				r = r.WithContext(ContextWithUser(r.Context(), user))
+			}
+
+			orgID, err := orgIDExtractor(r)
+			if err != nil {
+				o.BadRequest(w, r, err)
+				return
+			}
+
+			orgMemberInfo := user.GetOrgMemberInfo(orgID)
+			if orgMemberInfo == nil {
+				o.Forbidden(w, r, fmt.Errorf("User is not a member of org %s", orgID))
+				return
+			}
+
+			if err := check(orgMemberInfo); err != nil {
+				o.Forbidden(w, r, err)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(ContextWithOrgMemberInfo(r.Context(), orgMemberInfo)))
+		})
+	}
+}
+
+// ContextWithOrgMemberInfo returns a copy of ctx that carries the org membership, which can be read back with
+// OrgMemberInfoFromContext.
+func ContextWithOrgMemberInfo(ctx context.Context, orgMemberInfo *models.OrgMemberInfoFromToken) context.Context {
+	return context.WithValue(ctx, orgMemberInfoContextKey{}, orgMemberInfo)
+}
+
+// OrgMemberInfoFromContext returns the org membership that one of the org middlewares checked, if there is one.
+func OrgMemberInfoFromContext(ctx context.Context) (*models.OrgMemberInfoFromToken, bool) {
+	orgMemberInfo, ok := ctx.Value(orgMemberInfoContextKey{}).(*models.OrgMemberInfoFromToken)
+
+	return orgMemberInfo, ok && orgMemberInfo != nil
+}
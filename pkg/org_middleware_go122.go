@@ -0,0 +1,18 @@
+//go:build go1.22
+
+package client
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// OrgIDFromPathValue reads the org ID from the named wildcard in the route pattern, like {orgID} in
+// "GET /orgs/{orgID}/billing". It requires Go 1.22 or later, and a http.ServeMux that sets path values.
+func OrgIDFromPathValue(name string) OrgIDExtractor {
+	return func(r *http.Request) (uuid.UUID, error) {
+		return parseOrgID(r.PathValue(name), fmt.Sprintf("path value %s", name))
+	}
+}
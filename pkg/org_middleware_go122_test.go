@@ -0,0 +1,47 @@
+//go:build go1.22
+
+// the module targets an older Go version, so opt into the Go 1.22 ServeMux patterns
+//go:debug httpmuxgo121=0
+
+package client_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	propelauth "github.com/propelauth/propelauth-go/pkg"
+)
+
+func TestOrgMiddlewarePathValue(t *testing.T) {
+	// setup
+
+	middleware, org, authHeader := orgMiddlewareSetup(t)
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /orgs/{orgID}", middleware.RequireOrgMember(propelauth.OrgIDFromPathValue("orgID"))(orgHandler))
+
+	// run tests
+
+	t.Run("org ID is read from the path", func(t *testing.T) {
+		request := httptest.NewRequest("GET", "/orgs/"+org.OrgID.String(), nil)
+		request.Header.Set("Authorization", authHeader)
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, request)
+
+		if recorder.Code != 200 || recorder.Body.String() != org.OrgID.String() {
+			t.Errorf("expected 200 with the org ID, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+	})
+
+	t.Run("invalid org ID in the path is a 400", func(t *testing.T) {
+		request := httptest.NewRequest("GET", "/orgs/not-a-uuid", nil)
+		request.Header.Set("Authorization", authHeader)
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, request)
+
+		if recorder.Code != 400 {
+			t.Errorf("expected 400, got %d", recorder.Code)
+		}
+	})
+}
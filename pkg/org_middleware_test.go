@@ -0,0 +1,143 @@
+package client_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	propelauth "github.com/propelauth/propelauth-go/pkg"
+	"github.com/propelauth/propelauth-go/pkg/models"
+	testHelpers "github.com/propelauth/propelauth-go/pkg/test"
+)
+
+// orgMiddlewareSetup creates middleware and an access token for a user who is an Admin in one org.
+func orgMiddlewareSetup(t *testing.T) (*propelauth.AuthMiddleware, models.OrgMemberInfoFromToken, string) {
+	privateKey, publicKey := testHelpers.GenerateRSAKeys()
+
+	client, err := propelauth.InitBaseAuth("https://auth.example.com", "apikey", &models.TokenVerificationMetadataInput{
+		VerifierKey: publicKey,
+		Issuer:      "issuertest",
+	})
+	if err != nil {
+		t.Fatalf("NewClient returned an error, cannot even begin the tests: %s", err)
+	}
+
+	org := testHelpers.RandomOrg("Admin", false)
+	org.UserInheritedRolesPlusCurrentRole = []string{"Admin", "Member"}
+	org.UserPermissions = []string{"can_view_billing", "can_invite"}
+
+	user := models.UserFromToken{
+		UserID:               testHelpers.RandomUserID(),
+		ActiveOrgId:          &org.OrgID,
+		OrgIDToOrgMemberInfo: testHelpers.OrgsToOrgIDMap([]models.OrgMemberInfoFromToken{org}),
+	}
+
+	return propelauth.NewAuthMiddleware(client), org, "Bearer " + testHelpers.CreateAccessToken(user, privateKey)
+}
+
+// orgHandler reports the org it found in the context.
+var orgHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	orgMemberInfo, ok := propelauth.OrgMemberInfoFromContext(r.Context())
+	if !ok {
+		_, _ = w.Write([]byte("no org"))
+		return
+	}
+	_, _ = w.Write([]byte(orgMemberInfo.OrgID.String()))
+})
+
+func TestOrgMiddleware(t *testing.T) {
+	// setup
+
+	middleware, org, authHeader := orgMiddlewareSetup(t)
+	fromHeader := propelauth.OrgIDFromHeader("X-Org-ID")
+
+	serve := func(handler http.Handler, orgID uuid.UUID, authHeader string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest("GET", "/org?org_id="+orgID.String(), nil)
+		request.Header.Set("X-Org-ID", orgID.String())
+		if authHeader != "" {
+			request.Header.Set("Authorization", authHeader)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+
+		return recorder
+	}
+
+	// run tests
+
+	t.Run("each extractor finds the org", func(t *testing.T) {
+		extractors := map[string]propelauth.OrgIDExtractor{
+			"header":      fromHeader,
+			"query param": propelauth.OrgIDFromQueryParam("org_id"),
+			"active org":  propelauth.OrgIDFromActiveOrg(),
+		}
+		for name, extractor := range extractors {
+			recorder := serve(middleware.RequireOrgMember(extractor)(orgHandler), org.OrgID, authHeader)
+			if recorder.Code != 200 || recorder.Body.String() != org.OrgID.String() {
+				t.Errorf("%s: expected 200 with the org ID, got %d: %s", name, recorder.Code, recorder.Body.String())
+			}
+		}
+	})
+
+	t.Run("missing token is a 401", func(t *testing.T) {
+		recorder := serve(middleware.RequireOrgMember(fromHeader)(orgHandler), org.OrgID, "")
+		if recorder.Code != 401 {
+			t.Errorf("expected 401, got %d", recorder.Code)
+		}
+	})
+
+	t.Run("another org is a 403", func(t *testing.T) {
+		recorder := serve(middleware.RequireOrgMember(fromHeader)(orgHandler), testHelpers.RandomOrgID(), authHeader)
+		if recorder.Code != 403 {
+			t.Errorf("expected 403, got %d", recorder.Code)
+		}
+	})
+
+	t.Run("missing or invalid org ID is a 400", func(t *testing.T) {
+		recorder := serve(middleware.RequireOrgMember(propelauth.OrgIDFromHeader("X-Other"))(orgHandler), org.OrgID, authHeader)
+		if recorder.Code != 400 {
+			t.Errorf("missing: expected 400, got %d", recorder.Code)
+		}
+
+		request := httptest.NewRequest("GET", "/org", nil)
+		request.Header.Set("X-Org-ID", "not-an-org-id")
+		request.Header.Set("Authorization", authHeader)
+		recorder = httptest.NewRecorder()
+		middleware.RequireOrgMember(fromHeader)(orgHandler).ServeHTTP(recorder, request)
+		if recorder.Code != 400 {
+			t.Errorf("invalid: expected 400, got %d", recorder.Code)
+		}
+	})
+
+	t.Run("roles and permissions are checked", func(t *testing.T) {
+		tests := []struct {
+			name       string
+			middleware func(http.Handler) http.Handler
+			expected   int
+		}{
+			{"exact role", middleware.RequireOrgRole(fromHeader, "Admin"), 200},
+			{"wrong exact role", middleware.RequireOrgRole(fromHeader, "Member"), 403},
+			{"at least a lower role", middleware.RequireAtLeastOrgRole(fromHeader, "Member"), 200},
+			{"at least a higher role", middleware.RequireAtLeastOrgRole(fromHeader, "Owner"), 403},
+			{"permission", middleware.RequireOrgPermission(fromHeader, "can_view_billing"), 200},
+			{"missing permission", middleware.RequireOrgPermission(fromHeader, "can_delete"), 403},
+			{"all permissions", middleware.RequireOrgPermissions(fromHeader, []string{"can_view_billing", "can_invite"}), 200},
+			{"some permissions", middleware.RequireOrgPermissions(fromHeader, []string{"can_view_billing", "can_delete"}), 403},
+		}
+		for _, test := range tests {
+			recorder := serve(test.middleware(orgHandler), org.OrgID, authHeader)
+			if recorder.Code != test.expected {
+				t.Errorf("%s: expected %d, got %d", test.name, test.expected, recorder.Code)
+			}
+		}
+	})
+
+	t.Run("reuses the user from RequireUser", func(t *testing.T) {
+		handler := middleware.RequireUser(middleware.RequireOrgMember(propelauth.OrgIDFromActiveOrg())(orgHandler))
+		recorder := serve(handler, org.OrgID, authHeader)
+		if recorder.Code != 200 || recorder.Body.String() != org.OrgID.String() {
+			t.Errorf("expected 200 with the org ID, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+	})
+}
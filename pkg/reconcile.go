@@ -0,0 +1,434 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/propelauth/propelauth-go/pkg/models"
+)
+
+const defaultMaxRemovals = 10
+
+// ErrTooManyRemovals is returned by OrgReconciler.Reconcile when the changes it planned would remove more members
+// and invites than the limit set with WithMaxRemovals. Nothing is applied when this happens.
+var ErrTooManyRemovals = errors.New("too many removals")
+
+// ErrReinviteFailed is set on a reinvite's OrgChange when the old invite was revoked, but the new one couldn't be
+// sent. The person no longer has an invite, so they need to be invited again.
+var ErrReinviteFailed = errors.New("the invite was revoked, but couldn't be sent again")
+
+// DesiredOrg is the membership an organization should have. Members are keyed by user ID. Invites are keyed by
+// email, and are for people who should be in the org but may not have a user yet. Once someone accepts their
+// invite, they're treated as a member with the invite's role.
+type DesiredOrg struct {
+	OrgID   uuid.UUID
+	Members map[uuid.UUID]DesiredMember
+	Invites map[string]DesiredMember
+}
+
+// DesiredMember is the role, and any additional roles, a member or invitee should have.
+type DesiredMember struct {
+	Role            string
+	AdditionalRoles []string
+}
+
+// OrgChangeKind is the kind of change the reconciler makes.
+type OrgChangeKind string
+
+const (
+	OrgChangeAddMember    OrgChangeKind = "add_member"
+	OrgChangeChangeRole   OrgChangeKind = "change_role"
+	OrgChangeRemoveMember OrgChangeKind = "remove_member"
+	OrgChangeInvite       OrgChangeKind = "invite"
+	OrgChangeReinvite     OrgChangeKind = "reinvite"
+	OrgChangeRevokeInvite OrgChangeKind = "revoke_invite"
+)
+
+// OrgChange is one change the reconciler planned, and after applying it, whether it failed.
+type OrgChange struct {
+	Kind  OrgChangeKind
+	OrgID uuid.UUID
+	// UserID is set for changes to members.
+	UserID *uuid.UUID
+	// Email is set for changes to invites, and for members when we know it.
+	Email           string
+	Role            string
+	AdditionalRoles []string
+	// PreviousRole and PreviousAdditionalRoles are set when a role changes.
+	PreviousRole            string
+	PreviousAdditionalRoles []string
+	// Err is set if applying the change failed.
+	Err error
+}
+
+// String describes the change, like "add user ada@example.com to org ... as Admin", for dry-run output.
+func (o OrgChange) String() string {
+	who := o.Email
+	if who == "" && o.UserID != nil {
+		who = o.UserID.String()
+	}
+
+	switch o.Kind {
+	case OrgChangeAddMember:
+		return fmt.Sprintf("add user %s to org %s as %s", who, o.OrgID, describeRoles(o.Role, o.AdditionalRoles))
+	case OrgChangeChangeRole:
+		return fmt.Sprintf("change the role of user %s in org %s from %s to %s", who, o.OrgID,
+			describeRoles(o.PreviousRole, o.PreviousAdditionalRoles), describeRoles(o.Role, o.AdditionalRoles))
+	case OrgChangeRemoveMember:
+		return fmt.Sprintf("remove user %s from org %s", who, o.OrgID)
+	case OrgChangeInvite:
+		return fmt.Sprintf("invite %s to org %s as %s", who, o.OrgID, describeRoles(o.Role, o.AdditionalRoles))
+	case OrgChangeReinvite:
+		return fmt.Sprintf("reinvite %s to org %s as %s instead of %s", who, o.OrgID,
+			describeRoles(o.Role, o.AdditionalRoles), describeRoles(o.PreviousRole, o.PreviousAdditionalRoles))
+	case OrgChangeRevokeInvite:
+		return fmt.Sprintf("revoke the invite of %s to org %s", who, o.OrgID)
+	}
+
+	return fmt.Sprintf("%s %s in org %s", o.Kind, who, o.OrgID)
+}
+
+func describeRoles(role string, additionalRoles []string) string {
+	if len(additionalRoles) == 0 {
+		return role
+	}
+
+	return fmt.Sprintf("%s (and %s)", role, strings.Join(additionalRoles, ", "))
+}
+
+// ReconcileResult has every change the reconciler planned, in the order they were, or would be, applied.
+type ReconcileResult struct {
+	Changes []OrgChange
+	DryRun  bool
+}
+
+// Failed returns the changes that failed to apply.
+func (o *ReconcileResult) Failed() []OrgChange {
+	failed := []OrgChange{}
+	for _, change := range o.Changes {
+		if change.Err != nil {
+			failed = append(failed, change)
+		}
+	}
+
+	return failed
+}
+
+// Err joins the errors of every change that failed to apply, or returns nil if they all succeeded.
+func (o *ReconcileResult) Err() error {
+	errs := []error{}
+	for _, change := range o.Failed() {
+		errs = append(errs, fmt.Errorf("%s: %w", change, change.Err))
+	}
+
+	return errors.Join(errs...)
+}
+
+// ReconcileOption configures optional behavior of the OrgReconciler returned by NewOrgReconciler.
+type ReconcileOption func(*OrgReconciler)
+
+// OrgReconciler brings organizations' members and pending invites in line with a desired state, making as few
+// changes as it can.
+type OrgReconciler struct {
+	client      ClientInterface
+	dryRun      bool
+	maxRemovals int
+}
+
+// NewOrgReconciler creates a reconciler that makes its changes with client. By default, it refuses to remove more
+// than 10 members and invites in one call to Reconcile.
+func NewOrgReconciler(client ClientInterface, opts ...ReconcileOption) *OrgReconciler {
+	reconciler := &OrgReconciler{
+		client:      client,
+		maxRemovals: defaultMaxRemovals,
+	}
+
+	for _, opt := range opts {
+		opt(reconciler)
+	}
+
+	return reconciler
+}
+
+// WithDryRun plans the changes without applying them.
+func WithDryRun() ReconcileOption {
+	return func(o *OrgReconciler) {
+		o.dryRun = true
+	}
+}
+
+// WithMaxRemovals changes how many members and invites one call to Reconcile may remove. Reinvites count too,
+// since the old invite is revoked first. A negative limit turns the check off.
+func WithMaxRemovals(maxRemovals int) ReconcileOption {
+	return func(o *OrgReconciler) {
+		o.maxRemovals = maxRemovals
+	}
+}
+
+// Reconcile fetches the current members and pending invites of each org, plans the changes that make them match,
+// and applies them. Members are added and invited before anyone is removed. A change that fails doesn't stop the
+// others, so check the result's Err or Failed. An error is only returned if the current state couldn't be fetched,
+// or if there were too many removals, in which case the result still has the planned changes.
+func (o *OrgReconciler) Reconcile(desired ...DesiredOrg) (*ReconcileResult, error) {
+	result := &ReconcileResult{DryRun: o.dryRun}
+	for _, desiredOrg := range desired {
+		changes, err := o.plan(desiredOrg)
+		if err != nil {
+			return nil, err
+		}
+		result.Changes = append(result.Changes, changes...)
+	}
+
+	// apply additions before removals, so members are never missing a role while they're moved around
+	sort.SliceStable(result.Changes, func(i, j int) bool {
+		return !isRemoval(result.Changes[i]) && isRemoval(result.Changes[j])
+	})
+
+	removals := 0
+	for _, change := range result.Changes {
+		if isRemoval(change) || change.Kind == OrgChangeReinvite {
+			removals++
+		}
+	}
+	if o.maxRemovals >= 0 && removals > o.maxRemovals {
+		return result, fmt.Errorf("Error on reconciling orgs, %d removals is over the limit of %d: %w", removals, o.maxRemovals, ErrTooManyRemovals)
+	}
+
+	if o.dryRun {
+		return result, nil
+	}
+
+	for i := range result.Changes {
+		result.Changes[i].Err = o.apply(result.Changes[i])
+	}
+
+	return result, nil
+}
+
+func isRemoval(change OrgChange) bool {
+	return change.Kind == OrgChangeRemoveMember || change.Kind == OrgChangeRevokeInvite
+}
+
+// plan compares the org's current members and invites with the desired ones.
+func (o *OrgReconciler) plan(desired DesiredOrg) ([]OrgChange, error) {
+	members := map[uuid.UUID]models.UserMetadata{}
+	includeOrgs := true
+	users := IterateUsersInOrg(o.client, desired.OrgID, models.UserInOrgQueryParams{IncludeOrgs: &includeOrgs})
+	for users.Next() {
+		members[users.Value().UserID] = users.Value()
+	}
+	if err := users.Err(); err != nil {
+		return nil, fmt.Errorf("Error on fetching the members of org %s: %w", desired.OrgID, err)
+	}
+
+	invites := map[string]models.PendingInvite{}
+	pendingInvites := IteratePendingInvites(o.client, models.FetchPendingInvitesParams{OrgID: &desired.OrgID})
+	for pendingInvites.Next() {
+		invites[strings.ToLower(pendingInvites.Value().InviteeEmail)] = pendingInvites.Value()
+	}
+	if err := pendingInvites.Err(); err != nil {
+		return nil, fmt.Errorf("Error on fetching the pending invites of org %s: %w", desired.OrgID, err)
+	}
+
+	desiredInvites := map[string]DesiredMember{}
+	inviteEmails := map[string]string{}
+	for email, desiredMember := range desired.Invites {
+		desiredInvites[strings.ToLower(email)] = desiredMember
+		inviteEmails[strings.ToLower(email)] = email
+	}
+
+	// people who accepted their invite are members now, with the role they were invited with
+	desiredMembers := map[uuid.UUID]DesiredMember{}
+	for userID, desiredMember := range desired.Members {
+		desiredMembers[userID] = desiredMember
+	}
+	for userID, member := range members {
+		email := strings.ToLower(member.Email)
+		if desiredMember, ok := desiredInvites[email]; ok {
+			if _, ok := desiredMembers[userID]; !ok {
+				desiredMembers[userID] = desiredMember
+			}
+			delete(desiredInvites, email)
+		}
+	}
+
+	changes := []OrgChange{}
+
+	for _, userID := range sortedKeys(desiredMembers, uuid.UUID.String) {
+		desiredMember := desiredMembers[userID]
+		userID := userID
+		member, ok := members[userID]
+		if !ok {
+			changes = append(changes, OrgChange{
+				Kind:            OrgChangeAddMember,
+				OrgID:           desired.OrgID,
+				UserID:          &userID,
+				Role:            desiredMember.Role,
+				AdditionalRoles: desiredMember.AdditionalRoles,
+			})
+			continue
+		}
+
+		role, additionalRoles := memberRoles(member, desired.OrgID)
+		if role != desiredMember.Role || !sameRoles(additionalRoles, desiredMember.AdditionalRoles) {
+			changes = append(changes, OrgChange{
+				Kind:                    OrgChangeChangeRole,
+				OrgID:                   desired.OrgID,
+				UserID:                  &userID,
+				Email:                   member.Email,
+				Role:                    desiredMember.Role,
+				AdditionalRoles:         desiredMember.AdditionalRoles,
+				PreviousRole:            role,
+				PreviousAdditionalRoles: additionalRoles,
+			})
+		}
+	}
+
+	for _, email := range sortedKeys(desiredInvites, func(email string) string { return email }) {
+		desiredMember := desiredInvites[email]
+		invite, ok := invites[email]
+		if !ok {
+			changes = append(changes, OrgChange{
+				Kind:            OrgChangeInvite,
+				OrgID:           desired.OrgID,
+				Email:           inviteEmails[email],
+				Role:            desiredMember.Role,
+				AdditionalRoles: desiredMember.AdditionalRoles,
+			})
+		} else if invite.RoleInOrg != desiredMember.Role || !sameRoles(invite.AdditionalRolesInOrg, desiredMember.AdditionalRoles) {
+			changes = append(changes, OrgChange{
+				Kind:                    OrgChangeReinvite,
+				OrgID:                   desired.OrgID,
+				Email:                   invite.InviteeEmail,
+				Role:                    desiredMember.Role,
+				AdditionalRoles:         desiredMember.AdditionalRoles,
+				PreviousRole:            invite.RoleInOrg,
+				PreviousAdditionalRoles: invite.AdditionalRolesInOrg,
+			})
+		}
+	}
+
+	for _, userID := range sortedKeys(members, uuid.UUID.String) {
+		if _, ok := desiredMembers[userID]; !ok {
+			userID := userID
+			changes = append(changes, OrgChange{
+				Kind:   OrgChangeRemoveMember,
+				OrgID:  desired.OrgID,
+				UserID: &userID,
+				Email:  members[userID].Email,
+			})
+		}
+	}
+
+	for _, email := range sortedKeys(invites, func(email string) string { return email }) {
+		if _, ok := desiredInvites[email]; !ok {
+			changes = append(changes, OrgChange{
+				Kind:  OrgChangeRevokeInvite,
+				OrgID: desired.OrgID,
+				Email: invites[email].InviteeEmail,
+			})
+		}
+	}
+
+	return changes, nil
+}
+
+// apply makes the change with the client.
+func (o *OrgReconciler) apply(change OrgChange) error {
+	var err error
+	switch change.Kind {
+	case OrgChangeAddMember:
+		_, err = o.client.AddUserToOrg(models.AddUserToOrg{
+			UserID:          *change.UserID,
+			OrgID:           change.OrgID,
+			Role:            change.Role,
+			AdditionalRoles: change.AdditionalRoles,
+		})
+	case OrgChangeChangeRole:
+		_, err = o.client.ChangeUserRoleInOrg(models.ChangeUserRoleInOrg{
+			UserID:          *change.UserID,
+			OrgID:           change.OrgID,
+			Role:            change.Role,
+			AdditionalRoles: change.AdditionalRoles,
+		})
+	case OrgChangeRemoveMember:
+		_, err = o.client.RemoveUserFromOrg(models.RemoveUserFromOrg{
+			UserID: *change.UserID,
+			OrgID:  change.OrgID,
+		})
+	case OrgChangeInvite:
+		_, err = o.client.InviteUserToOrg(models.InviteUserToOrg{
+			Email:           change.Email,
+			OrgID:           change.OrgID,
+			Role:            change.Role,
+			AdditionalRoles: change.AdditionalRoles,
+		})
+	case OrgChangeReinvite:
+		orgID := change.OrgID
+		_, err = o.client.RevokePendingOrgInvite(models.RevokePendingOrgInvite{OrgID: &orgID, InviteeEmail: change.Email})
+		if err == nil {
+			_, err = o.client.InviteUserToOrg(models.InviteUserToOrg{
+				Email:           change.Email,
+				OrgID:           change.OrgID,
+				Role:            change.Role,
+				AdditionalRoles: change.AdditionalRoles,
+			})
+			if err != nil {
+				err = fmt.Errorf("%w: %w", ErrReinviteFailed, err)
+			}
+		}
+	case OrgChangeRevokeInvite:
+		orgID := change.OrgID
+		_, err = o.client.RevokePendingOrgInvite(models.RevokePendingOrgInvite{OrgID: &orgID, InviteeEmail: change.Email})
+	default:
+		err = fmt.Errorf("Unknown change %s", change.Kind)
+	}
+
+	return err
+}
+
+// memberRoles returns the user's role and additional roles in the org, from their org info.
+func memberRoles(member models.UserMetadata, orgID uuid.UUID) (string, []string) {
+	if member.OrgIDToOrgInfo == nil {
+		return "", nil
+	}
+
+	orgInfo, ok := (*member.OrgIDToOrgInfo)[orgID]
+	if !ok {
+		return "", nil
+	}
+
+	return orgInfo.UserRole, orgInfo.AdditionalRoles
+}
+
+// sameRoles compares two lists of roles, ignoring their order.
+func sameRoles(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	sortedA := slices.Clone(a)
+	sortedB := slices.Clone(b)
+	sort.Strings(sortedA)
+	sort.Strings(sortedB)
+
+	return slices.Equal(sortedA, sortedB)
+}
+
+// sortedKeys returns the keys of the map in a stable order, so plans are the same every time.
+func sortedKeys[K comparable, V any](m map[K]V, toString func(key K) string) []K {
+	keys := make([]K, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return toString(keys[i]) < toString(keys[j])
+	})
+
+	return keys
+}
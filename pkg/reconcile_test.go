@@ -0,0 +1,222 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+	propelauth "github.com/propelauth/propelauth-go/pkg"
+	"github.com/propelauth/propelauth-go/pkg/models"
+	testHelpers "github.com/propelauth/propelauth-go/pkg/test"
+)
+
+// inviteFailingClient fails every invite.
+type inviteFailingClient struct {
+	propelauth.ClientInterface
+}
+
+func (o *inviteFailingClient) WithContext(ctx context.Context) propelauth.ClientInterface {
+	return o
+}
+
+func (o *inviteFailingClient) InviteUserToOrg(params models.InviteUserToOrg) (bool, error) {
+	return false, errors.New("email provider is down")
+}
+
+func TestOrgReconciler(t *testing.T) {
+	// setup an org with a few members and pending invites
+
+	fake := testHelpers.NewFakeClient(testHelpers.WithFakeRoles("Owner", "Admin", "Member", "Billing"))
+
+	org, err := fake.CreateOrg("Acme")
+	if err != nil {
+		t.Fatalf("CreateOrg returned an error, cannot even begin the tests: %s", err)
+	}
+
+	createMember := func(email string, role string) uuid.UUID {
+		user, err := fake.CreateUser(models.CreateUserParams{Email: email})
+		if err != nil {
+			t.Fatalf("CreateUser returned an error, cannot even begin the tests: %s", err)
+		}
+		if role != "" {
+			if _, err := fake.AddUserToOrg(models.AddUserToOrg{UserID: user.UserID, OrgID: org.OrgID, Role: role}); err != nil {
+				t.Fatalf("AddUserToOrg returned an error, cannot even begin the tests: %s", err)
+			}
+		}
+		return user.UserID
+	}
+
+	unchanged := createMember("unchanged@example.com", "Admin")
+	promoted := createMember("promoted@example.com", "Member")
+	accepted := createMember("accepted@example.com", "Member")
+	createMember("removed@example.com", "Member")
+	added := createMember("added@example.com", "")
+
+	for email, role := range map[string]string{"pending@example.com": "Member", "reinvited@example.com": "Admin"} {
+		if _, err := fake.InviteUserToOrg(models.InviteUserToOrg{Email: email, OrgID: org.OrgID, Role: role}); err != nil {
+			t.Fatalf("InviteUserToOrg returned an error, cannot even begin the tests: %s", err)
+		}
+	}
+
+	desired := propelauth.DesiredOrg{
+		OrgID: org.OrgID,
+		Members: map[uuid.UUID]propelauth.DesiredMember{
+			unchanged: {Role: "Admin"},
+			promoted:  {Role: "Admin", AdditionalRoles: []string{"Billing"}},
+			added:     {Role: "Member"},
+		},
+		Invites: map[string]propelauth.DesiredMember{
+			"pending@example.com":   {Role: "Member"},
+			"Reinvited@example.com": {Role: "Member"},
+			"new@example.com":       {Role: "Admin"},
+			"accepted@example.com":  {Role: "Member"},
+		},
+	}
+
+	kinds := func(result *propelauth.ReconcileResult) []propelauth.OrgChangeKind {
+		kinds := []propelauth.OrgChangeKind{}
+		for _, change := range result.Changes {
+			kinds = append(kinds, change.Kind)
+		}
+		return kinds
+	}
+
+	expectedKinds := []propelauth.OrgChangeKind{
+		propelauth.OrgChangeAddMember,
+		propelauth.OrgChangeChangeRole,
+		propelauth.OrgChangeInvite,
+		propelauth.OrgChangeReinvite,
+		propelauth.OrgChangeRemoveMember,
+	}
+
+	// run tests
+
+	t.Run("dry runs plan the minimal changes without applying them", func(t *testing.T) {
+		result, err := propelauth.NewOrgReconciler(fake, propelauth.WithDryRun()).Reconcile(desired)
+		if err != nil {
+			t.Fatalf("Reconcile returned an error: %s", err)
+		}
+
+		got := kinds(result)
+		if len(got) != len(expectedKinds) || got[4] != propelauth.OrgChangeRemoveMember {
+			t.Fatalf("expected %v with the removal last, got %v", expectedKinds, got)
+		}
+		for _, kind := range expectedKinds {
+			if !slices.Contains(got, kind) {
+				t.Fatalf("expected %v, got %v", expectedKinds, got)
+			}
+		}
+
+		if description := result.Changes[4].String(); description != "remove user removed@example.com from org "+org.OrgID.String() {
+			t.Errorf("expected a readable description, got %q", description)
+		}
+
+		users, _ := fake.FetchUsersInOrg(org.OrgID, models.UserInOrgQueryParams{})
+		if users.TotalUsers != 4 {
+			t.Errorf("expected the dry run to leave the 4 members alone, got %d", users.TotalUsers)
+		}
+	})
+
+	t.Run("too many removals are refused", func(t *testing.T) {
+		result, err := propelauth.NewOrgReconciler(fake, propelauth.WithMaxRemovals(0)).Reconcile(desired)
+		if !errors.Is(err, propelauth.ErrTooManyRemovals) {
+			t.Fatalf("expected ErrTooManyRemovals, got %v", err)
+		}
+		if len(result.Changes) != len(expectedKinds) {
+			t.Errorf("expected the planned changes, got %v", kinds(result))
+		}
+
+		users, _ := fake.FetchUsersInOrg(org.OrgID, models.UserInOrgQueryParams{})
+		if users.TotalUsers != 4 {
+			t.Errorf("expected nothing to be applied, got %d members", users.TotalUsers)
+		}
+	})
+
+	t.Run("changes are applied, and applying again changes nothing", func(t *testing.T) {
+		result, err := propelauth.NewOrgReconciler(fake).Reconcile(desired)
+		if err != nil || result.Err() != nil {
+			t.Fatalf("Reconcile returned an error: %v, %v", err, result.Err())
+		}
+
+		user, err := fake.FetchUserMetadataByUserID(promoted, true)
+		if err != nil {
+			t.Fatalf("FetchUserMetadataByUserID returned an error: %s", err)
+		}
+		orgInfo := (*user.OrgIDToOrgInfo)[org.OrgID]
+		if orgInfo.UserRole != "Admin" || len(orgInfo.AdditionalRoles) != 1 || orgInfo.AdditionalRoles[0] != "Billing" {
+			t.Errorf("expected Admin and Billing, got %+v", orgInfo)
+		}
+
+		again, err := propelauth.NewOrgReconciler(fake).Reconcile(desired)
+		if err != nil || len(again.Changes) != 0 {
+			t.Errorf("expected no changes, got %v and %v", kinds(again), err)
+		}
+
+		if _, ok := desired.Members[accepted]; ok {
+			t.Errorf("the desired state should not have been modified")
+		}
+	})
+
+	t.Run("failed changes are reported without stopping the others", func(t *testing.T) {
+		desired.Members[uuid.New()] = propelauth.DesiredMember{Role: "Member"}
+		desired.Members[unchanged] = propelauth.DesiredMember{Role: "Owner"}
+
+		result, err := propelauth.NewOrgReconciler(fake).Reconcile(desired)
+		if err != nil {
+			t.Fatalf("Reconcile returned an error: %s", err)
+		}
+
+		failed := result.Failed()
+		if len(result.Changes) != 2 || len(failed) != 1 || !errors.Is(failed[0].Err, models.ErrNotFound) || result.Err() == nil {
+			t.Errorf("expected one of two changes to fail, got %v", result.Changes)
+		}
+
+		user, _ := fake.FetchUserMetadataByUserID(unchanged, true)
+		if (*user.OrgIDToOrgInfo)[org.OrgID].UserRole != "Owner" {
+			t.Errorf("expected the other change to be applied")
+		}
+	})
+
+	t.Run("failed reinvites are reported as ErrReinviteFailed", func(t *testing.T) {
+		other, err := fake.CreateOrg("Globex")
+		if err != nil {
+			t.Fatalf("CreateOrg returned an error: %s", err)
+		}
+		if _, err := fake.InviteUserToOrg(models.InviteUserToOrg{Email: "retry@example.com", OrgID: other.OrgID, Role: "Member"}); err != nil {
+			t.Fatalf("InviteUserToOrg returned an error: %s", err)
+		}
+
+		result, err := propelauth.NewOrgReconciler(&inviteFailingClient{ClientInterface: fake}).Reconcile(propelauth.DesiredOrg{
+			OrgID:   other.OrgID,
+			Invites: map[string]propelauth.DesiredMember{"retry@example.com": {Role: "Admin"}},
+		})
+		if err != nil {
+			t.Fatalf("Reconcile returned an error: %s", err)
+		}
+
+		failed := result.Failed()
+		if len(failed) != 1 || failed[0].Kind != propelauth.OrgChangeReinvite || !errors.Is(failed[0].Err, propelauth.ErrReinviteFailed) {
+			t.Errorf("expected the reinvite to fail with ErrReinviteFailed, got %v", result.Changes)
+		}
+	})
+
+	t.Run("reinvites count as removals", func(t *testing.T) {
+		other, err := fake.CreateOrg("Initech")
+		if err != nil {
+			t.Fatalf("CreateOrg returned an error: %s", err)
+		}
+		if _, err := fake.InviteUserToOrg(models.InviteUserToOrg{Email: "moved@example.com", OrgID: other.OrgID, Role: "Member"}); err != nil {
+			t.Fatalf("InviteUserToOrg returned an error: %s", err)
+		}
+
+		_, err = propelauth.NewOrgReconciler(fake, propelauth.WithMaxRemovals(0)).Reconcile(propelauth.DesiredOrg{
+			OrgID:   other.OrgID,
+			Invites: map[string]propelauth.DesiredMember{"moved@example.com": {Role: "Admin"}},
+		})
+		if !errors.Is(err, propelauth.ErrTooManyRemovals) {
+			t.Errorf("expected ErrTooManyRemovals, got %v", err)
+		}
+	})
+}
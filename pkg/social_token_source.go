@@ -0,0 +1,213 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/propelauth/propelauth-go/pkg/models"
+)
+
+const defaultSocialLoginTokenRefreshBefore = 5 * time.Minute
+
+// ErrMissingScopes is returned by a SocialLoginTokenSource when the user didn't authorize every scope set with
+// WithRequiredScopes. The error says which were missing.
+var ErrMissingScopes = errors.New("the token is missing required scopes")
+
+// SocialLoginTokenSourceOption configures optional behavior of the SocialLoginTokenSource returned by
+// NewSocialLoginTokenSource.
+type SocialLoginTokenSourceOption func(*SocialLoginTokenSource)
+
+// SocialLoginTokenSource provides a user's OAuth token for a social login provider, like Google or GitHub, so you
+// can call the provider's APIs as the user. The first token comes from FetchUserOAuthTokens, and it's replaced with
+// one from FetchFreshTokenFromProvider shortly before it expires. It's safe to use from multiple goroutines, and
+// concurrent calls while a token is being fetched wait for it, like with AccessTokenSource. They wait even if their
+// own context is done, so a slow fetch holds up everyone who needs the token.
+//
+// Its Token method has the same shape as golang.org/x/oauth2's TokenSource, so it can be adapted to one, or you can
+// send requests through Client.
+type SocialLoginTokenSource struct {
+	client         ClientInterface
+	userID         uuid.UUID
+	provider       models.SocialLoginTokenProvider
+	requiredScopes []string
+	refreshBefore  time.Duration
+
+	mu      sync.Mutex
+	token   *models.SocialLoginToken
+	fetched bool
+}
+
+// NewSocialLoginTokenSource creates a source of userID's tokens for provider. By default, tokens are replaced five
+// minutes before they expire. Tokens without an expiration are kept until Invalidate is called.
+func NewSocialLoginTokenSource(client ClientInterface, userID uuid.UUID, provider models.SocialLoginTokenProvider, opts ...SocialLoginTokenSourceOption) *SocialLoginTokenSource {
+	source := &SocialLoginTokenSource{
+		client:        client,
+		userID:        userID,
+		provider:      provider,
+		refreshBefore: defaultSocialLoginTokenRefreshBefore,
+	}
+
+	for _, opt := range opts {
+		opt(source)
+	}
+
+	return source
+}
+
+// WithRequiredScopes makes the source return ErrMissingScopes, rather than a token, if the user didn't authorize
+// all of scopes. The token is kept, so the error is returned without fetching again until the token is about to
+// expire or Invalidate is called.
+func WithRequiredScopes(scopes ...string) SocialLoginTokenSourceOption {
+	return func(o *SocialLoginTokenSource) {
+		o.requiredScopes = scopes
+	}
+}
+
+// WithSocialLoginTokenRefreshBefore changes how long before a token expires it's replaced.
+func WithSocialLoginTokenRefreshBefore(refreshBefore time.Duration) SocialLoginTokenSourceOption {
+	return func(o *SocialLoginTokenSource) {
+		o.refreshBefore = refreshBefore
+	}
+}
+
+// Token returns the user's token for the provider, fetching a fresh one if there isn't one or it's about to
+// expire.
+func (o *SocialLoginTokenSource) Token() (*models.SocialLoginToken, error) {
+	return o.TokenContext(context.Background())
+}
+
+// TokenContext is like Token, but fetches the token with ctx.
+func (o *SocialLoginTokenSource) TokenContext(ctx context.Context) (*models.SocialLoginToken, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.token == nil || o.expiresSoon(o.token) {
+		token, err := o.fetch(ctx)
+		if err != nil {
+			return nil, err
+		}
+		o.token = token
+	}
+
+	if missing := missingScopes(o.token.AuthorizedScopes, o.requiredScopes); len(missing) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrMissingScopes, strings.Join(missing, ", "))
+	}
+
+	return o.token, nil
+}
+
+// fetch returns the stored token the first time it's called, if it isn't about to expire, and a fresh one
+// otherwise. The caller must hold mu.
+func (o *SocialLoginTokenSource) fetch(ctx context.Context) (*models.SocialLoginToken, error) {
+	client := o.client.WithContext(ctx)
+
+	var token *models.SocialLoginToken
+	if !o.fetched {
+		// the stored token is often still good, and it's cheaper than asking the provider for a new one
+		tokens, err := client.FetchUserOAuthTokens(o.userID)
+		if err != nil {
+			return nil, err
+		}
+		if tokens != nil {
+			token = (*tokens)[o.provider]
+		}
+	}
+
+	if token == nil || o.expiresSoon(token) {
+		fresh, err := client.FetchFreshTokenFromProvider(o.userID, o.provider)
+		if err != nil {
+			return nil, err
+		}
+		token = fresh
+	}
+	o.fetched = true
+
+	return token, nil
+}
+
+// Invalidate drops the current token, so the next call fetches a fresh one. Use it when the provider rejects a
+// token.
+func (o *SocialLoginTokenSource) Invalidate() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.token = nil
+}
+
+// invalidate drops token if it's still the current one, like AccessTokenSource.invalidate.
+func (o *SocialLoginTokenSource) invalidate(token *models.SocialLoginToken) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.token == token {
+		o.token = nil
+	}
+}
+
+// Client returns an http.Client that sends each request with the user's token in the Authorization header, through
+// a SocialLoginTokenTransport.
+func (o *SocialLoginTokenSource) Client() *http.Client {
+	return &http.Client{Transport: &SocialLoginTokenTransport{Source: o}}
+}
+
+func (o *SocialLoginTokenSource) expiresSoon(token *models.SocialLoginToken) bool {
+	if token.TokenExpiration == nil {
+		return false
+	}
+	return time.Now().Add(o.refreshBefore).After(time.Unix(*token.TokenExpiration, 0))
+}
+
+// missingScopes returns the required scopes that weren't authorized, in order.
+func missingScopes(authorized []string, required []string) []string {
+	missing := []string{}
+	for _, scope := range required {
+		if !slices.Contains(authorized, scope) {
+			missing = append(missing, scope)
+		}
+	}
+	return missing
+}
+
+// SocialLoginTokenTransport is an http.RoundTripper that sets the Authorization header of each request to the
+// user's token from Source, like "Bearer <token>". If a response is a 401, the token is invalidated, so the next
+// request fetches a fresh one. The request isn't retried.
+type SocialLoginTokenTransport struct {
+	Source *SocialLoginTokenSource
+	// Base is the transport requests are sent with. If it's nil, http.DefaultTransport is used.
+	Base http.RoundTripper
+}
+
+// RoundTrip sends a copy of req with the Authorization header set.
+func (o *SocialLoginTokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := o.Source.TokenContext(req.Context())
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, fmt.Errorf("Error on getting a %s token: %w", o.Source.provider, err)
+	}
+
+	// a RoundTripper mustn't change the request it's given
+	authenticated := req.Clone(req.Context())
+	authenticated.Header.Set("Authorization", "Bearer "+token.AccessToken)
+
+	base := o.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	resp, err := base.RoundTrip(authenticated)
+	if err == nil && resp.StatusCode == http.StatusUnauthorized {
+		o.Source.invalidate(token)
+	}
+
+	return resp, err
+}
@@ -0,0 +1,170 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	propelauth "github.com/propelauth/propelauth-go/pkg"
+	"github.com/propelauth/propelauth-go/pkg/models"
+	testHelpers "github.com/propelauth/propelauth-go/pkg/test"
+)
+
+// providerTokenClient stores a token for the user, and hands out fresh ones that last for lifetime.
+type providerTokenClient struct {
+	propelauth.ClientInterface
+	stored   *models.SocialLoginToken
+	lifetime time.Duration
+	scopes   []string
+	fetches  atomic.Int32
+	freshes  atomic.Int32
+}
+
+func (o *providerTokenClient) WithContext(ctx context.Context) propelauth.ClientInterface {
+	return o
+}
+
+func (o *providerTokenClient) FetchUserOAuthTokens(userID uuid.UUID) (*models.SocialLoginTokensResponse, error) {
+	o.fetches.Add(1)
+	tokens := models.SocialLoginTokensResponse{}
+	if o.stored != nil {
+		tokens[o.stored.TokenProvider] = o.stored
+	}
+	return &tokens, nil
+}
+
+func (o *providerTokenClient) FetchFreshTokenFromProvider(userID uuid.UUID, provider models.SocialLoginTokenProvider) (*models.SocialLoginToken, error) {
+	count := o.freshes.Add(1)
+	expiration := time.Now().Add(o.lifetime).Unix()
+	return &models.SocialLoginToken{
+		AccessToken:      fmt.Sprintf("fresh-%d", count),
+		TokenProvider:    provider,
+		TokenExpiration:  &expiration,
+		AuthorizedScopes: o.scopes,
+	}, nil
+}
+
+func TestSocialLoginTokenSource(t *testing.T) {
+	// setup a user, with a stored GitHub token
+
+	fake := testHelpers.NewFakeClient()
+
+	user, err := fake.CreateUser(models.CreateUserParams{Email: "octocat@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser returned an error, cannot even begin the tests: %s", err)
+	}
+
+	storedToken := func(expiresIn time.Duration) *models.SocialLoginToken {
+		expiration := time.Now().Add(expiresIn).Unix()
+		return &models.SocialLoginToken{
+			AccessToken:      "stored",
+			TokenProvider:    models.SocialLoginTokenProviderGitHub,
+			TokenExpiration:  &expiration,
+			AuthorizedScopes: []string{"repo", "read:user"},
+		}
+	}
+
+	// run tests
+
+	t.Run("a stored token is used until it's about to expire", func(t *testing.T) {
+		client := &providerTokenClient{ClientInterface: fake, stored: storedToken(time.Hour), lifetime: time.Hour, scopes: []string{"repo"}}
+		source := propelauth.NewSocialLoginTokenSource(client, user.UserID, models.SocialLoginTokenProviderGitHub, propelauth.WithRequiredScopes("repo"))
+
+		for i := 0; i < 3; i++ {
+			token, err := source.Token()
+			if err != nil || token.AccessToken != "stored" {
+				t.Fatalf("expected the stored token, got %+v and %v", token, err)
+			}
+		}
+		if client.fetches.Load() != 1 || client.freshes.Load() != 0 {
+			t.Errorf("expected 1 fetch and no fresh tokens, got %d and %d", client.fetches.Load(), client.freshes.Load())
+		}
+
+		source.Invalidate()
+		if token, err := source.Token(); err != nil || token.AccessToken != "fresh-1" {
+			t.Errorf("expected a fresh token after Invalidate, got %+v and %v", token, err)
+		}
+	})
+
+	t.Run("tokens close to expiring are refreshed", func(t *testing.T) {
+		client := &providerTokenClient{ClientInterface: fake, stored: storedToken(time.Minute), lifetime: 2 * time.Minute}
+		source := propelauth.NewSocialLoginTokenSource(client, user.UserID, models.SocialLoginTokenProviderGitHub)
+
+		// the stored token expires within the default 5 minutes, and so does each fresh one
+		for i := 1; i <= 2; i++ {
+			token, err := source.Token()
+			if err != nil || token.AccessToken != fmt.Sprintf("fresh-%d", i) {
+				t.Errorf("expected fresh token %d, got %+v and %v", i, token, err)
+			}
+		}
+
+		withShortRefresh := propelauth.NewSocialLoginTokenSource(client, user.UserID, models.SocialLoginTokenProviderGitHub,
+			propelauth.WithSocialLoginTokenRefreshBefore(30*time.Second))
+		if token, err := withShortRefresh.Token(); err != nil || token.AccessToken != "stored" {
+			t.Errorf("expected the stored token with a shorter refresh window, got %+v and %v", token, err)
+		}
+	})
+
+	t.Run("missing scopes are an error", func(t *testing.T) {
+		client := &providerTokenClient{ClientInterface: fake, stored: storedToken(time.Hour), lifetime: time.Hour}
+		source := propelauth.NewSocialLoginTokenSource(client, user.UserID, models.SocialLoginTokenProviderGitHub,
+			propelauth.WithRequiredScopes("repo", "admin:org", "gist"))
+
+		_, err := source.Token()
+		if !errors.Is(err, propelauth.ErrMissingScopes) || err.Error() != "the token is missing required scopes: admin:org, gist" {
+			t.Errorf("expected ErrMissingScopes listing admin:org and gist, got %v", err)
+		}
+
+		// the token is kept, so asking again doesn't fetch until it's invalidated
+		if _, err := source.Token(); !errors.Is(err, propelauth.ErrMissingScopes) || client.fetches.Load() != 1 || client.freshes.Load() != 0 {
+			t.Errorf("expected the cached ErrMissingScopes, got %v after %d fetches and %d fresh tokens", err, client.fetches.Load(), client.freshes.Load())
+		}
+		source.Invalidate()
+		if _, err := source.Token(); !errors.Is(err, propelauth.ErrMissingScopes) || client.freshes.Load() != 1 {
+			t.Errorf("expected a fresh token after Invalidate, got %v after %d fresh tokens", err, client.freshes.Load())
+		}
+	})
+
+	t.Run("errors from the backend are returned", func(t *testing.T) {
+		source := propelauth.NewSocialLoginTokenSource(fake, user.UserID, models.SocialLoginTokenProviderGoogle)
+
+		if _, err := source.Token(); !errors.Is(err, propelauth.ErrNotFound) {
+			t.Errorf("expected ErrNotFound for a user who hasn't logged in with Google, got %v", err)
+		}
+	})
+
+	t.Run("the client sets the Authorization header", func(t *testing.T) {
+		client := &providerTokenClient{ClientInterface: fake, stored: storedToken(time.Hour), lifetime: time.Hour}
+		source := propelauth.NewSocialLoginTokenSource(client, user.UserID, models.SocialLoginTokenProviderGitHub)
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// the provider has revoked the stored token
+			if r.Header.Get("Authorization") != "Bearer fresh-1" {
+				w.WriteHeader(401)
+				return
+			}
+			w.WriteHeader(200)
+		}))
+		defer server.Close()
+
+		statuses := []int{}
+		for i := 0; i < 2; i++ {
+			resp, err := source.Client().Get(server.URL)
+			if err != nil {
+				t.Fatalf("Get returned an error: %s", err)
+			}
+			resp.Body.Close()
+			statuses = append(statuses, resp.StatusCode)
+		}
+
+		if statuses[0] != 401 || statuses[1] != 200 {
+			t.Errorf("expected a 401 with the stored token, then a 200 with a fresh one, got %v", statuses)
+		}
+	})
+}
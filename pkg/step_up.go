@@ -0,0 +1,356 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/propelauth/propelauth-go/pkg/models"
+)
+
+const defaultStepUpGrantHeader = "X-Step-Up-Grant"
+const defaultStepUpGrantValidFor = 5 * time.Minute
+
+// maxStepUpRequestSize is the largest request body the step-up handlers read.
+const maxStepUpRequestSize = 4096
+
+// StepUpOption configures optional behavior of the StepUp returned by NewStepUp.
+type StepUpOption func(*StepUp)
+
+// StepUpRequiredResponder writes the response for a request that needs a step-up grant it doesn't have.
+type StepUpRequiredResponder func(w http.ResponseWriter, r *http.Request, required StepUpRequired)
+
+// StepUp runs step-up MFA for users who are already logged in, by asking them for a code before a sensitive
+// action. The handlers let your frontend list the user's methods, send an SMS code, and exchange a code for a
+// grant, and RequireStepUpGrant protects the routes that need one.
+//
+// The handlers and the middleware need the user, so put them behind AuthMiddleware.RequireUser.
+type StepUp struct {
+	client                  ClientInterface
+	header                  string
+	grantType               models.StepUpMfaGrantType
+	validFor                time.Duration
+	stepUpRequiredResponder StepUpRequiredResponder
+	unauthorizedResponder   ErrorResponder
+	rateLimitedResponder    ErrorResponder
+	unavailableResponder    ErrorResponder
+}
+
+// StepUpMethod is a way the user can step up. Type is "totp" for an authenticator app, or "sms" for a phone,
+// which is identified by MfaPhoneID.
+type StepUpMethod struct {
+	Type                 string `json:"type"`
+	MfaPhoneID           string `json:"mfa_phone_id,omitempty"`
+	MfaPhoneNumberSuffix string `json:"mfa_phone_number_suffix,omitempty"`
+}
+
+// StepUpRequired is what RequireStepUpGrant tells the caller when a request doesn't have a valid grant: the action
+// it needs a grant for, and the methods the user can get one with. Err says why the grant wasn't accepted, and is
+// meant for logging rather than for the caller.
+type StepUpRequired struct {
+	ActionType string         `json:"action_type"`
+	Methods    []StepUpMethod `json:"methods"`
+	Err        error          `json:"-"`
+}
+
+// NewStepUp creates a StepUp that verifies codes and grants with client. By default, grants are one time use and
+// valid for five minutes, and RequireStepUpGrant reads them from the X-Step-Up-Grant header.
+func NewStepUp(client ClientInterface, opts ...StepUpOption) *StepUp {
+	stepUp := &StepUp{
+		client:                  client,
+		header:                  defaultStepUpGrantHeader,
+		grantType:               models.StepUpMfaGrantTypeOneTimeUse,
+		validFor:                defaultStepUpGrantValidFor,
+		stepUpRequiredResponder: DefaultStepUpRequiredResponder,
+		unauthorizedResponder:   DefaultUnauthorizedResponder,
+		rateLimitedResponder:    DefaultRateLimitedResponder,
+		unavailableResponder:    DefaultUnavailableResponder,
+	}
+
+	for _, opt := range opts {
+		opt(stepUp)
+	}
+
+	return stepUp
+}
+
+// WithStepUpGrantHeader reads grants from the named header instead of X-Step-Up-Grant.
+func WithStepUpGrantHeader(name string) StepUpOption {
+	return func(o *StepUp) {
+		o.header = name
+	}
+}
+
+// WithStepUpGrant changes the kind of grant a verified code gives, and how long it's valid for. A time based grant
+// can be used for any number of requests until it expires.
+func WithStepUpGrant(grantType models.StepUpMfaGrantType, validFor time.Duration) StepUpOption {
+	return func(o *StepUp) {
+		o.grantType = grantType
+		o.validFor = validFor
+	}
+}
+
+// WithStepUpRequiredResponder replaces the response written when a request doesn't have a valid grant.
+func WithStepUpRequiredResponder(responder StepUpRequiredResponder) StepUpOption {
+	return func(o *StepUp) {
+		o.stepUpRequiredResponder = responder
+	}
+}
+
+// WithStepUpUnauthorizedResponder replaces the response written when a request has no user in its context, like
+// when the handler isn't behind RequireUser.
+func WithStepUpUnauthorizedResponder(responder ErrorResponder) StepUpOption {
+	return func(o *StepUp) {
+		o.unauthorizedResponder = responder
+	}
+}
+
+// WithStepUpRateLimitedResponder replaces the response written when PropelAuth rate limited a request, like after
+// too many wrong codes.
+func WithStepUpRateLimitedResponder(responder ErrorResponder) StepUpOption {
+	return func(o *StepUp) {
+		o.rateLimitedResponder = responder
+	}
+}
+
+// WithStepUpUnavailableResponder replaces the response written when PropelAuth couldn't be reached.
+func WithStepUpUnavailableResponder(responder ErrorResponder) StepUpOption {
+	return func(o *StepUp) {
+		o.unavailableResponder = responder
+	}
+}
+
+// MethodsHandler responds with the user's step-up methods, like {"methods": [{"type": "totp"}]}. The list is empty
+// if the user hasn't set up MFA.
+func (o *StepUp) MethodsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, ok := UserFromContext(r.Context())
+		if !ok {
+			o.unauthorizedResponder(w, r, errors.New("No user in the request context"))
+			return
+		}
+
+		methods, err := o.methods(r, user.UserID)
+		if err != nil {
+			o.respondToError(w, r, err, "")
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]interface{}{"methods": methods})
+	})
+}
+
+// StartSmsHandler sends a code to one of the user's phones. It takes a JSON body like
+// {"action_type": "delete_org", "mfa_phone_id": "..."}, and responds with {"challenge_id": "..."}, which is sent
+// back to VerifyHandler with the code.
+func (o *StepUp) StartSmsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, ok := UserFromContext(r.Context())
+		if !ok {
+			o.unauthorizedResponder(w, r, errors.New("No user in the request context"))
+			return
+		}
+
+		body := struct {
+			ActionType string `json:"action_type"`
+			MfaPhoneID string `json:"mfa_phone_id"`
+		}{}
+		if err := readStepUpRequest(w, r, &body); err != nil || body.ActionType == "" {
+			writeJSONError(w, http.StatusBadRequest, "bad_request")
+			return
+		}
+		mfaPhoneID, err := uuid.Parse(body.MfaPhoneID)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "bad_request")
+			return
+		}
+
+		challenge, err := o.client.WithContext(r.Context()).SendSmsMfaCode(models.SendSmsMfaCodeRequest{
+			ActionType:      body.ActionType,
+			UserID:          user.UserID,
+			MfaPhoneID:      mfaPhoneID,
+			GrantType:       o.grantType,
+			ValidForSeconds: int(o.validFor.Seconds()),
+		})
+		if err != nil {
+			o.respondToError(w, r, err, "sms_failed")
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]string{"challenge_id": challenge.ChallengeID})
+	})
+}
+
+// VerifyHandler exchanges a code for a grant. It takes a JSON body like {"action_type": "delete_org", "code":
+// "123456"} for a TOTP code, or {"challenge_id": "...", "code": "123456"} for an SMS code, and responds with
+// {"step_up_grant": "..."}. A wrong code gets a 400 with {"error": "invalid_code"}.
+func (o *StepUp) VerifyHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, ok := UserFromContext(r.Context())
+		if !ok {
+			o.unauthorizedResponder(w, r, errors.New("No user in the request context"))
+			return
+		}
+
+		body := struct {
+			ActionType  string `json:"action_type"`
+			ChallengeID string `json:"challenge_id"`
+			Code        string `json:"code"`
+		}{}
+		if err := readStepUpRequest(w, r, &body); err != nil || body.Code == "" || (body.ActionType == "" && body.ChallengeID == "") {
+			writeJSONError(w, http.StatusBadRequest, "bad_request")
+			return
+		}
+
+		client := o.client.WithContext(r.Context())
+
+		var grant string
+		if body.ChallengeID != "" {
+			verified, err := client.VerifySmsChallenge(models.VerifySmsChallengeRequest{
+				ChallengeID: body.ChallengeID,
+				UserID:      user.UserID,
+				Code:        body.Code,
+			})
+			if err != nil {
+				o.respondToError(w, r, err, "invalid_code")
+				return
+			}
+			grant = verified.StepUpGrant
+		} else {
+			verified, err := client.VerifyStepUpTotpChallenge(models.VerifyTotpChallengeRequest{
+				ActionType:      body.ActionType,
+				UserID:          user.UserID,
+				Code:            body.Code,
+				GrantType:       o.grantType,
+				ValidForSeconds: int(o.validFor.Seconds()),
+			})
+			if err != nil {
+				o.respondToError(w, r, err, "invalid_code")
+				return
+			}
+			grant = verified.StepUpGrant
+		}
+
+		writeJSON(w, http.StatusOK, map[string]string{"step_up_grant": grant})
+	})
+}
+
+// RequireStepUpGrant only calls next if the request has a grant for actionType, from VerifyHandler, in the grant
+// header. Otherwise it responds with the methods the user can step up with, which by default is a 403 with a JSON
+// body like {"error": "step_up_required", "action_type": "delete_org", "methods": [{"type": "totp"}]}.
+func (o *StepUp) RequireStepUpGrant(actionType string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, ok := UserFromContext(r.Context())
+			if !ok {
+				o.unauthorizedResponder(w, r, errors.New("No user in the request context"))
+				return
+			}
+
+			grant := strings.TrimSpace(r.Header.Get(o.header))
+			if grant == "" {
+				o.stepUpRequired(w, r, user.UserID, actionType, fmt.Errorf("Missing step-up grant in header %s", o.header))
+				return
+			}
+
+			verified, err := o.client.WithContext(r.Context()).VerifyStepUpGrant(models.VerifyStepUpGrantRequest{
+				ActionType: actionType,
+				UserID:     user.UserID,
+				Grant:      grant,
+			})
+			if isRejection(err) {
+				o.stepUpRequired(w, r, user.UserID, actionType, err)
+				return
+			}
+			if err != nil {
+				o.respondToError(w, r, err, "")
+				return
+			}
+			if !verified.Success {
+				o.stepUpRequired(w, r, user.UserID, actionType, fmt.Errorf("The step-up grant isn't valid for %s", actionType))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (o *StepUp) stepUpRequired(w http.ResponseWriter, r *http.Request, userID uuid.UUID, actionType string, reason error) {
+	methods, err := o.methods(r, userID)
+	if err != nil {
+		o.respondToError(w, r, err, "")
+		return
+	}
+
+	o.stepUpRequiredResponder(w, r, StepUpRequired{ActionType: actionType, Methods: methods, Err: reason})
+}
+
+// methods lists the user's step-up methods, from FetchUserMfaMethods.
+func (o *StepUp) methods(r *http.Request, userID uuid.UUID) ([]StepUpMethod, error) {
+	mfaMethods, err := o.client.WithContext(r.Context()).FetchUserMfaMethods(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	methods := []StepUpMethod{}
+	if strings.EqualFold(mfaMethods.MfaSetup.Type, "totp") {
+		methods = append(methods, StepUpMethod{Type: "totp"})
+	}
+	if mfaMethods.MfaSetup.PhoneNumbers != nil {
+		for _, phone := range *mfaMethods.MfaSetup.PhoneNumbers {
+			methods = append(methods, StepUpMethod{
+				Type:                 "sms",
+				MfaPhoneID:           phone.MfaPhoneID,
+				MfaPhoneNumberSuffix: phone.MfaPhoneNumberSuffix,
+			})
+		}
+	}
+
+	return methods, nil
+}
+
+// respondToError responds with a 429 if PropelAuth rate limited the request, a 400 with rejectedCode as the error
+// if it rejected what was sent, like a wrong code, and otherwise with a 503.
+func (o *StepUp) respondToError(w http.ResponseWriter, r *http.Request, err error, rejectedCode string) {
+	if errors.Is(err, ErrRateLimited) {
+		o.rateLimitedResponder(w, r, err)
+		return
+	}
+
+	if rejectedCode != "" && isRejection(err) {
+		writeJSONError(w, http.StatusBadRequest, rejectedCode)
+		return
+	}
+
+	o.unavailableResponder(w, r, err)
+}
+
+// isRejection reports whether PropelAuth rejected what was sent, rather than failing or rate limiting the request.
+func isRejection(err error) bool {
+	apiError := &models.APIError{}
+	return errors.As(err, &apiError) && apiError.StatusCode >= 400 && apiError.StatusCode < 500 &&
+		apiError.StatusCode != 401 && apiError.StatusCode != 429
+}
+
+// DefaultStepUpRequiredResponder responds with a 403 and a JSON body like {"error": "step_up_required",
+// "action_type": "delete_org", "methods": [...]}.
+func DefaultStepUpRequiredResponder(w http.ResponseWriter, r *http.Request, required StepUpRequired) {
+	writeJSON(w, http.StatusForbidden, struct {
+		Error string `json:"error"`
+		StepUpRequired
+	}{"step_up_required", required})
+}
+
+func readStepUpRequest(w http.ResponseWriter, r *http.Request, body interface{}) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxStepUpRequestSize))
+	if err := decoder.Decode(body); err != nil {
+		return fmt.Errorf("Error on unmarshalling the request body: %w", err)
+	}
+	return nil
+}
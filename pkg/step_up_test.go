@@ -0,0 +1,237 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	propelauth "github.com/propelauth/propelauth-go/pkg"
+	"github.com/propelauth/propelauth-go/pkg/models"
+	testHelpers "github.com/propelauth/propelauth-go/pkg/test"
+)
+
+// stepUpClient gives the fake's users TOTP and a phone, accepts the code 123456, rate limits the code 429429, and
+// hands out one time use grants.
+type stepUpClient struct {
+	propelauth.ClientInterface
+	phoneID uuid.UUID
+
+	mu     sync.Mutex
+	grants map[string]string
+}
+
+func (o *stepUpClient) WithContext(ctx context.Context) propelauth.ClientInterface {
+	return o
+}
+
+func (o *stepUpClient) FetchUserMfaMethods(userID uuid.UUID) (*models.FetchUserMfaMethodsResponse, error) {
+	phones := []models.MfaPhones{{MfaPhoneID: o.phoneID.String(), MfaPhoneNumberSuffix: "1234"}}
+	return &models.FetchUserMfaMethodsResponse{MfaSetup: models.MfaSetupType{Type: "Totp", PhoneNumbers: &phones}}, nil
+}
+
+func (o *stepUpClient) VerifyStepUpTotpChallenge(params models.VerifyTotpChallengeRequest) (*models.StepUpMfaVerifyTotpResponse, error) {
+	if params.Code == "429429" {
+		return nil, models.NewAPIError(429, nil, "Too many attempts", models.ErrRateLimited)
+	}
+	if params.Code != "123456" {
+		return o.ClientInterface.VerifyStepUpTotpChallenge(params)
+	}
+	return &models.StepUpMfaVerifyTotpResponse{StepUpGrant: o.grant(params.ActionType)}, nil
+}
+
+func (o *stepUpClient) SendSmsMfaCode(params models.SendSmsMfaCodeRequest) (*models.SendSmsMfaCodeResponse, error) {
+	if params.MfaPhoneID != o.phoneID {
+		return o.ClientInterface.SendSmsMfaCode(params)
+	}
+	return &models.SendSmsMfaCodeResponse{ChallengeID: params.ActionType}, nil
+}
+
+func (o *stepUpClient) VerifySmsChallenge(params models.VerifySmsChallengeRequest) (*models.VerifySmsChallengeResponse, error) {
+	if params.Code != "123456" {
+		return o.ClientInterface.VerifySmsChallenge(params)
+	}
+	// the challenge ID is the action type, see SendSmsMfaCode
+	return &models.VerifySmsChallengeResponse{StepUpGrant: o.grant(params.ChallengeID)}, nil
+}
+
+func (o *stepUpClient) VerifyStepUpGrant(params models.VerifyStepUpGrantRequest) (*models.StepUpMfaVerifyGrantResponse, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	actionType, ok := o.grants[params.Grant]
+	delete(o.grants, params.Grant)
+	return &models.StepUpMfaVerifyGrantResponse{Success: ok && actionType == params.ActionType}, nil
+}
+
+func (o *stepUpClient) grant(actionType string) string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	grant := uuid.NewString()
+	o.grants[grant] = actionType
+	return grant
+}
+
+func TestStepUp(t *testing.T) {
+	// setup a user with step-up methods, and a route that needs a grant
+
+	fake := testHelpers.NewFakeClient()
+
+	user, err := fake.CreateUser(models.CreateUserParams{Email: "careful@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser returned an error, cannot even begin the tests: %s", err)
+	}
+
+	client := &stepUpClient{ClientInterface: fake, phoneID: uuid.New(), grants: map[string]string{}}
+	stepUp := propelauth.NewStepUp(client)
+
+	deleteOrg := stepUp.RequireStepUpGrant("delete_org")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("deleted"))
+	}))
+
+	serve := func(handler http.Handler, body string, grant string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		request := httptest.NewRequest("POST", "/step-up", strings.NewReader(body))
+		request = request.WithContext(propelauth.ContextWithUser(request.Context(), &models.UserFromToken{UserID: user.UserID}))
+		if grant != "" {
+			request.Header.Set("X-Step-Up-Grant", grant)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+
+		response := map[string]interface{}{}
+		_ = json.Unmarshal(recorder.Body.Bytes(), &response)
+		return recorder, response
+	}
+
+	// run tests
+
+	t.Run("the methods handler lists TOTP and phones", func(t *testing.T) {
+		recorder, response := serve(stepUp.MethodsHandler(), "", "")
+		methods, _ := response["methods"].([]interface{})
+		if recorder.Code != 200 || len(methods) != 2 {
+			t.Fatalf("expected 2 methods, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+		if sms := methods[1].(map[string]interface{}); sms["type"] != "sms" || sms["mfa_phone_id"] != client.phoneID.String() {
+			t.Errorf("expected the phone second, got %v", sms)
+		}
+	})
+
+	t.Run("a route without a grant responds with the methods", func(t *testing.T) {
+		recorder, response := serve(deleteOrg, "", "")
+		methods, _ := response["methods"].([]interface{})
+		if recorder.Code != 403 || response["error"] != "step_up_required" || response["action_type"] != "delete_org" || len(methods) != 2 {
+			t.Errorf("expected a step-up required 403, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+
+		if recorder, _ := serve(deleteOrg, "", "made-up"); recorder.Code != 403 {
+			t.Errorf("expected a 403 for a made up grant, got %d", recorder.Code)
+		}
+	})
+
+	t.Run("a TOTP code gives a one time grant", func(t *testing.T) {
+		recorder, response := serve(stepUp.VerifyHandler(), `{"action_type": "delete_org", "code": "123456"}`, "")
+		grant, _ := response["step_up_grant"].(string)
+		if recorder.Code != 200 || grant == "" {
+			t.Fatalf("expected a grant, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+
+		if recorder, _ := serve(deleteOrg, "", grant); recorder.Code != 200 || recorder.Body.String() != "deleted" {
+			t.Errorf("expected the grant to be accepted, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+		if recorder, _ := serve(deleteOrg, "", grant); recorder.Code != 403 {
+			t.Errorf("expected the grant to only work once, got %d", recorder.Code)
+		}
+	})
+
+	t.Run("grants are for one action", func(t *testing.T) {
+		_, response := serve(stepUp.VerifyHandler(), `{"action_type": "export_data", "code": "123456"}`, "")
+
+		if recorder, _ := serve(deleteOrg, "", response["step_up_grant"].(string)); recorder.Code != 403 {
+			t.Errorf("expected a grant for another action to be refused, got %d", recorder.Code)
+		}
+	})
+
+	t.Run("an SMS code gives a grant", func(t *testing.T) {
+		recorder, response := serve(stepUp.StartSmsHandler(), `{"action_type": "delete_org", "mfa_phone_id": "`+client.phoneID.String()+`"}`, "")
+		challengeID, _ := response["challenge_id"].(string)
+		if recorder.Code != 200 || challengeID == "" {
+			t.Fatalf("expected a challenge, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+
+		_, response = serve(stepUp.VerifyHandler(), `{"challenge_id": "`+challengeID+`", "code": "123456"}`, "")
+		if recorder, _ := serve(deleteOrg, "", response["step_up_grant"].(string)); recorder.Code != 200 {
+			t.Errorf("expected the SMS grant to be accepted, got %d", recorder.Code)
+		}
+	})
+
+	t.Run("bad requests and wrong codes are 400s", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			handler  http.Handler
+			body     string
+			expected string
+		}{
+			{"not JSON", stepUp.VerifyHandler(), "code=123456", "bad_request"},
+			{"no code", stepUp.VerifyHandler(), `{"action_type": "delete_org"}`, "bad_request"},
+			{"wrong TOTP code", stepUp.VerifyHandler(), `{"action_type": "delete_org", "code": "000000"}`, "invalid_code"},
+			{"wrong SMS code", stepUp.VerifyHandler(), `{"challenge_id": "delete_org", "code": "000000"}`, "invalid_code"},
+			{"bad phone ID", stepUp.StartSmsHandler(), `{"action_type": "delete_org", "mfa_phone_id": "phone"}`, "bad_request"},
+			{"unknown phone", stepUp.StartSmsHandler(), `{"action_type": "delete_org", "mfa_phone_id": "` + uuid.NewString() + `"}`, "sms_failed"},
+		}
+
+		for _, test := range tests {
+			recorder, response := serve(test.handler, test.body, "")
+			if recorder.Code != 400 || response["error"] != test.expected {
+				t.Errorf("%s: expected a 400 with %s, got %d: %s", test.name, test.expected, recorder.Code, recorder.Body.String())
+			}
+		}
+	})
+
+	t.Run("requests without a user are rejected", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		stepUp.MethodsHandler().ServeHTTP(recorder, httptest.NewRequest("GET", "/step-up", nil))
+		if recorder.Code != 401 {
+			t.Errorf("expected 401, got %d", recorder.Code)
+		}
+	})
+
+	t.Run("the step-up required response can be replaced", func(t *testing.T) {
+		custom := propelauth.NewStepUp(client, propelauth.WithStepUpGrantHeader("X-Grant"),
+			propelauth.WithStepUpRequiredResponder(func(w http.ResponseWriter, r *http.Request, required propelauth.StepUpRequired) {
+				w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_user_authentication"`)
+				w.WriteHeader(401)
+			}))
+
+		recorder, _ := serve(custom.RequireStepUpGrant("delete_org")(http.NotFoundHandler()), "", "ignored-header")
+		if recorder.Code != 401 || recorder.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("expected the custom 401, got %d", recorder.Code)
+		}
+	})
+
+	t.Run("the unauthorized and rate limited responses can be replaced", func(t *testing.T) {
+		custom := propelauth.NewStepUp(client,
+			propelauth.WithStepUpUnauthorizedResponder(func(w http.ResponseWriter, r *http.Request, err error) {
+				w.WriteHeader(419)
+			}),
+			propelauth.WithStepUpRateLimitedResponder(func(w http.ResponseWriter, r *http.Request, err error) {
+				w.Header().Set("Retry-After", "60")
+				w.WriteHeader(429)
+			}))
+
+		recorder := httptest.NewRecorder()
+		custom.MethodsHandler().ServeHTTP(recorder, httptest.NewRequest("GET", "/step-up", nil))
+		if recorder.Code != 419 {
+			t.Errorf("expected the custom unauthorized response, got %d", recorder.Code)
+		}
+
+		recorder, _ = serve(custom.VerifyHandler(), `{"action_type": "delete_org", "code": "429429"}`, "")
+		if recorder.Code != 429 || recorder.Header().Get("Retry-After") != "60" {
+			t.Errorf("expected the custom rate limited response, got %d", recorder.Code)
+		}
+	})
+}
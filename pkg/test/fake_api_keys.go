@@ -0,0 +1,326 @@
+package test
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/propelauth/propelauth-go/pkg/models"
+)
+
+type fakeAPIKey struct {
+	metadata models.APIKeyFull
+	token    string
+	imported bool
+	archived bool
+	usage    map[string]int
+}
+
+// expired returns true if the key has an expiration that has passed.
+func (o *fakeAPIKey) expired() bool {
+	return o.metadata.ExpiresAtSeconds > 0 && int64(o.metadata.ExpiresAtSeconds) <= time.Now().Unix()
+}
+
+// apiKey returns the key, or an error that matches models.ErrNotFound.
+func (o *fakeState) apiKey(apiKeyID string) (*fakeAPIKey, error) {
+	apiKey, ok := o.apiKeys[apiKeyID]
+	if !ok {
+		return nil, fakeNotFound("API key not found")
+	}
+
+	return apiKey, nil
+}
+
+// addAPIKey checks that the owners exist, and stores the key with a new ID.
+func (o *fakeState) addAPIKey(token string, imported bool, orgID *uuid.UUID, userID *uuid.UUID, expiresAtSeconds *int, metadata *map[string]interface{}, displayName *string) (*fakeAPIKey, error) {
+	apiKey := &fakeAPIKey{
+		metadata: models.APIKeyFull{
+			APIKeyId:         randomHex(16),
+			CreatedAt:        int(time.Now().Unix()),
+			ExpiresAtSeconds: valueOr(expiresAtSeconds, 0),
+			Metadata:         valueOr(metadata, map[string]interface{}{}),
+			DisplayName:      displayName,
+		},
+		token:    token,
+		imported: imported,
+		usage:    map[string]int{},
+	}
+
+	if userID != nil {
+		user, err := o.user(*userID)
+		if err != nil {
+			return nil, err
+		}
+		if orgID != nil {
+			if _, ok := user.memberships[*orgID]; !ok {
+				return nil, fakeBadRequest("user_id", "User is not in this org")
+			}
+		}
+		apiKey.metadata.UserID = *userID
+	}
+	if orgID != nil {
+		if _, err := o.org(*orgID); err != nil {
+			return nil, err
+		}
+		apiKey.metadata.OrgID = *orgID
+	}
+
+	o.apiKeys[apiKey.metadata.APIKeyId] = apiKey
+	o.keyOrder = append(o.keyOrder, apiKey.metadata.APIKeyId)
+
+	return apiKey, nil
+}
+
+func (o *FakeClient) CreateAPIKey(params models.APIKeyCreateParams) (*models.APIKeyNew, error) {
+	if err := o.lock(); err != nil {
+		return nil, err
+	}
+	defer o.state.mu.Unlock()
+
+	apiKey, err := o.state.addAPIKey(randomHex(32), false, params.OrgID, params.UserID, params.ExpiresAtSeconds, params.Metadata, params.DisplayName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.APIKeyNew{APIKeyID: apiKey.metadata.APIKeyId, APIKeyToken: apiKey.token}, nil
+}
+
+func (o *FakeClient) ImportAPIKey(params models.APIKeyImportParams) (*models.APIKeyImportedNew, error) {
+	if err := o.lock(); err != nil {
+		return nil, err
+	}
+	defer o.state.mu.Unlock()
+
+	if params.ImportedAPIKey == "" {
+		return nil, fakeBadRequest("imported_api_key", "API key is required")
+	}
+	for _, existing := range o.state.apiKeys {
+		if existing.imported && existing.token == params.ImportedAPIKey {
+			return nil, fakeBadRequest("imported_api_key", "API key has already been imported")
+		}
+	}
+
+	apiKey, err := o.state.addAPIKey(params.ImportedAPIKey, true, params.OrgID, params.UserID, params.ExpiresAtSeconds, params.Metadata, params.DisplayName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.APIKeyImportedNew{APIKeyID: apiKey.metadata.APIKeyId}, nil
+}
+
+func (o *FakeClient) FetchAPIKey(apiKeyID string) (*models.APIKeyFull, error) {
+	if err := o.lock(); err != nil {
+		return nil, err
+	}
+	defer o.state.mu.Unlock()
+
+	apiKey, err := o.state.apiKey(apiKeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := apiKey.metadata
+
+	return &metadata, nil
+}
+
+func (o *FakeClient) UpdateAPIKey(apiKeyID string, params models.APIKeyUpdateParams) (bool, error) {
+	if err := o.lock(); err != nil {
+		return false, err
+	}
+	defer o.state.mu.Unlock()
+
+	apiKey, err := o.state.apiKey(apiKeyID)
+	if err != nil || apiKey.archived {
+		return false, fakeNotFound("API key not found")
+	}
+
+	if params.ExpiresAtSeconds != nil {
+		apiKey.metadata.ExpiresAtSeconds = *params.ExpiresAtSeconds
+	}
+	if valueOr(params.SetToNeverExpire, false) {
+		apiKey.metadata.ExpiresAtSeconds = 0
+	}
+	if params.Metadata != nil {
+		apiKey.metadata.Metadata = *params.Metadata
+	}
+
+	return true, nil
+}
+
+func (o *FakeClient) DeleteAPIKey(apiKeyID string) (bool, error) {
+	if err := o.lock(); err != nil {
+		return false, err
+	}
+	defer o.state.mu.Unlock()
+
+	apiKey, err := o.state.apiKey(apiKeyID)
+	if err != nil || apiKey.archived {
+		return false, fakeNotFound("API key not found")
+	}
+
+	apiKey.archived = true
+
+	return true, nil
+}
+
+func (o *FakeClient) FetchCurrentAPIKeys(params models.APIKeysQueryParams) (*models.APIKeyResultPage, error) {
+	return o.fetchAPIKeys(params, false)
+}
+
+func (o *FakeClient) FetchArchivedAPIKeys(params models.APIKeysQueryParams) (*models.APIKeyResultPage, error) {
+	return o.fetchAPIKeys(params, true)
+}
+
+// fetchAPIKeys lists the keys matching the query. Archived keys are the ones that were deleted or have expired.
+func (o *FakeClient) fetchAPIKeys(params models.APIKeysQueryParams, archived bool) (*models.APIKeyResultPage, error) {
+	if err := o.lock(); err != nil {
+		return nil, err
+	}
+	defer o.state.mu.Unlock()
+
+	apiKeys := []models.APIKeyFull{}
+	for _, apiKeyID := range o.state.keyOrder {
+		apiKey := o.state.apiKeys[apiKeyID]
+		if (apiKey.archived || apiKey.expired()) != archived {
+			continue
+		}
+		if params.OrgID != nil && apiKey.metadata.OrgID != *params.OrgID {
+			continue
+		}
+		if params.UserID != nil && apiKey.metadata.UserID != *params.UserID {
+			continue
+		}
+		if params.UserEmail != nil {
+			user, ok := o.state.users[apiKey.metadata.UserID]
+			if !ok || !strings.EqualFold(user.metadata.Email, *params.UserEmail) {
+				continue
+			}
+		}
+		apiKeys = append(apiKeys, apiKey.metadata)
+	}
+
+	page, total, hasMoreResults := paginate(apiKeys, params.PageSize, params.PageNumber)
+
+	return &models.APIKeyResultPage{
+		APIKeys:        page,
+		TotalAPIKeys:   total,
+		CurrentPage:    valueOr(params.PageNumber, 0),
+		PageSize:       valueOr(params.PageSize, fakeDefaultPageSize),
+		HasMoreResults: hasMoreResults,
+	}, nil
+}
+
+func (o *FakeClient) ValidateAPIKey(apiKeyToken string) (*models.APIKeyValidation, error) {
+	return o.validateAPIKey(apiKeyToken, false)
+}
+
+func (o *FakeClient) ValidateImportedAPIKey(apiKeyToken string) (*models.APIKeyValidation, error) {
+	return o.validateAPIKey(apiKeyToken, true)
+}
+
+func (o *FakeClient) ValidatePersonalAPIKey(apiKeyToken string) (*models.PersonalAPIKeyValidation, error) {
+	apiKeyValidate, err := o.ValidateAPIKey(apiKeyToken)
+	if err != nil {
+		return nil, err
+	}
+	if apiKeyValidate.Org != nil || apiKeyValidate.User == nil {
+		return nil, fakeBadRequest("api_key_token", "not a personal API Key")
+	}
+
+	return &models.PersonalAPIKeyValidation{
+		User:     *apiKeyValidate.User,
+		Metadata: apiKeyValidate.Metadata,
+	}, nil
+}
+
+func (o *FakeClient) ValidateOrgAPIKey(apiKeyToken string) (*models.OrgAPIKeyValidation, error) {
+	apiKeyValidate, err := o.ValidateAPIKey(apiKeyToken)
+	if err != nil {
+		return nil, err
+	}
+	if apiKeyValidate.Org == nil {
+		return nil, fakeBadRequest("api_key_token", "not an org API Key")
+	}
+
+	return &models.OrgAPIKeyValidation{
+		Org:       *apiKeyValidate.Org,
+		Metadata:  apiKeyValidate.Metadata,
+		User:      apiKeyValidate.User,
+		UserInOrg: apiKeyValidate.UserInOrg,
+	}, nil
+}
+
+// validateAPIKey finds the key for the token and records its usage. Keys that were deleted or have expired, and
+// keys whose user was disabled, are invalid.
+func (o *FakeClient) validateAPIKey(apiKeyToken string, imported bool) (*models.APIKeyValidation, error) {
+	if err := o.lock(); err != nil {
+		return nil, err
+	}
+	defer o.state.mu.Unlock()
+
+	var apiKey *fakeAPIKey
+	for _, existing := range o.state.apiKeys {
+		if existing.imported == imported && existing.token == apiKeyToken {
+			apiKey = existing
+			break
+		}
+	}
+	if apiKey == nil || apiKey.archived || apiKey.expired() {
+		return nil, fakeBadRequest("api_key_token", "Invalid API Key")
+	}
+
+	validation := &models.APIKeyValidation{Metadata: apiKey.metadata.Metadata}
+
+	if user, ok := o.state.users[apiKey.metadata.UserID]; ok {
+		if !user.metadata.Enabled {
+			return nil, fakeBadRequest("api_key_token", "Invalid API Key")
+		}
+		metadata := o.state.userMetadata(user, false)
+		validation.User = &metadata
+
+		if _, ok := user.memberships[apiKey.metadata.OrgID]; ok {
+			validation.UserInOrg = o.state.orgMemberInfo(user, apiKey.metadata.OrgID)
+		}
+	}
+
+	if org, ok := o.state.orgs[apiKey.metadata.OrgID]; ok {
+		validation.Org = &models.APIKeyOrgMetadata{
+			OrgID:        org.metadata.OrgID,
+			OrgName:      org.metadata.Name,
+			CanSetupSaml: org.metadata.CanSetupSaml,
+			Metadata:     org.metadata.Metadata,
+		}
+	}
+
+	apiKey.usage[time.Now().UTC().Format("2006-01-02")]++
+
+	return validation, nil
+}
+
+func (o *FakeClient) FetchAPIKeyUsage(params models.FetchAPIKeyUsageParams) (*models.APIKeyUsage, error) {
+	if err := o.lock(); err != nil {
+		return nil, err
+	}
+	defer o.state.mu.Unlock()
+
+	if _, err := time.Parse("2006-01-02", params.Date); err != nil {
+		return nil, fakeBadRequest("date", "Date must be in the format YYYY-MM-DD")
+	}
+
+	count := 0
+	for _, apiKey := range o.state.apiKeys {
+		if params.OrgID != nil && apiKey.metadata.OrgID != *params.OrgID {
+			continue
+		}
+		if params.UserID != nil && apiKey.metadata.UserID != *params.UserID {
+			continue
+		}
+		if params.APIKeyID != nil && apiKey.metadata.APIKeyId != *params.APIKeyID {
+			continue
+		}
+		count += apiKey.usage[params.Date]
+	}
+
+	return &models.APIKeyUsage{Count: count}, nil
+}
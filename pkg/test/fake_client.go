@@ -0,0 +1,998 @@
+package test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	propelauth "github.com/propelauth/propelauth-go/pkg"
+	"github.com/propelauth/propelauth-go/pkg/helpers"
+	"github.com/propelauth/propelauth-go/pkg/models"
+)
+
+const fakeDefaultPageSize = 10
+const fakeMaxBatchSize = 100
+
+// FakeClient is an in-memory stand-in for the PropelAuth backend that implements propelauth.ClientInterface, so
+// code that depends on the interface can be tested offline. It keeps users, orgs, memberships, invites, API keys,
+// magic links and step-up MFA, and behaves like the real service: emails and usernames are unique, missing users
+// and orgs return errors that match models.ErrNotFound, lists are paged, and API keys and step-up grants can be
+// validated. Access tokens from CreateAccessToken are signed with the fake's own key and can be checked with GetUser.
+//
+// Anything PropelAuth would do outside of the API, like accepting an invite or logging in, can be simulated with
+// the extra methods, like AcceptInvite.
+type FakeClient struct {
+	ctx   context.Context
+	state *fakeState
+}
+
+// FakeClientOption configures the FakeClient returned by NewFakeClient.
+type FakeClientOption func(*fakeState)
+
+// FakeMagicLink is a magic link created by CreateMagicLink.
+type FakeMagicLink struct {
+	Email       string
+	URL         string
+	RedirectURL *string
+	ExpiresAt   time.Time
+}
+
+type fakeState struct {
+	mu sync.Mutex
+
+	authURL         string
+	privateKey      *rsa.PrivateKey
+	roles           []string
+	rolePermissions map[string][]string
+	roleMappings    []string
+
+	users      map[uuid.UUID]*fakeUser
+	userOrder  []uuid.UUID
+	orgs       map[uuid.UUID]*fakeOrg
+	orgOrder   []uuid.UUID
+	invites    []models.PendingInvite
+	apiKeys    map[string]*fakeAPIKey
+	keyOrder   []string
+	magicLinks []FakeMagicLink
+
+	smsChallenges []fakeSmsChallenge
+	stepUpGrants  map[string]*fakeStepUpGrant
+}
+
+type fakeUser struct {
+	metadata    models.UserMetadata
+	memberships map[uuid.UUID]*fakeMembership
+	totpCode    string
+	mfaPhones   []models.MfaPhones
+}
+
+type fakeMembership struct {
+	role            string
+	additionalRoles []string
+}
+
+// check that the fake has every method of the real client
+var _ propelauth.ClientInterface = (*FakeClient)(nil)
+
+// NewFakeClient creates an empty fake. By default, orgs use the roles Owner, Admin and Member, from highest to lowest.
+func NewFakeClient(opts ...FakeClientOption) *FakeClient {
+	privateKey, _ := GenerateRSAKeys()
+
+	state := &fakeState{
+		authURL:         "https://auth.example.com",
+		privateKey:      privateKey,
+		roles:           []string{"Owner", "Admin", "Member"},
+		rolePermissions: map[string][]string{},
+		users:           map[uuid.UUID]*fakeUser{},
+		orgs:            map[uuid.UUID]*fakeOrg{},
+		apiKeys:         map[string]*fakeAPIKey{},
+		stepUpGrants:    map[string]*fakeStepUpGrant{},
+	}
+
+	for _, opt := range opts {
+		opt(state)
+	}
+
+	return &FakeClient{ctx: context.Background(), state: state}
+}
+
+// WithFakeAuthURL sets the auth URL used as the issuer of access tokens, and in magic links. It defaults to
+// https://auth.example.com.
+func WithFakeAuthURL(authURL string) FakeClientOption {
+	return func(o *fakeState) {
+		o.authURL = authURL
+	}
+}
+
+// WithFakeRoles replaces the roles users can have in an org, ordered from highest to lowest.
+func WithFakeRoles(roles ...string) FakeClientOption {
+	return func(o *fakeState) {
+		o.roles = roles
+	}
+}
+
+// WithFakeRolePermissions sets the permissions a role has, which show up in access tokens and API key validations.
+func WithFakeRolePermissions(role string, permissions ...string) FakeClientOption {
+	return func(o *fakeState) {
+		o.rolePermissions[role] = permissions
+	}
+}
+
+// WithFakeCustomRoleMapping adds a custom role mapping that orgs can subscribe to.
+func WithFakeCustomRoleMapping(name string) FakeClientOption {
+	return func(o *fakeState) {
+		o.roleMappings = append(o.roleMappings, name)
+	}
+}
+
+// WithContext returns a copy of the fake that shares its data, and returns the context's error from every call once
+// the context is done.
+func (o *FakeClient) WithContext(ctx context.Context) propelauth.ClientInterface {
+	if ctx == nil {
+		panic("nil context")
+	}
+
+	return &FakeClient{ctx: ctx, state: o.state}
+}
+
+// TokenVerificationMetadata returns the key and issuer that access tokens from the fake are signed with, to
+// initialize a real client that validates them.
+func (o *FakeClient) TokenVerificationMetadata() models.TokenVerificationMetadata {
+	return models.TokenVerificationMetadata{
+		VerifierKey: o.state.privateKey.PublicKey,
+		Issuer:      o.state.authURL,
+	}
+}
+
+// PrivateKey returns the key that access tokens from the fake are signed with.
+func (o *FakeClient) PrivateKey() *rsa.PrivateKey {
+	return o.state.privateKey
+}
+
+// MagicLinks returns the magic links created for the email, oldest first.
+func (o *FakeClient) MagicLinks(email string) []FakeMagicLink {
+	o.state.mu.Lock()
+	defer o.state.mu.Unlock()
+
+	magicLinks := []FakeMagicLink{}
+	for _, magicLink := range o.state.magicLinks {
+		if strings.EqualFold(magicLink.Email, email) {
+			magicLinks = append(magicLinks, magicLink)
+		}
+	}
+
+	return magicLinks
+}
+
+// lock waits for exclusive access to the fake's data, unless the context is already done. If it returns nil,
+// the caller must unlock o.state.mu.
+func (o *FakeClient) lock() error {
+	if err := o.ctx.Err(); err != nil {
+		return err
+	}
+
+	o.state.mu.Lock()
+
+	return nil
+}
+
+// Errors, shaped like the ones the real backend returns
+
+func fakeNotFound(message string) error {
+	return models.NewAPIError(404, nil, message, nil)
+}
+
+func fakeBadRequest(field string, message string) error {
+	body, _ := json.Marshal(map[string]interface{}{
+		"field_to_errors": map[string][]string{field: {message}},
+	})
+
+	return models.NewAPIError(400, body, fmt.Sprintf("Bad request: %s", body), nil)
+}
+
+// Access tokens
+
+func (o *FakeClient) CreateAccessToken(userID uuid.UUID, durationInMinutes int, createAccessTokenOptions ...models.CreateAccessTokenOptions) (*models.AccessToken, error) {
+	if err := o.lock(); err != nil {
+		return nil, err
+	}
+	defer o.state.mu.Unlock()
+
+	user, ok := o.state.users[userID]
+	if !ok || !user.metadata.Enabled {
+		return nil, models.NewAPIError(403, nil, "User not found", models.ErrNotFound)
+	}
+
+	claims := o.state.userFromToken(user)
+	if len(createAccessTokenOptions) == 1 && createAccessTokenOptions[0].ActiveOrgId != nil {
+		activeOrgID := *createAccessTokenOptions[0].ActiveOrgId
+		orgMemberInfo, ok := claims.OrgIDToOrgMemberInfo[activeOrgID.String()]
+		if !ok {
+			return nil, fakeBadRequest("active_org_id", "User is not a member of this org")
+		}
+		claims.OrgIDToOrgMemberInfo = nil
+		claims.OrgMemberInfo = orgMemberInfo
+	}
+
+	now := time.Now()
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ExpiresAt: jwt.NewNumericDate(now.Add(time.Duration(durationInMinutes) * time.Minute)),
+		IssuedAt:  jwt.NewNumericDate(now),
+		Issuer:    o.state.authURL,
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(o.state.privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("Error on creating access token: %w", err)
+	}
+
+	return &models.AccessToken{AccessToken: token}, nil
+}
+
+func (o *FakeClient) GetUser(authHeader string) (*models.UserFromToken, error) {
+	validationHelper := &helpers.ValidationHelper{}
+
+	accessToken, err := validationHelper.ExtractTokenFromAuthorizationHeader(authHeader)
+	if err != nil {
+		return nil, fmt.Errorf("Error on extracting token from authorization header: %w", err)
+	}
+
+	user, err := validationHelper.ValidateAccessTokenAndGetUser(accessToken, o.TokenVerificationMetadata())
+	if err != nil {
+		return nil, fmt.Errorf("Error on validating access token and getting user: %w", err)
+	}
+
+	return user, nil
+}
+
+func (o *FakeClient) TokenVerificationStatus() models.TokenVerificationStatus {
+	return models.TokenVerificationStatus{KeyCount: 1}
+}
+
+// userFromToken builds the claims PropelAuth puts in a user's access token.
+func (o *fakeState) userFromToken(user *fakeUser) models.UserFromToken {
+	email := user.metadata.Email
+	claims := models.UserFromToken{
+		UserID:               user.metadata.UserID,
+		LegacyUserID:         user.metadata.LegacyUserID,
+		OrgIDToOrgMemberInfo: map[string]*models.OrgMemberInfoFromToken{},
+		Email:                &email,
+		FirstName:            user.metadata.FirstName,
+		LastName:             user.metadata.LastName,
+		Username:             user.metadata.Username,
+		LoginMethod:          &models.LoginMethod{LoginMethod: "password"},
+	}
+	if user.metadata.Metadata != nil {
+		claims.Metadata = *user.metadata.Metadata
+	}
+	if user.metadata.Properties != nil {
+		claims.Properties = *user.metadata.Properties
+	}
+
+	for orgID := range user.memberships {
+		claims.OrgIDToOrgMemberInfo[orgID.String()] = o.orgMemberInfo(user, orgID)
+	}
+
+	return claims
+}
+
+// orgMemberInfo describes the user's membership in the org, like it appears in an access token.
+func (o *fakeState) orgMemberInfo(user *fakeUser, orgID uuid.UUID) *models.OrgMemberInfoFromToken {
+	org := o.orgs[orgID]
+	membership := user.memberships[orgID]
+
+	return &models.OrgMemberInfoFromToken{
+		OrgID:                             orgID,
+		OrgName:                           org.metadata.Name,
+		OrgMetadata:                       org.metadata.Metadata,
+		URLSafeOrgName:                    org.metadata.UrlSafeOrgSlug,
+		OrgRoleStructure:                  models.SingleRoleInHierarchy,
+		UserAssignedRole:                  membership.role,
+		UserInheritedRolesPlusCurrentRole: o.inheritedRoles(membership.role),
+		UserPermissions:                   o.permissions(membership),
+		UserAssignedAdditionalRoles:       membership.additionalRoles,
+	}
+}
+
+// inheritedRoles returns the role and every role below it.
+func (o *fakeState) inheritedRoles(role string) []string {
+	for i, r := range o.roles {
+		if r == role {
+			return append([]string{}, o.roles[i:]...)
+		}
+	}
+
+	return []string{role}
+}
+
+func (o *fakeState) permissions(membership *fakeMembership) []string {
+	seen := map[string]bool{}
+	permissions := []string{}
+	for _, role := range append([]string{membership.role}, membership.additionalRoles...) {
+		for _, permission := range o.rolePermissions[role] {
+			if !seen[permission] {
+				seen[permission] = true
+				permissions = append(permissions, permission)
+			}
+		}
+	}
+
+	return permissions
+}
+
+func (o *fakeState) validateRole(role string) error {
+	for _, r := range o.roles {
+		if r == role {
+			return nil
+		}
+	}
+
+	return fakeBadRequest("role", fmt.Sprintf("Role %s does not exist", role))
+}
+
+// Users
+
+func (o *FakeClient) CreateUser(params models.CreateUserParams) (*models.UserID, error) {
+	if err := o.lock(); err != nil {
+		return nil, err
+	}
+	defer o.state.mu.Unlock()
+
+	metadata := models.UserMetadata{
+		Email:          params.Email,
+		EmailConfirmed: valueOr(params.EmailConfirmed, false),
+		HasPassword:    params.Password != nil,
+		Username:       params.Username,
+		FirstName:      params.FirstName,
+		LastName:       params.LastName,
+		Enabled:        true,
+		CanCreateOrgs:  valueOr(params.CanCreateOrgs, false),
+		Metadata:       params.Metadata,
+		Properties:     params.Properties,
+	}
+
+	user, err := o.state.addUser(metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.UserID{UserID: user.metadata.UserID}, nil
+}
+
+func (o *FakeClient) MigrateUserFromExternalSource(params models.MigrateUserParams) (bool, error) {
+	if err := o.lock(); err != nil {
+		return false, err
+	}
+	defer o.state.mu.Unlock()
+
+	metadata := models.UserMetadata{
+		Email:          params.Email,
+		EmailConfirmed: valueOr(params.EmailConfirmed, false),
+		HasPassword:    params.ExistingPasswordHash != nil,
+		Username:       params.Username,
+		FirstName:      params.FirstName,
+		LastName:       params.LastName,
+		PictureURL:     params.PictureUrl,
+		Enabled:        valueOr(params.Enabled, true),
+		MfaEnabled:     params.ExistingMfaBase32EncodedSecret != nil,
+		LegacyUserID:   params.ExistingUserID,
+		Properties:     params.Properties,
+	}
+
+	if _, err := o.state.addUser(metadata); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// addUser checks that the email and username are free, and stores the user with a new ID.
+func (o *fakeState) addUser(metadata models.UserMetadata) (*fakeUser, error) {
+	if strings.TrimSpace(metadata.Email) == "" || !strings.Contains(metadata.Email, "@") {
+		return nil, fakeBadRequest("email", "Invalid email address")
+	}
+	if o.userByEmail(metadata.Email) != nil {
+		return nil, fakeBadRequest("email", "Email already in use")
+	}
+	if metadata.Username != nil && o.userByUsername(*metadata.Username) != nil {
+		return nil, fakeBadRequest("username", "Username already in use")
+	}
+
+	metadata.UserID = uuid.New()
+	metadata.CreatedAt = time.Now().Unix()
+	metadata.LastActiveAt = metadata.CreatedAt
+
+	user := &fakeUser{metadata: metadata, memberships: map[uuid.UUID]*fakeMembership{}}
+	o.users[metadata.UserID] = user
+	o.userOrder = append(o.userOrder, metadata.UserID)
+
+	return user, nil
+}
+
+func (o *fakeState) userByEmail(email string) *fakeUser {
+	for _, userID := range o.userOrder {
+		if strings.EqualFold(o.users[userID].metadata.Email, email) {
+			return o.users[userID]
+		}
+	}
+
+	return nil
+}
+
+func (o *fakeState) userByUsername(username string) *fakeUser {
+	for _, userID := range o.userOrder {
+		existing := o.users[userID].metadata.Username
+		if existing != nil && strings.EqualFold(*existing, username) {
+			return o.users[userID]
+		}
+	}
+
+	return nil
+}
+
+// user returns the user, or an error that matches models.ErrNotFound.
+func (o *fakeState) user(userID uuid.UUID) (*fakeUser, error) {
+	user, ok := o.users[userID]
+	if !ok {
+		return nil, fakeNotFound("User not found")
+	}
+
+	return user, nil
+}
+
+// userMetadata returns a copy of the user's metadata, with their orgs if includeOrgs is true.
+func (o *fakeState) userMetadata(user *fakeUser, includeOrgs bool) models.UserMetadata {
+	metadata := user.metadata
+	metadata.OrgIDToOrgInfo = nil
+
+	if includeOrgs {
+		orgIDToOrgInfo := map[uuid.UUID]models.OrgInfo{}
+		for orgID, membership := range user.memberships {
+			orgIDToOrgInfo[orgID] = models.OrgInfo{
+				OrgID:            orgID,
+				OrgName:          o.orgs[orgID].metadata.Name,
+				OrgRoleStructure: models.SingleRoleInHierarchy,
+				UserRole:         membership.role,
+				AdditionalRoles:  membership.additionalRoles,
+				UserPermissions:  o.permissions(membership),
+			}
+		}
+		metadata.OrgIDToOrgInfo = &orgIDToOrgInfo
+	}
+
+	return metadata
+}
+
+func (o *FakeClient) FetchUserMetadataByUserID(userID uuid.UUID, includeOrgs bool) (*models.UserMetadata, error) {
+	if err := o.lock(); err != nil {
+		return nil, err
+	}
+	defer o.state.mu.Unlock()
+
+	user, err := o.state.user(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := o.state.userMetadata(user, includeOrgs)
+
+	return &metadata, nil
+}
+
+func (o *FakeClient) FetchUserMetadataByEmail(email string, includeOrgs bool) (*models.UserMetadata, error) {
+	if err := o.lock(); err != nil {
+		return nil, err
+	}
+	defer o.state.mu.Unlock()
+
+	user := o.state.userByEmail(email)
+	if user == nil {
+		return nil, fakeNotFound("User not found")
+	}
+
+	metadata := o.state.userMetadata(user, includeOrgs)
+
+	return &metadata, nil
+}
+
+func (o *FakeClient) FetchUserMetadataByUsername(username string, includeOrgs bool) (*models.UserMetadata, error) {
+	if err := o.lock(); err != nil {
+		return nil, err
+	}
+	defer o.state.mu.Unlock()
+
+	user := o.state.userByUsername(username)
+	if user == nil {
+		return nil, fakeNotFound("User not found")
+	}
+
+	metadata := o.state.userMetadata(user, includeOrgs)
+
+	return &metadata, nil
+}
+
+func (o *FakeClient) FetchBatchUserMetadataByUserIds(userIds []uuid.UUID, includeOrgs bool) (map[uuid.UUID]models.UserMetadata, error) {
+	if err := o.lock(); err != nil {
+		return nil, err
+	}
+	defer o.state.mu.Unlock()
+
+	if len(userIds) > fakeMaxBatchSize {
+		return nil, fakeBadRequest("user_ids", fmt.Sprintf("Cannot fetch more than %d users at once", fakeMaxBatchSize))
+	}
+
+	users := map[uuid.UUID]models.UserMetadata{}
+	for _, userID := range userIds {
+		if user, ok := o.state.users[userID]; ok {
+			users[userID] = o.state.userMetadata(user, includeOrgs)
+		}
+	}
+
+	return users, nil
+}
+
+func (o *FakeClient) FetchBatchUserMetadataByEmails(emails []string, includeOrgs bool) (map[string]models.UserMetadata, error) {
+	if err := o.lock(); err != nil {
+		return nil, err
+	}
+	defer o.state.mu.Unlock()
+
+	if len(emails) > fakeMaxBatchSize {
+		return nil, fakeBadRequest("emails", fmt.Sprintf("Cannot fetch more than %d users at once", fakeMaxBatchSize))
+	}
+
+	users := map[string]models.UserMetadata{}
+	for _, email := range emails {
+		if user := o.state.userByEmail(email); user != nil {
+			users[email] = o.state.userMetadata(user, includeOrgs)
+		}
+	}
+
+	return users, nil
+}
+
+func (o *FakeClient) FetchBatchUserMetadataByUsernames(usernames []string, includeOrgs bool) (map[string]models.UserMetadata, error) {
+	if err := o.lock(); err != nil {
+		return nil, err
+	}
+	defer o.state.mu.Unlock()
+
+	if len(usernames) > fakeMaxBatchSize {
+		return nil, fakeBadRequest("usernames", fmt.Sprintf("Cannot fetch more than %d users at once", fakeMaxBatchSize))
+	}
+
+	users := map[string]models.UserMetadata{}
+	for _, username := range usernames {
+		if user := o.state.userByUsername(username); user != nil {
+			users[username] = o.state.userMetadata(user, includeOrgs)
+		}
+	}
+
+	return users, nil
+}
+
+func (o *FakeClient) FetchUsersByQuery(params models.UserQueryParams) (*models.UserList, error) {
+	if err := o.lock(); err != nil {
+		return nil, err
+	}
+	defer o.state.mu.Unlock()
+
+	users := []models.UserMetadata{}
+	for _, userID := range o.state.userOrder {
+		user := o.state.users[userID]
+		if params.EmailOrUsername != nil && !userMatches(user, *params.EmailOrUsername) {
+			continue
+		}
+		if params.LegacyUserID != nil && (user.metadata.LegacyUserID == nil || *user.metadata.LegacyUserID != *params.LegacyUserID) {
+			continue
+		}
+		users = append(users, o.state.userMetadata(user, valueOr(params.IncludeOrgs, false)))
+	}
+
+	sortUsers(users, valueOr(params.OrderBy, "CREATED_AT_ASC"))
+
+	page, total, hasMoreResults := paginate(users, params.PageSize, params.PageNumber)
+
+	return &models.UserList{
+		TotalUsers:     total,
+		CurrentPage:    valueOr(params.PageNumber, 0),
+		PageSize:       valueOr(params.PageSize, fakeDefaultPageSize),
+		HasMoreResults: hasMoreResults,
+		Users:          page,
+	}, nil
+}
+
+func userMatches(user *fakeUser, emailOrUsername string) bool {
+	search := strings.ToLower(emailOrUsername)
+	if strings.Contains(strings.ToLower(user.metadata.Email), search) {
+		return true
+	}
+
+	return user.metadata.Username != nil && strings.Contains(strings.ToLower(*user.metadata.Username), search)
+}
+
+func sortUsers(users []models.UserMetadata, orderBy string) {
+	switch orderBy {
+	case "CREATED_AT_DESC":
+		// users are already in the order they were created
+		for i, j := 0, len(users)-1; i < j; i, j = i+1, j-1 {
+			users[i], users[j] = users[j], users[i]
+		}
+	case "EMAIL":
+		sort.SliceStable(users, func(i, j int) bool { return users[i].Email < users[j].Email })
+	case "USERNAME":
+		sort.SliceStable(users, func(i, j int) bool {
+			return valueOr(users[i].Username, "") < valueOr(users[j].Username, "")
+		})
+	}
+}
+
+func (o *FakeClient) UpdateUserEmail(userID uuid.UUID, params models.UpdateEmail) (bool, error) {
+	if err := o.lock(); err != nil {
+		return false, err
+	}
+	defer o.state.mu.Unlock()
+
+	user, err := o.state.user(userID)
+	if err != nil {
+		return false, err
+	}
+
+	if existing := o.state.userByEmail(params.Email); existing != nil && existing != user {
+		return false, fakeBadRequest("new_email", "Email already in use")
+	}
+
+	user.metadata.Email = params.Email
+	user.metadata.EmailConfirmed = !params.RequireEmailConfirmation
+
+	return true, nil
+}
+
+func (o *FakeClient) UpdateUserMetadata(userID uuid.UUID, params models.UpdateUserMetadata) (bool, error) {
+	if err := o.lock(); err != nil {
+		return false, err
+	}
+	defer o.state.mu.Unlock()
+
+	user, err := o.state.user(userID)
+	if err != nil {
+		return false, err
+	}
+
+	if params.Username != nil {
+		if existing := o.state.userByUsername(*params.Username); existing != nil && existing != user {
+			return false, fakeBadRequest("username", "Username already in use")
+		}
+		user.metadata.Username = params.Username
+	}
+	if params.FirstName != nil {
+		user.metadata.FirstName = params.FirstName
+	}
+	if params.LastName != nil {
+		user.metadata.LastName = params.LastName
+	}
+	if params.PictureURL != nil {
+		user.metadata.PictureURL = params.PictureURL
+	}
+	if params.CanCreateOrgs != nil {
+		user.metadata.CanCreateOrgs = *params.CanCreateOrgs
+	}
+	if params.Metadata != nil {
+		user.metadata.Metadata = params.Metadata
+	}
+	if params.Properties != nil {
+		// properties are merged, rather than replaced
+		properties := map[string]interface{}{}
+		if user.metadata.Properties != nil {
+			for key, value := range *user.metadata.Properties {
+				properties[key] = value
+			}
+		}
+		for key, value := range *params.Properties {
+			properties[key] = value
+		}
+		user.metadata.Properties = &properties
+	}
+	if params.LegacyUserID != nil {
+		user.metadata.LegacyUserID = params.LegacyUserID
+	}
+
+	return true, nil
+}
+
+// updateUser runs update on the user, or returns an error that matches models.ErrNotFound.
+func (o *FakeClient) updateUser(userID uuid.UUID, update func(user *fakeUser)) (bool, error) {
+	if err := o.lock(); err != nil {
+		return false, err
+	}
+	defer o.state.mu.Unlock()
+
+	user, err := o.state.user(userID)
+	if err != nil {
+		return false, err
+	}
+
+	update(user)
+
+	return true, nil
+}
+
+func (o *FakeClient) UpdateUserPassword(userID uuid.UUID, params models.UpdateUserPasswordParam) (bool, error) {
+	if params.Password == "" {
+		return false, fakeBadRequest("password", "Password is required")
+	}
+
+	return o.updateUser(userID, func(user *fakeUser) {
+		user.metadata.HasPassword = true
+	})
+}
+
+func (o *FakeClient) MigrateUserPassword(params models.MigrateUserPasswordParams) (bool, error) {
+	if params.PasswordHash == "" {
+		return false, fakeBadRequest("password_hash", "Password hash is required")
+	}
+
+	return o.updateUser(params.UserID, func(user *fakeUser) {
+		user.metadata.HasPassword = true
+	})
+}
+
+func (o *FakeClient) ClearUserPassword(userID uuid.UUID) (bool, error) {
+	return o.updateUser(userID, func(user *fakeUser) {
+		user.metadata.HasPassword = false
+	})
+}
+
+func (o *FakeClient) DisableUser(userID uuid.UUID) (bool, error) {
+	return o.updateUser(userID, func(user *fakeUser) {
+		user.metadata.Enabled = false
+	})
+}
+
+func (o *FakeClient) EnableUser(userID uuid.UUID) (bool, error) {
+	return o.updateUser(userID, func(user *fakeUser) {
+		user.metadata.Enabled = true
+	})
+}
+
+func (o *FakeClient) EnableUserCanCreateOrgs(userID uuid.UUID) (bool, error) {
+	return o.updateUser(userID, func(user *fakeUser) {
+		user.metadata.CanCreateOrgs = true
+	})
+}
+
+func (o *FakeClient) DisableUserCanCreateOrgs(userID uuid.UUID) (bool, error) {
+	return o.updateUser(userID, func(user *fakeUser) {
+		user.metadata.CanCreateOrgs = false
+	})
+}
+
+func (o *FakeClient) DisableUser2fa(userID uuid.UUID) (bool, error) {
+	return o.updateUser(userID, func(user *fakeUser) {
+		user.metadata.MfaEnabled = false
+		user.totpCode = ""
+		user.mfaPhones = nil
+	})
+}
+
+func (o *FakeClient) ResendEmailConfirmation(userID uuid.UUID) (bool, error) {
+	return o.updateUser(userID, func(user *fakeUser) {})
+}
+
+func (o *FakeClient) LogoutAllUserSessions(userID uuid.UUID) (bool, error) {
+	return o.updateUser(userID, func(user *fakeUser) {})
+}
+
+func (o *FakeClient) DeleteUser(userID uuid.UUID) (bool, error) {
+	if err := o.lock(); err != nil {
+		return false, err
+	}
+	defer o.state.mu.Unlock()
+
+	if _, err := o.state.user(userID); err != nil {
+		return false, err
+	}
+
+	delete(o.state.users, userID)
+	o.state.userOrder = removeID(o.state.userOrder, userID)
+
+	// the user's personal API keys go with them
+	for _, apiKey := range o.state.apiKeys {
+		if apiKey.metadata.UserID == userID && apiKey.metadata.OrgID == uuid.Nil {
+			apiKey.archived = true
+		}
+	}
+
+	return true, nil
+}
+
+func (o *FakeClient) FetchUserSignupQueryParameters(userID uuid.UUID) (*models.UserSignupQueryParamsResponse, error) {
+	if err := o.lock(); err != nil {
+		return nil, err
+	}
+	defer o.state.mu.Unlock()
+
+	if _, err := o.state.user(userID); err != nil {
+		return nil, err
+	}
+
+	return &models.UserSignupQueryParamsResponse{UserSignupQueryParameters: map[string]interface{}{}}, nil
+}
+
+func (o *FakeClient) FetchEmployeeByID(employeeID uuid.UUID) (*models.FetchEmployeeByIDResponse, error) {
+	return nil, fakeNotFound("Employee not found")
+}
+
+func (o *FakeClient) FetchUserOAuthTokens(userID uuid.UUID) (*models.SocialLoginTokensResponse, error) {
+	if err := o.lock(); err != nil {
+		return nil, err
+	}
+	defer o.state.mu.Unlock()
+
+	if _, err := o.state.user(userID); err != nil {
+		return nil, err
+	}
+
+	return &models.SocialLoginTokensResponse{}, nil
+}
+
+func (o *FakeClient) FetchFreshTokenFromProvider(userID uuid.UUID, provider models.SocialLoginTokenProvider) (*models.SocialLoginToken, error) {
+	if err := o.lock(); err != nil {
+		return nil, err
+	}
+	defer o.state.mu.Unlock()
+
+	if _, err := o.state.user(userID); err != nil {
+		return nil, err
+	}
+
+	return nil, fakeNotFound(fmt.Sprintf("User has not logged in with %s", provider))
+}
+
+// Magic links
+
+func (o *FakeClient) CreateMagicLink(params models.CreateMagicLinkParams) (*models.CreateMagicLinkResponse, error) {
+	if err := o.lock(); err != nil {
+		return nil, err
+	}
+	defer o.state.mu.Unlock()
+
+	if o.state.userByEmail(params.Email) == nil {
+		if !valueOr(params.CreateNewUserIfOneDoesntExist, false) {
+			return nil, fakeNotFound("User not found")
+		}
+		if _, err := o.state.addUser(models.UserMetadata{Email: params.Email, Enabled: true}); err != nil {
+			return nil, err
+		}
+	}
+
+	magicLink := FakeMagicLink{
+		Email:       params.Email,
+		URL:         fmt.Sprintf("%s/magic_link?token=%s", o.state.authURL, randomHex(24)),
+		RedirectURL: params.RedirectURL,
+		ExpiresAt:   time.Now().Add(time.Duration(valueOr(params.ExpiresInHours, 24)) * time.Hour),
+	}
+	o.state.magicLinks = append(o.state.magicLinks, magicLink)
+
+	return &models.CreateMagicLinkResponse{URL: magicLink.URL}, nil
+}
+
+// User insights, which are always empty in the fake
+
+func (o *FakeClient) FetchUserTopInviterReport(reportInterval *string, pagination *models.ReportPagination) (*models.UserReport, error) {
+	return emptyUserReport(pagination), nil
+}
+
+func (o *FakeClient) FetchUserChampionReport(reportInterval *string, pagination *models.ReportPagination) (*models.UserReport, error) {
+	return emptyUserReport(pagination), nil
+}
+
+func (o *FakeClient) FetchUserChurnReport(reportInterval *string, pagination *models.ReportPagination) (*models.UserReport, error) {
+	return emptyUserReport(pagination), nil
+}
+
+func (o *FakeClient) FetchUserReengagementReport(reportInterval *string, pagination *models.ReportPagination) (*models.UserReport, error) {
+	return emptyUserReport(pagination), nil
+}
+
+func (o *FakeClient) FetchOrgGrowthReport(reportInterval *string, pagination *models.ReportPagination) (*models.OrgReport, error) {
+	return emptyOrgReport(pagination), nil
+}
+
+func (o *FakeClient) FetchOrgAttritionReport(reportInterval *string, pagination *models.ReportPagination) (*models.OrgReport, error) {
+	return emptyOrgReport(pagination), nil
+}
+
+func (o *FakeClient) FetchOrgChurnReport(reportInterval *string, pagination *models.ReportPagination) (*models.OrgReport, error) {
+	return emptyOrgReport(pagination), nil
+}
+
+func (o *FakeClient) FetchOrgReengagementReport(reportInterval *string, pagination *models.ReportPagination) (*models.OrgReport, error) {
+	return emptyOrgReport(pagination), nil
+}
+
+func (o *FakeClient) FetchChartMetricData(chartMetric string, cadence *string, chartRange *models.ChartRange) (*models.ChartData, error) {
+	return &models.ChartData{Metrics: []models.ChartDataPoint{}, Cadence: valueOr(cadence, "Daily"), ChartType: chartMetric}, nil
+}
+
+func emptyUserReport(pagination *models.ReportPagination) *models.UserReport {
+	report := &models.UserReport{UserReports: []models.UserReportRecord{}, PageSize: fakeDefaultPageSize, ReportTime: time.Now().Unix()}
+	if pagination != nil {
+		report.CurrentPage = valueOr(pagination.PageNumber, 0)
+		report.PageSize = valueOr(pagination.PageSize, fakeDefaultPageSize)
+	}
+
+	return report
+}
+
+func emptyOrgReport(pagination *models.ReportPagination) *models.OrgReport {
+	report := &models.OrgReport{OrgReports: []models.OrgReportRecord{}, PageSize: fakeDefaultPageSize, ReportTime: int(time.Now().Unix())}
+	if pagination != nil {
+		report.CurrentPage = valueOr(pagination.PageNumber, 0)
+		report.PageSize = valueOr(pagination.PageSize, fakeDefaultPageSize)
+	}
+
+	return report
+}
+
+// small helpers
+
+func valueOr[T any](value *T, fallback T) T {
+	if value == nil {
+		return fallback
+	}
+
+	return *value
+}
+
+// paginate returns the page of items, the total number of items, and whether there are more pages after it.
+func paginate[T any](items []T, pageSize *int, pageNumber *int) ([]T, int, bool) {
+	size := valueOr(pageSize, fakeDefaultPageSize)
+	if size <= 0 {
+		size = fakeDefaultPageSize
+	}
+	number := valueOr(pageNumber, 0)
+	if number < 0 {
+		number = 0
+	}
+
+	start := number * size
+	if start > len(items) {
+		start = len(items)
+	}
+	end := start + size
+	if end > len(items) {
+		end = len(items)
+	}
+
+	return append([]T{}, items[start:end]...), len(items), end < len(items)
+}
+
+func removeID(ids []uuid.UUID, id uuid.UUID) []uuid.UUID {
+	kept := ids[:0]
+	for _, existing := range ids {
+		if existing != id {
+			kept = append(kept, existing)
+		}
+	}
+
+	return kept
+}
+
+func randomHex(numBytes int) string {
+	bytes := make([]byte, numBytes)
+	if _, err := rand.Read(bytes); err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(bytes)
+}
@@ -0,0 +1,309 @@
+package test_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/google/uuid"
+	propelauth "github.com/propelauth/propelauth-go/pkg"
+	"github.com/propelauth/propelauth-go/pkg/models"
+	testHelpers "github.com/propelauth/propelauth-go/pkg/test"
+)
+
+func TestFakeClientUsers(t *testing.T) {
+	// setup
+
+	client := testHelpers.NewFakeClient()
+	username := "ada"
+
+	userID, err := client.CreateUser(models.CreateUserParams{Email: "ada@example.com", Username: &username})
+	if err != nil {
+		t.Fatalf("CreateUser returned an error, cannot even begin the tests: %s", err)
+	}
+
+	// run tests
+
+	t.Run("emails and usernames are unique", func(t *testing.T) {
+		_, err := client.CreateUser(models.CreateUserParams{Email: "ADA@example.com"})
+		apiError := &models.APIError{}
+		if !errors.As(err, &apiError) || apiError.StatusCode != 400 || apiError.FieldToErrors["email"] == nil {
+			t.Errorf("expected a 400 about the email, got %v", err)
+		}
+
+		_, err = client.CreateUser(models.CreateUserParams{Email: "other@example.com", Username: &username})
+		if !errors.As(err, &apiError) || apiError.FieldToErrors["username"] == nil {
+			t.Errorf("expected a 400 about the username, got %v", err)
+		}
+	})
+
+	t.Run("users can be fetched by ID, email and username", func(t *testing.T) {
+		byID, err := client.FetchUserMetadataByUserID(userID.UserID, false)
+		if err != nil || byID.Email != "ada@example.com" {
+			t.Errorf("expected to find the user by ID, got %v, %v", byID, err)
+		}
+		byEmail, err := client.FetchUserMetadataByEmail("ada@example.com", false)
+		if err != nil || byEmail.UserID != userID.UserID {
+			t.Errorf("expected to find the user by email, got %v, %v", byEmail, err)
+		}
+		byUsername, err := client.FetchUserMetadataByUsername("ada", false)
+		if err != nil || byUsername.UserID != userID.UserID {
+			t.Errorf("expected to find the user by username, got %v, %v", byUsername, err)
+		}
+	})
+
+	t.Run("missing users are not found", func(t *testing.T) {
+		_, err := client.FetchUserMetadataByUserID(uuid.New(), false)
+		if !errors.Is(err, models.ErrNotFound) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+		_, err = client.DeleteUser(uuid.New())
+		if !errors.Is(err, models.ErrNotFound) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("users are paged", func(t *testing.T) {
+		for i := 0; i < 14; i++ {
+			if _, err := client.CreateUser(models.CreateUserParams{Email: fmt.Sprintf("user%d@example.com", i)}); err != nil {
+				t.Fatalf("CreateUser returned an error: %s", err)
+			}
+		}
+
+		firstPage, err := client.FetchUsersByQuery(models.UserQueryParams{})
+		if err != nil || len(firstPage.Users) != 10 || !firstPage.HasMoreResults || firstPage.TotalUsers != 15 {
+			t.Errorf("expected the first page of 10 out of 15 users, got %+v, %v", firstPage, err)
+		}
+
+		users, err := propelauth.IterateUsersByQuery(client, models.UserQueryParams{}).All(0)
+		if err != nil || len(users) != 15 {
+			t.Errorf("expected to iterate through all 15 users, got %d, %v", len(users), err)
+		}
+	})
+
+	t.Run("calls fail once the context is done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := client.WithContext(ctx).FetchUserMetadataByUserID(userID.UserID, false)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context.Canceled, got %v", err)
+		}
+	})
+}
+
+func TestFakeClientStepUp(t *testing.T) {
+	// setup a user with TOTP and a phone
+
+	client := testHelpers.NewFakeClient()
+
+	userID, err := client.CreateUser(models.CreateUserParams{Email: "careful@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser returned an error, cannot even begin the tests: %s", err)
+	}
+	if err := client.SetUpTotp(userID.UserID, "123456"); err != nil {
+		t.Fatalf("SetUpTotp returned an error, cannot even begin the tests: %s", err)
+	}
+	phoneID, err := client.AddMfaPhone(userID.UserID, "1234")
+	if err != nil {
+		t.Fatalf("AddMfaPhone returned an error, cannot even begin the tests: %s", err)
+	}
+
+	verify := func(actionType string, grant string) bool {
+		response, err := client.VerifyStepUpGrant(models.VerifyStepUpGrantRequest{ActionType: actionType, UserID: userID.UserID, Grant: grant})
+		if err != nil {
+			t.Fatalf("VerifyStepUpGrant returned an error: %s", err)
+		}
+		return response.Success
+	}
+
+	// run tests
+
+	t.Run("the user's methods are listed", func(t *testing.T) {
+		methods, err := client.FetchUserMfaMethods(userID.UserID)
+		if err != nil || methods.MfaSetup.Type != "Totp" || methods.MfaSetup.PhoneNumbers == nil || (*methods.MfaSetup.PhoneNumbers)[0].MfaPhoneID != phoneID.String() {
+			t.Errorf("expected TOTP and the phone, got %+v, %v", methods, err)
+		}
+	})
+
+	t.Run("a TOTP code gives a one time grant for the action", func(t *testing.T) {
+		_, err := client.VerifyStepUpTotpChallenge(models.VerifyTotpChallengeRequest{ActionType: "delete", UserID: userID.UserID, Code: "000000"})
+		apiError := &models.APIError{}
+		if !errors.As(err, &apiError) || apiError.ErrorCode != "incorrect_mfa_code" {
+			t.Errorf("expected incorrect_mfa_code for the wrong code, got %v", err)
+		}
+
+		response, err := client.VerifyStepUpTotpChallenge(models.VerifyTotpChallengeRequest{
+			ActionType: "delete", UserID: userID.UserID, Code: "123456", GrantType: models.StepUpMfaGrantTypeOneTimeUse,
+		})
+		if err != nil {
+			t.Fatalf("VerifyStepUpTotpChallenge returned an error: %s", err)
+		}
+
+		if verify("export", response.StepUpGrant) {
+			t.Errorf("expected the grant to be refused for another action")
+		}
+		if !verify("delete", response.StepUpGrant) || verify("delete", response.StepUpGrant) {
+			t.Errorf("expected the grant to be accepted once")
+		}
+	})
+
+	t.Run("time based grants can be used until they expire", func(t *testing.T) {
+		response, err := client.VerifyStepUpTotpChallenge(models.VerifyTotpChallengeRequest{
+			ActionType: "delete", UserID: userID.UserID, Code: "123456", GrantType: models.StepUpMfaGrantTypeTimeBased, ValidForSeconds: 60,
+		})
+		if err != nil {
+			t.Fatalf("VerifyStepUpTotpChallenge returned an error: %s", err)
+		}
+
+		if !verify("delete", response.StepUpGrant) || !verify("delete", response.StepUpGrant) {
+			t.Errorf("expected the grant to be accepted more than once")
+		}
+	})
+
+	t.Run("an SMS code gives a grant", func(t *testing.T) {
+		sent, err := client.SendSmsMfaCode(models.SendSmsMfaCodeRequest{ActionType: "delete", UserID: userID.UserID, MfaPhoneID: phoneID})
+		if err != nil {
+			t.Fatalf("SendSmsMfaCode returned an error: %s", err)
+		}
+
+		challenges := client.SmsChallenges(userID.UserID)
+		if len(challenges) != 1 || challenges[0].ChallengeID != sent.ChallengeID || len(challenges[0].Code) != 6 {
+			t.Fatalf("expected the challenge with a six digit code, got %+v", challenges)
+		}
+
+		response, err := client.VerifySmsChallenge(models.VerifySmsChallengeRequest{ChallengeID: sent.ChallengeID, UserID: userID.UserID, Code: challenges[0].Code})
+		if err != nil || !verify("delete", response.StepUpGrant) {
+			t.Errorf("expected the SMS grant to be accepted, got %+v, %v", response, err)
+		}
+		if len(client.SmsChallenges(userID.UserID)) != 0 {
+			t.Errorf("expected the challenge to be used up")
+		}
+	})
+
+	t.Run("users without MFA can't step up", func(t *testing.T) {
+		other, _ := client.CreateUser(models.CreateUserParams{Email: "casual@example.com"})
+
+		_, err := client.VerifyStepUpTotpChallenge(models.VerifyTotpChallengeRequest{ActionType: "delete", UserID: other.UserID, Code: "123456"})
+		apiError := &models.APIError{}
+		if !errors.As(err, &apiError) || apiError.ErrorCode != "mfa_not_enabled" {
+			t.Errorf("expected mfa_not_enabled, got %v", err)
+		}
+
+		if _, err := client.SendSmsMfaCode(models.SendSmsMfaCodeRequest{ActionType: "delete", UserID: other.UserID, MfaPhoneID: phoneID}); err == nil {
+			t.Errorf("expected SendSmsMfaCode to fail for someone else's phone")
+		}
+	})
+}
+
+func TestFakeClientOrgs(t *testing.T) {
+	// setup
+
+	client := testHelpers.NewFakeClient(testHelpers.WithFakeRolePermissions("Admin", "can_invite"))
+
+	userID, err := client.CreateUser(models.CreateUserParams{Email: "grace@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser returned an error, cannot even begin the tests: %s", err)
+	}
+	org, err := client.CreateOrg("Acme")
+	if err != nil {
+		t.Fatalf("CreateOrg returned an error, cannot even begin the tests: %s", err)
+	}
+
+	// run tests
+
+	t.Run("members show up in access tokens", func(t *testing.T) {
+		_, err := client.AddUserToOrg(models.AddUserToOrg{UserID: userID.UserID, OrgID: org.OrgID, Role: "Admin"})
+		if err != nil {
+			t.Fatalf("AddUserToOrg returned an error: %s", err)
+		}
+
+		accessToken, err := client.CreateAccessToken(userID.UserID, 10)
+		if err != nil {
+			t.Fatalf("CreateAccessToken returned an error: %s", err)
+		}
+		user, err := client.GetUser("Bearer " + accessToken.AccessToken)
+		if err != nil {
+			t.Fatalf("GetUser returned an error: %s", err)
+		}
+
+		orgMemberInfo := user.GetOrgMemberInfo(org.OrgID)
+		if orgMemberInfo == nil || !orgMemberInfo.IsAtLeastRole("Member") || orgMemberInfo.IsAtLeastRole("Owner") || !orgMemberInfo.HasPermission("can_invite") {
+			t.Errorf("expected the user to be an Admin with can_invite, got %+v", orgMemberInfo)
+		}
+	})
+
+	t.Run("users can't be added twice or with an unknown role", func(t *testing.T) {
+		_, err := client.AddUserToOrg(models.AddUserToOrg{UserID: userID.UserID, OrgID: org.OrgID, Role: "Admin"})
+		if err == nil {
+			t.Errorf("expected an error adding the user twice")
+		}
+
+		otherOrg, _ := client.CreateOrg("Other")
+		_, err = client.AddUserToOrg(models.AddUserToOrg{UserID: userID.UserID, OrgID: otherOrg.OrgID, Role: "Janitor"})
+		if err == nil {
+			t.Errorf("expected an error adding the user with an unknown role")
+		}
+	})
+
+	t.Run("invites can be accepted", func(t *testing.T) {
+		_, err := client.InviteUserToOrg(models.InviteUserToOrg{Email: "linus@example.com", OrgID: org.OrgID, Role: "Member"})
+		if err != nil {
+			t.Fatalf("InviteUserToOrg returned an error: %s", err)
+		}
+
+		invites, err := client.FetchPendingInvites(models.FetchPendingInvitesParams{OrgID: &org.OrgID})
+		if err != nil || len(invites.Invites) != 1 {
+			t.Fatalf("expected 1 pending invite, got %v, %v", invites, err)
+		}
+
+		newUserID, err := client.AcceptInvite(org.OrgID, "linus@example.com")
+		if err != nil {
+			t.Fatalf("AcceptInvite returned an error: %s", err)
+		}
+
+		members, err := client.FetchUsersInOrg(org.OrgID, models.UserInOrgQueryParams{})
+		if err != nil || members.TotalUsers != 2 || members.Users[1].UserID != newUserID.UserID {
+			t.Errorf("expected the new user in the org, got %v, %v", members, err)
+		}
+	})
+
+	t.Run("API keys can be validated until they're deleted", func(t *testing.T) {
+		apiKey, err := client.CreateAPIKey(models.APIKeyCreateParams{OrgID: &org.OrgID, UserID: &userID.UserID})
+		if err != nil {
+			t.Fatalf("CreateAPIKey returned an error: %s", err)
+		}
+
+		validation, err := client.ValidateOrgAPIKey(apiKey.APIKeyToken)
+		if err != nil || validation.Org.OrgID != org.OrgID || validation.UserInOrg == nil || validation.UserInOrg.UserAssignedRole != "Admin" {
+			t.Errorf("expected the key to be valid for the org and user, got %+v, %v", validation, err)
+		}
+
+		if _, err := client.DeleteAPIKey(apiKey.APIKeyID); err != nil {
+			t.Fatalf("DeleteAPIKey returned an error: %s", err)
+		}
+		if _, err := client.ValidateAPIKey(apiKey.APIKeyToken); err == nil {
+			t.Errorf("expected a deleted key to be invalid")
+		}
+
+		archived, err := client.FetchArchivedAPIKeys(models.APIKeysQueryParams{OrgID: &org.OrgID})
+		if err != nil || len(archived.APIKeys) != 1 {
+			t.Errorf("expected the deleted key to be archived, got %v, %v", archived, err)
+		}
+	})
+
+	t.Run("deleting an org removes its members", func(t *testing.T) {
+		if _, err := client.DeleteOrg(org.OrgID); err != nil {
+			t.Fatalf("DeleteOrg returned an error: %s", err)
+		}
+
+		user, err := client.FetchUserMetadataByUserID(userID.UserID, true)
+		if err != nil || len(*user.OrgIDToOrgInfo) != 0 {
+			t.Errorf("expected the user to not be in any orgs, got %v, %v", user, err)
+		}
+		if _, err := client.FetchOrg(org.OrgID); !errors.Is(err, models.ErrNotFound) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+	})
+}
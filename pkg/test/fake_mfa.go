@@ -0,0 +1,237 @@
+package test
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/propelauth/propelauth-go/pkg/models"
+)
+
+// FakeSmsChallenge is a code sent by SendSmsMfaCode. Tests read the code with SmsChallenges, since the fake
+// doesn't send texts.
+type FakeSmsChallenge struct {
+	ChallengeID string
+	UserID      uuid.UUID
+	MfaPhoneID  uuid.UUID
+	ActionType  string
+	Code        string
+}
+
+type fakeSmsChallenge struct {
+	FakeSmsChallenge
+	grantType       models.StepUpMfaGrantType
+	validForSeconds int
+}
+
+type fakeStepUpGrant struct {
+	userID     uuid.UUID
+	actionType string
+	grantType  models.StepUpMfaGrantType
+	expiresAt  time.Time
+}
+
+// SetUpTotp simulates the user setting up an authenticator app. The fake accepts code as the app's current code in
+// VerifyStepUpTotpChallenge.
+func (o *FakeClient) SetUpTotp(userID uuid.UUID, code string) error {
+	_, err := o.updateUser(userID, func(user *fakeUser) {
+		user.totpCode = code
+		user.metadata.MfaEnabled = true
+	})
+
+	return err
+}
+
+// AddMfaPhone simulates the user adding a phone for MFA, and returns its ID to pass to SendSmsMfaCode.
+func (o *FakeClient) AddMfaPhone(userID uuid.UUID, phoneNumberSuffix string) (uuid.UUID, error) {
+	phoneID := uuid.New()
+	_, err := o.updateUser(userID, func(user *fakeUser) {
+		user.mfaPhones = append(user.mfaPhones, models.MfaPhones{MfaPhoneID: phoneID.String(), MfaPhoneNumberSuffix: phoneNumberSuffix})
+		user.metadata.MfaEnabled = true
+	})
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	return phoneID, nil
+}
+
+// SmsChallenges returns the SMS codes sent to the user that haven't been verified yet, oldest first.
+func (o *FakeClient) SmsChallenges(userID uuid.UUID) []FakeSmsChallenge {
+	o.state.mu.Lock()
+	defer o.state.mu.Unlock()
+
+	challenges := []FakeSmsChallenge{}
+	for _, challenge := range o.state.smsChallenges {
+		if challenge.UserID == userID {
+			challenges = append(challenges, challenge.FakeSmsChallenge)
+		}
+	}
+
+	return challenges
+}
+
+// Step up MFA, with grants that can be verified once for ONE_TIME_USE, or until they expire for TIME_BASED
+
+func (o *FakeClient) FetchUserMfaMethods(userID uuid.UUID) (*models.FetchUserMfaMethodsResponse, error) {
+	if err := o.lock(); err != nil {
+		return nil, err
+	}
+	defer o.state.mu.Unlock()
+
+	user, err := o.state.user(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	mfaMethods := &models.FetchUserMfaMethodsResponse{MfaSetup: models.MfaSetupType{Type: "None"}}
+	if user.totpCode != "" {
+		mfaMethods.MfaSetup.Type = "Totp"
+	}
+	if len(user.mfaPhones) > 0 {
+		phones := append([]models.MfaPhones{}, user.mfaPhones...)
+		mfaMethods.MfaSetup.PhoneNumbers = &phones
+	}
+
+	return mfaMethods, nil
+}
+
+func (o *FakeClient) VerifyStepUpGrant(params models.VerifyStepUpGrantRequest) (*models.StepUpMfaVerifyGrantResponse, error) {
+	if err := o.lock(); err != nil {
+		return nil, err
+	}
+	defer o.state.mu.Unlock()
+
+	grant, ok := o.state.stepUpGrants[params.Grant]
+	if !ok || grant.userID != params.UserID || grant.actionType != params.ActionType {
+		return &models.StepUpMfaVerifyGrantResponse{Success: false}, nil
+	}
+	if !grant.expiresAt.IsZero() && !time.Now().Before(grant.expiresAt) {
+		delete(o.state.stepUpGrants, params.Grant)
+		return &models.StepUpMfaVerifyGrantResponse{Success: false}, nil
+	}
+
+	if grant.grantType != models.StepUpMfaGrantTypeTimeBased {
+		delete(o.state.stepUpGrants, params.Grant)
+	}
+
+	return &models.StepUpMfaVerifyGrantResponse{Success: true}, nil
+}
+
+func (o *FakeClient) VerifyStepUpTotpChallenge(params models.VerifyTotpChallengeRequest) (*models.StepUpMfaVerifyTotpResponse, error) {
+	if err := o.lock(); err != nil {
+		return nil, err
+	}
+	defer o.state.mu.Unlock()
+
+	user, err := o.state.user(params.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user.totpCode == "" {
+		return nil, fakeMfaError("mfa_not_enabled", "MFA not enabled for this user")
+	}
+	if params.Code != user.totpCode {
+		return nil, fakeMfaError("incorrect_mfa_code", "Incorrect MFA code")
+	}
+
+	grant := o.state.addStepUpGrant(params.UserID, params.ActionType, params.GrantType, params.ValidForSeconds)
+
+	return &models.StepUpMfaVerifyTotpResponse{StepUpGrant: grant}, nil
+}
+
+func (o *FakeClient) SendSmsMfaCode(params models.SendSmsMfaCodeRequest) (*models.SendSmsMfaCodeResponse, error) {
+	if err := o.lock(); err != nil {
+		return nil, err
+	}
+	defer o.state.mu.Unlock()
+
+	user, err := o.state.user(params.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if len(user.mfaPhones) == 0 {
+		return nil, fakeMfaError("mfa_not_enabled", "MFA not enabled for this user")
+	}
+
+	hasPhone := false
+	for _, phone := range user.mfaPhones {
+		hasPhone = hasPhone || strings.EqualFold(phone.MfaPhoneID, params.MfaPhoneID.String())
+	}
+	if !hasPhone {
+		return nil, fakeBadRequest("mfa_phone_id", "Phone not found")
+	}
+
+	challenge := fakeSmsChallenge{
+		FakeSmsChallenge: FakeSmsChallenge{
+			ChallengeID: randomHex(16),
+			UserID:      params.UserID,
+			MfaPhoneID:  params.MfaPhoneID,
+			ActionType:  params.ActionType,
+			Code:        randomCode(),
+		},
+		grantType:       params.GrantType,
+		validForSeconds: params.ValidForSeconds,
+	}
+	o.state.smsChallenges = append(o.state.smsChallenges, challenge)
+
+	return &models.SendSmsMfaCodeResponse{ChallengeID: challenge.ChallengeID}, nil
+}
+
+func (o *FakeClient) VerifySmsChallenge(params models.VerifySmsChallengeRequest) (*models.VerifySmsChallengeResponse, error) {
+	if err := o.lock(); err != nil {
+		return nil, err
+	}
+	defer o.state.mu.Unlock()
+
+	for i, challenge := range o.state.smsChallenges {
+		if challenge.ChallengeID != params.ChallengeID || challenge.UserID != params.UserID {
+			continue
+		}
+		if params.Code != challenge.Code {
+			return nil, fakeMfaError("incorrect_mfa_code", "Incorrect MFA code")
+		}
+
+		o.state.smsChallenges = append(o.state.smsChallenges[:i], o.state.smsChallenges[i+1:]...)
+		grant := o.state.addStepUpGrant(challenge.UserID, challenge.ActionType, challenge.grantType, challenge.validForSeconds)
+
+		return &models.VerifySmsChallengeResponse{StepUpGrant: grant}, nil
+	}
+
+	return nil, fakeBadRequest("challenge_id", "Challenge not found")
+}
+
+// addStepUpGrant stores a new grant for the action, and returns it. Grants without a type are one time use, and
+// grants without a duration don't expire.
+func (o *fakeState) addStepUpGrant(userID uuid.UUID, actionType string, grantType models.StepUpMfaGrantType, validForSeconds int) string {
+	grant := &fakeStepUpGrant{userID: userID, actionType: actionType, grantType: grantType}
+	if validForSeconds > 0 {
+		grant.expiresAt = time.Now().Add(time.Duration(validForSeconds) * time.Second)
+	}
+
+	token := randomHex(24)
+	o.stepUpGrants[token] = grant
+
+	return token
+}
+
+// fakeMfaError is a 400 with an error code, like the step-up MFA endpoints return.
+func fakeMfaError(errorCode string, message string) error {
+	body, _ := json.Marshal(map[string]string{"error_code": errorCode})
+
+	return models.NewAPIError(400, body, message, nil)
+}
+
+// randomCode returns a six digit code, like the ones sent by text.
+func randomCode() string {
+	code, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		panic(err)
+	}
+
+	return fmt.Sprintf("%06d", code)
+}
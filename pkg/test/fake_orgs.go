@@ -0,0 +1,713 @@
+package test
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/propelauth/propelauth-go/pkg/models"
+)
+
+type fakeOrg struct {
+	metadata       models.OrgCompleteMetadata
+	createdAt      int
+	idpMetadataSet bool
+}
+
+var nonSlugCharacters = regexp.MustCompile(`[^a-z0-9]+`)
+
+// org returns the org, or an error that matches models.ErrNotFound.
+func (o *fakeState) org(orgID uuid.UUID) (*fakeOrg, error) {
+	org, ok := o.orgs[orgID]
+	if !ok {
+		return nil, fakeNotFound("Org not found")
+	}
+
+	return org, nil
+}
+
+func (o *fakeState) addOrg(metadata models.OrgCompleteMetadata) (*fakeOrg, error) {
+	if strings.TrimSpace(metadata.Name) == "" {
+		return nil, fakeBadRequest("name", "Name is required")
+	}
+	if metadata.CustomRoleMappingName != "" && !o.hasRoleMapping(metadata.CustomRoleMappingName) {
+		return nil, fakeBadRequest("custom_role_mapping_name", "Custom role mapping not found")
+	}
+	if metadata.Metadata == nil {
+		metadata.Metadata = map[string]interface{}{}
+	}
+
+	metadata.OrgID = uuid.New()
+	metadata.UrlSafeOrgSlug = strings.Trim(nonSlugCharacters.ReplaceAllString(strings.ToLower(metadata.Name), "-"), "-")
+
+	org := &fakeOrg{metadata: metadata, createdAt: int(time.Now().Unix())}
+	o.orgs[metadata.OrgID] = org
+	o.orgOrder = append(o.orgOrder, metadata.OrgID)
+
+	return org, nil
+}
+
+func (o *fakeState) hasRoleMapping(name string) bool {
+	for _, roleMapping := range o.roleMappings {
+		if roleMapping == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (o *fakeOrg) orgMetadata() models.OrgMetadata {
+	createdAt := o.createdAt
+
+	return models.OrgMetadata{
+		OrgID:                 o.metadata.OrgID,
+		Name:                  o.metadata.Name,
+		MaxUsers:              o.metadata.MaxUsers,
+		Metadata:              o.metadata.Metadata,
+		IsSamlConfigured:      o.metadata.IsSamlConfigured,
+		LegacyOrgId:           o.metadata.LegacyOrgId,
+		CustomRoleMappingName: o.metadata.CustomRoleMappingName,
+		CreatedAt:             &createdAt,
+	}
+}
+
+// members returns the users in the org, in the order they were created.
+func (o *fakeState) members(orgID uuid.UUID) []*fakeUser {
+	members := []*fakeUser{}
+	for _, userID := range o.userOrder {
+		if _, ok := o.users[userID].memberships[orgID]; ok {
+			members = append(members, o.users[userID])
+		}
+	}
+
+	return members
+}
+
+// Orgs
+
+func (o *FakeClient) CreateOrg(name string) (*models.OrgMetadata, error) {
+	if err := o.lock(); err != nil {
+		return nil, err
+	}
+	defer o.state.mu.Unlock()
+
+	org, err := o.state.addOrg(models.OrgCompleteMetadata{Name: name})
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := org.orgMetadata()
+
+	return &metadata, nil
+}
+
+func (o *FakeClient) CreateOrgV2(params models.CreateOrgV2Params) (*models.CreateOrgV2Response, error) {
+	if err := o.lock(); err != nil {
+		return nil, err
+	}
+	defer o.state.mu.Unlock()
+
+	metadata := models.OrgCompleteMetadata{
+		Name:           params.Name,
+		DomainAutojoin: params.EnableAutoJoiningByDomain,
+		DomainRestrict: params.MembersMustHaveMatchingDomain,
+		LegacyOrgId:    params.LegacyOrgId,
+	}
+	if params.Domain != "" {
+		metadata.Domain = &params.Domain
+	}
+	if params.MaxUsers > 0 {
+		metadata.MaxUsers = &params.MaxUsers
+	}
+	if params.CustomRoleMappingName != nil {
+		metadata.CustomRoleMappingName = *params.CustomRoleMappingName
+	}
+
+	org, err := o.state.addOrg(metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.CreateOrgV2Response{OrgID: org.metadata.OrgID, Name: org.metadata.Name}, nil
+}
+
+func (o *FakeClient) FetchOrg(orgID uuid.UUID) (*models.OrgCompleteMetadata, error) {
+	if err := o.lock(); err != nil {
+		return nil, err
+	}
+	defer o.state.mu.Unlock()
+
+	org, err := o.state.org(orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := org.metadata
+
+	return &metadata, nil
+}
+
+func (o *FakeClient) FetchOrgByQuery(params models.OrgQueryParams) (*models.OrgList, error) {
+	if err := o.lock(); err != nil {
+		return nil, err
+	}
+	defer o.state.mu.Unlock()
+
+	orgs := []models.OrgMetadata{}
+	for _, orgID := range o.state.orgOrder {
+		org := o.state.orgs[orgID]
+		if params.Name != nil && !strings.Contains(strings.ToLower(org.metadata.Name), strings.ToLower(*params.Name)) {
+			continue
+		}
+		if params.LegacyOrgId != nil && (org.metadata.LegacyOrgId == nil || *org.metadata.LegacyOrgId != *params.LegacyOrgId) {
+			continue
+		}
+		if params.Domain != nil && (org.metadata.Domain == nil || !strings.EqualFold(*org.metadata.Domain, *params.Domain)) {
+			continue
+		}
+		orgs = append(orgs, org.orgMetadata())
+	}
+
+	switch valueOr(params.OrderBy, "CREATED_AT_ASC") {
+	case "CREATED_AT_DESC":
+		// orgs are already in the order they were created
+		for i, j := 0, len(orgs)-1; i < j; i, j = i+1, j-1 {
+			orgs[i], orgs[j] = orgs[j], orgs[i]
+		}
+	case "NAME":
+		sort.SliceStable(orgs, func(i, j int) bool { return orgs[i].Name < orgs[j].Name })
+	}
+
+	page, total, hasMoreResults := paginate(orgs, params.PageSize, params.PageNumber)
+
+	return &models.OrgList{
+		TotalOrgs:      total,
+		CurrentPage:    valueOr(params.PageNumber, 0),
+		PageSize:       valueOr(params.PageSize, fakeDefaultPageSize),
+		HasMoreResults: hasMoreResults,
+		Orgs:           page,
+	}, nil
+}
+
+func (o *FakeClient) UpdateOrgMetadata(orgID uuid.UUID, params models.UpdateOrg) (bool, error) {
+	return o.updateOrg(orgID, func(org *fakeOrg) error {
+		if params.Name != nil {
+			if strings.TrimSpace(*params.Name) == "" {
+				return fakeBadRequest("name", "Name is required")
+			}
+			org.metadata.Name = *params.Name
+		}
+		if params.CanSetupSaml != nil {
+			org.metadata.CanSetupSaml = *params.CanSetupSaml
+		}
+		if params.AutojoinByDomain != nil {
+			org.metadata.DomainAutojoin = *params.AutojoinByDomain
+		}
+		if params.RestrictToDomain != nil {
+			org.metadata.DomainRestrict = *params.RestrictToDomain
+		}
+		if params.MaxUsers != nil {
+			org.metadata.MaxUsers = params.MaxUsers
+		}
+		if params.Metadata != nil {
+			org.metadata.Metadata = *params.Metadata
+		}
+		if params.Domain != nil {
+			org.metadata.Domain = params.Domain
+		}
+		if params.LegacyOrgId != nil {
+			org.metadata.LegacyOrgId = params.LegacyOrgId
+		}
+		if params.ExtraDomains != nil {
+			org.metadata.ExtraDomains = *params.ExtraDomains
+		}
+		if params.PasswordRotationEnabled != nil {
+			org.metadata.PasswordRotationEnabled = *params.PasswordRotationEnabled
+		}
+		if params.PasswordRotationHistorySize != nil {
+			org.metadata.PasswordRotationHistorySize = *params.PasswordRotationHistorySize
+		}
+		if params.PasswordRotationPeriod != nil {
+			org.metadata.PasswordRotationPeriod = *params.PasswordRotationPeriod
+		}
+		return nil
+	})
+}
+
+// updateOrg runs update on the org, or returns an error that matches models.ErrNotFound.
+func (o *FakeClient) updateOrg(orgID uuid.UUID, update func(org *fakeOrg) error) (bool, error) {
+	if err := o.lock(); err != nil {
+		return false, err
+	}
+	defer o.state.mu.Unlock()
+
+	org, err := o.state.org(orgID)
+	if err != nil {
+		return false, err
+	}
+
+	if err := update(org); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (o *FakeClient) DeleteOrg(orgID uuid.UUID) (bool, error) {
+	if err := o.lock(); err != nil {
+		return false, err
+	}
+	defer o.state.mu.Unlock()
+
+	if _, err := o.state.org(orgID); err != nil {
+		return false, err
+	}
+
+	delete(o.state.orgs, orgID)
+	o.state.orgOrder = removeID(o.state.orgOrder, orgID)
+
+	// memberships, invites, and API keys go with the org
+	for _, user := range o.state.users {
+		delete(user.memberships, orgID)
+	}
+	invites := o.state.invites[:0]
+	for _, invite := range o.state.invites {
+		if invite.OrgID != orgID {
+			invites = append(invites, invite)
+		}
+	}
+	o.state.invites = invites
+	for _, apiKey := range o.state.apiKeys {
+		if apiKey.metadata.OrgID == orgID {
+			apiKey.archived = true
+		}
+	}
+
+	return true, nil
+}
+
+func (o *FakeClient) FetchCustomRoleMappings() (*models.CustomRoleMappingList, error) {
+	if err := o.lock(); err != nil {
+		return nil, err
+	}
+	defer o.state.mu.Unlock()
+
+	roleMappings := []models.CustomRoleMapping{}
+	for _, name := range o.state.roleMappings {
+		subscribed := 0
+		for _, org := range o.state.orgs {
+			if org.metadata.CustomRoleMappingName == name {
+				subscribed++
+			}
+		}
+		roleMappings = append(roleMappings, models.CustomRoleMapping{CustomRoleMappingName: name, NumberOfOrgsSubscribed: subscribed})
+	}
+
+	return &models.CustomRoleMappingList{CustomRoleMappings: roleMappings}, nil
+}
+
+func (o *FakeClient) SubscribeOrgToRoleMapping(orgID uuid.UUID, params models.OrgRoleMappingSubscription) (bool, error) {
+	return o.updateOrg(orgID, func(org *fakeOrg) error {
+		if !o.state.hasRoleMapping(params.CustomRoleMappingName) {
+			return fakeBadRequest("custom_role_mapping_name", "Custom role mapping not found")
+		}
+		org.metadata.CustomRoleMappingName = params.CustomRoleMappingName
+		return nil
+	})
+}
+
+// SAML and OIDC
+
+func (o *FakeClient) AllowOrgToSetupSamlConnection(orgID uuid.UUID) (bool, error) {
+	return o.updateOrg(orgID, func(org *fakeOrg) error {
+		org.metadata.CanSetupSaml = true
+		return nil
+	})
+}
+
+func (o *FakeClient) DisallowOrgToSetupSamlConnection(orgID uuid.UUID) (bool, error) {
+	return o.updateOrg(orgID, func(org *fakeOrg) error {
+		org.metadata.CanSetupSaml = false
+		return nil
+	})
+}
+
+func (o *FakeClient) CreateOrgSamlConnectionLink(orgID uuid.UUID, params models.CreateSamlConnectionLinkBody) (*models.CreateSamlConnectionLinkResponse, error) {
+	if err := o.lock(); err != nil {
+		return nil, err
+	}
+	defer o.state.mu.Unlock()
+
+	org, err := o.state.org(orgID)
+	if err != nil {
+		return nil, err
+	}
+	if !org.metadata.CanSetupSaml {
+		return nil, fakeBadRequest("org_id", "Org is not allowed to set up SAML")
+	}
+
+	return &models.CreateSamlConnectionLinkResponse{URL: fmt.Sprintf("%s/saml/setup?token=%s", o.state.authURL, randomHex(24))}, nil
+}
+
+func (o *FakeClient) FetchSamlSpMetadata(orgID uuid.UUID) (*models.SamlSpMetadata, error) {
+	if err := o.lock(); err != nil {
+		return nil, err
+	}
+	defer o.state.mu.Unlock()
+
+	if _, err := o.state.org(orgID); err != nil {
+		return nil, err
+	}
+
+	return &models.SamlSpMetadata{
+		EntityId:  fmt.Sprintf("%s/saml/%s/metadata", o.state.authURL, orgID),
+		AcsUrl:    fmt.Sprintf("%s/saml/%s/acs", o.state.authURL, orgID),
+		LogoutUrl: fmt.Sprintf("%s/saml/%s/logout", o.state.authURL, orgID),
+	}, nil
+}
+
+func (o *FakeClient) SetSamlIdpMetadata(params models.SamlIdpMetadata) (bool, error) {
+	return o.updateOrg(params.OrgId, func(org *fakeOrg) error {
+		if params.IdpEntityId == "" || params.IdpSsoUrl == "" || params.IdpCertificate == "" {
+			return fakeBadRequest("idp_metadata", "Entity ID, SSO URL and certificate are required")
+		}
+		org.idpMetadataSet = true
+		org.metadata.IsSamlInTestMode = !org.metadata.IsSamlConfigured
+		return nil
+	})
+}
+
+func (o *FakeClient) SetOidcIdpMetadata(params models.SetOidcIdpMetadataRequest) (bool, error) {
+	var base models.SetOidcIdpMetadataRequestBase
+	switch request := params.(type) {
+	case models.SetGenericOidcMetadataRequest:
+		base = request.SetOidcIdpMetadataRequestBase
+	case models.SetOktaOidcMetadataRequest:
+		base = request.SetOidcIdpMetadataRequestBase
+	case models.SetAzureOidcMetadataRequest:
+		base = request.SetOidcIdpMetadataRequestBase
+	default:
+		return false, fakeBadRequest("idp_type", "Unknown OIDC provider")
+	}
+
+	return o.updateOrg(base.OrgID, func(org *fakeOrg) error {
+		if base.ClientID == "" {
+			return fakeBadRequest("client_id", "Client ID is required")
+		}
+		org.idpMetadataSet = true
+		org.metadata.IsSamlInTestMode = !org.metadata.IsSamlConfigured
+		return nil
+	})
+}
+
+func (o *FakeClient) SamlGoLive(orgId uuid.UUID) (bool, error) {
+	return o.updateOrg(orgId, func(org *fakeOrg) error {
+		if !org.idpMetadataSet {
+			return fakeBadRequest("org_id", "The identity provider has not been set up")
+		}
+		org.metadata.IsSamlConfigured = true
+		org.metadata.IsSamlInTestMode = false
+		return nil
+	})
+}
+
+func (o *FakeClient) DeleteSamlConnection(orgId uuid.UUID) (bool, error) {
+	return o.updateOrg(orgId, func(org *fakeOrg) error {
+		org.idpMetadataSet = false
+		org.metadata.IsSamlConfigured = false
+		org.metadata.IsSamlInTestMode = false
+		return nil
+	})
+}
+
+// Users in orgs
+
+func (o *FakeClient) AddUserToOrg(params models.AddUserToOrg) (bool, error) {
+	if err := o.lock(); err != nil {
+		return false, err
+	}
+	defer o.state.mu.Unlock()
+
+	if err := o.state.addMembership(params.UserID, params.OrgID, params.Role, params.AdditionalRoles); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (o *fakeState) addMembership(userID uuid.UUID, orgID uuid.UUID, role string, additionalRoles []string) error {
+	user, err := o.user(userID)
+	if err != nil {
+		return err
+	}
+	org, err := o.org(orgID)
+	if err != nil {
+		return err
+	}
+	if err := o.validateRole(role); err != nil {
+		return err
+	}
+	if _, ok := user.memberships[orgID]; ok {
+		return fakeBadRequest("user_id", "User is already in this org")
+	}
+	if org.metadata.MaxUsers != nil && len(o.members(orgID)) >= *org.metadata.MaxUsers {
+		return fakeBadRequest("org_id", "Org has reached its maximum number of users")
+	}
+
+	user.memberships[orgID] = &fakeMembership{role: role, additionalRoles: additionalRoles}
+
+	return nil
+}
+
+func (o *FakeClient) RemoveUserFromOrg(params models.RemoveUserFromOrg) (bool, error) {
+	if err := o.lock(); err != nil {
+		return false, err
+	}
+	defer o.state.mu.Unlock()
+
+	user, err := o.state.user(params.UserID)
+	if err != nil {
+		return false, err
+	}
+	if _, err := o.state.org(params.OrgID); err != nil {
+		return false, err
+	}
+	if _, ok := user.memberships[params.OrgID]; !ok {
+		return false, fakeBadRequest("user_id", "User is not in this org")
+	}
+
+	delete(user.memberships, params.OrgID)
+
+	return true, nil
+}
+
+func (o *FakeClient) ChangeUserRoleInOrg(params models.ChangeUserRoleInOrg) (bool, error) {
+	if err := o.lock(); err != nil {
+		return false, err
+	}
+	defer o.state.mu.Unlock()
+
+	user, err := o.state.user(params.UserID)
+	if err != nil {
+		return false, err
+	}
+	if _, err := o.state.org(params.OrgID); err != nil {
+		return false, err
+	}
+	if err := o.state.validateRole(params.Role); err != nil {
+		return false, err
+	}
+
+	membership, ok := user.memberships[params.OrgID]
+	if !ok {
+		return false, fakeBadRequest("user_id", "User is not in this org")
+	}
+	membership.role = params.Role
+	membership.additionalRoles = params.AdditionalRoles
+
+	return true, nil
+}
+
+func (o *FakeClient) FetchUsersInOrg(orgID uuid.UUID, params models.UserInOrgQueryParams) (*models.UserList, error) {
+	if err := o.lock(); err != nil {
+		return nil, err
+	}
+	defer o.state.mu.Unlock()
+
+	if _, err := o.state.org(orgID); err != nil {
+		return nil, err
+	}
+
+	users := []models.UserMetadata{}
+	for _, user := range o.state.members(orgID) {
+		if params.Role != nil && user.memberships[orgID].role != *params.Role {
+			continue
+		}
+		users = append(users, o.state.userMetadata(user, valueOr(params.IncludeOrgs, false)))
+	}
+
+	page, total, hasMoreResults := paginate(users, params.PageSize, params.PageNumber)
+
+	return &models.UserList{
+		TotalUsers:     total,
+		CurrentPage:    valueOr(params.PageNumber, 0),
+		PageSize:       valueOr(params.PageSize, fakeDefaultPageSize),
+		HasMoreResults: hasMoreResults,
+		Users:          page,
+	}, nil
+}
+
+// Invites
+
+func (o *FakeClient) InviteUserToOrg(params models.InviteUserToOrg) (bool, error) {
+	if err := o.lock(); err != nil {
+		return false, err
+	}
+	defer o.state.mu.Unlock()
+
+	if err := o.state.addInvite(params.Email, params.OrgID, params.Role, params.AdditionalRoles); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (o *FakeClient) InviteUserToOrgByUserID(params models.InviteUserToOrgByUserID) (bool, error) {
+	if err := o.lock(); err != nil {
+		return false, err
+	}
+	defer o.state.mu.Unlock()
+
+	user, err := o.state.user(params.UserID)
+	if err != nil {
+		return false, err
+	}
+
+	if err := o.state.addInvite(user.metadata.Email, params.OrgID, params.Role, params.AdditionalRoles); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// addInvite creates a pending invite, replacing any earlier invite for the same email and org.
+func (o *fakeState) addInvite(email string, orgID uuid.UUID, role string, additionalRoles []string) error {
+	org, err := o.org(orgID)
+	if err != nil {
+		return err
+	}
+	if err := o.validateRole(role); err != nil {
+		return err
+	}
+	if !strings.Contains(email, "@") {
+		return fakeBadRequest("email", "Invalid email address")
+	}
+	if user := o.userByEmail(email); user != nil {
+		if _, ok := user.memberships[orgID]; ok {
+			return fakeBadRequest("email", "User is already in this org")
+		}
+	}
+
+	o.removeInvite(email, orgID)
+
+	now := time.Now()
+	o.invites = append(o.invites, models.PendingInvite{
+		InviteeEmail:         email,
+		OrgID:                orgID,
+		OrgName:              org.metadata.Name,
+		RoleInOrg:            role,
+		AdditionalRolesInOrg: additionalRoles,
+		CreatedAt:            now.Unix(),
+		ExpiresAt:            now.Add(7 * 24 * time.Hour).Unix(),
+	})
+
+	return nil
+}
+
+// removeInvite removes the pending invite for the email and org, and returns it if there was one.
+func (o *fakeState) removeInvite(email string, orgID uuid.UUID) (models.PendingInvite, bool) {
+	for i, invite := range o.invites {
+		if invite.OrgID == orgID && strings.EqualFold(invite.InviteeEmail, email) {
+			o.invites = append(o.invites[:i], o.invites[i+1:]...)
+			return invite, true
+		}
+	}
+
+	return models.PendingInvite{}, false
+}
+
+func (o *FakeClient) FetchPendingInvites(params models.FetchPendingInvitesParams) (*models.PendingInvitesPage, error) {
+	if err := o.lock(); err != nil {
+		return nil, err
+	}
+	defer o.state.mu.Unlock()
+
+	invites := []models.PendingInvite{}
+	for _, invite := range o.state.invites {
+		if params.OrgID == nil || invite.OrgID == *params.OrgID {
+			invites = append(invites, invite)
+		}
+	}
+
+	page, total, hasMoreResults := paginate(invites, params.PageSize, params.PageNumber)
+
+	return &models.PendingInvitesPage{
+		TotalInvites:   total,
+		CurrentPage:    valueOr(params.PageNumber, 0),
+		PageSize:       valueOr(params.PageSize, fakeDefaultPageSize),
+		HasMoreResults: hasMoreResults,
+		Invites:        page,
+	}, nil
+}
+
+func (o *FakeClient) RevokePendingOrgInvite(params models.RevokePendingOrgInvite) (bool, error) {
+	if err := o.lock(); err != nil {
+		return false, err
+	}
+	defer o.state.mu.Unlock()
+
+	if params.OrgID == nil {
+		return false, fakeBadRequest("org_id", "Org ID is required")
+	}
+	if _, ok := o.state.removeInvite(params.InviteeEmail, *params.OrgID); !ok {
+		return false, fakeNotFound("Invite not found")
+	}
+
+	return true, nil
+}
+
+// AcceptInvite simulates the invitee accepting a pending invite. If there's no user with the email yet, one is
+// created, like when someone signs up from an invite.
+func (o *FakeClient) AcceptInvite(orgID uuid.UUID, email string) (*models.UserID, error) {
+	if err := o.lock(); err != nil {
+		return nil, err
+	}
+	defer o.state.mu.Unlock()
+
+	invite, ok := o.state.removeInvite(email, orgID)
+	if !ok {
+		return nil, fakeNotFound("Invite not found")
+	}
+
+	user := o.state.userByEmail(email)
+	if user == nil {
+		var err error
+		user, err = o.state.addUser(models.UserMetadata{Email: email, EmailConfirmed: true, Enabled: true})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := o.state.addMembership(user.metadata.UserID, orgID, invite.RoleInOrg, invite.AdditionalRolesInOrg); err != nil {
+		return nil, err
+	}
+
+	return &models.UserID{UserID: user.metadata.UserID}, nil
+}
+
+// SCIM, which the fake doesn't support, so orgs never have any groups
+
+func (o *FakeClient) FetchOrgScimGroups(params models.FetchOrgScimGroupsRequest) (*models.ScimGroupResultPage, error) {
+	if err := o.lock(); err != nil {
+		return nil, err
+	}
+	defer o.state.mu.Unlock()
+
+	if _, err := o.state.org(params.OrgID); err != nil {
+		return nil, err
+	}
+
+	return &models.ScimGroupResultPage{
+		Groups:     []models.ScimGroupResult{},
+		PageNumber: valueOr(params.PageNumber, 0),
+		PageSize:   valueOr(params.PageSize, fakeDefaultPageSize),
+	}, nil
+}
+
+func (o *FakeClient) FetchScimGroup(params models.FetchScimGroupRequest) (*models.ScimGroup, error) {
+	return nil, fakeNotFound("SCIM group not found")
+}
@@ -0,0 +1,261 @@
+package test
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	propelauth "github.com/propelauth/propelauth-go/pkg"
+	"github.com/propelauth/propelauth-go/pkg/models"
+)
+
+const fakeBackendAPIPath = "/api/backend/v1/"
+const fakeTokenVerificationPath = "/api/v1/token_verification_metadata"
+
+// FakeServer is an HTTP server that implements the PropelAuth backend API on top of a FakeClient, so tests can
+// exercise the real client, including how requests are sent, retried and decoded. Point a client at it with
+// NewClient, or by passing propelauth.WithBackendOrigin(server.URL) to propelauth.InitBaseAuth.
+//
+// Data can be set up through the API or directly through Fake. Faults, like rate limits, slow responses and
+// malformed bodies, can be injected with InjectFault.
+type FakeServer struct {
+	*httptest.Server
+
+	// IntegrationAPIKey is the only API key the server accepts, every other key gets a 401.
+	IntegrationAPIKey string
+
+	fake     *FakeClient
+	mu       sync.Mutex
+	faults   []*FakeServerFault
+	requests []FakeServerRequest
+}
+
+// FakeServerFault is injected into the responses of the requests it matches, instead of the normal response.
+type FakeServerFault struct {
+	// Method matches the request method, or any method if it's empty.
+	Method string
+	// PathPrefix matches the start of the request path, like "/api/backend/v1/user/", or any path if it's empty.
+	PathPrefix string
+	// Times is how many requests the fault applies to, after which it's removed. If it's 0, it applies to every
+	// matching request.
+	Times int
+
+	// Delay is how long to wait before responding. If the client gives up first, no response is sent.
+	Delay time.Duration
+	// StatusCode, Header and Body are the response to send instead of the normal one. If StatusCode is 0, the
+	// request is handled normally once the Delay has passed.
+	StatusCode int
+	Header     http.Header
+	Body       string
+}
+
+// FakeServerRequest is a request the server received.
+type FakeServerRequest struct {
+	Method string
+	Path   string
+	Query  url.Values
+	Body   []byte
+}
+
+// NewFakeServer starts a server backed by a new FakeClient created with the options. Call Close when you're done
+// with it.
+func NewFakeServer(opts ...FakeClientOption) *FakeServer {
+	server := &FakeServer{
+		IntegrationAPIKey: randomHex(32),
+		fake:              NewFakeClient(opts...),
+	}
+	server.Server = httptest.NewServer(http.HandlerFunc(server.serveHTTP))
+
+	return server
+}
+
+// Fake returns the FakeClient that holds the server's data, to set up or inspect it without going through HTTP.
+func (o *FakeServer) Fake() *FakeClient {
+	return o.fake
+}
+
+// AuthURL returns the auth URL the server's access tokens are issued by. Pass it to propelauth.InitBaseAuth.
+func (o *FakeServer) AuthURL() string {
+	return o.fake.state.authURL
+}
+
+// NewClient creates a real client that talks to the server. It fetches its token verification metadata from the
+// server, so it validates the access tokens the server creates.
+func (o *FakeServer) NewClient(opts ...propelauth.ClientOption) (propelauth.ClientInterface, error) {
+	opts = append(opts, propelauth.WithBackendOrigin(o.URL), propelauth.WithAllowInsecureHTTP())
+
+	return propelauth.InitBaseAuth(o.AuthURL(), o.IntegrationAPIKey, nil, opts...)
+}
+
+// InjectFault adds a fault. Faults are checked in the order they were added, and the first match applies.
+func (o *FakeServer) InjectFault(fault FakeServerFault) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.faults = append(o.faults, &fault)
+}
+
+// ClearFaults removes every fault, so requests are handled normally again.
+func (o *FakeServer) ClearFaults() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.faults = nil
+}
+
+// Requests returns the requests the server received, oldest first, including ones that got a fault.
+func (o *FakeServer) Requests() []FakeServerRequest {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return append([]FakeServerRequest{}, o.requests...)
+}
+
+func (o *FakeServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "couldn't read the request body", http.StatusBadRequest)
+		return
+	}
+
+	fault := o.recordRequest(r, body)
+	if fault != nil {
+		if fault.Delay > 0 {
+			timer := time.NewTimer(fault.Delay)
+			defer timer.Stop()
+
+			select {
+			case <-timer.C:
+			case <-r.Context().Done():
+				return
+			}
+		}
+
+		if fault.StatusCode != 0 {
+			for name, values := range fault.Header {
+				w.Header()[name] = values
+			}
+			w.WriteHeader(fault.StatusCode)
+			_, _ = io.WriteString(w, fault.Body)
+			return
+		}
+	}
+
+	if r.Header.Get("Authorization") != "Bearer "+o.IntegrationAPIKey {
+		writeFakeError(w, models.NewAPIError(401, nil, "API Key is incorrect", nil))
+		return
+	}
+
+	// the client is bound to the request, so the fake stops work for requests that were cancelled
+	client := &FakeClient{ctx: r.Context(), state: o.fake.state}
+
+	if r.URL.Path == fakeTokenVerificationPath && r.Method == http.MethodGet {
+		publicKey, err := x509.MarshalPKIXPublicKey(&o.fake.state.privateKey.PublicKey)
+		if err != nil {
+			writeFakeError(w, err)
+			return
+		}
+		writeFakeJSON(w, models.AuthTokenVerificationMetadataResponse{
+			VerifierKeyPem: string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKey})),
+		})
+		return
+	}
+
+	if !strings.HasPrefix(r.URL.Path, fakeBackendAPIPath) {
+		writeFakeError(w, fakeNotFound("Route not found"))
+		return
+	}
+
+	request := &fakeRequest{
+		client: client,
+		query:  r.URL.Query(),
+		body:   body,
+	}
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, fakeBackendAPIPath), "/")
+
+	for _, route := range fakeRoutes {
+		if route.method != r.Method {
+			continue
+		}
+		params, ok := matchFakeRoute(route.pattern, path)
+		if !ok {
+			continue
+		}
+
+		request.params = params
+		response, err := route.handle(request)
+		if err != nil {
+			writeFakeError(w, err)
+			return
+		}
+		writeFakeJSON(w, response)
+		return
+	}
+
+	writeFakeError(w, fakeNotFound("Route not found"))
+}
+
+// recordRequest adds the request to the log, and returns the fault to apply to it, if any.
+func (o *FakeServer) recordRequest(r *http.Request, body []byte) *FakeServerFault {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.requests = append(o.requests, FakeServerRequest{
+		Method: r.Method,
+		Path:   r.URL.Path,
+		Query:  r.URL.Query(),
+		Body:   body,
+	})
+
+	for i, fault := range o.faults {
+		if fault.Method != "" && fault.Method != r.Method {
+			continue
+		}
+		if !strings.HasPrefix(r.URL.Path, fault.PathPrefix) {
+			continue
+		}
+
+		if fault.Times > 0 {
+			fault.Times--
+			if fault.Times == 0 {
+				o.faults = append(o.faults[:i], o.faults[i+1:]...)
+			}
+		}
+
+		return fault
+	}
+
+	return nil
+}
+
+func writeFakeJSON(w http.ResponseWriter, response interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// writeFakeError responds with the status code and body of an APIError. Any other error is a 500.
+func writeFakeError(w http.ResponseWriter, err error) {
+	apiError := &models.APIError{}
+	if !errors.As(err, &apiError) {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	body := apiError.Body
+	if body == "" {
+		bodyJSON, _ := json.Marshal(map[string]string{"error": apiError.Error()})
+		body = string(bodyJSON)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(apiError.StatusCode)
+	_, _ = io.WriteString(w, body)
+}
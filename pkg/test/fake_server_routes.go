@@ -0,0 +1,781 @@
+package test
+
+import (
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/propelauth/propelauth-go/pkg/models"
+)
+
+// fakeRequest is what a route handler gets: the fake bound to the request, the path parameters matched by the
+// route's {} segments, the query and the body.
+type fakeRequest struct {
+	client *FakeClient
+	params []string
+	query  url.Values
+	body   []byte
+}
+
+type fakeRoute struct {
+	method  string
+	pattern string
+	handle  func(r *fakeRequest) (interface{}, error)
+}
+
+// fakeRoutes are the backend routes, relative to /api/backend/v1/ and without trailing slashes. They're checked
+// in order, so routes with fixed segments, like user/email, come before the ones they overlap with, like user/{}.
+var fakeRoutes = []fakeRoute{
+	// users
+	{"GET", "user/email", func(r *fakeRequest) (interface{}, error) {
+		return r.client.FetchUserMetadataByEmail(r.query.Get("email"), r.boolQuery("include_orgs"))
+	}},
+	{"GET", "user/username", func(r *fakeRequest) (interface{}, error) {
+		return r.client.FetchUserMetadataByUsername(r.query.Get("username"), r.boolQuery("include_orgs"))
+	}},
+	{"GET", "user/query", func(r *fakeRequest) (interface{}, error) {
+		params := models.UserQueryParams{
+			OrderBy:         r.stringQuery("order_by"),
+			EmailOrUsername: r.stringQuery("email_or_username"),
+			IncludeOrgs:     r.boolPointerQuery("include_orgs"),
+		}
+		if err := r.pageQuery(&params.PageSize, &params.PageNumber); err != nil {
+			return nil, err
+		}
+		return r.client.FetchUsersByQuery(params)
+	}},
+	{"POST", "user/user_ids", func(r *fakeRequest) (interface{}, error) {
+		body := struct {
+			UserIds []uuid.UUID `json:"user_ids"`
+		}{}
+		if err := r.decode(&body); err != nil {
+			return nil, err
+		}
+		users, err := r.client.FetchBatchUserMetadataByUserIds(body.UserIds, r.boolQuery("include_orgs"))
+		return batchOf(body.UserIds, users), err
+	}},
+	{"POST", "user/emails", func(r *fakeRequest) (interface{}, error) {
+		body := struct {
+			Emails []string `json:"emails"`
+		}{}
+		if err := r.decode(&body); err != nil {
+			return nil, err
+		}
+		users, err := r.client.FetchBatchUserMetadataByEmails(body.Emails, r.boolQuery("include_orgs"))
+		return batchOf(body.Emails, users), err
+	}},
+	{"POST", "user/usernames", func(r *fakeRequest) (interface{}, error) {
+		body := struct {
+			Usernames []string `json:"usernames"`
+		}{}
+		if err := r.decode(&body); err != nil {
+			return nil, err
+		}
+		users, err := r.client.FetchBatchUserMetadataByUsernames(body.Usernames, r.boolQuery("include_orgs"))
+		return batchOf(body.Usernames, users), err
+	}},
+	{"GET", "user/org/{}", func(r *fakeRequest) (interface{}, error) {
+		orgID, err := r.uuidParam(0)
+		if err != nil {
+			return nil, err
+		}
+		params := models.UserInOrgQueryParams{
+			IncludeOrgs: r.boolPointerQuery("include_orgs"),
+			Role:        r.stringQuery("role"),
+		}
+		if err := r.pageQuery(&params.PageSize, &params.PageNumber); err != nil {
+			return nil, err
+		}
+		return r.client.FetchUsersInOrg(orgID, params)
+	}},
+	{"POST", "user", func(r *fakeRequest) (interface{}, error) {
+		params := models.CreateUserParams{}
+		if err := r.decode(&params); err != nil {
+			return nil, err
+		}
+		return r.client.CreateUser(params)
+	}},
+	{"GET", "user/{}", func(r *fakeRequest) (interface{}, error) {
+		userID, err := r.uuidParam(0)
+		if err != nil {
+			return nil, err
+		}
+		return r.client.FetchUserMetadataByUserID(userID, r.boolQuery("include_orgs"))
+	}},
+	{"PUT", "user/{}", func(r *fakeRequest) (interface{}, error) {
+		params := models.UpdateUserMetadata{}
+		if err := r.decode(&params); err != nil {
+			return nil, err
+		}
+		return r.userAction(func(userID uuid.UUID) (bool, error) {
+			return r.client.UpdateUserMetadata(userID, params)
+		})
+	}},
+	{"PUT", "user/{}/email", func(r *fakeRequest) (interface{}, error) {
+		params := models.UpdateEmail{}
+		if err := r.decode(&params); err != nil {
+			return nil, err
+		}
+		return r.userAction(func(userID uuid.UUID) (bool, error) {
+			return r.client.UpdateUserEmail(userID, params)
+		})
+	}},
+	{"PUT", "user/{}/password", func(r *fakeRequest) (interface{}, error) {
+		params := models.UpdateUserPasswordParam{}
+		if err := r.decode(&params); err != nil {
+			return nil, err
+		}
+		return r.userAction(func(userID uuid.UUID) (bool, error) {
+			return r.client.UpdateUserPassword(userID, params)
+		})
+	}},
+	{"PUT", "user/{}/clear_password", func(r *fakeRequest) (interface{}, error) {
+		return r.userAction(r.client.ClearUserPassword)
+	}},
+	{"DELETE", "user/{}", func(r *fakeRequest) (interface{}, error) {
+		return r.userAction(r.client.DeleteUser)
+	}},
+	{"POST", "user/{}/disable", func(r *fakeRequest) (interface{}, error) {
+		return r.userAction(r.client.DisableUser)
+	}},
+	{"POST", "user/{}/enable", func(r *fakeRequest) (interface{}, error) {
+		return r.userAction(r.client.EnableUser)
+	}},
+	{"PUT", "user/{}/can_create_orgs/enable", func(r *fakeRequest) (interface{}, error) {
+		return r.userAction(r.client.EnableUserCanCreateOrgs)
+	}},
+	{"PUT", "user/{}/can_create_orgs/disable", func(r *fakeRequest) (interface{}, error) {
+		return r.userAction(r.client.DisableUserCanCreateOrgs)
+	}},
+	{"POST", "user/{}/disable_2fa", func(r *fakeRequest) (interface{}, error) {
+		return r.userAction(r.client.DisableUser2fa)
+	}},
+	{"POST", "user/{}/logout_all_sessions", func(r *fakeRequest) (interface{}, error) {
+		return r.userAction(r.client.LogoutAllUserSessions)
+	}},
+	{"GET", "user/{}/mfa", func(r *fakeRequest) (interface{}, error) {
+		userID, err := r.uuidParam(0)
+		if err != nil {
+			return nil, err
+		}
+		return r.client.FetchUserMfaMethods(userID)
+	}},
+	{"GET", "user/{}/signup_query_parameters", func(r *fakeRequest) (interface{}, error) {
+		userID, err := r.uuidParam(0)
+		if err != nil {
+			return nil, err
+		}
+		return r.client.FetchUserSignupQueryParameters(userID)
+	}},
+	{"GET", "user/{}/oauth_token", func(r *fakeRequest) (interface{}, error) {
+		userID, err := r.uuidParam(0)
+		if err != nil {
+			return nil, err
+		}
+		return r.client.FetchUserOAuthTokens(userID)
+	}},
+	{"GET", "user/{}/{}/fresh_token", func(r *fakeRequest) (interface{}, error) {
+		userID, err := r.uuidParam(0)
+		if err != nil {
+			return nil, err
+		}
+		return r.client.FetchFreshTokenFromProvider(userID, models.SocialLoginTokenProvider(r.params[1]))
+	}},
+	{"POST", "migrate_user", func(r *fakeRequest) (interface{}, error) {
+		params := models.MigrateUserParams{}
+		if err := r.decode(&params); err != nil {
+			return nil, err
+		}
+		return fakeOK(r.client.MigrateUserFromExternalSource(params))
+	}},
+	{"POST", "migrate_user/password", func(r *fakeRequest) (interface{}, error) {
+		params := models.MigrateUserPasswordParams{}
+		if err := r.decode(&params); err != nil {
+			return nil, err
+		}
+		return fakeOK(r.client.MigrateUserPassword(params))
+	}},
+	{"POST", "resend_email_confirmation", func(r *fakeRequest) (interface{}, error) {
+		body := struct {
+			UserID uuid.UUID `json:"user_id"`
+		}{}
+		if err := r.decode(&body); err != nil {
+			return nil, err
+		}
+		return fakeOK(r.client.ResendEmailConfirmation(body.UserID))
+	}},
+	{"GET", "employee/{}", func(r *fakeRequest) (interface{}, error) {
+		employeeID, err := r.uuidParam(0)
+		if err != nil {
+			return nil, err
+		}
+		return r.client.FetchEmployeeByID(employeeID)
+	}},
+	{"POST", "magic_link", func(r *fakeRequest) (interface{}, error) {
+		params := models.CreateMagicLinkParams{}
+		if err := r.decode(&params); err != nil {
+			return nil, err
+		}
+		return r.client.CreateMagicLink(params)
+	}},
+	{"POST", "access_token", func(r *fakeRequest) (interface{}, error) {
+		body := struct {
+			UserID            uuid.UUID  `json:"user_id"`
+			DurationInMinutes int        `json:"duration_in_minutes"`
+			ActiveOrgId       *uuid.UUID `json:"active_org_id"`
+		}{}
+		if err := r.decode(&body); err != nil {
+			return nil, err
+		}
+		return r.client.CreateAccessToken(body.UserID, body.DurationInMinutes, models.CreateAccessTokenOptions{ActiveOrgId: body.ActiveOrgId})
+	}},
+
+	// orgs
+	{"POST", "org/add_user", func(r *fakeRequest) (interface{}, error) {
+		params := models.AddUserToOrg{}
+		if err := r.decode(&params); err != nil {
+			return nil, err
+		}
+		return fakeOK(r.client.AddUserToOrg(params))
+	}},
+	{"POST", "org/remove_user", func(r *fakeRequest) (interface{}, error) {
+		params := models.RemoveUserFromOrg{}
+		if err := r.decode(&params); err != nil {
+			return nil, err
+		}
+		return fakeOK(r.client.RemoveUserFromOrg(params))
+	}},
+	{"POST", "org/change_role", func(r *fakeRequest) (interface{}, error) {
+		params := models.ChangeUserRoleInOrg{}
+		if err := r.decode(&params); err != nil {
+			return nil, err
+		}
+		return fakeOK(r.client.ChangeUserRoleInOrg(params))
+	}},
+	{"POST", "org/query", func(r *fakeRequest) (interface{}, error) {
+		params := models.OrgQueryParams{}
+		if err := r.decode(&params); err != nil {
+			return nil, err
+		}
+		return r.client.FetchOrgByQuery(params)
+	}},
+	{"POST", "org", func(r *fakeRequest) (interface{}, error) {
+		// CreateOrg and CreateOrgV2 share this route, so respond with the fields of both
+		params := models.CreateOrgV2Params{}
+		if err := r.decode(&params); err != nil {
+			return nil, err
+		}
+		org, err := r.client.CreateOrgV2(params)
+		if err != nil {
+			return nil, err
+		}
+		r.client.state.mu.Lock()
+		defer r.client.state.mu.Unlock()
+		return r.client.state.orgs[org.OrgID].orgMetadata(), nil
+	}},
+	{"GET", "org/{}", func(r *fakeRequest) (interface{}, error) {
+		orgID, err := r.uuidParam(0)
+		if err != nil {
+			return nil, err
+		}
+		return r.client.FetchOrg(orgID)
+	}},
+	{"PUT", "org/{}", func(r *fakeRequest) (interface{}, error) {
+		// UpdateOrgMetadata and SubscribeOrgToRoleMapping share this route, only the subscription sends nothing
+		// but the custom role mapping
+		fields := map[string]json.RawMessage{}
+		if err := r.decode(&fields); err != nil {
+			return nil, err
+		}
+		if _, ok := fields["custom_role_mapping_name"]; ok && len(fields) == 1 {
+			params := models.OrgRoleMappingSubscription{}
+			if err := r.decode(&params); err != nil {
+				return nil, err
+			}
+			return r.orgAction(func(orgID uuid.UUID) (bool, error) {
+				return r.client.SubscribeOrgToRoleMapping(orgID, params)
+			})
+		}
+
+		params := models.UpdateOrg{}
+		if err := r.decode(&params); err != nil {
+			return nil, err
+		}
+		return r.orgAction(func(orgID uuid.UUID) (bool, error) {
+			return r.client.UpdateOrgMetadata(orgID, params)
+		})
+	}},
+	{"DELETE", "org/{}", func(r *fakeRequest) (interface{}, error) {
+		return r.orgAction(r.client.DeleteOrg)
+	}},
+	{"POST", "org/{}/allow_saml", func(r *fakeRequest) (interface{}, error) {
+		return r.orgAction(r.client.AllowOrgToSetupSamlConnection)
+	}},
+	{"POST", "org/{}/disallow_saml", func(r *fakeRequest) (interface{}, error) {
+		return r.orgAction(r.client.DisallowOrgToSetupSamlConnection)
+	}},
+	{"POST", "org/{}/create_saml_connection_link", func(r *fakeRequest) (interface{}, error) {
+		orgID, err := r.uuidParam(0)
+		if err != nil {
+			return nil, err
+		}
+		params := models.CreateSamlConnectionLinkBody{}
+		if err := r.decode(&params); err != nil {
+			return nil, err
+		}
+		return r.client.CreateOrgSamlConnectionLink(orgID, params)
+	}},
+	{"GET", "custom_role_mappings", func(r *fakeRequest) (interface{}, error) {
+		return r.client.FetchCustomRoleMappings()
+	}},
+	{"POST", "invite_user", func(r *fakeRequest) (interface{}, error) {
+		params := models.InviteUserToOrg{}
+		if err := r.decode(&params); err != nil {
+			return nil, err
+		}
+		return fakeOK(r.client.InviteUserToOrg(params))
+	}},
+	{"POST", "invite_user_by_id", func(r *fakeRequest) (interface{}, error) {
+		params := models.InviteUserToOrgByUserID{}
+		if err := r.decode(&params); err != nil {
+			return nil, err
+		}
+		return fakeOK(r.client.InviteUserToOrgByUserID(params))
+	}},
+	{"GET", "pending_org_invites", func(r *fakeRequest) (interface{}, error) {
+		params := models.FetchPendingInvitesParams{}
+		if err := r.pageQuery(&params.PageSize, &params.PageNumber); err != nil {
+			return nil, err
+		}
+		orgID, err := r.uuidQuery("org_id")
+		if err != nil {
+			return nil, err
+		}
+		params.OrgID = orgID
+		return r.client.FetchPendingInvites(params)
+	}},
+	{"DELETE", "pending_org_invites", func(r *fakeRequest) (interface{}, error) {
+		params := models.RevokePendingOrgInvite{}
+		if err := r.decode(&params); err != nil {
+			return nil, err
+		}
+		return fakeOK(r.client.RevokePendingOrgInvite(params))
+	}},
+	{"GET", "saml_sp_metadata/{}", func(r *fakeRequest) (interface{}, error) {
+		orgID, err := r.uuidParam(0)
+		if err != nil {
+			return nil, err
+		}
+		return r.client.FetchSamlSpMetadata(orgID)
+	}},
+	{"POST", "saml_idp_metadata", func(r *fakeRequest) (interface{}, error) {
+		params := models.SamlIdpMetadata{}
+		if err := r.decode(&params); err != nil {
+			return nil, err
+		}
+		return fakeOK(r.client.SetSamlIdpMetadata(params))
+	}},
+	{"POST", "saml_idp_metadata/go_live/{}", func(r *fakeRequest) (interface{}, error) {
+		return r.orgAction(r.client.SamlGoLive)
+	}},
+	{"DELETE", "saml_idp_metadata/{}", func(r *fakeRequest) (interface{}, error) {
+		return r.orgAction(r.client.DeleteSamlConnection)
+	}},
+	{"POST", "oidc_idp_metadata", func(r *fakeRequest) (interface{}, error) {
+		base := models.SetOidcIdpMetadataRequestBase{}
+		if err := r.decode(&base); err != nil {
+			return nil, err
+		}
+
+		var err error
+		switch base.IdpType {
+		case models.OidcIdpTypeGeneric:
+			params := models.SetGenericOidcMetadataRequest{}
+			err = r.decode(&params)
+			if err == nil {
+				return fakeOK(r.client.SetOidcIdpMetadata(params))
+			}
+		case models.OidcIdpTypeOkta:
+			params := models.SetOktaOidcMetadataRequest{}
+			err = r.decode(&params)
+			if err == nil {
+				return fakeOK(r.client.SetOidcIdpMetadata(params))
+			}
+		case models.OidcIdpTypeAzure:
+			params := models.SetAzureOidcMetadataRequest{}
+			err = r.decode(&params)
+			if err == nil {
+				return fakeOK(r.client.SetOidcIdpMetadata(params))
+			}
+		default:
+			err = fakeBadRequest("idp_type", "Unknown OIDC provider")
+		}
+
+		return nil, err
+	}},
+	{"GET", "scim/{}/groups", func(r *fakeRequest) (interface{}, error) {
+		orgID, err := r.uuidParam(0)
+		if err != nil {
+			return nil, err
+		}
+		params := models.FetchOrgScimGroupsRequest{OrgID: orgID}
+		if err := r.pageQuery(&params.PageSize, &params.PageNumber); err != nil {
+			return nil, err
+		}
+		if params.UserID, err = r.uuidQuery("user_id"); err != nil {
+			return nil, err
+		}
+		return r.client.FetchOrgScimGroups(params)
+	}},
+	{"GET", "scim/{}/groups/{}", func(r *fakeRequest) (interface{}, error) {
+		orgID, err := r.uuidParam(0)
+		if err != nil {
+			return nil, err
+		}
+		groupID, err := r.uuidParam(1)
+		if err != nil {
+			return nil, err
+		}
+		params := models.FetchScimGroupRequest{OrgID: orgID, GroupID: groupID}
+		if params.MembersPageSize, err = r.intQuery("members_page_size"); err != nil {
+			return nil, err
+		}
+		if params.MembersPageNumber, err = r.intQuery("members_page_number"); err != nil {
+			return nil, err
+		}
+		return r.client.FetchScimGroup(params)
+	}},
+
+	// API keys
+	{"GET", "end_user_api_keys", func(r *fakeRequest) (interface{}, error) {
+		params, err := r.apiKeysQuery()
+		if err != nil {
+			return nil, err
+		}
+		return r.client.FetchCurrentAPIKeys(params)
+	}},
+	{"GET", "end_user_api_keys/archived", func(r *fakeRequest) (interface{}, error) {
+		params, err := r.apiKeysQuery()
+		if err != nil {
+			return nil, err
+		}
+		return r.client.FetchArchivedAPIKeys(params)
+	}},
+	{"GET", "end_user_api_keys/usage", func(r *fakeRequest) (interface{}, error) {
+		params := models.FetchAPIKeyUsageParams{
+			Date:     r.query.Get("date"),
+			APIKeyID: r.stringQuery("api_key_id"),
+		}
+		var err error
+		if params.OrgID, err = r.uuidQuery("org_id"); err != nil {
+			return nil, err
+		}
+		if params.UserID, err = r.uuidQuery("user_id"); err != nil {
+			return nil, err
+		}
+		return r.client.FetchAPIKeyUsage(params)
+	}},
+	{"POST", "end_user_api_keys", func(r *fakeRequest) (interface{}, error) {
+		params := models.APIKeyCreateParams{}
+		if err := r.decode(&params); err != nil {
+			return nil, err
+		}
+		return r.client.CreateAPIKey(params)
+	}},
+	{"POST", "end_user_api_keys/import", func(r *fakeRequest) (interface{}, error) {
+		params := models.APIKeyImportParams{}
+		if err := r.decode(&params); err != nil {
+			return nil, err
+		}
+		return r.client.ImportAPIKey(params)
+	}},
+	{"POST", "end_user_api_keys/validate", func(r *fakeRequest) (interface{}, error) {
+		token, err := r.apiKeyToken()
+		if err != nil {
+			return nil, err
+		}
+		return r.client.ValidateAPIKey(token)
+	}},
+	{"POST", "end_user_api_keys/validate_imported", func(r *fakeRequest) (interface{}, error) {
+		token, err := r.apiKeyToken()
+		if err != nil {
+			return nil, err
+		}
+		return r.client.ValidateImportedAPIKey(token)
+	}},
+	{"GET", "end_user_api_keys/{}", func(r *fakeRequest) (interface{}, error) {
+		return r.client.FetchAPIKey(r.params[0])
+	}},
+	{"PATCH", "end_user_api_keys/{}", func(r *fakeRequest) (interface{}, error) {
+		params := models.APIKeyUpdateParams{}
+		if err := r.decode(&params); err != nil {
+			return nil, err
+		}
+		return fakeOK(r.client.UpdateAPIKey(r.params[0], params))
+	}},
+	{"DELETE", "end_user_api_keys/{}", func(r *fakeRequest) (interface{}, error) {
+		return fakeOK(r.client.DeleteAPIKey(r.params[0]))
+	}},
+
+	// step up MFA
+	{"POST", "mfa/step-up/verify-grant", func(r *fakeRequest) (interface{}, error) {
+		params := models.VerifyStepUpGrantRequest{}
+		if err := r.decode(&params); err != nil {
+			return nil, err
+		}
+		response, err := r.client.VerifyStepUpGrant(params)
+		if err != nil {
+			return nil, err
+		}
+		if !response.Success {
+			// the backend says a grant is invalid with a 400, not with success: false
+			body, _ := json.Marshal(map[string]interface{}{
+				"error_code":      "invalid_request_fields",
+				"field_to_errors": map[string]string{"grant": "grant_not_found"},
+			})
+			return nil, models.NewAPIError(400, body, "Grant not found", nil)
+		}
+		return response, nil
+	}},
+	{"POST", "mfa/step-up/verify-totp", func(r *fakeRequest) (interface{}, error) {
+		params := models.VerifyTotpChallengeRequest{}
+		if err := r.decode(&params); err != nil {
+			return nil, err
+		}
+		return r.client.VerifyStepUpTotpChallenge(params)
+	}},
+	{"POST", "mfa/step-up/phone/send", func(r *fakeRequest) (interface{}, error) {
+		params := models.SendSmsMfaCodeRequest{}
+		if err := r.decode(&params); err != nil {
+			return nil, err
+		}
+		return r.client.SendSmsMfaCode(params)
+	}},
+	{"POST", "mfa/step-up/phone/verify", func(r *fakeRequest) (interface{}, error) {
+		params := models.VerifySmsChallengeRequest{}
+		if err := r.decode(&params); err != nil {
+			return nil, err
+		}
+		return r.client.VerifySmsChallenge(params)
+	}},
+
+	// user insights
+	{"GET", "user_report/{}", func(r *fakeRequest) (interface{}, error) {
+		fetchReport, ok := map[string]func(*string, *models.ReportPagination) (*models.UserReport, error){
+			"top_inviter":  r.client.FetchUserTopInviterReport,
+			"champion":     r.client.FetchUserChampionReport,
+			"churn":        r.client.FetchUserChurnReport,
+			"reengagement": r.client.FetchUserReengagementReport,
+		}[r.params[0]]
+		if !ok {
+			return nil, fakeNotFound("Report not found")
+		}
+		pagination, err := r.reportPagination()
+		if err != nil {
+			return nil, err
+		}
+		return fetchReport(r.stringQuery("report_interval"), pagination)
+	}},
+	{"GET", "org_report/{}", func(r *fakeRequest) (interface{}, error) {
+		fetchReport, ok := map[string]func(*string, *models.ReportPagination) (*models.OrgReport, error){
+			"growth":       r.client.FetchOrgGrowthReport,
+			"attrition":    r.client.FetchOrgAttritionReport,
+			"churn":        r.client.FetchOrgChurnReport,
+			"reengagement": r.client.FetchOrgReengagementReport,
+		}[r.params[0]]
+		if !ok {
+			return nil, fakeNotFound("Report not found")
+		}
+		pagination, err := r.reportPagination()
+		if err != nil {
+			return nil, err
+		}
+		return fetchReport(r.stringQuery("report_interval"), pagination)
+	}},
+	{"GET", "chart_metrics/{}", func(r *fakeRequest) (interface{}, error) {
+		chartRange := &models.ChartRange{StartDate: r.stringQuery("start_date"), EndDate: r.stringQuery("end_date")}
+		return r.client.FetchChartMetricData(r.params[0], r.stringQuery("cadence"), chartRange)
+	}},
+}
+
+// matchFakeRoute checks the path against a pattern, where each {} matches any one segment, and returns the
+// segments the {}s matched.
+func matchFakeRoute(pattern string, path string) ([]string, bool) {
+	patternSegments := strings.Split(pattern, "/")
+	pathSegments := strings.Split(path, "/")
+	if len(patternSegments) != len(pathSegments) {
+		return nil, false
+	}
+
+	params := []string{}
+	for i, segment := range patternSegments {
+		if segment == "{}" {
+			params = append(params, pathSegments[i])
+		} else if segment != pathSegments[i] {
+			return nil, false
+		}
+	}
+
+	return params, true
+}
+
+// fakeOK turns the result of a method that returns a bool into a response. The client only looks at the status
+// code, so the body is empty.
+func fakeOK(_ bool, err error) (interface{}, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	return struct{}{}, nil
+}
+
+// batchOf lists the users that were found, in the order they were asked for, like the batch endpoints do.
+func batchOf[K comparable](keys []K, users map[K]models.UserMetadata) []models.UserMetadata {
+	batch := []models.UserMetadata{}
+	for _, key := range keys {
+		if user, ok := users[key]; ok {
+			batch = append(batch, user)
+			delete(users, key)
+		}
+	}
+
+	return batch
+}
+
+// request parsing
+
+func (o *fakeRequest) decode(v interface{}) error {
+	if err := json.Unmarshal(o.body, v); err != nil {
+		return fakeBadRequest("body", "Invalid JSON: "+err.Error())
+	}
+
+	return nil
+}
+
+func (o *fakeRequest) uuidParam(index int) (uuid.UUID, error) {
+	id, err := uuid.Parse(o.params[index])
+	if err != nil {
+		return uuid.Nil, fakeNotFound("Invalid ID")
+	}
+
+	return id, nil
+}
+
+func (o *fakeRequest) userAction(action func(userID uuid.UUID) (bool, error)) (interface{}, error) {
+	userID, err := o.uuidParam(0)
+	if err != nil {
+		return nil, err
+	}
+
+	return fakeOK(action(userID))
+}
+
+func (o *fakeRequest) orgAction(action func(orgID uuid.UUID) (bool, error)) (interface{}, error) {
+	orgID, err := o.uuidParam(0)
+	if err != nil {
+		return nil, err
+	}
+
+	return fakeOK(action(orgID))
+}
+
+func (o *fakeRequest) apiKeyToken() (string, error) {
+	body := struct {
+		APIKeyToken string `json:"api_key_token"`
+	}{}
+	if err := o.decode(&body); err != nil {
+		return "", err
+	}
+
+	return body.APIKeyToken, nil
+}
+
+func (o *fakeRequest) apiKeysQuery() (models.APIKeysQueryParams, error) {
+	params := models.APIKeysQueryParams{UserEmail: o.stringQuery("user_email")}
+	if err := o.pageQuery(&params.PageSize, &params.PageNumber); err != nil {
+		return params, err
+	}
+
+	var err error
+	if params.UserID, err = o.uuidQuery("user_id"); err != nil {
+		return params, err
+	}
+	if params.OrgID, err = o.uuidQuery("org_id"); err != nil {
+		return params, err
+	}
+
+	return params, nil
+}
+
+func (o *fakeRequest) reportPagination() (*models.ReportPagination, error) {
+	pagination := &models.ReportPagination{}
+	if err := o.pageQuery(&pagination.PageSize, &pagination.PageNumber); err != nil {
+		return nil, err
+	}
+
+	return pagination, nil
+}
+
+func (o *fakeRequest) pageQuery(pageSize **int, pageNumber **int) error {
+	var err error
+	if *pageSize, err = o.intQuery("page_size"); err != nil {
+		return err
+	}
+	if *pageNumber, err = o.intQuery("page_number"); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (o *fakeRequest) stringQuery(name string) *string {
+	if !o.query.Has(name) {
+		return nil
+	}
+
+	value := o.query.Get(name)
+
+	return &value
+}
+
+func (o *fakeRequest) intQuery(name string) (*int, error) {
+	if !o.query.Has(name) {
+		return nil, nil
+	}
+
+	value, err := strconv.Atoi(o.query.Get(name))
+	if err != nil {
+		return nil, fakeBadRequest(name, "Must be a number")
+	}
+
+	return &value, nil
+}
+
+func (o *fakeRequest) uuidQuery(name string) (*uuid.UUID, error) {
+	if !o.query.Has(name) {
+		return nil, nil
+	}
+
+	value, err := uuid.Parse(o.query.Get(name))
+	if err != nil {
+		return nil, fakeBadRequest(name, "Must be a UUID")
+	}
+
+	return &value, nil
+}
+
+func (o *fakeRequest) boolQuery(name string) bool {
+	return o.query.Get(name) == "true"
+}
+
+func (o *fakeRequest) boolPointerQuery(name string) *bool {
+	if !o.query.Has(name) {
+		return nil
+	}
+
+	value := o.boolQuery(name)
+
+	return &value
+}
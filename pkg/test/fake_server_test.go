@@ -0,0 +1,201 @@
+package test_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	propelauth "github.com/propelauth/propelauth-go/pkg"
+	"github.com/propelauth/propelauth-go/pkg/models"
+	testHelpers "github.com/propelauth/propelauth-go/pkg/test"
+)
+
+func TestFakeServer(t *testing.T) {
+	// setup
+
+	server := testHelpers.NewFakeServer(testHelpers.WithFakeRolePermissions("Admin", "can_invite"))
+	defer server.Close()
+
+	client, err := server.NewClient(propelauth.WithRetryPolicy(propelauth.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: time.Second}))
+	if err != nil {
+		t.Fatalf("NewClient returned an error, cannot even begin the tests: %s", err)
+	}
+
+	userID, err := client.CreateUser(models.CreateUserParams{Email: "ada@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser returned an error, cannot even begin the tests: %s", err)
+	}
+	org, err := client.CreateOrg("Acme")
+	if err != nil {
+		t.Fatalf("CreateOrg returned an error, cannot even begin the tests: %s", err)
+	}
+
+	// run tests
+
+	t.Run("users are served through the API", func(t *testing.T) {
+		byEmail, err := client.FetchUserMetadataByEmail("ada@example.com", false)
+		if err != nil || byEmail.UserID != userID.UserID {
+			t.Errorf("expected to find the user by email, got %v, %v", byEmail, err)
+		}
+
+		missingID := uuid.New()
+		batch, err := client.FetchBatchUserMetadataByUserIds([]uuid.UUID{userID.UserID, missingID}, false)
+		if err != nil || len(batch) != 1 || batch[userID.UserID].Email != "ada@example.com" {
+			t.Errorf("expected a batch with just the existing user, got %v, %v", batch, err)
+		}
+
+		_, err = client.FetchUserMetadataByUserID(missingID, false)
+		if !errors.Is(err, models.ErrNotFound) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+
+		_, err = client.CreateUser(models.CreateUserParams{Email: "ada@example.com"})
+		apiError := &models.APIError{}
+		if !errors.As(err, &apiError) || apiError.StatusCode != 400 || apiError.FieldToErrors["email"] == nil {
+			t.Errorf("expected a 400 about the email, got %v", err)
+		}
+	})
+
+	t.Run("access tokens from the server are validated by the client", func(t *testing.T) {
+		_, err := client.AddUserToOrg(models.AddUserToOrg{UserID: userID.UserID, OrgID: org.OrgID, Role: "Admin"})
+		if err != nil {
+			t.Fatalf("AddUserToOrg returned an error: %s", err)
+		}
+
+		accessToken, err := client.CreateAccessToken(userID.UserID, 10, models.CreateAccessTokenOptions{ActiveOrgId: &org.OrgID})
+		if err != nil {
+			t.Fatalf("CreateAccessToken returned an error: %s", err)
+		}
+
+		user, err := client.GetUser("Bearer " + accessToken.AccessToken)
+		if err != nil {
+			t.Fatalf("GetUser returned an error: %s", err)
+		}
+		if user.UserID != userID.UserID || user.GetActiveOrgMemberInfo() == nil || !user.GetActiveOrgMemberInfo().HasPermission("can_invite") {
+			t.Errorf("expected the user to be an Admin of the active org, got %+v", user)
+		}
+
+		_, err = client.CreateAccessToken(uuid.New(), 10)
+		if !errors.Is(err, models.ErrNotFound) {
+			t.Errorf("expected ErrNotFound for a missing user, got %v", err)
+		}
+	})
+
+	t.Run("API keys are validated through the API", func(t *testing.T) {
+		apiKey, err := client.CreateAPIKey(models.APIKeyCreateParams{OrgID: &org.OrgID})
+		if err != nil {
+			t.Fatalf("CreateAPIKey returned an error: %s", err)
+		}
+
+		validation, err := client.ValidateOrgAPIKey(apiKey.APIKeyToken)
+		if err != nil || validation.Org.OrgID != org.OrgID {
+			t.Errorf("expected the key to be valid for the org, got %+v, %v", validation, err)
+		}
+
+		if _, err := client.ValidateAPIKey("not-a-key"); err == nil {
+			t.Errorf("expected an unknown key to be invalid")
+		}
+	})
+
+	t.Run("step up grants that don't exist aren't valid", func(t *testing.T) {
+		response, err := client.VerifyStepUpGrant(models.VerifyStepUpGrantRequest{ActionType: "delete", UserID: userID.UserID, Grant: "grant"})
+		if err != nil || response.Success {
+			t.Errorf("expected an unsuccessful verification, got %+v, %v", response, err)
+		}
+	})
+
+	t.Run("step up grants from a TOTP code are valid", func(t *testing.T) {
+		if err := server.Fake().SetUpTotp(userID.UserID, "123456"); err != nil {
+			t.Fatalf("SetUpTotp returned an error: %s", err)
+		}
+
+		_, err := client.VerifyStepUpTotpChallenge(models.VerifyTotpChallengeRequest{ActionType: "delete", UserID: userID.UserID, Code: "000000"})
+		apiError := &models.APIError{}
+		if !errors.As(err, &apiError) || apiError.ErrorCode != "incorrect_mfa_code" {
+			t.Errorf("expected incorrect_mfa_code for the wrong code, got %v", err)
+		}
+
+		response, err := client.VerifyStepUpTotpChallenge(models.VerifyTotpChallengeRequest{ActionType: "delete", UserID: userID.UserID, Code: "123456"})
+		if err != nil {
+			t.Fatalf("VerifyStepUpTotpChallenge returned an error: %s", err)
+		}
+		verified, err := client.VerifyStepUpGrant(models.VerifyStepUpGrantRequest{ActionType: "delete", UserID: userID.UserID, Grant: response.StepUpGrant})
+		if err != nil || !verified.Success {
+			t.Errorf("expected the grant to be valid, got %+v, %v", verified, err)
+		}
+	})
+
+	t.Run("the wrong integration API key is rejected", func(t *testing.T) {
+		_, err := propelauth.InitBaseAuth(server.AuthURL(), "wrong", nil, propelauth.WithBackendOrigin(server.URL), propelauth.WithAllowInsecureHTTP())
+		if !errors.Is(err, models.ErrUnauthorized) {
+			t.Errorf("expected ErrUnauthorized, got %v", err)
+		}
+	})
+
+	t.Run("rate limits are retried", func(t *testing.T) {
+		server.InjectFault(testHelpers.FakeServerFault{
+			Method:     "GET",
+			PathPrefix: "/api/backend/v1/user/",
+			Times:      1,
+			StatusCode: http.StatusTooManyRequests,
+			Header:     http.Header{"Retry-After": {"0"}},
+		})
+		requestCount := len(server.Requests())
+
+		user, err := client.FetchUserMetadataByUserID(userID.UserID, false)
+		if err != nil || user.UserID != userID.UserID {
+			t.Errorf("expected the retry to succeed, got %v, %v", user, err)
+		}
+		if attempts := len(server.Requests()) - requestCount; attempts != 2 {
+			t.Errorf("expected 2 attempts, got %d", attempts)
+		}
+	})
+
+	t.Run("end user rate limits are returned as ApiKeyRateLimitError", func(t *testing.T) {
+		server.InjectFault(testHelpers.FakeServerFault{
+			PathPrefix: "/api/backend/v1/end_user_api_keys/validate",
+			StatusCode: http.StatusTooManyRequests,
+			Body:       `{"wait_seconds": 60, "error_code": "end_user_rate_limited", "user_facing_error": "Too many requests"}`,
+		})
+		defer server.ClearFaults()
+
+		_, err := client.ValidateAPIKey("any-key")
+		rateLimitError := &models.ApiKeyRateLimitError{}
+		if !errors.As(err, &rateLimitError) || rateLimitError.WaitSeconds != 60 {
+			t.Errorf("expected an ApiKeyRateLimitError, got %v", err)
+		}
+	})
+
+	t.Run("slow responses time out", func(t *testing.T) {
+		impatientClient, err := server.NewClient(propelauth.WithTimeout(50 * time.Millisecond))
+		if err != nil {
+			t.Fatalf("NewClient returned an error: %s", err)
+		}
+
+		server.InjectFault(testHelpers.FakeServerFault{PathPrefix: "/api/backend/v1/org/", Delay: time.Second})
+		defer server.ClearFaults()
+
+		start := time.Now()
+		if _, err := impatientClient.FetchOrg(org.OrgID); err == nil {
+			t.Errorf("expected the request to time out")
+		}
+		if elapsed := time.Since(start); elapsed >= time.Second {
+			t.Errorf("expected the request to give up before the response, took %s", elapsed)
+		}
+	})
+
+	t.Run("malformed bodies are reported", func(t *testing.T) {
+		server.InjectFault(testHelpers.FakeServerFault{
+			PathPrefix: "/api/backend/v1/org/",
+			StatusCode: http.StatusOK,
+			Body:       `{"org_id": `,
+		})
+		defer server.ClearFaults()
+
+		if _, err := client.FetchOrg(org.OrgID); err == nil {
+			t.Errorf("expected an error for a malformed body")
+		}
+	})
+}
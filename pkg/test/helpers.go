@@ -56,10 +56,15 @@ func OrgsToOrgIDMap(orgs []models.OrgMemberInfoFromToken) map[string]*models.Org
 
 // Create a JWT access token with the UserFromToken data.
 func CreateAccessToken(user models.UserFromToken, privateKeyPem *rsa.PrivateKey) string {
+	return CreateAccessTokenWithIssuer(user, privateKeyPem, "issuertest")
+}
+
+// Create a JWT access token with the UserFromToken data, for a specific issuer.
+func CreateAccessTokenWithIssuer(user models.UserFromToken, privateKeyPem *rsa.PrivateKey, issuer string) string {
 	user.RegisteredClaims = jwt.RegisteredClaims{
 		ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
 		IssuedAt:  jwt.NewNumericDate(time.Now()),
-		Issuer:    "issuertest",
+		Issuer:    issuer,
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodRS256, user)
@@ -0,0 +1,207 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+
+	"github.com/propelauth/propelauth-go/pkg/models"
+)
+
+const defaultAccessTokenDuration = 15 * time.Minute
+const defaultAccessTokenRefreshBefore = time.Minute
+
+// AccessTokenSourceOption configures optional behavior of the AccessTokenSource returned by NewAccessTokenSource.
+type AccessTokenSourceOption func(*AccessTokenSource)
+
+// AccessTokenSource creates access tokens for a user with CreateAccessToken, and reuses each one until shortly
+// before it expires, so a background worker can act as the user without tracking expiry itself. It's safe to use
+// from multiple goroutines, and concurrent calls while a token is being created wait for it, rather than each
+// creating their own.
+type AccessTokenSource struct {
+	client        ClientInterface
+	userID        uuid.UUID
+	orgID         *uuid.UUID
+	duration      time.Duration
+	refreshBefore time.Duration
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+	refreshAt time.Time
+}
+
+// NewAccessTokenSource creates a source of access tokens for userID. If orgID isn't nil, the tokens are created with
+// it as the user's active org, like CreateAccessTokenOptions.ActiveOrgId. By default, tokens last 15 minutes and are
+// replaced a minute before they expire.
+func NewAccessTokenSource(client ClientInterface, userID uuid.UUID, orgID *uuid.UUID, opts ...AccessTokenSourceOption) *AccessTokenSource {
+	source := &AccessTokenSource{
+		client:        client,
+		userID:        userID,
+		orgID:         orgID,
+		duration:      defaultAccessTokenDuration,
+		refreshBefore: defaultAccessTokenRefreshBefore,
+	}
+
+	for _, opt := range opts {
+		opt(source)
+	}
+
+	return source
+}
+
+// WithAccessTokenDuration changes how long each access token lasts. It's rounded up to whole minutes, since that's
+// what CreateAccessToken takes.
+func WithAccessTokenDuration(duration time.Duration) AccessTokenSourceOption {
+	return func(o *AccessTokenSource) {
+		o.duration = duration
+	}
+}
+
+// WithAccessTokenRefreshBefore changes how long before a token expires it's replaced. If a token doesn't last that
+// long, it's replaced halfway through its lifetime instead.
+func WithAccessTokenRefreshBefore(refreshBefore time.Duration) AccessTokenSourceOption {
+	return func(o *AccessTokenSource) {
+		o.refreshBefore = refreshBefore
+	}
+}
+
+// Token returns an access token for the user, creating one if there isn't one or it's about to expire.
+func (o *AccessTokenSource) Token() (string, error) {
+	return o.TokenContext(context.Background())
+}
+
+// TokenContext is like Token, but creates the token with ctx.
+func (o *AccessTokenSource) TokenContext(ctx context.Context) (string, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.token != "" && time.Now().Before(o.refreshAt) {
+		return o.token, nil
+	}
+
+	accessToken, err := o.client.WithContext(ctx).CreateAccessToken(o.userID, durationInMinutes(o.duration), models.CreateAccessTokenOptions{ActiveOrgId: o.orgID})
+	if err != nil {
+		return "", err
+	}
+
+	createdAt := time.Now()
+	expiresAt, err := tokenExpiry(accessToken.AccessToken)
+	if err != nil {
+		return "", err
+	}
+	if expiresAt.IsZero() {
+		expiresAt = createdAt.Add(time.Duration(durationInMinutes(o.duration)) * time.Minute)
+	}
+
+	refreshAt := expiresAt.Add(-o.refreshBefore)
+	if refreshAt.Before(createdAt) {
+		refreshAt = createdAt.Add(expiresAt.Sub(createdAt) / 2)
+	}
+
+	o.token = accessToken.AccessToken
+	o.expiresAt = expiresAt
+	o.refreshAt = refreshAt
+
+	return o.token, nil
+}
+
+// ExpiresAt returns when the current token expires, or the zero time if there isn't one yet.
+func (o *AccessTokenSource) ExpiresAt() time.Time {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.expiresAt
+}
+
+// Invalidate drops the current token, so the next call creates a new one. Use it when a token is rejected, for
+// example after the user's roles change.
+func (o *AccessTokenSource) Invalidate() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.clear()
+}
+
+// invalidate drops token if it's still the current one, so a slow request that was rejected doesn't throw away a
+// token that replaced it in the meantime.
+func (o *AccessTokenSource) invalidate(token string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.token == token {
+		o.clear()
+	}
+}
+
+func (o *AccessTokenSource) clear() {
+	o.token = ""
+	o.expiresAt = time.Time{}
+	o.refreshAt = time.Time{}
+}
+
+// AccessTokenTransport is an http.RoundTripper that sets the Authorization header of each request to a token from
+// Source, like "Bearer <token>". If a response is a 401, the token is invalidated, so the next request gets a new
+// one. The request isn't retried.
+//
+//	httpClient := &http.Client{Transport: &propelauth.AccessTokenTransport{Source: source}}
+type AccessTokenTransport struct {
+	Source *AccessTokenSource
+	// Base is the transport requests are sent with. If it's nil, http.DefaultTransport is used.
+	Base http.RoundTripper
+}
+
+// RoundTrip sends a copy of req with the Authorization header set.
+func (o *AccessTokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := o.Source.TokenContext(req.Context())
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, fmt.Errorf("Error on getting an access token: %w", err)
+	}
+
+	// a RoundTripper mustn't change the request it's given
+	authenticated := req.Clone(req.Context())
+	authenticated.Header.Set("Authorization", "Bearer "+token)
+
+	base := o.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	resp, err := base.RoundTrip(authenticated)
+	if err == nil && resp.StatusCode == http.StatusUnauthorized {
+		o.Source.invalidate(token)
+	}
+
+	return resp, err
+}
+
+// durationInMinutes rounds duration up to whole minutes, and at least one.
+func durationInMinutes(duration time.Duration) int {
+	minutes := int((duration + time.Minute - 1) / time.Minute)
+	if minutes < 1 {
+		return 1
+	}
+	return minutes
+}
+
+// tokenExpiry reads the exp claim of an access token we just created, without verifying it. It's the zero time if
+// there isn't one.
+func tokenExpiry(accessToken string) (time.Time, error) {
+	claims := jwt.RegisteredClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(accessToken, &claims); err != nil {
+		return time.Time{}, fmt.Errorf("Error on decoding access token: %w", err)
+	}
+
+	if claims.ExpiresAt == nil {
+		return time.Time{}, nil
+	}
+	return claims.ExpiresAt.Time, nil
+}
@@ -0,0 +1,182 @@
+package client_test
+
+import (
+	"context"
+	"crypto/rsa"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	propelauth "github.com/propelauth/propelauth-go/pkg"
+	"github.com/propelauth/propelauth-go/pkg/models"
+	testHelpers "github.com/propelauth/propelauth-go/pkg/test"
+)
+
+// tokenCountingClient counts the access tokens created, and can make them last less than a minute.
+type tokenCountingClient struct {
+	propelauth.ClientInterface
+	created    atomic.Int32
+	lifetime   time.Duration
+	privateKey *rsa.PrivateKey
+}
+
+func (o *tokenCountingClient) WithContext(ctx context.Context) propelauth.ClientInterface {
+	return o
+}
+
+func (o *tokenCountingClient) CreateAccessToken(userID uuid.UUID, durationInMinutes int, createAccessTokenOptions ...models.CreateAccessTokenOptions) (*models.AccessToken, error) {
+	o.created.Add(1)
+	if o.lifetime == 0 {
+		return o.ClientInterface.CreateAccessToken(userID, durationInMinutes, createAccessTokenOptions...)
+	}
+
+	claims := models.UserFromToken{UserID: userID}
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(o.lifetime)),
+		ID:        uuid.NewString(),
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(o.privateKey)
+	if err != nil {
+		return nil, err
+	}
+	return &models.AccessToken{AccessToken: token}, nil
+}
+
+func TestAccessTokenSource(t *testing.T) {
+	// setup a user in an org
+
+	fake := testHelpers.NewFakeClient(testHelpers.WithFakeRoles("Owner", "Member"))
+
+	user, err := fake.CreateUser(models.CreateUserParams{Email: "worker@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser returned an error, cannot even begin the tests: %s", err)
+	}
+	org, err := fake.CreateOrg("Acme")
+	if err != nil {
+		t.Fatalf("CreateOrg returned an error, cannot even begin the tests: %s", err)
+	}
+	if _, err := fake.AddUserToOrg(models.AddUserToOrg{UserID: user.UserID, OrgID: org.OrgID, Role: "Member"}); err != nil {
+		t.Fatalf("AddUserToOrg returned an error, cannot even begin the tests: %s", err)
+	}
+
+	// run tests
+
+	t.Run("tokens are reused until they're about to expire", func(t *testing.T) {
+		client := &tokenCountingClient{ClientInterface: fake}
+		source := propelauth.NewAccessTokenSource(client, user.UserID, &org.OrgID, propelauth.WithAccessTokenDuration(10*time.Minute))
+
+		first, err := source.Token()
+		if err != nil {
+			t.Fatalf("Token returned an error: %s", err)
+		}
+		second, err := source.Token()
+		if err != nil || second != first || client.created.Load() != 1 {
+			t.Errorf("expected the token to be reused, got %d tokens created and %v", client.created.Load(), err)
+		}
+
+		if expiresIn := time.Until(source.ExpiresAt()); expiresIn < 9*time.Minute || expiresIn > 10*time.Minute {
+			t.Errorf("expected the token to expire in 10 minutes, got %s", expiresIn)
+		}
+
+		fromToken, err := fake.GetUser("Bearer " + first)
+		if err != nil || fromToken.ActiveOrgId == nil || *fromToken.ActiveOrgId != org.OrgID {
+			t.Errorf("expected a token with the org active, got %+v and %v", fromToken, err)
+		}
+
+		source.Invalidate()
+		if _, err := source.Token(); err != nil || client.created.Load() != 2 {
+			t.Errorf("expected a new token after Invalidate, got %d tokens created and %v", client.created.Load(), err)
+		}
+	})
+
+	t.Run("short lived tokens are replaced halfway through", func(t *testing.T) {
+		client := &tokenCountingClient{ClientInterface: fake, lifetime: 2 * time.Second, privateKey: fake.PrivateKey()}
+		source := propelauth.NewAccessTokenSource(client, user.UserID, nil)
+
+		first, _ := source.Token()
+		if second, _ := source.Token(); second != first {
+			t.Errorf("expected the token to be reused right away")
+		}
+
+		time.Sleep(1100 * time.Millisecond)
+
+		if third, err := source.Token(); err != nil || third == first || client.created.Load() != 2 {
+			t.Errorf("expected a new token after half its lifetime, got %d tokens created and %v", client.created.Load(), err)
+		}
+	})
+
+	t.Run("concurrent calls share one token", func(t *testing.T) {
+		client := &tokenCountingClient{ClientInterface: fake}
+		source := propelauth.NewAccessTokenSource(client, user.UserID, nil)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := source.Token(); err != nil {
+					t.Errorf("Token returned an error: %s", err)
+				}
+			}()
+		}
+		wg.Wait()
+
+		if client.created.Load() != 1 {
+			t.Errorf("expected 1 token to be created, got %d", client.created.Load())
+		}
+	})
+
+	t.Run("errors are returned", func(t *testing.T) {
+		source := propelauth.NewAccessTokenSource(fake, uuid.New(), nil)
+
+		if _, err := source.Token(); !errors.Is(err, propelauth.ErrNotFound) {
+			t.Errorf("expected ErrNotFound for a missing user, got %v", err)
+		}
+	})
+
+	t.Run("the transport sets the Authorization header", func(t *testing.T) {
+		client := &tokenCountingClient{ClientInterface: fake}
+		source := propelauth.NewAccessTokenSource(client, user.UserID, nil)
+
+		var rejectNext atomic.Bool
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, err := fake.GetUser(r.Header.Get("Authorization")); err != nil || rejectNext.Swap(false) {
+				w.WriteHeader(401)
+				return
+			}
+			w.WriteHeader(200)
+		}))
+		defer server.Close()
+
+		httpClient := &http.Client{Transport: &propelauth.AccessTokenTransport{Source: source}}
+
+		req, _ := http.NewRequest("GET", server.URL, nil)
+		resp, err := httpClient.Do(req)
+		if err != nil || resp.StatusCode != 200 {
+			t.Fatalf("expected a 200, got %v and %v", resp, err)
+		}
+		resp.Body.Close()
+		if req.Header.Get("Authorization") != "" {
+			t.Errorf("expected the original request to be left alone")
+		}
+
+		// a 401 means the next request gets a new token
+		rejectNext.Store(true)
+		for i := 0; i < 2; i++ {
+			resp, err := httpClient.Get(server.URL)
+			if err != nil {
+				t.Fatalf("Get returned an error: %s", err)
+			}
+			resp.Body.Close()
+		}
+		if client.created.Load() != 2 {
+			t.Errorf("expected a new token after the 401, got %d tokens created", client.created.Load())
+		}
+	})
+}
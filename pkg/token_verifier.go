@@ -0,0 +1,243 @@
+package client
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+
+	"github.com/propelauth/propelauth-go/pkg/helpers"
+	"github.com/propelauth/propelauth-go/pkg/models"
+)
+
+// TokenVerifier verifies access tokens without calling the PropelAuth backend, so it doesn't need an integration
+// API key. It's what Client.GetUser uses, and it's useful on its own at the edge, where you only check tokens.
+//
+// Errors can be checked with errors.Is against ErrAuthorizationHeaderInvalid, ErrTokenMalformed, ErrTokenExpired,
+// ErrTokenNotValidYet, ErrTokenSignatureInvalid and ErrTokenIssuerInvalid, as well as the errors of the checks added
+// by options like WithTokenMaxAge and WithTokenAudience.
+type TokenVerifier struct {
+	ctx               context.Context
+	keyManager        *verifierKeyManager
+	validationHelper  helpers.ValidationHelperInterface
+	validationOptions helpers.TokenValidationOptions
+}
+
+// NewTokenVerifier creates a verifier from the issuer and PEM encoded keys in tokenVerificationMetadataInput, which
+// can be found in your PropelAuth dashboard, in the "Backend Integrations" section. The keys are never refreshed.
+// Options like WithTokenLeeway and WithTokenAudience add checks on the tokens.
+func NewTokenVerifier(tokenVerificationMetadataInput models.TokenVerificationMetadataInput, opts ...ClientOption) (*TokenVerifier, error) {
+	options := newClientOptions(opts)
+	validationHelper := &helpers.ValidationHelper{}
+
+	tokenVerificationMetadata, err := parseTokenVerificationMetadata(tokenVerificationMetadataInput, validationHelper)
+	if err != nil {
+		return nil, err
+	}
+
+	keyManager := newVerifierKeyManager(*tokenVerificationMetadata, nil, options)
+
+	return newTokenVerifier(keyManager, validationHelper, options), nil
+}
+
+// FetchTokenVerifier creates a verifier that trusts tokens from issuer, signed with the keys published at jwksURL
+// as a JSON Web Key Set. The keys are fetched right away, and then kept fresh like the client's, so WithHTTPClient,
+// WithTimeout, WithKeyMaxAge, WithMinKeyRefreshInterval and WithAllowInsecureHTTP all apply, as do the
+// token checks like WithTokenLeeway.
+func FetchTokenVerifier(ctx context.Context, issuer string, jwksURL string, opts ...ClientOption) (*TokenVerifier, error) {
+	options := newClientOptions(opts)
+
+	parsedURL, err := url.Parse(jwksURL)
+	if err != nil || (parsedURL.Scheme != "https" && !(options.allowInsecureHTTP && parsedURL.Scheme == "http")) || parsedURL.Host == "" {
+		return nil, fmt.Errorf("Invalid JWKS URL: %s", jwksURL)
+	}
+
+	httpClient := options.buildHTTPClient()
+	keys, err := fetchJWKS(ctx, httpClient, jwksURL)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenVerificationMetadata := models.TokenVerificationMetadata{
+		VerifierKey:            keys[0].PublicKey,
+		VerifierKeyID:          keys[0].KeyID,
+		Issuer:                 issuer,
+		AdditionalVerifierKeys: keys[1:],
+	}
+
+	// every refresh replaces the whole set, so keys removed from the JWKS are retired
+	fetchKeys := func(ctx context.Context) ([]models.VerifierKey, error) {
+		return fetchJWKS(ctx, httpClient, jwksURL)
+	}
+
+	keyManager := newVerifierKeyManager(tokenVerificationMetadata, fetchKeys, options)
+
+	return newTokenVerifier(keyManager, &helpers.ValidationHelper{}, options), nil
+}
+
+func newTokenVerifier(keyManager *verifierKeyManager, validationHelper helpers.ValidationHelperInterface, options *clientOptions) *TokenVerifier {
+	return &TokenVerifier{
+		ctx:               context.Background(),
+		keyManager:        keyManager,
+		validationHelper:  validationHelper,
+		validationOptions: options.tokenValidation,
+	}
+}
+
+// WithContext returns a copy of the verifier that refreshes its keys with ctx, when a token's signature doesn't
+// match. The copy shares the keys with the original.
+func (o *TokenVerifier) WithContext(ctx context.Context) *TokenVerifier {
+	if ctx == nil {
+		panic("nil context")
+	}
+
+	copied := *o
+	copied.ctx = ctx
+
+	return &copied
+}
+
+// VerifyHeader verifies the access token in an Authorization header, like "Bearer <token>", and returns its user.
+func (o *TokenVerifier) VerifyHeader(authHeader string) (*models.UserFromToken, error) {
+	return o.verifyHeader(o.ctx, authHeader)
+}
+
+// VerifyToken verifies an access token, and returns its user.
+func (o *TokenVerifier) VerifyToken(accessToken string) (*models.UserFromToken, error) {
+	return o.verifyToken(o.ctx, accessToken)
+}
+
+// Status reports the keys used to verify access tokens, and when they were last refreshed.
+func (o *TokenVerifier) Status() models.TokenVerificationStatus {
+	return o.keyManager.status()
+}
+
+func (o *TokenVerifier) verifyHeader(ctx context.Context, authHeader string) (*models.UserFromToken, error) {
+	accessToken, err := o.validationHelper.ExtractTokenFromAuthorizationHeader(authHeader)
+	if err != nil {
+		return nil, fmt.Errorf("Error on extracting token from authorization header: %w", err)
+	}
+
+	return o.verifyToken(ctx, accessToken)
+}
+
+func (o *TokenVerifier) verifyToken(ctx context.Context, accessToken string) (*models.UserFromToken, error) {
+	o.keyManager.refreshIfStale()
+
+	user, err := o.validationHelper.ValidateAccessTokenAndGetUserWithOptions(accessToken, o.keyManager.current(), o.validationOptions)
+	if errors.Is(err, models.ErrTokenSignatureInvalid) && o.keyManager.refreshAfterFailure(ctx) {
+		// the key may have been rotated, so try again with the new one
+		user, err = o.validationHelper.ValidateAccessTokenAndGetUserWithOptions(accessToken, o.keyManager.current(), o.validationOptions)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Error on validating access token and getting user: %w", err)
+	}
+
+	return user, nil
+}
+
+// parseTokenVerificationMetadata converts the PEM encoded keys in tokenVerificationMetadataInput to RSA public keys.
+func parseTokenVerificationMetadata(tokenVerificationMetadataInput models.TokenVerificationMetadataInput, validationHelper helpers.ValidationHelperInterface) (*models.TokenVerificationMetadata, error) {
+	rsaPublicKey, err := validationHelper.ConvertPEMStringToRSAPublicKey(tokenVerificationMetadataInput.VerifierKey)
+	if err != nil {
+		return nil, fmt.Errorf("Error converting a PEM string to an RSA Public Key: %w", err)
+	}
+
+	tokenVerificationMetadata := &models.TokenVerificationMetadata{
+		VerifierKey: *rsaPublicKey,
+		Issuer:      tokenVerificationMetadataInput.Issuer,
+	}
+
+	for _, additionalKey := range tokenVerificationMetadataInput.AdditionalVerifierKeys {
+		rsaPublicKey, err := validationHelper.ConvertPEMStringToRSAPublicKey(additionalKey.VerifierKey)
+		if err != nil {
+			return nil, fmt.Errorf("Error converting a PEM string to an RSA Public Key: %w", err)
+		}
+
+		tokenVerificationMetadata.AdditionalVerifierKeys = append(tokenVerificationMetadata.AdditionalVerifierKeys, models.VerifierKey{
+			KeyID:     additionalKey.KeyID,
+			PublicKey: *rsaPublicKey,
+		})
+	}
+
+	return tokenVerificationMetadata, nil
+}
+
+// jsonWebKey is an RSA key in a JSON Web Key Set.
+type jsonWebKey struct {
+	KeyType string `json:"kty"`
+	KeyID   string `json:"kid"`
+	Use     string `json:"use"`
+	N       string `json:"n"`
+	E       string `json:"e"`
+}
+
+// fetchJWKS fetches the RSA signing keys at jwksURL. There's always at least one.
+func fetchJWKS(ctx context.Context, httpClient *http.Client, jwksURL string) ([]models.VerifierKey, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", jwksURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Error on creating request: %w", err)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Error on fetching the JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("Error on reading response body: %w", err)
+	}
+	if resp.StatusCode != 200 {
+		return nil, models.NewAPIError(resp.StatusCode, body, fmt.Sprintf("Unknown error when fetching the JWKS. Status code: %d. Body: %s", resp.StatusCode, body), nil)
+	}
+
+	jwks := struct {
+		Keys []jsonWebKey `json:"keys"`
+	}{}
+	if err := json.Unmarshal(body, &jwks); err != nil {
+		return nil, fmt.Errorf("Error on unmarshalling the JWKS: %w", err)
+	}
+
+	keys := []models.VerifierKey{}
+	for _, key := range jwks.Keys {
+		if key.KeyType != "RSA" || (key.Use != "" && key.Use != "sig") {
+			continue
+		}
+
+		n, err := base64.RawURLEncoding.DecodeString(key.N)
+		if err != nil {
+			return nil, fmt.Errorf("Error on decoding the modulus of key %s: %w", key.KeyID, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(key.E)
+		if err != nil {
+			return nil, fmt.Errorf("Error on decoding the exponent of key %s: %w", key.KeyID, err)
+		}
+		// RSA exponents are small and odd, anything else would make a key that can't verify anything
+		exponent := new(big.Int).SetBytes(e)
+		if len(e) > 4 || exponent.Int64() < 3 || exponent.Bit(0) == 0 {
+			return nil, fmt.Errorf("Invalid exponent for key %s: %s", key.KeyID, key.E)
+		}
+
+		keys = append(keys, models.VerifierKey{
+			KeyID: key.KeyID,
+			PublicKey: rsa.PublicKey{
+				N: new(big.Int).SetBytes(n),
+				E: int(exponent.Int64()),
+			},
+		})
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("The JWKS has no RSA signing keys")
+	}
+
+	return keys, nil
+}
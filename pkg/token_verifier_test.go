@@ -0,0 +1,359 @@
+package client_test
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	propelauth "github.com/propelauth/propelauth-go/pkg"
+	"github.com/propelauth/propelauth-go/pkg/models"
+	testHelpers "github.com/propelauth/propelauth-go/pkg/test"
+)
+
+func TestTokenVerifier(t *testing.T) {
+	// setup a verifier from a PEM key, and tokens that fail in each way
+
+	privateKey, publicKey := testHelpers.GenerateRSAKeys()
+	otherPrivateKey, _ := testHelpers.GenerateRSAKeys()
+
+	verifier, err := propelauth.NewTokenVerifier(models.TokenVerificationMetadataInput{VerifierKey: publicKey, Issuer: "issuertest"})
+	if err != nil {
+		t.Fatalf("NewTokenVerifier returned an error, cannot even begin the tests: %s", err)
+	}
+
+	user := models.UserFromToken{UserID: testHelpers.RandomUserID()}
+
+	// and helpers to serve keys from a JWKS URL, and sign tokens with a kid header
+
+	jwk := func(keyID string, key *rsa.PrivateKey) map[string]string {
+		return map[string]string{
+			"kty": "RSA",
+			"kid": keyID,
+			"n":   base64.RawURLEncoding.EncodeToString(key.PublicKey.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.PublicKey.E)).Bytes()),
+		}
+	}
+	serveJWKS := func(keys ...map[string]string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys})
+		}))
+	}
+	signWithKeyID := func(keyID string, key *rsa.PrivateKey) string {
+		now := time.Now()
+		claims := models.UserFromToken{UserID: user.UserID}
+		claims.RegisteredClaims = jwt.RegisteredClaims{
+			Issuer:    "issuertest",
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = keyID
+		signed, err := token.SignedString(key)
+		if err != nil {
+			t.Fatalf("couldn't sign the token: %s", err)
+		}
+		return signed
+	}
+
+	// run tests
+
+	t.Run("valid tokens return their user", func(t *testing.T) {
+		verified, err := verifier.VerifyHeader("Bearer " + testHelpers.CreateAccessToken(user, privateKey))
+		if err != nil || verified.UserID != user.UserID {
+			t.Errorf("expected the user, got %+v and %v", verified, err)
+		}
+
+		verified, err = verifier.VerifyToken(testHelpers.CreateAccessToken(user, privateKey))
+		if err != nil || verified.UserID != user.UserID {
+			t.Errorf("expected the user, got %+v and %v", verified, err)
+		}
+	})
+
+	t.Run("invalid tokens return a matching error", func(t *testing.T) {
+		tests := []struct {
+			name       string
+			authHeader string
+			expected   error
+		}{
+			{"bad header", "Basic abc", propelauth.ErrAuthorizationHeaderInvalid},
+			{"malformed", "Bearer thisisafaketoken", propelauth.ErrTokenMalformed},
+			{"expired", "Bearer " + testHelpers.CreateExpiredAccessToken(user, privateKey), propelauth.ErrTokenExpired},
+			{"bad signature", "Bearer " + testHelpers.CreateAccessToken(user, otherPrivateKey), propelauth.ErrTokenSignatureInvalid},
+			{"wrong issuer", "Bearer " + testHelpers.CreateAccessTokenWithIssuer(user, privateKey, "https://other.example.com"), propelauth.ErrTokenIssuerInvalid},
+		}
+
+		for _, test := range tests {
+			if _, err := verifier.VerifyHeader(test.authHeader); !errors.Is(err, test.expected) {
+				t.Errorf("%s: expected %v, got %v", test.name, test.expected, err)
+			}
+		}
+	})
+
+	t.Run("the client returns the same errors", func(t *testing.T) {
+		client, err := propelauth.InitBaseAuth("https://auth.example.com", "apikey", &models.TokenVerificationMetadataInput{VerifierKey: publicKey, Issuer: "issuertest"})
+		if err != nil {
+			t.Fatalf("InitBaseAuth returned an error: %s", err)
+		}
+
+		if _, err := client.GetUser("Bearer " + testHelpers.CreateExpiredAccessToken(user, privateKey)); !errors.Is(err, propelauth.ErrTokenExpired) {
+			t.Errorf("expected ErrTokenExpired, got %v", err)
+		}
+	})
+
+	t.Run("keys can be fetched from a JWKS URL", func(t *testing.T) {
+		var requests atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests.Add(1)
+			encode := func(value []byte) string { return base64.RawURLEncoding.EncodeToString(value) }
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"keys": []map[string]string{
+					{"kty": "EC", "kid": "ignored"},
+					{
+						"kty": "RSA",
+						"kid": "key-1",
+						"use": "sig",
+						"n":   encode(privateKey.PublicKey.N.Bytes()),
+						"e":   encode(big.NewInt(int64(privateKey.PublicKey.E)).Bytes()),
+					},
+				},
+			})
+		}))
+		defer server.Close()
+
+		if _, err := propelauth.FetchTokenVerifier(context.Background(), "issuertest", server.URL); err == nil {
+			t.Errorf("expected http to be refused without WithAllowInsecureHTTP")
+		}
+
+		fetched, err := propelauth.FetchTokenVerifier(context.Background(), "issuertest", server.URL, propelauth.WithAllowInsecureHTTP())
+		if err != nil {
+			t.Fatalf("FetchTokenVerifier returned an error: %s", err)
+		}
+
+		if _, err := fetched.VerifyHeader("Bearer " + testHelpers.CreateAccessToken(user, privateKey)); err != nil {
+			t.Errorf("expected the token to be valid, got %v", err)
+		}
+		if _, err := fetched.VerifyHeader("Bearer " + testHelpers.CreateAccessToken(user, otherPrivateKey)); !errors.Is(err, propelauth.ErrTokenSignatureInvalid) {
+			t.Errorf("expected ErrTokenSignatureInvalid, got %v", err)
+		}
+		if status := fetched.Status(); status.KeyCount != 1 || requests.Load() != 1 {
+			t.Errorf("expected 1 key from 1 request, got %+v after %d requests", status, requests.Load())
+		}
+	})
+
+	t.Run("refreshes replace the whole JWKS", func(t *testing.T) {
+		rotatedPrivateKey, _ := testHelpers.GenerateRSAKeys()
+
+		var rotated atomic.Bool
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			keys := []map[string]string{jwk("key-1", privateKey), jwk("key-2", otherPrivateKey)}
+			if rotated.Load() {
+				keys = []map[string]string{jwk("key-1", privateKey), jwk("key-3", rotatedPrivateKey)}
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys})
+		}))
+		defer server.Close()
+
+		fetched, err := propelauth.FetchTokenVerifier(context.Background(), "issuertest", server.URL,
+			propelauth.WithAllowInsecureHTTP(), propelauth.WithMinKeyRefreshInterval(0))
+		if err != nil {
+			t.Fatalf("FetchTokenVerifier returned an error: %s", err)
+		}
+
+		// the first key stays the same, so only the others tell the refresh something changed
+		rotated.Store(true)
+		if _, err := fetched.VerifyHeader("Bearer " + testHelpers.CreateAccessToken(user, rotatedPrivateKey)); err != nil {
+			t.Errorf("expected a token signed with the new key to be valid, got %v", err)
+		}
+		if _, err := fetched.VerifyHeader("Bearer " + testHelpers.CreateAccessToken(user, otherPrivateKey)); err != nil {
+			t.Errorf("expected the removed key to be retired rather than dropped, got %v", err)
+		}
+		if status := fetched.Status(); status.KeyCount != 3 {
+			t.Errorf("expected 2 keys and 1 retired key, got %+v", status)
+		}
+	})
+
+	t.Run("refreshing an unchanged JWKS doesn't change the keys", func(t *testing.T) {
+		unknownPrivateKey, _ := testHelpers.GenerateRSAKeys()
+		server := serveJWKS(jwk("key-1", privateKey), jwk("key-2", otherPrivateKey))
+		defer server.Close()
+
+		fetched, err := propelauth.FetchTokenVerifier(context.Background(), "issuertest", server.URL,
+			propelauth.WithAllowInsecureHTTP(), propelauth.WithMinKeyRefreshInterval(0))
+		if err != nil {
+			t.Fatalf("FetchTokenVerifier returned an error: %s", err)
+		}
+		before := fetched.Status()
+		time.Sleep(time.Millisecond)
+
+		// a token signed with a key we don't have makes the verifier refresh its keys
+		if _, err := fetched.VerifyHeader("Bearer " + testHelpers.CreateAccessToken(user, unknownPrivateKey)); !errors.Is(err, propelauth.ErrTokenSignatureInvalid) {
+			t.Errorf("expected ErrTokenSignatureInvalid, got %v", err)
+		}
+
+		after := fetched.Status()
+		if !after.LastRefreshAt.After(before.LastRefreshAt) {
+			t.Errorf("expected the keys to be refreshed, got %+v", after)
+		}
+		if !after.LastKeyChangeAt.Equal(before.LastKeyChangeAt) || after.KeyCount != 2 {
+			t.Errorf("expected the same 2 keys, got %+v", after)
+		}
+	})
+
+	t.Run("keys older than the max age are refreshed when a token is validated", func(t *testing.T) {
+		server := serveJWKS(jwk("key-1", privateKey))
+		defer server.Close()
+
+		fetched, err := propelauth.FetchTokenVerifier(context.Background(), "issuertest", server.URL,
+			propelauth.WithAllowInsecureHTTP(), propelauth.WithKeyMaxAge(time.Millisecond))
+		if err != nil {
+			t.Fatalf("FetchTokenVerifier returned an error: %s", err)
+		}
+		before := fetched.Status()
+		time.Sleep(5 * time.Millisecond)
+
+		// there's no timer, so nothing is refreshed until a token is validated
+		if status := fetched.Status(); !status.LastRefreshAttemptAt.Equal(before.LastRefreshAttemptAt) {
+			t.Errorf("expected no refresh yet, got %+v", status)
+		}
+		if _, err := fetched.VerifyHeader("Bearer " + testHelpers.CreateAccessToken(user, privateKey)); err != nil {
+			t.Errorf("expected the token to be valid, got %v", err)
+		}
+
+		deadline := time.Now().Add(time.Second)
+		for fetched.Status().LastRefreshAt.Equal(before.LastRefreshAt) && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		if status := fetched.Status(); !status.LastRefreshAt.After(before.LastRefreshAt) {
+			t.Errorf("expected the keys to be refreshed in the background, got %+v", status)
+		}
+	})
+
+	t.Run("tokens with a kid are checked against that key", func(t *testing.T) {
+		server := serveJWKS(jwk("key-1", privateKey), jwk("key-2", otherPrivateKey))
+		defer server.Close()
+
+		fetched, err := propelauth.FetchTokenVerifier(context.Background(), "issuertest", server.URL, propelauth.WithAllowInsecureHTTP())
+		if err != nil {
+			t.Fatalf("FetchTokenVerifier returned an error: %s", err)
+		}
+
+		if _, err := fetched.VerifyHeader("Bearer " + signWithKeyID("key-1", privateKey)); err != nil {
+			t.Errorf("expected a token signed with the first key to be valid, got %v", err)
+		}
+		if _, err := fetched.VerifyHeader("Bearer " + signWithKeyID("key-2", otherPrivateKey)); err != nil {
+			t.Errorf("expected a token signed with the second key to be valid, got %v", err)
+		}
+		if _, err := fetched.VerifyHeader("Bearer " + signWithKeyID("key-1", otherPrivateKey)); !errors.Is(err, propelauth.ErrTokenSignatureInvalid) {
+			t.Errorf("expected a token signed with a different key than its kid to be invalid, got %v", err)
+		}
+	})
+
+	t.Run("keys with an invalid exponent are rejected", func(t *testing.T) {
+		for _, exponent := range []string{"AQ", "Ag", "AQAA", "AQAAAAE"} {
+			key := jwk("bad-key", privateKey)
+			key["e"] = exponent
+			server := serveJWKS(key)
+
+			_, err := propelauth.FetchTokenVerifier(context.Background(), "issuertest", server.URL, propelauth.WithAllowInsecureHTTP())
+			if err == nil || !strings.Contains(err.Error(), "bad-key") {
+				t.Errorf("exponent %s: expected an error naming the key, got %v", exponent, err)
+			}
+			server.Close()
+		}
+	})
+
+	t.Run("options add checks on the claims", func(t *testing.T) {
+		now := time.Now()
+		sign := func(edit func(user *models.UserFromToken)) string {
+			claims := models.UserFromToken{UserID: user.UserID}
+			claims.RegisteredClaims = jwt.RegisteredClaims{
+				Issuer:    "issuertest",
+				IssuedAt:  jwt.NewNumericDate(now),
+				ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+			}
+			edit(&claims)
+			token, err := jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(privateKey)
+			if err != nil {
+				t.Fatalf("couldn't sign the token: %s", err)
+			}
+			return token
+		}
+
+		notYetValid := sign(func(user *models.UserFromToken) { user.NotBefore = jwt.NewNumericDate(now.Add(30 * time.Second)) })
+		justExpired := sign(func(user *models.UserFromToken) { user.ExpiresAt = jwt.NewNumericDate(now.Add(-10 * time.Second)) })
+		old := sign(func(user *models.UserFromToken) { user.IssuedAt = jwt.NewNumericDate(now.Add(-2 * time.Hour)) })
+		withoutIssuedAt := sign(func(user *models.UserFromToken) { user.IssuedAt = nil })
+		withoutExpiration := sign(func(user *models.UserFromToken) { user.ExpiresAt = nil })
+		withoutIssuer := sign(func(user *models.UserFromToken) { user.Issuer = "" })
+		forAPI := sign(func(user *models.UserFromToken) { user.Audience = jwt.ClaimStrings{"api"} })
+		withSaml := sign(func(user *models.UserFromToken) { user.LoginMethod = &models.LoginMethod{LoginMethod: "saml_sso"} })
+		withPassword := sign(func(user *models.UserFromToken) { user.LoginMethod = &models.LoginMethod{LoginMethod: "password"} })
+		impersonatorID := uuid.New()
+		impersonated := sign(func(user *models.UserFromToken) { user.ImpersonatorUserID = &impersonatorID })
+
+		newVerifier := func(opts ...propelauth.ClientOption) *propelauth.TokenVerifier {
+			verifier, err := propelauth.NewTokenVerifier(models.TokenVerificationMetadataInput{VerifierKey: publicKey, Issuer: "issuertest"}, opts...)
+			if err != nil {
+				t.Fatalf("NewTokenVerifier returned an error: %s", err)
+			}
+			return verifier
+		}
+		withLeeway := newVerifier(propelauth.WithTokenLeeway(time.Minute))
+		withMaxAge := newVerifier(propelauth.WithTokenMaxAge(time.Hour))
+		withAudience := newVerifier(propelauth.WithTokenAudience("api"))
+		withLoginMethods := newVerifier(propelauth.WithAllowedLoginMethods("password", "magic_link"))
+		withoutImpersonation := newVerifier(propelauth.WithoutImpersonation())
+
+		tests := []struct {
+			name        string
+			verifier    *propelauth.TokenVerifier
+			accessToken string
+			expected    error
+		}{
+			{"not valid yet", verifier, notYetValid, propelauth.ErrTokenNotValidYet},
+			{"not valid yet within the leeway", withLeeway, notYetValid, nil},
+			{"expired", verifier, justExpired, propelauth.ErrTokenExpired},
+			{"expired within the leeway", withLeeway, justExpired, nil},
+			{"too old", withMaxAge, old, propelauth.ErrTokenTooOld},
+			{"no iat with a max age", withMaxAge, withoutIssuedAt, propelauth.ErrTokenTooOld},
+			{"old without a max age", verifier, old, nil},
+			{"no exp", verifier, withoutExpiration, propelauth.ErrTokenMalformed},
+			{"no iss", verifier, withoutIssuer, propelauth.ErrTokenIssuerInvalid},
+			{"no iss with an audience", withAudience, withoutIssuer, propelauth.ErrTokenIssuerInvalid},
+			{"missing audience", withAudience, withSaml, propelauth.ErrTokenAudienceInvalid},
+			{"matching audience", withAudience, forAPI, nil},
+			{"login method not allowed", withLoginMethods, withSaml, propelauth.ErrTokenLoginMethodInvalid},
+			{"unknown login method", withLoginMethods, forAPI, propelauth.ErrTokenLoginMethodInvalid},
+			{"login method allowed", withLoginMethods, withPassword, nil},
+			{"impersonated", withoutImpersonation, impersonated, propelauth.ErrTokenImpersonated},
+			{"impersonation allowed by default", verifier, impersonated, nil},
+		}
+
+		for _, test := range tests {
+			_, err := test.verifier.VerifyToken(test.accessToken)
+			if (test.expected == nil && err != nil) || !errors.Is(err, test.expected) {
+				t.Errorf("%s: expected %v, got %v", test.name, test.expected, err)
+			}
+		}
+
+		client, err := propelauth.InitBaseAuth("https://auth.example.com", "apikey",
+			&models.TokenVerificationMetadataInput{VerifierKey: publicKey, Issuer: "issuertest"}, propelauth.WithoutImpersonation())
+		if err != nil {
+			t.Fatalf("InitBaseAuth returned an error: %s", err)
+		}
+		if _, err := client.GetUser("Bearer " + impersonated); !errors.Is(err, propelauth.ErrTokenImpersonated) {
+			t.Errorf("expected the client to apply the options, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,428 @@
+package client
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/propelauth/propelauth-go/pkg/models"
+)
+
+const defaultWebhookTolerance = 5 * time.Minute
+const maxWebhookBodySize = 1 << 20
+
+// WebhookHandler receives PropelAuth webhooks. It verifies each request's signature and timestamp, decodes the
+// event, and calls the callback registered for the event's type:
+//
+//	webhooks, err := propelauth.NewWebhookHandler(os.Getenv("PROPELAUTH_WEBHOOK_SECRET"))
+//	webhooks.OnUserCreated(func(ctx context.Context, event models.UserCreatedEvent) error {
+//		return sendWelcomeEmail(ctx, event.Email)
+//	})
+//	http.Handle("/webhooks/propelauth", webhooks)
+//
+// Webhooks are signed the way Svix signs them, with the svix-id, svix-timestamp and svix-signature headers.
+// Requests that are too old are rejected, and requests that were already handled are acknowledged without calling
+// the callback again, so a captured request can't be replayed. If a callback returns an error, the handler
+// responds with a 500 so the webhook is retried later. Callbacks can be registered while the handler is serving.
+type WebhookHandler struct {
+	signingSecrets []string
+	secrets        [][]byte
+	tolerance      time.Duration
+	now            func() time.Time
+	errorResponder ErrorResponder
+
+	callbacksMu sync.RWMutex
+	callbacks   map[models.WebhookEventType]func(ctx context.Context, body []byte) error
+	fallback    func(ctx context.Context, eventType models.WebhookEventType, body []byte) error
+
+	mu           sync.Mutex
+	handled      map[string]time.Time
+	handledOrder []handledWebhook
+	inFlight     map[string]struct{}
+}
+
+// handledWebhook is an entry in the order webhooks were handled in, so they can be forgotten oldest first.
+type handledWebhook struct {
+	messageID string
+	handledAt time.Time
+}
+
+// WebhookOption configures optional behavior of the WebhookHandler returned by NewWebhookHandler.
+type WebhookOption func(*WebhookHandler)
+
+// NewWebhookHandler creates a handler that verifies webhooks with the signing secret from your PropelAuth
+// dashboard, which looks like whsec_....
+func NewWebhookHandler(signingSecret string, opts ...WebhookOption) (*WebhookHandler, error) {
+	handler := &WebhookHandler{
+		signingSecrets: []string{signingSecret},
+		tolerance:      defaultWebhookTolerance,
+		now:            time.Now,
+		callbacks:      map[models.WebhookEventType]func(ctx context.Context, body []byte) error{},
+		errorResponder: DefaultWebhookErrorResponder,
+		handled:        map[string]time.Time{},
+		inFlight:       map[string]struct{}{},
+	}
+
+	for _, opt := range opts {
+		opt(handler)
+	}
+
+	for _, signingSecret := range handler.signingSecrets {
+		secret, err := decodeWebhookSecret(signingSecret)
+		if err != nil {
+			return nil, err
+		}
+		handler.secrets = append(handler.secrets, secret)
+	}
+
+	return handler, nil
+}
+
+// WithAdditionalWebhookSecret accepts webhooks signed with another secret too, for while you're rotating it.
+func WithAdditionalWebhookSecret(signingSecret string) WebhookOption {
+	return func(o *WebhookHandler) {
+		o.signingSecrets = append(o.signingSecrets, signingSecret)
+	}
+}
+
+// WithWebhookTolerance sets how far a webhook's timestamp can be from the current time, in either direction.
+// It defaults to 5 minutes.
+func WithWebhookTolerance(tolerance time.Duration) WebhookOption {
+	return func(o *WebhookHandler) {
+		o.tolerance = tolerance
+	}
+}
+
+// WithWebhookClock replaces the clock used to check timestamps, for tests.
+func WithWebhookClock(now func() time.Time) WebhookOption {
+	return func(o *WebhookHandler) {
+		o.now = now
+	}
+}
+
+// WithWebhookErrorResponder replaces the response written when a webhook can't be verified or handled.
+func WithWebhookErrorResponder(responder ErrorResponder) WebhookOption {
+	return func(o *WebhookHandler) {
+		o.errorResponder = responder
+	}
+}
+
+// Callbacks for each event type. Registering a callback for a type replaces the previous one.
+
+func (o *WebhookHandler) OnUserCreated(callback func(ctx context.Context, event models.UserCreatedEvent) error) {
+	onWebhookEvent(o, models.WebhookEventUserCreated, callback)
+}
+
+func (o *WebhookHandler) OnUserUpdated(callback func(ctx context.Context, event models.UserUpdatedEvent) error) {
+	onWebhookEvent(o, models.WebhookEventUserUpdated, callback)
+}
+
+func (o *WebhookHandler) OnUserDeleted(callback func(ctx context.Context, event models.UserEvent) error) {
+	onWebhookEvent(o, models.WebhookEventUserDeleted, callback)
+}
+
+func (o *WebhookHandler) OnUserEnabled(callback func(ctx context.Context, event models.UserEvent) error) {
+	onWebhookEvent(o, models.WebhookEventUserEnabled, callback)
+}
+
+func (o *WebhookHandler) OnUserDisabled(callback func(ctx context.Context, event models.UserEvent) error) {
+	onWebhookEvent(o, models.WebhookEventUserDisabled, callback)
+}
+
+func (o *WebhookHandler) OnUserLocked(callback func(ctx context.Context, event models.UserEvent) error) {
+	onWebhookEvent(o, models.WebhookEventUserLocked, callback)
+}
+
+func (o *WebhookHandler) OnUserAddedToOrg(callback func(ctx context.Context, event models.UserAddedToOrgEvent) error) {
+	onWebhookEvent(o, models.WebhookEventUserAddedToOrg, callback)
+}
+
+func (o *WebhookHandler) OnUserRemovedFromOrg(callback func(ctx context.Context, event models.UserRemovedFromOrgEvent) error) {
+	onWebhookEvent(o, models.WebhookEventUserRemovedFromOrg, callback)
+}
+
+func (o *WebhookHandler) OnUserRoleChangedInOrg(callback func(ctx context.Context, event models.UserRoleChangedInOrgEvent) error) {
+	onWebhookEvent(o, models.WebhookEventUserRoleChangedInOrg, callback)
+}
+
+func (o *WebhookHandler) OnOrgCreated(callback func(ctx context.Context, event models.OrgEvent) error) {
+	onWebhookEvent(o, models.WebhookEventOrgCreated, callback)
+}
+
+func (o *WebhookHandler) OnOrgUpdated(callback func(ctx context.Context, event models.OrgEvent) error) {
+	onWebhookEvent(o, models.WebhookEventOrgUpdated, callback)
+}
+
+func (o *WebhookHandler) OnOrgDeleted(callback func(ctx context.Context, event models.OrgEvent) error) {
+	onWebhookEvent(o, models.WebhookEventOrgDeleted, callback)
+}
+
+// OnOtherEvent registers a callback for events that don't have their own callback, including event types this
+// SDK doesn't know about yet. The body is the raw JSON of the event. Without it, those events are acknowledged
+// and ignored.
+func (o *WebhookHandler) OnOtherEvent(callback func(ctx context.Context, eventType models.WebhookEventType, body []byte) error) {
+	o.callbacksMu.Lock()
+	defer o.callbacksMu.Unlock()
+
+	o.fallback = callback
+}
+
+func onWebhookEvent[T any](o *WebhookHandler, eventType models.WebhookEventType, callback func(ctx context.Context, event T) error) {
+	o.callbacksMu.Lock()
+	defer o.callbacksMu.Unlock()
+
+	o.callbacks[eventType] = func(ctx context.Context, body []byte) error {
+		var event T
+		if err := json.Unmarshal(body, &event); err != nil {
+			return fmt.Errorf("Error on unmarshalling %s webhook: %w", eventType, err)
+		}
+		return callback(ctx, event)
+	}
+}
+
+// ServeHTTP verifies the webhook and calls its callback. It responds with a 401 if the signature or timestamp
+// is invalid, a 400 if the body isn't an event, and a 500 if the callback failed. Webhooks that were already
+// handled are acknowledged without calling the callback again, and ones that are being handled by another request
+// get a 409, so they're retried if that request fails.
+func (o *WebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeJSONError(w, http.StatusMethodNotAllowed, "method_not_allowed")
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBodySize+1))
+	if err != nil {
+		o.respondWithError(w, r, http.StatusBadRequest, fmt.Errorf("Error on reading webhook body: %w", err))
+		return
+	}
+	if len(body) > maxWebhookBodySize {
+		o.respondWithError(w, r, http.StatusRequestEntityTooLarge, fmt.Errorf("Webhook body is too large"))
+		return
+	}
+
+	if err := o.Verify(r.Header, body); err != nil {
+		o.respondWithError(w, r, http.StatusUnauthorized, err)
+		return
+	}
+
+	event := models.WebhookEvent{}
+	if err := json.Unmarshal(body, &event); err != nil || event.EventType == "" {
+		o.respondWithError(w, r, http.StatusBadRequest, fmt.Errorf("Webhook body isn't an event: %s", body))
+		return
+	}
+
+	messageID := webhookHeader(r.Header, "id")
+	switch o.reserve(messageID) {
+	case webhookAlreadyHandled:
+		w.WriteHeader(http.StatusNoContent)
+		return
+	case webhookInFlight:
+		o.respondWithError(w, r, http.StatusConflict, fmt.Errorf("Webhook %s is already being handled", messageID))
+		return
+	}
+
+	if err := o.dispatchReserved(r.Context(), messageID, event.EventType, body); err != nil {
+		o.respondWithError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Verify checks that the body was signed with one of the secrets, and that the timestamp is within the
+// tolerance. Use it directly if you aren't using ServeHTTP, passing in the raw, unparsed body.
+func (o *WebhookHandler) Verify(header http.Header, body []byte) error {
+	messageID := webhookHeader(header, "id")
+	timestamp := webhookHeader(header, "timestamp")
+	signatures := webhookHeader(header, "signature")
+	if messageID == "" || timestamp == "" || signatures == "" {
+		return fmt.Errorf("Missing webhook headers: %w", models.ErrWebhookSignatureInvalid)
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("Invalid webhook timestamp %q: %w", timestamp, models.ErrWebhookTimestampInvalid)
+	}
+	age := o.now().Sub(time.Unix(seconds, 0))
+	if age > o.tolerance || age < -o.tolerance {
+		return fmt.Errorf("Webhook timestamp is %s away from now: %w", age.Round(time.Second), models.ErrWebhookTimestampInvalid)
+	}
+
+	signedContent := []byte(messageID + "." + timestamp + "." + string(body))
+	for _, signature := range strings.Fields(signatures) {
+		version, encoded, ok := strings.Cut(signature, ",")
+		if !ok || version != "v1" {
+			continue
+		}
+		expected, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			continue
+		}
+
+		for _, secret := range o.secrets {
+			mac := hmac.New(sha256.New, secret)
+			mac.Write(signedContent)
+			if hmac.Equal(mac.Sum(nil), expected) {
+				return nil
+			}
+		}
+	}
+
+	return fmt.Errorf("No matching webhook signature: %w", models.ErrWebhookSignatureInvalid)
+}
+
+// SignWebhook returns the svix-signature header value for a webhook, for testing your handlers.
+func SignWebhook(signingSecret string, messageID string, timestamp time.Time, body []byte) (string, error) {
+	secret, err := decodeWebhookSecret(signingSecret)
+	if err != nil {
+		return "", err
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(messageID + "." + strconv.FormatInt(timestamp.Unix(), 10) + "." + string(body)))
+
+	return "v1," + base64.StdEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// dispatchReserved calls the callback for a webhook reserved with reserve. The webhook is marked handled if the
+// callback succeeds, and released otherwise, including if it panics, so a retry calls the callback again.
+func (o *WebhookHandler) dispatchReserved(ctx context.Context, messageID string, eventType models.WebhookEventType, body []byte) error {
+	succeeded := false
+	defer func() {
+		o.finish(messageID, succeeded)
+	}()
+
+	err := o.dispatch(ctx, eventType, body)
+	succeeded = err == nil
+
+	return err
+}
+
+func (o *WebhookHandler) dispatch(ctx context.Context, eventType models.WebhookEventType, body []byte) error {
+	o.callbacksMu.RLock()
+	callback, ok := o.callbacks[eventType]
+	fallback := o.fallback
+	o.callbacksMu.RUnlock()
+
+	if ok {
+		return callback(ctx, body)
+	}
+	if fallback != nil {
+		return fallback(ctx, eventType, body)
+	}
+
+	return nil
+}
+
+type webhookReservation int
+
+const (
+	webhookReserved webhookReservation = iota
+	webhookInFlight
+	webhookAlreadyHandled
+)
+
+// reserve marks the message ID as in flight, unless it's already in flight or was handled within the tolerance,
+// so only one request calls the callback for a webhook. Older webhooks are rejected for their timestamp, so
+// there's no need to remember them for longer.
+func (o *WebhookHandler) reserve(messageID string) webhookReservation {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if _, ok := o.inFlight[messageID]; ok {
+		return webhookInFlight
+	}
+	if handledAt, ok := o.handled[messageID]; ok && o.now().Sub(handledAt) <= 2*o.tolerance {
+		return webhookAlreadyHandled
+	}
+
+	o.inFlight[messageID] = struct{}{}
+
+	return webhookReserved
+}
+
+// finish releases a reserved message ID, remembering it as handled if the callback succeeded.
+func (o *WebhookHandler) finish(messageID string, handled bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	delete(o.inFlight, messageID)
+	if !handled {
+		return
+	}
+
+	// webhooks are handled in time order, so the ones that are too old to be replayed are all at the front
+	now := o.now()
+	expired := 0
+	for _, entry := range o.handledOrder {
+		if now.Sub(entry.handledAt) <= 2*o.tolerance {
+			break
+		}
+		// the webhook may have been handled again since, in which case it has a newer entry further back
+		if o.handled[entry.messageID].Equal(entry.handledAt) {
+			delete(o.handled, entry.messageID)
+		}
+		expired++
+	}
+	o.handledOrder = o.handledOrder[expired:]
+
+	o.handled[messageID] = now
+	o.handledOrder = append(o.handledOrder, handledWebhook{messageID: messageID, handledAt: now})
+}
+
+func (o *WebhookHandler) respondWithError(w http.ResponseWriter, r *http.Request, statusCode int, err error) {
+	o.errorResponder(w, r, &WebhookError{StatusCode: statusCode, Err: err})
+}
+
+// WebhookError is passed to the error responder when a webhook is rejected. StatusCode is the status the
+// default responder uses.
+type WebhookError struct {
+	StatusCode int
+	Err        error
+}
+
+func (e *WebhookError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *WebhookError) Unwrap() error {
+	return e.Err
+}
+
+// DefaultWebhookErrorResponder responds with the WebhookError's status code and a JSON error body.
+func DefaultWebhookErrorResponder(w http.ResponseWriter, r *http.Request, err error) {
+	webhookError := &WebhookError{StatusCode: http.StatusInternalServerError}
+	errors.As(err, &webhookError)
+
+	writeJSONError(w, webhookError.StatusCode, strings.ToLower(strings.ReplaceAll(http.StatusText(webhookError.StatusCode), " ", "_")))
+}
+
+func decodeWebhookSecret(signingSecret string) ([]byte, error) {
+	secret, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(signingSecret, "whsec_"))
+	if err != nil || len(secret) == 0 {
+		return nil, fmt.Errorf("Invalid webhook signing secret, it should look like whsec_...")
+	}
+
+	return secret, nil
+}
+
+// webhookHeader reads a svix- header, falling back to the webhook- headers from the Standard Webhooks spec,
+// which Svix also sends.
+func webhookHeader(header http.Header, name string) string {
+	if value := header.Get("svix-" + name); value != "" {
+		return value
+	}
+
+	return header.Get("webhook-" + name)
+}
@@ -0,0 +1,184 @@
+package client_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	propelauth "github.com/propelauth/propelauth-go/pkg"
+	"github.com/propelauth/propelauth-go/pkg/models"
+)
+
+func TestWebhookHandler(t *testing.T) {
+	// setup a handler that records the events it got
+
+	secret := "whsec_" + base64.StdEncoding.EncodeToString([]byte("a-very-secret-signing-key"))
+	now := time.Now()
+
+	handler, err := propelauth.NewWebhookHandler(secret, propelauth.WithWebhookClock(func() time.Time { return now }))
+	if err != nil {
+		t.Fatalf("NewWebhookHandler returned an error, cannot even begin the tests: %s", err)
+	}
+
+	createdUsers := []models.UserCreatedEvent{}
+	handler.OnUserCreated(func(ctx context.Context, event models.UserCreatedEvent) error {
+		createdUsers = append(createdUsers, event)
+		return nil
+	})
+	handler.OnOrgDeleted(func(ctx context.Context, event models.OrgEvent) error {
+		return errors.New("database is down")
+	})
+
+	userID := uuid.New()
+	userCreated := []byte(`{"event_type": "user.created", "user_id": "` + userID.String() + `", "email": "ada@example.com", "email_confirmed": true}`)
+
+	send := func(messageID string, timestamp time.Time, body []byte, signingSecret string) *httptest.ResponseRecorder {
+		signature, err := propelauth.SignWebhook(signingSecret, messageID, timestamp, body)
+		if err != nil {
+			t.Fatalf("SignWebhook returned an error: %s", err)
+		}
+
+		request := httptest.NewRequest("POST", "/webhooks", bytes.NewReader(body))
+		request.Header.Set("svix-id", messageID)
+		request.Header.Set("svix-timestamp", strconv.FormatInt(timestamp.Unix(), 10))
+		request.Header.Set("svix-signature", "v1,bm90LXRoZS1zaWduYXR1cmU= "+signature)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+
+		return recorder
+	}
+
+	// run tests
+
+	t.Run("signed events are decoded and dispatched", func(t *testing.T) {
+		recorder := send("msg_1", now, userCreated, secret)
+		if recorder.Code != 204 {
+			t.Fatalf("expected 204, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+		if len(createdUsers) != 1 || createdUsers[0].UserID != userID || createdUsers[0].Email != "ada@example.com" || !createdUsers[0].EmailConfirmed {
+			t.Errorf("expected the decoded event, got %+v", createdUsers)
+		}
+	})
+
+	t.Run("replayed events are acknowledged but not dispatched again", func(t *testing.T) {
+		recorder := send("msg_1", now, userCreated, secret)
+		if recorder.Code != 204 || len(createdUsers) != 1 {
+			t.Errorf("expected 204 without another callback, got %d and %d events", recorder.Code, len(createdUsers))
+		}
+	})
+
+	t.Run("events signed with another secret are rejected", func(t *testing.T) {
+		otherSecret := "whsec_" + base64.StdEncoding.EncodeToString([]byte("someone-else"))
+		recorder := send("msg_2", now, userCreated, otherSecret)
+		if recorder.Code != 401 || len(createdUsers) != 1 {
+			t.Errorf("expected 401, got %d", recorder.Code)
+		}
+	})
+
+	t.Run("tampered events are rejected", func(t *testing.T) {
+		signature, _ := propelauth.SignWebhook(secret, "msg_3", now, userCreated)
+		header := http.Header{}
+		header.Set("svix-id", "msg_3")
+		header.Set("svix-timestamp", strconv.FormatInt(now.Unix(), 10))
+		header.Set("svix-signature", signature)
+
+		err := handler.Verify(header, []byte(`{"event_type": "user.created"}`))
+		if !errors.Is(err, models.ErrWebhookSignatureInvalid) {
+			t.Errorf("expected ErrWebhookSignatureInvalid, got %v", err)
+		}
+	})
+
+	t.Run("old and future timestamps are rejected", func(t *testing.T) {
+		for _, timestamp := range []time.Time{now.Add(-10 * time.Minute), now.Add(10 * time.Minute)} {
+			recorder := send("msg_4", timestamp, userCreated, secret)
+			if recorder.Code != 401 || len(createdUsers) != 1 {
+				t.Errorf("expected 401 for %s, got %d", timestamp, recorder.Code)
+			}
+		}
+	})
+
+	t.Run("failed callbacks respond with a 500 so the event is retried", func(t *testing.T) {
+		orgDeleted := []byte(`{"event_type": "org.deleted", "org_id": "` + uuid.New().String() + `", "name": "Acme"}`)
+
+		for i := 0; i < 2; i++ {
+			recorder := send("msg_5", now, orgDeleted, secret)
+			if recorder.Code != 500 {
+				t.Errorf("expected 500 on attempt %d, got %d", i+1, recorder.Code)
+			}
+		}
+	})
+
+	t.Run("concurrent deliveries only call the callback once", func(t *testing.T) {
+		started := make(chan struct{})
+		finish := make(chan error)
+		var calls atomic.Int32
+		handler.OnOrgUpdated(func(ctx context.Context, event models.OrgEvent) error {
+			calls.Add(1)
+			started <- struct{}{}
+			return <-finish
+		})
+		orgUpdated := []byte(`{"event_type": "org.updated", "org_id": "` + uuid.New().String() + `", "name": "Acme"}`)
+
+		first := make(chan int)
+		go func() { first <- send("msg_7", now, orgUpdated, secret).Code }()
+		<-started
+
+		if recorder := send("msg_7", now, orgUpdated, secret); recorder.Code != 409 {
+			t.Errorf("expected 409 while the first delivery is in flight, got %d", recorder.Code)
+		}
+
+		// the first delivery fails, so a retry calls the callback again
+		finish <- errors.New("database is down")
+		if code := <-first; code != 500 {
+			t.Errorf("expected the first delivery to get a 500, got %d", code)
+		}
+
+		go func() { finish <- nil }()
+		retried := make(chan int)
+		go func() { retried <- send("msg_7", now, orgUpdated, secret).Code }()
+		<-started
+		if code := <-retried; code != 204 || calls.Load() != 2 {
+			t.Errorf("expected the retry to be handled, got %d after %d calls", code, calls.Load())
+		}
+
+		if recorder := send("msg_7", now, orgUpdated, secret); recorder.Code != 204 || calls.Load() != 2 {
+			t.Errorf("expected the handled event not to be dispatched again, got %d after %d calls", recorder.Code, calls.Load())
+		}
+	})
+
+	t.Run("events without a callback are acknowledged", func(t *testing.T) {
+		recorder := send("msg_6", now, []byte(`{"event_type": "user.impersonated"}`), secret)
+		if recorder.Code != 204 {
+			t.Errorf("expected 204, got %d", recorder.Code)
+		}
+	})
+
+	t.Run("handled events are forgotten once they're too old to be replayed", func(t *testing.T) {
+		before := now
+		defer func() { now = before }()
+
+		// msg_1 was handled in the first test, and its timestamp would be rejected by now
+		now = now.Add(11 * time.Minute)
+		handled := len(createdUsers)
+		if recorder := send("msg_1", now, userCreated, secret); recorder.Code != 204 || len(createdUsers) != handled+1 {
+			t.Errorf("expected the event to be dispatched again, got %d after %d events", recorder.Code, len(createdUsers))
+		}
+		if recorder := send("msg_1", now, userCreated, secret); recorder.Code != 204 || len(createdUsers) != handled+1 {
+			t.Errorf("expected the event handled again not to be dispatched a third time, got %d after %d events", recorder.Code, len(createdUsers))
+		}
+	})
+
+	t.Run("invalid secrets are rejected", func(t *testing.T) {
+		if _, err := propelauth.NewWebhookHandler("whsec_not base64!"); err == nil {
+			t.Errorf("expected an error for an invalid secret")
+		}
+	})
+}